@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/dragon-huang0403/todo-go/internal/controller"
@@ -17,12 +18,67 @@ import (
 func Start(ctx context.Context, config AppConfig, validator *validator.Validator) error {
 	wg, ctx := errgroup.WithContext(ctx)
 
-	db := db.New()
-	store := store.New(db)
-	controller := controller.New(store)
+	database, closeDB, err := newDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := closeDB(); err != nil {
+			logger.Error(ctx, "Failed to close database", zap.Error(err))
+		}
+	}()
+
+	if len(config.Database.Quotas) > 0 {
+		limiter, ok := database.(db.Limiter)
+		if !ok {
+			return fmt.Errorf("%s database can't take quotas: %w", config.Database.Driver, db.ErrNotSupported)
+		}
+		for model, quota := range config.Database.Quotas {
+			limiter.SetQuota(model, quota)
+		}
+	}
+
+	// the faults only reach the store, the database keeps running normally underneath
+	storeDB, err := withChaos(ctx, config.Chaos, database)
+	if err != nil {
+		return err
+	}
+
+	store, err := store.New(storeDB)
+	if err != nil {
+		return err
+	}
+	ctl := controller.New(store, config.Replication, config.Workflow)
+
+	if fileDB, ok := database.(*db.FileDatabase); ok {
+		wg.Go(func() error {
+			return fileDB.Run(ctx)
+		})
+	}
 
+	// a follower takes the expiries and purges of its primary, it only runs its own once promoted
 	wg.Go(func() error {
-		return httpserver.Start(ctx, config.HTTPServer, controller, validator)
+		if err := ctl.Replication.Follow(ctx); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		background, ctx := errgroup.WithContext(ctx)
+		if sweeper, ok := database.(db.Sweeper); ok {
+			background.Go(func() error {
+				return sweeper.RunSweeper(ctx)
+			})
+		}
+		background.Go(func() error {
+			return controller.RunTrashPurger(ctx, ctl.Task, config.Trash)
+		})
+		return background.Wait()
+	})
+
+	wg.Go(func() error {
+		return httpserver.Start(ctx, config.HTTPServer, ctl, validator)
 	})
 
 	<-ctx.Done()
@@ -46,3 +102,26 @@ func Start(ctx context.Context, config AppConfig, validator *validator.Validator
 
 	return nil
 }
+
+func newDatabase(config db.Config) (db.Database, func() error, error) {
+	switch config.Driver {
+	case db.DriverFile:
+		fileDB, err := db.NewFile(config.File, store.Schema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open file database: %w", err)
+		}
+		return fileDB, fileDB.Close, nil
+	case db.DriverSQL:
+		// the rows are queried in plain text for reporting
+		if config.File.Encryption.KeyFile != "" {
+			return nil, nil, fmt.Errorf("the sql database can't be encrypted, unset database.file.encryption.key_file")
+		}
+		sqlDB, err := db.NewSQL(config.SQL, store.Schema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open sql database: %w", err)
+		}
+		return sqlDB, sqlDB.Close, nil
+	default:
+		return db.New(), func() error { return nil }, nil
+	}
+}
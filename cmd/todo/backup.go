@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+)
+
+// runBackup saves a backup of a running server, the database is only opened by the server
+func runBackup(ctx context.Context, config AppConfig, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	server := flags.String("server", "http://"+config.HTTPServer.AddrPort, "url of the todo server")
+	output := flags.String("o", "", "backup file path, stdout by default")
+	token := flags.String("token", config.HTTPServer.AdminToken, "admin token of the server")
+	_ = flags.Parse(args)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *server+"/admin/backup", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request backup: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	if *output == "" {
+		_, err = io.Copy(os.Stdout, resp.Body)
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(*output)
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	return file.Close()
+}
+
+// runRestore sends a backup to a running server, which verifies it before restoring anything
+func runRestore(ctx context.Context, config AppConfig, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	server := flags.String("server", "http://"+config.HTTPServer.AddrPort, "url of the todo server")
+	input := flags.String("i", "", "backup file path, stdin by default")
+	policy := flags.String("policy", "fail", "what to do with a task which exists already: skip, overwrite or fail")
+	token := flags.String("token", config.HTTPServer.AdminToken, "admin token of the server")
+	_ = flags.Parse(args)
+
+	var body io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		body = file
+	}
+
+	query := url.Values{"policy": {*policy}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *server+"/admin/restore?"+query.Encode(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+*token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request restore: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	var result struct {
+		Data models.RestoreResult `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	fmt.Printf("restored: %d created, %d overwritten, %d skipped\n",
+		result.Data.Created, result.Data.Overwritten, result.Data.Skipped)
+	return nil
+}
+
+// responseError returns the message of a failed response
+func responseError(resp *http.Response) error {
+	var failure struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Message == "" {
+		return errors.New(resp.Status)
+	}
+
+	return fmt.Errorf("%s: %s", resp.Status, failure.Message)
+}
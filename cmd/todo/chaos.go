@@ -0,0 +1,24 @@
+//go:build !production
+
+package main
+
+import (
+	"context"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/db/chaos"
+	"github.com/dragon-huang0403/todo-go/internal/db/chaos/chaosconfig"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// withChaos injects faults into the database when config enables it
+func withChaos(ctx context.Context, config chaosconfig.Config, database db.Database) (db.Database, error) {
+	if !config.Enabled {
+		return database, nil
+	}
+
+	chaosDB := chaos.New(database, config)
+	logger.Warn(ctx, "injecting faults into the database", zap.Int64("seed", chaosDB.Seed()), zap.Any("rules", config.Rules))
+	return chaosDB, nil
+}
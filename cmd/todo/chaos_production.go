@@ -0,0 +1,20 @@
+//go:build production
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/db/chaos/chaosconfig"
+)
+
+// withChaos refuses to start with faults, they are left out of production builds
+func withChaos(_ context.Context, config chaosconfig.Config, database db.Database) (db.Database, error) {
+	if config.Enabled {
+		return nil, errors.New("chaos is not available in production builds")
+	}
+
+	return database, nil
+}
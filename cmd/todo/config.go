@@ -4,19 +4,32 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/db/chaos/chaosconfig"
 	httpserver "github.com/dragon-huang0403/todo-go/internal/http/server"
 	"github.com/dragon-huang0403/todo-go/pkg/config"
 )
 
 type AppConfig struct {
-	HTTPServer httpserver.Config `koanf:"http_server" validate:"required"`
-	Operation  OperationConfig   `koanf:"operation" validate:"required"`
+	HTTPServer  httpserver.Config            `koanf:"http_server" validate:"required"`
+	Database    db.Config                    `koanf:"database" validate:"required"`
+	Trash       controller.TrashConfig       `koanf:"trash" validate:"required"`
+	Replication controller.ReplicationConfig `koanf:"replication" validate:"required"`
+	Workflow    controller.WorkflowConfig    `koanf:"workflow" validate:"required"`
+	Operation   OperationConfig              `koanf:"operation" validate:"required"`
+	Chaos       chaosconfig.Config           `koanf:"chaos"`
 }
 
 func (AppConfig) Default() AppConfig {
 	return AppConfig{
-		HTTPServer: httpserver.Config{}.Default(),
-		Operation:  OperationConfig{}.Default(),
+		HTTPServer:  httpserver.Config{}.Default(),
+		Database:    db.Config{}.Default(),
+		Trash:       controller.TrashConfig{}.Default(),
+		Replication: controller.ReplicationConfig{}.Default(),
+		Workflow:    controller.WorkflowConfig{}.Default(),
+		Operation:   OperationConfig{}.Default(),
+		Chaos:       chaosconfig.Config{}.Default(),
 	}
 }
 
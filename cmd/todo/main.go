@@ -42,6 +42,30 @@ func main() {
 		log.Fatalf("failed to validate config: %v", err)
 	}
 
+	// the commands write to stdout, so they run before the logger
+	switch flag.Arg(0) {
+	case "backup":
+		if err := runBackup(ctx, *appConfig, flag.Args()[1:]); err != nil {
+			log.Fatalf("failed to backup: %v", err)
+		}
+		return
+	case "restore":
+		if err := runRestore(ctx, *appConfig, flag.Args()[1:]); err != nil {
+			log.Fatalf("failed to restore: %v", err)
+		}
+		return
+	case "migrate":
+		if err := runMigrate(ctx, *appConfig); err != nil {
+			log.Fatalf("failed to migrate: %v", err)
+		}
+		return
+	case "promote":
+		if err := runPromote(ctx, *appConfig, flag.Args()[1:]); err != nil {
+			log.Fatalf("failed to promote: %v", err)
+		}
+		return
+	}
+
 	ctx, err = logger.Init(ctx, appConfig.Operation.LogLevel)
 	if err != nil {
 		log.Fatalf("failed to create logger: %v", err)
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+)
+
+// runMigrate applies the migrations the sql database of the config misses
+func runMigrate(ctx context.Context, config AppConfig) error {
+	applied, err := db.Migrate(ctx, config.Database.SQL)
+	for _, m := range applied {
+		fmt.Printf("applied migration %04d %s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("schema is up to date")
+	}
+	return nil
+}
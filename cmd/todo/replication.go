@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+)
+
+// runPromote makes a running follower stop following its primary and take writes
+func runPromote(ctx context.Context, config AppConfig, args []string) error {
+	flags := flag.NewFlagSet("promote", flag.ExitOnError)
+	server := flags.String("server", "http://"+config.HTTPServer.AddrPort, "url of the follower to promote")
+	token := flags.String("token", config.HTTPServer.AdminToken, "admin token of the server")
+	_ = flags.Parse(args)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *server+"/admin/promote", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request promote: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	var result struct {
+		Data models.ReplicationStatus `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	fmt.Printf("promoted to %s at seq %d\n", result.Data.Role, result.Data.Seq)
+	return nil
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"io"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/dragon-huang0403/todo-go/internal/store"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ConflictPolicy tells a restore what to do with a record whose id is already used
+type ConflictPolicy = store.ConflictPolicy
+
+const (
+	ConflictSkip      = store.ConflictSkip
+	ConflictOverwrite = store.ConflictOverwrite
+	ConflictFail      = store.ConflictFail
+)
+
+type Admin interface {
+	// Backup streams a checksummed archive of every record to w
+	Backup(ctx context.Context, w io.Writer) error
+	// Restore applies an archive of Backup all at once, after verifying it
+	Restore(ctx context.Context, r io.Reader, policy ConflictPolicy) (*models.RestoreResult, error)
+	// Usage returns what every model takes along with its quota
+	Usage(ctx context.Context) []*models.Usage
+}
+
+type adminImpl struct {
+	store store.Store
+}
+
+func NewAdmin(store store.Store) Admin {
+	return &adminImpl{
+		store: store,
+	}
+}
+
+func (a *adminImpl) Backup(ctx context.Context, w io.Writer) error {
+	logger.Debug(ctx, "Backup")
+
+	if err := a.store.Backup(w); err != nil {
+		logger.Error(ctx, "Failed to backup", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (a *adminImpl) Restore(ctx context.Context, r io.Reader, policy ConflictPolicy) (*models.RestoreResult, error) {
+	logger.Debug(ctx, "Restore backup", zap.Any("policy", policy))
+
+	result, err := a.store.Restore(r, policy)
+	if err != nil {
+		logger.Error(ctx, "Failed to restore backup", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info(ctx, "Restored backup", zap.Any("result", result))
+	return result, nil
+}
+
+func (a *adminImpl) Usage(ctx context.Context) []*models.Usage {
+	logger.Debug(ctx, "Usage")
+
+	return a.store.Usage()
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		var buf bytes.Buffer
+
+		// stubs
+		m.mockStore.EXPECT().Backup(&buf).Return(nil)
+
+		// assert
+		err := m.controller.Admin.Backup(ctx, &buf)
+		require.NoError(t, err)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		r := strings.NewReader("archive")
+		expected := &models.RestoreResult{Created: 1, Skipped: 2}
+
+		// stubs
+		m.mockStore.EXPECT().Restore(r, ConflictSkip).Return(expected, nil)
+
+		// assert
+		result, err := m.controller.Admin.Restore(ctx, r, ConflictSkip)
+		require.NoError(t, err)
+		require.Equal(t, expected, result)
+	})
+
+	t.Run("invalid backup", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		r := strings.NewReader("archive")
+
+		// stubs
+		m.mockStore.EXPECT().Restore(r, ConflictFail).Return(nil, ErrInvalidBackup)
+
+		// assert
+		_, err := m.controller.Admin.Restore(ctx, r, ConflictFail)
+		require.ErrorIs(t, err, ErrInvalidBackup)
+	})
+}
+
+func TestUsage(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		expected := []*models.Usage{{Model: "task", Records: 2, Bytes: 100, MaxRecords: 10}}
+
+		// stubs
+		m.mockStore.EXPECT().Usage().Return(expected)
+
+		// assert
+		require.Equal(t, expected, m.controller.Admin.Usage(ctx))
+	})
+}
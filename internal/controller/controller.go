@@ -3,15 +3,28 @@ package controller
 import "github.com/dragon-huang0403/todo-go/internal/store"
 
 var (
-	ErrNotFound = store.ErrNotFound
+	ErrNotFound      = store.ErrNotFound
+	ErrConflict      = store.ErrConflict
+	ErrInvalidCursor = store.ErrInvalidCursor
+	ErrFeedTruncated = store.ErrFeedTruncated
+	ErrInvalidBackup = store.ErrInvalidBackup
+	ErrQuotaExceeded = store.ErrQuotaExceeded
+	// ErrProjectNotFound is an ErrNotFound of the project a task is put in
+	ErrProjectNotFound = store.ErrProjectNotFound
 )
 
 type Controller struct {
-	Task Task
+	Task        Task
+	Project     Project
+	Admin       Admin
+	Replication Replication
 }
 
-func New(store store.Store) *Controller {
+func New(store store.Store, replication ReplicationConfig, workflow WorkflowConfig) *Controller {
 	return &Controller{
-		Task: NewTask(store),
+		Task:        NewTask(store, workflow),
+		Project:     NewProject(store),
+		Admin:       NewAdmin(store),
+		Replication: NewReplication(store, replication),
 	}
 }
@@ -19,7 +19,7 @@ func setup(t *testing.T) *testMain {
 
 	mockStore := mock_store.NewMockStore(ctl)
 
-	controller := New(mockStore)
+	controller := New(mockStore, ReplicationConfig{}.Default(), WorkflowConfig{}.Default())
 
 	return &testMain{
 		controller: controller,
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/dragon-huang0403/todo-go/internal/store"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type Project interface {
+	Create(context.Context, CreateProjectParams) (*models.Project, error)
+	// Delete moves the project to the trash along with its tasks
+	Delete(context.Context, DeleteProjectParams) error
+	Get(context.Context, uuid.UUID) (*models.Project, error)
+	List(context.Context) ([]*models.Project, error)
+	Update(context.Context, UpdateProjectParams) (*models.Project, error)
+
+	ListTrash(context.Context) ([]*models.TrashedProject, error)
+	// Restore moves the project out of the trash along with the tasks deleted with it
+	Restore(context.Context, uuid.UUID) (*models.Project, error)
+	// Purge removes the project for good along with its tasks in the trash
+	Purge(context.Context, uuid.UUID) error
+}
+
+type projectImpl struct {
+	store store.Store
+}
+
+func NewProject(store store.Store) Project {
+	return &projectImpl{
+		store: store,
+	}
+}
+
+type CreateProjectParams struct {
+	Name        string
+	Description string
+}
+
+func (p *projectImpl) Create(ctx context.Context, params CreateProjectParams) (*models.Project, error) {
+	logger.Debug(ctx, "Create project", zap.Any("params", params))
+
+	project, err := p.store.CreateProject(store.CreateProjectParams(params))
+	if err != nil {
+		logger.Error(ctx, "Failed to create project", zap.Error(err))
+		return nil, err
+	}
+
+	return project, nil
+}
+
+type DeleteProjectParams struct {
+	ID       uuid.UUID
+	Versions []uint64
+}
+
+func (p *projectImpl) Delete(ctx context.Context, params DeleteProjectParams) error {
+	logger.Debug(ctx, "Delete project", zap.Any("params", params))
+
+	if err := p.store.DeleteProject(store.DeleteProjectParams(params)); err != nil {
+		logger.Error(ctx, "Failed to delete project", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (p *projectImpl) Get(ctx context.Context, id uuid.UUID) (*models.Project, error) {
+	logger.Debug(ctx, "Get project", zap.Any("id", id))
+
+	project, err := p.store.GetProject(id)
+	if err != nil {
+		logger.Error(ctx, "Failed to get project", zap.Error(err))
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (p *projectImpl) List(ctx context.Context) ([]*models.Project, error) {
+	logger.Debug(ctx, "List projects")
+
+	projects, err := p.store.ListProjects()
+	if err != nil {
+		logger.Error(ctx, "Failed to list projects", zap.Error(err))
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+type UpdateProjectParams struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	Versions    []uint64
+}
+
+func (p *projectImpl) Update(ctx context.Context, params UpdateProjectParams) (*models.Project, error) {
+	logger.Debug(ctx, "Update project", zap.Any("params", params))
+
+	project, err := p.store.UpdateProject(store.UpdateProjectParams(params))
+	if err != nil {
+		logger.Error(ctx, "Failed to update project", zap.Error(err))
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (p *projectImpl) ListTrash(ctx context.Context) ([]*models.TrashedProject, error) {
+	logger.Debug(ctx, "List trashed projects")
+
+	projects, err := p.store.ListTrashedProjects()
+	if err != nil {
+		logger.Error(ctx, "Failed to list trashed projects", zap.Error(err))
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func (p *projectImpl) Restore(ctx context.Context, id uuid.UUID) (*models.Project, error) {
+	logger.Debug(ctx, "Restore project", zap.Any("id", id))
+
+	project, err := p.store.RestoreProject(id)
+	if err != nil {
+		logger.Error(ctx, "Failed to restore project", zap.Error(err))
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (p *projectImpl) Purge(ctx context.Context, id uuid.UUID) error {
+	logger.Debug(ctx, "Purge project", zap.Any("id", id))
+
+	if err := p.store.PurgeProject(id); err != nil {
+		logger.Error(ctx, "Failed to purge project", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/dragon-huang0403/todo-go/internal/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProject(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		arg := CreateProjectParams{Name: gofakeit.Name(), Description: gofakeit.Sentence(5)}
+		expected := &models.Project{ID: uuid.New(), Name: arg.Name, Description: arg.Description, Version: 1}
+
+		// stubs
+		m.mockStore.EXPECT().CreateProject(store.CreateProjectParams(arg)).Return(expected, nil)
+
+		// assert
+		project, err := m.controller.Project.Create(ctx, arg)
+		require.NoError(t, err)
+		require.Equal(t, expected, project)
+	})
+
+	t.Run("update conflict", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		version := uint64(gofakeit.Number(1, 10))
+		arg := UpdateProjectParams{ID: uuid.New(), Name: gofakeit.Name(), Versions: []uint64{version}}
+
+		// stubs
+		m.mockStore.EXPECT().UpdateProject(store.UpdateProjectParams(arg)).Return(nil, store.ErrConflict)
+
+		// assert
+		project, err := m.controller.Project.Update(ctx, arg)
+		require.ErrorIs(t, err, ErrConflict)
+		require.Nil(t, project)
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		id := uuid.New()
+
+		// stubs
+		m.mockStore.EXPECT().DeleteProject(store.DeleteProjectParams{ID: id}).Return(store.ErrNotFound)
+
+		// assert
+		err := m.controller.Project.Delete(ctx, DeleteProjectParams{ID: id})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		expected := &models.Project{ID: uuid.New(), Name: gofakeit.Name()}
+		trashed := []*models.TrashedProject{{Project: *expected, DeletedAt: time.Now()}}
+
+		// stubs
+		m.mockStore.EXPECT().ListTrashedProjects().Return(trashed, nil)
+		m.mockStore.EXPECT().RestoreProject(expected.ID).Return(expected, nil)
+
+		// assert
+		list, err := m.controller.Project.ListTrash(ctx)
+		require.NoError(t, err)
+		require.Equal(t, trashed, list)
+
+		project, err := m.controller.Project.Restore(ctx, expected.ID)
+		require.NoError(t, err)
+		require.Equal(t, expected, project)
+	})
+}
+
+func TestMoveTask(t *testing.T) {
+	t.Run("project not found", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		projectID := uuid.New()
+		arg := MoveTaskParams{ID: uuid.New(), ProjectID: &projectID}
+
+		// stubs
+		m.mockStore.EXPECT().MoveTask(store.MoveTaskParams(arg)).Return(nil, store.ErrProjectNotFound)
+
+		// assert
+		task, err := m.controller.Task.Move(ctx, arg)
+		require.ErrorIs(t, err, ErrProjectNotFound)
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Nil(t, task)
+	})
+}
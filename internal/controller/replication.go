@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/dragon-huang0403/todo-go/internal/store"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotFollower is returned when promoting a server which is the primary already
+	ErrNotFollower = errors.New("not a follower")
+)
+
+type ReplicationConfig struct {
+	// Primary is the url of the server to follow, the server is the primary when it is empty
+	Primary string `koanf:"primary" validate:"omitempty,http_url"`
+	// PrimaryToken is the admin token of the primary
+	PrimaryToken string `koanf:"primary_token" validate:"required_with=Primary"`
+	// RetryInterval is how long a follower waits before reconnecting to its primary
+	RetryInterval time.Duration `koanf:"retry_interval" validate:"required"`
+	// Timeout is how long a follower waits for its primary to send anything before reconnecting,
+	// the primary sends a heartbeat every second
+	Timeout time.Duration `koanf:"timeout" validate:"required"`
+}
+
+func (ReplicationConfig) Default() ReplicationConfig {
+	return ReplicationConfig{
+		RetryInterval: time.Second,
+		Timeout:       5 * time.Second,
+	}
+}
+
+type Replication interface {
+	// Stream ships the changes after `after` to a follower until ctx is done,
+	// starting with a copy of every record when after is nil or those changes are gone
+	Stream(ctx context.Context, after *uint64, w io.Writer) error
+	// Follow applies the changes of the primary until ctx is done or the server is promoted,
+	// it returns at once on a primary
+	Follow(ctx context.Context) error
+	Status(ctx context.Context) (*models.ReplicationStatus, error)
+	// Promote stops following the primary and makes the server take writes
+	Promote(ctx context.Context) (*models.ReplicationStatus, error)
+	// ReadOnly reports whether the server refuses writes, which a follower does until it is promoted
+	ReadOnly() bool
+}
+
+type replicationImpl struct {
+	store  store.Store
+	config ReplicationConfig
+
+	mu         sync.Mutex
+	role       models.ReplicationRole
+	promoting  bool
+	connected  bool
+	primarySeq uint64
+	// lastContact is the last time the primary sent a change or a heartbeat
+	lastContact time.Time
+
+	// stop ends Follow, which closes stopped once it doesn't apply anything anymore
+	stop    context.CancelFunc
+	stopped chan struct{}
+}
+
+func NewReplication(store store.Store, config ReplicationConfig) Replication {
+	role := models.ReplicationPrimary
+	if config.Primary != "" {
+		role = models.ReplicationFollower
+	}
+
+	return &replicationImpl{
+		store:  store,
+		config: config,
+		role:   role,
+	}
+}
+
+func (r *replicationImpl) Stream(ctx context.Context, after *uint64, w io.Writer) error {
+	logger.Debug(ctx, "Stream changes", zap.Any("after", after))
+
+	if err := r.store.Replicate(ctx, after, w); err != nil {
+		logger.Warn(ctx, "Failed to stream changes", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *replicationImpl) Follow(ctx context.Context) error {
+	r.mu.Lock()
+	if r.role != models.ReplicationFollower || r.promoting {
+		r.mu.Unlock()
+		return nil
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	stopped := make(chan struct{})
+	defer close(stopped)
+	r.stop, r.stopped = stop, stopped
+	r.mu.Unlock()
+
+	logger.Info(ctx, "Following the primary", zap.String("primary", r.config.Primary))
+
+	// the first stream starts from a copy, as the database may hold anything before it
+	synced := false
+	for {
+		var after *uint64
+		if synced {
+			seq, err := r.store.Seq()
+			if err != nil {
+				return err
+			}
+			after = &seq
+		}
+
+		err := r.follow(ctx, after, func() { synced = true })
+		if ctx.Err() != nil {
+			return nil
+		}
+		logger.Warn(ctx, "Lost the primary, reconnecting", zap.Error(err), zap.Duration("retry_interval", r.config.RetryInterval))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.config.RetryInterval):
+		}
+	}
+}
+
+// follow applies one stream of the primary until it ends or stalls, synced is called once something is applied
+func (r *replicationImpl) follow(ctx context.Context, after *uint64, synced func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	url := r.config.Primary + "/admin/replication"
+	if after != nil {
+		url += "?after=" + strconv.FormatUint(*after, 10)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.config.PrimaryToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("primary answered %s", resp.Status)
+	}
+
+	stalled := time.AfterFunc(r.config.Timeout, cancel)
+	defer stalled.Stop()
+
+	r.mu.Lock()
+	r.connected = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.connected = false
+		r.mu.Unlock()
+	}()
+
+	body := &stallReader{r: resp.Body, timer: stalled, timeout: r.config.Timeout}
+	err = r.store.Follow(body, func(progress store.ReplicaProgress) {
+		synced()
+
+		r.mu.Lock()
+		r.primarySeq = progress.PrimarySeq
+		r.lastContact = time.Now().UTC()
+		r.mu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+
+	return errors.New("primary ended the stream")
+}
+
+// stallReader pushes back its timer on every read which gets something
+type stallReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.timer.Reset(s.timeout)
+	}
+
+	return n, err
+}
+
+func (r *replicationImpl) Status(ctx context.Context) (*models.ReplicationStatus, error) {
+	logger.Debug(ctx, "Replication status")
+
+	seq, err := r.store.Seq()
+	if err != nil {
+		logger.Error(ctx, "Failed to get the last change", zap.Error(err))
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := &models.ReplicationStatus{Role: r.role, Seq: seq}
+	if r.role == models.ReplicationFollower {
+		status.Primary = r.config.Primary
+		status.Connected = r.connected
+		status.PrimarySeq = max(r.primarySeq, seq)
+		status.Lag = status.PrimarySeq - seq
+		if !r.lastContact.IsZero() {
+			lastContact := r.lastContact
+			status.LastContact = &lastContact
+		}
+	}
+
+	return status, nil
+}
+
+func (r *replicationImpl) Promote(ctx context.Context) (*models.ReplicationStatus, error) {
+	logger.Debug(ctx, "Promote")
+
+	r.mu.Lock()
+	if r.role != models.ReplicationFollower || r.promoting {
+		r.mu.Unlock()
+		return nil, ErrNotFollower
+	}
+	r.promoting = true
+	stop, stopped := r.stop, r.stopped
+	r.mu.Unlock()
+
+	// the writes are only taken once nothing of the primary is applied anymore
+	if stop != nil {
+		stop()
+		<-stopped
+	}
+
+	r.mu.Lock()
+	r.role = models.ReplicationPrimary
+	r.promoting = false
+	r.mu.Unlock()
+
+	logger.Info(ctx, "Promoted to primary", zap.String("former_primary", r.config.Primary))
+	return r.Status(ctx)
+}
+
+func (r *replicationImpl) ReadOnly() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.role == models.ReplicationFollower
+}
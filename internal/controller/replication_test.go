@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationStatus(t *testing.T) {
+	t.Run("primary", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// stubs
+		m.mockStore.EXPECT().Seq().Return(uint64(3), nil)
+
+		// assert
+		status, err := m.controller.Replication.Status(ctx)
+		require.NoError(t, err)
+		require.Equal(t, &models.ReplicationStatus{Role: models.ReplicationPrimary, Seq: 3}, status)
+		require.False(t, m.controller.Replication.ReadOnly())
+	})
+
+	t.Run("follower", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		replication := NewReplication(m.mockStore, ReplicationConfig{Primary: "http://127.0.0.1:1"})
+
+		// stubs
+		m.mockStore.EXPECT().Seq().Return(uint64(3), nil)
+
+		// assert, nothing was heard from the primary yet
+		status, err := replication.Status(ctx)
+		require.NoError(t, err)
+		require.Equal(t, &models.ReplicationStatus{
+			Role:       models.ReplicationFollower,
+			Seq:        3,
+			Primary:    "http://127.0.0.1:1",
+			PrimarySeq: 3,
+		}, status)
+		require.True(t, replication.ReadOnly())
+	})
+}
+
+func TestPromote(t *testing.T) {
+	t.Run("primary", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// assert
+		_, err := m.controller.Replication.Promote(ctx)
+		require.ErrorIs(t, err, ErrNotFollower)
+	})
+
+	t.Run("follower", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange, the primary is unreachable so the follower keeps reconnecting
+		replication := NewReplication(m.mockStore, ReplicationConfig{
+			Primary:       "http://127.0.0.1:1",
+			RetryInterval: time.Hour,
+			Timeout:       time.Second,
+		})
+		followed := make(chan error, 1)
+		go func() {
+			followed <- replication.Follow(ctx)
+		}()
+
+		// stubs
+		m.mockStore.EXPECT().Seq().Return(uint64(0), nil)
+
+		// assert, promoting ends Follow
+		status, err := replication.Promote(ctx)
+		require.NoError(t, err)
+		require.Equal(t, models.ReplicationPrimary, status.Role)
+		require.False(t, replication.ReadOnly())
+		require.NoError(t, <-followed)
+
+		_, err = replication.Promote(ctx)
+		require.ErrorIs(t, err, ErrNotFollower)
+		require.NoError(t, replication.Follow(ctx))
+	})
+}
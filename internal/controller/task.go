@@ -2,6 +2,9 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/dragon-huang0403/todo-go/internal/models"
 	"github.com/dragon-huang0403/todo-go/internal/store"
@@ -12,30 +15,62 @@ import (
 
 type Task interface {
 	Create(context.Context, CreateTaskParams) (*models.Task, error)
-	Delete(context.Context, uuid.UUID) error
+	// Delete moves the task to the trash
+	Delete(context.Context, DeleteTaskParams) error
 	Get(context.Context, uuid.UUID) (*models.Task, error)
-	List(context.Context) ([]*models.Task, error)
+	List(context.Context, ListTasksParams) (*models.TaskPage, error)
+	Search(context.Context, SearchTasksParams) ([]*models.TaskMatch, error)
 	Update(context.Context, UpdateTaskParams) (*models.Task, error)
+	// Move moves the task to another project
+	Move(context.Context, MoveTaskParams) (*models.Task, error)
+	Watch(context.Context, WatchTasksParams) (<-chan models.TaskEvent, error)
+
+	ListTrash(context.Context) ([]*models.TrashedTask, error)
+	Restore(context.Context, uuid.UUID) (*models.Task, error)
+	Purge(context.Context, uuid.UUID) error
+	// PurgeTrash removes the tasks deleted before the given time for good
+	// along with the projects, see Project.Delete
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
 }
 
 type taskImpl struct {
-	store store.Store
+	store    store.Store
+	workflow workflow
 }
 
-func NewTask(store store.Store) Task {
+func NewTask(store store.Store, config WorkflowConfig) Task {
 	return &taskImpl{
-		store: store,
+		store:    store,
+		workflow: workflow{config: config},
 	}
 }
 
 type CreateTaskParams struct {
 	Name   string
 	Status models.TaskStatus
+	// State takes precedence over Status, which only moves the task to the state of the status
+	State     models.TaskState
+	ExpiresAt *time.Time
+
+	Description string
+	Priority    models.TaskPriority
+	StartAt     *time.Time
+	DueAt       *time.Time
+
+	ProjectID *uuid.UUID
 }
 
 func (t *taskImpl) Create(ctx context.Context, params CreateTaskParams) (*models.Task, error) {
 	logger.Debug(ctx, "Create task", zap.Any("params", params))
 
+	if params.State == "" {
+		params.State = params.Status.State("")
+	}
+	if err := t.workflow.checkCreate(params.State); err != nil {
+		logger.Debug(ctx, "Invalid state of task", zap.Error(err))
+		return nil, err
+	}
+
 	task, err := t.store.CreateTask(store.CreateTaskParams(params))
 	if err != nil {
 		logger.Error(ctx, "Failed to create task", zap.Error(err))
@@ -45,10 +80,15 @@ func (t *taskImpl) Create(ctx context.Context, params CreateTaskParams) (*models
 	return task, nil
 }
 
-func (t *taskImpl) Delete(ctx context.Context, id uuid.UUID) error {
-	logger.Debug(ctx, "Delete task", zap.Any("id", id))
+type DeleteTaskParams struct {
+	ID       uuid.UUID
+	Versions []uint64
+}
+
+func (t *taskImpl) Delete(ctx context.Context, params DeleteTaskParams) error {
+	logger.Debug(ctx, "Delete task", zap.Any("params", params))
 
-	if err := t.store.DeleteTask(id); err != nil {
+	if err := t.store.DeleteTask(store.DeleteTaskParams(params)); err != nil {
 		logger.Error(ctx, "Failed to delete task", zap.Error(err))
 		return err
 	}
@@ -68,32 +108,203 @@ func (t *taskImpl) Get(ctx context.Context, id uuid.UUID) (*models.Task, error)
 	return task, nil
 }
 
-func (t *taskImpl) List(ctx context.Context) ([]*models.Task, error) {
-	logger.Debug(ctx, "List tasks")
+// TaskFilter keeps the tasks matching every field which is set
+type TaskFilter = store.TaskFilter
+
+type ListTasksParams struct {
+	Cursor   string
+	Limit    int
+	Backward bool
+
+	Filter TaskFilter
+	// Sort is a comma separated list of fields, each prefixed by - to sort in descending order
+	Sort string
+	// Fields is a comma separated list of the fields to return
+	Fields string
+}
+
+// csvItems splits a comma separated list, empty has no items
+func csvItems(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
+
+func (p ListTasksParams) storeParams() store.ListTasksParams {
+	params := store.ListTasksParams{
+		Cursor:   p.Cursor,
+		Limit:    p.Limit,
+		Backward: p.Backward,
+		Filter:   p.Filter,
+	}
+	for _, item := range csvItems(p.Sort) {
+		field, descending := strings.CutPrefix(item, "-")
+		params.Sort = append(params.Sort, store.TaskSort{Field: models.TaskField(field), Descending: descending})
+	}
+	for _, item := range csvItems(p.Fields) {
+		params.Fields = append(params.Fields, models.TaskField(item))
+	}
+
+	return params
+}
+
+func (t *taskImpl) List(ctx context.Context, params ListTasksParams) (*models.TaskPage, error) {
+	logger.Debug(ctx, "List tasks", zap.Any("params", params))
 
-	tasks, err := t.store.ListTasks()
+	page, err := t.store.ListTasks(params.storeParams())
 	if err != nil {
 		logger.Error(ctx, "Failed to list tasks", zap.Error(err))
 		return nil, err
 	}
 
-	return tasks, nil
+	return page, nil
+}
+
+type SearchTasksParams struct {
+	Query string
+	Limit int
+}
+
+func (t *taskImpl) Search(ctx context.Context, params SearchTasksParams) ([]*models.TaskMatch, error) {
+	logger.Debug(ctx, "Search tasks", zap.Any("params", params))
+
+	matches, err := t.store.SearchTasks(store.SearchTasksParams(params))
+	if err != nil {
+		logger.Error(ctx, "Failed to search tasks", zap.Error(err))
+		return nil, err
+	}
+
+	return matches, nil
 }
 
 type UpdateTaskParams struct {
 	ID     uuid.UUID
 	Name   string
 	Status models.TaskStatus
+	// State takes precedence over Status, which only moves the task to the state of the status
+	State     models.TaskState
+	ExpiresAt *time.Time
+
+	Description string
+	Priority    models.TaskPriority
+	StartAt     *time.Time
+	DueAt       *time.Time
+
+	Versions []uint64
 }
 
 func (t *taskImpl) Update(ctx context.Context, params UpdateTaskParams) (*models.Task, error) {
 	logger.Debug(ctx, "Update task", zap.Any("params", params))
 
-	task, err := t.store.UpdateTask(store.UpdateTaskParams(params))
+	task, err := t.store.UpdateTask(store.UpdateTaskParams{
+		ID:          params.ID,
+		Name:        params.Name,
+		Status:      params.Status,
+		State:       params.State,
+		Transition:  t.workflow.check,
+		ExpiresAt:   params.ExpiresAt,
+		Description: params.Description,
+		Priority:    params.Priority,
+		StartAt:     params.StartAt,
+		DueAt:       params.DueAt,
+		Versions:    params.Versions,
+	})
 	if err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			logger.Debug(ctx, "Invalid transition of task", zap.Error(err))
+			return nil, err
+		}
 		logger.Error(ctx, "Failed to update task", zap.Error(err))
 		return nil, err
 	}
 
 	return task, nil
 }
+
+type MoveTaskParams struct {
+	ID        uuid.UUID
+	ProjectID *uuid.UUID
+	Versions  []uint64
+}
+
+func (t *taskImpl) Move(ctx context.Context, params MoveTaskParams) (*models.Task, error) {
+	logger.Debug(ctx, "Move task", zap.Any("params", params))
+
+	task, err := t.store.MoveTask(store.MoveTaskParams(params))
+	if err != nil {
+		logger.Error(ctx, "Failed to move task", zap.Error(err))
+		return nil, err
+	}
+
+	return task, nil
+}
+
+type WatchTasksParams struct {
+	After *uint64
+}
+
+// Watch streams the changes of tasks until ctx is done
+func (t *taskImpl) Watch(ctx context.Context, params WatchTasksParams) (<-chan models.TaskEvent, error) {
+	logger.Debug(ctx, "Watch tasks", zap.Any("params", params))
+
+	events, err := t.store.WatchTasks(ctx, store.WatchTasksParams(params))
+	if err != nil {
+		logger.Error(ctx, "Failed to watch tasks", zap.Error(err))
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (t *taskImpl) ListTrash(ctx context.Context) ([]*models.TrashedTask, error) {
+	logger.Debug(ctx, "List trashed tasks")
+
+	tasks, err := t.store.ListTrashedTasks()
+	if err != nil {
+		logger.Error(ctx, "Failed to list trashed tasks", zap.Error(err))
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (t *taskImpl) Restore(ctx context.Context, id uuid.UUID) (*models.Task, error) {
+	logger.Debug(ctx, "Restore task", zap.Any("id", id))
+
+	task, err := t.store.RestoreTask(id)
+	if err != nil {
+		logger.Error(ctx, "Failed to restore task", zap.Error(err))
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (t *taskImpl) Purge(ctx context.Context, id uuid.UUID) error {
+	logger.Debug(ctx, "Purge task", zap.Any("id", id))
+
+	if err := t.store.PurgeTask(id); err != nil {
+		logger.Error(ctx, "Failed to purge task", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (t *taskImpl) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	logger.Debug(ctx, "Purge trash", zap.Time("before", before))
+
+	purged, err := t.store.PurgeTrash(before)
+	if err != nil {
+		logger.Error(ctx, "Failed to purge trash", zap.Error(err))
+		return 0, err
+	}
+
+	return purged, nil
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/dragon-huang0403/todo-go/internal/store"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func TestCreateTask(t *testing.T) {
@@ -32,7 +34,11 @@ func TestCreateTask(t *testing.T) {
 		}
 
 		// stubs
-		m.mockStore.EXPECT().CreateTask(store.CreateTaskParams(arg)).Return(&expectedTask, nil)
+		m.mockStore.EXPECT().CreateTask(store.CreateTaskParams{
+			Name:   arg.Name,
+			Status: arg.Status,
+			State:  arg.Status.State(""),
+		}).Return(&expectedTask, nil)
 
 		// assert
 		task, err := m.controller.Task.Create(ctx, arg)
@@ -45,6 +51,16 @@ func TestCreateTask(t *testing.T) {
 		require.WithinDuration(t, expectedTask.CreatedAt, task.CreatedAt, time.Second)
 		require.WithinDuration(t, expectedTask.UpdatedAt, task.UpdatedAt, time.Second)
 	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// assert, the store isn't called
+		task, err := m.controller.Task.Create(ctx, CreateTaskParams{Name: gofakeit.Name(), State: models.TaskStateInReview})
+		require.ErrorIs(t, err, ErrInvalidTransition)
+		require.Nil(t, task)
+	})
 }
 
 func TestDeleteTask(t *testing.T) {
@@ -56,10 +72,10 @@ func TestDeleteTask(t *testing.T) {
 		id := uuid.New()
 
 		// stubs
-		m.mockStore.EXPECT().DeleteTask(id).Return(nil)
+		m.mockStore.EXPECT().DeleteTask(store.DeleteTaskParams{ID: id}).Return(nil)
 
 		// assert
-		err := m.controller.Task.Delete(ctx, id)
+		err := m.controller.Task.Delete(ctx, DeleteTaskParams{ID: id})
 		require.NoError(t, err)
 	})
 
@@ -71,12 +87,28 @@ func TestDeleteTask(t *testing.T) {
 		id := uuid.New()
 
 		// stubs
-		m.mockStore.EXPECT().DeleteTask(id).Return(store.ErrNotFound)
+		m.mockStore.EXPECT().DeleteTask(store.DeleteTaskParams{ID: id}).Return(store.ErrNotFound)
 
 		// assert
-		err := m.controller.Task.Delete(ctx, id)
+		err := m.controller.Task.Delete(ctx, DeleteTaskParams{ID: id})
 		require.ErrorIs(t, err, ErrNotFound)
 	})
+
+	t.Run("conflict", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		version := uint64(gofakeit.Number(1, 10))
+		arg := DeleteTaskParams{ID: uuid.New(), Versions: []uint64{version}}
+
+		// stubs
+		m.mockStore.EXPECT().DeleteTask(store.DeleteTaskParams(arg)).Return(store.ErrConflict)
+
+		// assert
+		err := m.controller.Task.Delete(ctx, arg)
+		require.ErrorIs(t, err, ErrConflict)
+	})
 }
 
 func TestGetTask(t *testing.T) {
@@ -147,17 +179,26 @@ func TestListTasks(t *testing.T) {
 			expectedTasks = append(expectedTasks, &task)
 		}
 
+		params := ListTasksParams{
+			Cursor: gofakeit.UUID(),
+			Limit:  n,
+		}
+		nextCursor := gofakeit.UUID()
+
 		// stubs
-		m.mockStore.EXPECT().ListTasks().Return(expectedTasks, nil)
+		m.mockStore.EXPECT().
+			ListTasks(store.ListTasksParams{Cursor: params.Cursor, Limit: params.Limit}).
+			Return(&models.TaskPage{Tasks: expectedTasks, NextCursor: nextCursor}, nil)
 
 		// assert
-		tasks, err := m.controller.Task.List(ctx)
+		page, err := m.controller.Task.List(ctx, params)
 		require.NoError(t, err)
-		require.NotNil(t, tasks)
-		require.Len(t, tasks, len(expectedTasks))
+		require.NotNil(t, page)
+		require.Equal(t, nextCursor, page.NextCursor)
+		require.Len(t, page.Tasks, len(expectedTasks))
 
 		for i, expectedTask := range expectedTasks {
-			task := tasks[i]
+			task := page.Tasks[i]
 			require.Equal(t, expectedTask.ID, task.ID)
 			require.Equal(t, expectedTask.Name, task.Name)
 			require.Equal(t, expectedTask.Status, task.Status)
@@ -167,6 +208,81 @@ func TestListTasks(t *testing.T) {
 	})
 }
 
+func TestListTasksQuery(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		status := models.TaskStatusCompleted
+		after := time.Now()
+		params := ListTasksParams{
+			Backward: true,
+			Filter:   TaskFilter{Status: &status, Name: gofakeit.Word(), CreatedAfter: &after},
+			Sort:     "status, -updated_at",
+			Fields:   "id,name",
+		}
+		expected := &models.TaskPage{Tasks: []*models.Task{}}
+
+		// stubs
+		m.mockStore.EXPECT().
+			ListTasks(store.ListTasksParams{
+				Backward: true,
+				Filter:   params.Filter,
+				Sort: []store.TaskSort{
+					{Field: models.TaskFieldStatus},
+					{Field: models.TaskFieldUpdatedAt, Descending: true},
+				},
+				Fields: []models.TaskField{models.TaskFieldID, models.TaskFieldName},
+			}).
+			Return(expected, nil)
+
+		// assert
+		page, err := m.controller.Task.List(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, expected, page)
+	})
+}
+
+func TestSearchTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		params := SearchTasksParams{
+			Query: gofakeit.Word(),
+			Limit: gofakeit.Number(1, 10),
+		}
+		expected := []*models.TaskMatch{{
+			Task:       &models.Task{ID: uuid.New(), Name: params.Query},
+			Score:      2,
+			Highlights: []models.Highlight{{Start: 0, End: len(params.Query)}},
+		}}
+
+		// stubs
+		m.mockStore.EXPECT().SearchTasks(store.SearchTasksParams(params)).Return(expected, nil)
+
+		// assert
+		matches, err := m.controller.Task.Search(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, expected, matches)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// stubs
+		m.mockStore.EXPECT().SearchTasks(gomock.Any()).Return(nil, gofakeit.Error())
+
+		// assert
+		matches, err := m.controller.Task.Search(ctx, SearchTasksParams{Query: gofakeit.Word()})
+		require.Error(t, err)
+		require.Nil(t, matches)
+	})
+}
+
 func TestUpdateTask(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		ctx := context.Background()
@@ -187,7 +303,7 @@ func TestUpdateTask(t *testing.T) {
 		}
 
 		// stubs
-		m.mockStore.EXPECT().UpdateTask(store.UpdateTaskParams(arg)).Return(&expectedTask, nil)
+		m.mockStore.EXPECT().UpdateTask(updateTaskParams(arg)).Return(&expectedTask, nil)
 
 		// assert
 		task, err := m.controller.Task.Update(ctx, arg)
@@ -201,6 +317,32 @@ func TestUpdateTask(t *testing.T) {
 		require.WithinDuration(t, expectedTask.UpdatedAt, task.UpdatedAt, time.Second)
 	})
 
+	t.Run("invalid transition", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		arg := UpdateTaskParams{
+			ID:    uuid.New(),
+			Name:  gofakeit.Name(),
+			State: models.TaskStateInReview,
+		}
+
+		// stubs
+		m.mockStore.EXPECT().UpdateTask(updateTaskParams(arg)).DoAndReturn(func(params store.UpdateTaskParams) (*models.Task, error) {
+			return nil, params.Transition(models.TaskStateTodo, params.State)
+		})
+
+		// assert
+		task, err := m.controller.Task.Update(ctx, arg)
+		require.ErrorIs(t, err, ErrInvalidTransition)
+		require.Nil(t, task)
+
+		var transition *TransitionError
+		require.ErrorAs(t, err, &transition)
+		require.Equal(t, WorkflowConfig{}.Default().Transitions[models.TaskStateTodo], transition.Allowed)
+	})
+
 	t.Run("not found", func(t *testing.T) {
 		ctx := context.Background()
 		m := setup(t)
@@ -212,11 +354,167 @@ func TestUpdateTask(t *testing.T) {
 		}
 
 		// stubs
-		m.mockStore.EXPECT().UpdateTask(store.UpdateTaskParams(arg)).Return(nil, store.ErrNotFound)
+		m.mockStore.EXPECT().UpdateTask(updateTaskParams(arg)).Return(nil, store.ErrNotFound)
 
 		// assert
 		task, err := m.controller.Task.Update(ctx, arg)
 		require.ErrorIs(t, err, ErrNotFound)
 		require.Nil(t, task)
 	})
+
+	t.Run("conflict", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		version := uint64(gofakeit.Number(1, 10))
+		arg := UpdateTaskParams{
+			ID:       uuid.New(),
+			Name:     gofakeit.Name(),
+			Versions: []uint64{version},
+		}
+
+		// stubs
+		m.mockStore.EXPECT().UpdateTask(updateTaskParams(arg)).Return(nil, store.ErrConflict)
+
+		// assert
+		task, err := m.controller.Task.Update(ctx, arg)
+		require.ErrorIs(t, err, ErrConflict)
+		require.Nil(t, task)
+	})
+}
+
+func TestWatchTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		after := uint64(gofakeit.Number(1, 10))
+		arg := WatchTasksParams{After: &after}
+		expected := make(chan models.TaskEvent)
+
+		// stubs
+		m.mockStore.EXPECT().WatchTasks(ctx, store.WatchTasksParams(arg)).Return(expected, nil)
+
+		// assert
+		events, err := m.controller.Task.Watch(ctx, arg)
+		require.NoError(t, err)
+		require.Equal(t, (<-chan models.TaskEvent)(expected), events)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// stubs
+		m.mockStore.EXPECT().WatchTasks(ctx, store.WatchTasksParams{}).Return(nil, store.ErrFeedTruncated)
+
+		// assert
+		events, err := m.controller.Task.Watch(ctx, WatchTasksParams{})
+		require.ErrorIs(t, err, ErrFeedTruncated)
+		require.Nil(t, events)
+	})
+}
+
+func TestTrash(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		expected := []*models.TrashedTask{{Task: models.Task{ID: uuid.New()}, DeletedAt: time.Now()}}
+
+		// stubs
+		m.mockStore.EXPECT().ListTrashedTasks().Return(expected, nil)
+
+		// assert
+		tasks, err := m.controller.Task.ListTrash(ctx)
+		require.NoError(t, err)
+		require.Equal(t, expected, tasks)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		expected := &models.Task{ID: uuid.New(), Name: gofakeit.Name()}
+
+		// stubs
+		m.mockStore.EXPECT().RestoreTask(expected.ID).Return(expected, nil)
+
+		// assert
+		task, err := m.controller.Task.Restore(ctx, expected.ID)
+		require.NoError(t, err)
+		require.Equal(t, expected, task)
+	})
+
+	t.Run("purge not found", func(t *testing.T) {
+		ctx := context.Background()
+		m := setup(t)
+
+		// arrange
+		id := uuid.New()
+
+		// stubs
+		m.mockStore.EXPECT().PurgeTask(id).Return(store.ErrNotFound)
+
+		// assert
+		err := m.controller.Task.Purge(ctx, id)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRunTrashPurger(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		m := setup(t)
+
+		// arrange
+		config := TrashConfig{Retention: time.Hour, PurgeInterval: time.Millisecond}
+
+		// stubs
+		m.mockStore.EXPECT().PurgeTrash(gomock.Any()).DoAndReturn(func(before time.Time) (int, error) {
+			require.WithinDuration(t, time.Now().Add(-config.Retention), before, time.Second)
+			cancel()
+			return 1, nil
+		}).MinTimes(1)
+
+		// assert
+		err := RunTrashPurger(ctx, m.controller.Task, config)
+		require.NoError(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		m := setup(t)
+
+		// assert
+		err := RunTrashPurger(context.Background(), m.controller.Task, TrashConfig{PurgeInterval: time.Millisecond})
+		require.NoError(t, err)
+	})
+}
+
+// updateTaskParams matches the store params of arg, which check the transitions
+func updateTaskParams(arg UpdateTaskParams) gomock.Matcher {
+	expected := store.UpdateTaskParams{
+		ID:          arg.ID,
+		Name:        arg.Name,
+		Status:      arg.Status,
+		State:       arg.State,
+		ExpiresAt:   arg.ExpiresAt,
+		Description: arg.Description,
+		Priority:    arg.Priority,
+		StartAt:     arg.StartAt,
+		DueAt:       arg.DueAt,
+		Versions:    arg.Versions,
+	}
+	return gomock.Cond(func(x any) bool {
+		params, ok := x.(store.UpdateTaskParams)
+		if !ok || params.Transition == nil {
+			return false
+		}
+		params.Transition = nil
+		return reflect.DeepEqual(expected, params)
+	})
 }
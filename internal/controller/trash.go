@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type TrashConfig struct {
+	// Retention is how long deleted tasks stay in the trash, zero keeps them forever
+	Retention     time.Duration `koanf:"retention" validate:"gte=0"`
+	PurgeInterval time.Duration `koanf:"purge_interval" validate:"required"`
+}
+
+func (TrashConfig) Default() TrashConfig {
+	return TrashConfig{
+		Retention:     30 * 24 * time.Hour,
+		PurgeInterval: time.Hour,
+	}
+}
+
+// RunTrashPurger purges the tasks which stayed in the trash longer than the retention
+// every purge interval. It blocks until the context is done.
+func RunTrashPurger(ctx context.Context, task Task, config TrashConfig) error {
+	if config.Retention == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(config.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			purged, err := task.PurgeTrash(ctx, now.Add(-config.Retention))
+			if err != nil {
+				// the next tick tries again
+				continue
+			}
+			if purged > 0 {
+				logger.Info(ctx, "Purged trash", zap.Int("count", purged))
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+)
+
+var (
+	// ErrInvalidTransition is returned when a task can't move to a state, see TransitionError
+	ErrInvalidTransition = errors.New("invalid transition")
+)
+
+type WorkflowConfig struct {
+	// Transitions are the states a task can move to from each state, a state left out can't be left.
+	// A task is created in todo or in a state todo can move to.
+	Transitions map[models.TaskState][]models.TaskState `koanf:"transitions" validate:"required,dive,keys,oneof=todo in_progress in_review blocked done cancelled,endkeys,dive,oneof=todo in_progress in_review blocked done cancelled"`
+}
+
+func (WorkflowConfig) Default() WorkflowConfig {
+	return WorkflowConfig{
+		Transitions: map[models.TaskState][]models.TaskState{
+			models.TaskStateTodo: {
+				models.TaskStateInProgress, models.TaskStateBlocked, models.TaskStateDone, models.TaskStateCancelled,
+			},
+			models.TaskStateInProgress: {
+				models.TaskStateTodo, models.TaskStateInReview, models.TaskStateBlocked, models.TaskStateDone, models.TaskStateCancelled,
+			},
+			models.TaskStateInReview: {
+				models.TaskStateInProgress, models.TaskStateBlocked, models.TaskStateDone, models.TaskStateCancelled,
+			},
+			models.TaskStateBlocked: {
+				models.TaskStateTodo, models.TaskStateInProgress, models.TaskStateCancelled,
+			},
+			models.TaskStateDone: {
+				models.TaskStateTodo, models.TaskStateInProgress,
+			},
+			models.TaskStateCancelled: {
+				models.TaskStateTodo,
+			},
+		},
+	}
+}
+
+// TransitionError is an ErrInvalidTransition along with the states the task can move to
+type TransitionError struct {
+	// From is empty when creating a task
+	From    models.TaskState
+	To      models.TaskState
+	Allowed []models.TaskState
+}
+
+func (e *TransitionError) Error() string {
+	if e.From == "" {
+		return fmt.Sprintf("%s: a task can't be created in %s", ErrInvalidTransition, e.To)
+	}
+	return fmt.Sprintf("%s: a task can't move from %s to %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// workflow enforces the transitions of the config
+type workflow struct {
+	config WorkflowConfig
+}
+
+// check fails with a TransitionError unless a task in from can move to to,
+// moving to the same state is always allowed
+func (w workflow) check(from, to models.TaskState) error {
+	if from == to {
+		return nil
+	}
+
+	allowed := w.config.Transitions[from]
+	if slices.Contains(allowed, to) {
+		return nil
+	}
+	return &TransitionError{From: from, To: to, Allowed: slices.Clone(allowed)}
+}
+
+// checkCreate fails with a TransitionError unless a task can be created in state
+func (w workflow) checkCreate(state models.TaskState) error {
+	if err := w.check(models.TaskStateTodo, state); err != nil {
+		allowed := append([]models.TaskState{models.TaskStateTodo}, w.config.Transitions[models.TaskStateTodo]...)
+		return &TransitionError{To: state, Allowed: allowed}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflow(t *testing.T) {
+	w := workflow{config: WorkflowConfig{
+		Transitions: map[models.TaskState][]models.TaskState{
+			models.TaskStateTodo:       {models.TaskStateInProgress},
+			models.TaskStateInProgress: {models.TaskStateDone, models.TaskStateBlocked},
+		},
+	}}
+
+	t.Run("check", func(t *testing.T) {
+		require.NoError(t, w.check(models.TaskStateTodo, models.TaskStateInProgress))
+		require.NoError(t, w.check(models.TaskStateDone, models.TaskStateDone))
+
+		err := w.check(models.TaskStateTodo, models.TaskStateDone)
+		require.ErrorIs(t, err, ErrInvalidTransition)
+		require.EqualError(t, err, "invalid transition: a task can't move from todo to done")
+
+		var transition *TransitionError
+		require.ErrorAs(t, err, &transition)
+		require.Equal(t, []models.TaskState{models.TaskStateInProgress}, transition.Allowed)
+
+		// a state left out of the config can't be left
+		err = w.check(models.TaskStateDone, models.TaskStateTodo)
+		require.ErrorAs(t, err, &transition)
+		require.Empty(t, transition.Allowed)
+	})
+
+	t.Run("check create", func(t *testing.T) {
+		require.NoError(t, w.checkCreate(models.TaskStateTodo))
+		require.NoError(t, w.checkCreate(models.TaskStateInProgress))
+
+		err := w.checkCreate(models.TaskStateDone)
+		require.EqualError(t, err, "invalid transition: a task can't be created in done")
+
+		var transition *TransitionError
+		require.ErrorAs(t, err, &transition)
+		require.Equal(t, []models.TaskState{models.TaskStateTodo, models.TaskStateInProgress}, transition.Allowed)
+	})
+}
@@ -0,0 +1,311 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidBackup means a backup is corrupted, truncated or of an unknown version,
+	// nothing of it has been restored
+	ErrInvalidBackup = errors.New("invalid backup")
+	// ErrInvalidPolicy is returned for an unknown ConflictPolicy
+	ErrInvalidPolicy = errors.New("invalid conflict policy")
+)
+
+// backups of version 1 only hold the live records, version 2 adds the trashed ones
+const (
+	backupFormat  = "todo-backup"
+	backupVersion = 2
+)
+
+// a backup is a header frame, a frame for every record by model then create order,
+// and a footer frame with the checksum of every frame before it
+type backupFrame struct {
+	Header *backupHeader `json:"header,omitempty"`
+	Record *backupRecord `json:"record,omitempty"`
+	Footer *backupFooter `json:"footer,omitempty"`
+}
+
+type backupHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// Seq is the sequence of the last change in the backup
+	Seq       uint64    `json:"seq"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type backupRecord struct {
+	Model Model           `json:"model"`
+	ID    uuid.UUID       `json:"id"`
+	Value json.RawMessage `json:"value"`
+	// DeletedAt is only set on trashed records
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type backupFooter struct {
+	Count int `json:"count"`
+	// Checksum is the hex SHA-256 of the frames before the footer
+	Checksum string `json:"checksum"`
+}
+
+// backupWriter writes frames and sums them up for the footer
+type backupWriter struct {
+	w     io.Writer
+	hash  hash.Hash
+	count int
+}
+
+func (b *backupWriter) write(frame backupFrame) error {
+	buf, err := encodeFrame(frame)
+	if err != nil {
+		return err
+	}
+	if _, err := b.w.Write(buf); err != nil {
+		return err
+	}
+
+	b.hash.Write(buf)
+	if frame.Record != nil {
+		b.count++
+	}
+	return nil
+}
+
+// WriteBackup streams the records of every model to w, trashed ones included. It reads a snapshot,
+// so writes go on meanwhile and none of them is half in the backup.
+func WriteBackup(database Snapshotter, w io.Writer) error {
+	snapshot, err := database.OpenSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	b := &backupWriter{w: w, hash: sha256.New()}
+	header := backupHeader{Format: backupFormat, Version: backupVersion, Seq: snapshot.Seq(), CreatedAt: time.Now().UTC()}
+	if err := b.write(backupFrame{Header: &header}); err != nil {
+		return err
+	}
+
+	for _, model := range snapshot.models() {
+		entries, err := snapshot.entries(model)
+		if err != nil {
+			return err
+		}
+		trashed, err := snapshot.trash(model)
+		if err != nil {
+			return err
+		}
+
+		// the trashed records are merged into the create order, so a restore gives them their place back
+		entries = append(entries, trashed...)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].position < entries[j].position })
+
+		for _, entry := range entries {
+			value, err := json.Marshal(entry.value)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s %s: %w", model, entry.id, err)
+			}
+			record := &backupRecord{Model: model, ID: entry.id, Value: value}
+			if !entry.deletedAt.IsZero() {
+				record.DeletedAt = &entry.deletedAt
+			}
+			if err := b.write(backupFrame{Record: record}); err != nil {
+				return err
+			}
+		}
+	}
+
+	footer := backupFooter{Count: b.count, Checksum: hex.EncodeToString(b.hash.Sum(nil))}
+	buf, err := encodeFrame(backupFrame{Footer: &footer})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// ConflictPolicy tells a restore what to do with a record whose id is already used
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the record of the database
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the record of the database at its position, live or trashed as in the backup
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail aborts the restore with ErrAlreadyExists
+	ConflictFail ConflictPolicy = "fail"
+)
+
+func (p ConflictPolicy) validate() error {
+	switch p {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidPolicy, p)
+}
+
+// RestoreResult counts the records of a backup by what the restore did with them
+type RestoreResult struct {
+	Created     int
+	Overwritten int
+	Skipped     int
+}
+
+type restoredRecord struct {
+	model     Model
+	id        uuid.UUID
+	value     interface{}
+	deletedAt time.Time
+}
+
+// readBackup decodes and verifies the whole backup
+func readBackup(r io.Reader, schema Schema) ([]restoredRecord, error) {
+	var header *backupHeader
+	var footer *backupFooter
+	records := []restoredRecord{}
+	sum := sha256.New()
+
+	_, err := readFrames(r, func(payload []byte) error {
+		var frame backupFrame
+		if err := json.Unmarshal(payload, &frame); err != nil {
+			return err
+		}
+
+		switch {
+		case header == nil:
+			if frame.Header == nil || frame.Header.Format != backupFormat {
+				return errors.New("missing header")
+			}
+			if frame.Header.Version < 1 || frame.Header.Version > backupVersion {
+				return fmt.Errorf("unsupported version %d", frame.Header.Version)
+			}
+			header = frame.Header
+		case footer != nil:
+			return errors.New("data after footer")
+		case frame.Footer != nil:
+			footer = frame.Footer
+			return nil
+		case frame.Record != nil:
+			value, err := schema.decode(frame.Record.Model, frame.Record.Value)
+			if err != nil {
+				return fmt.Errorf("failed to decode %s %s: %w", frame.Record.Model, frame.Record.ID, err)
+			}
+			restored := restoredRecord{model: frame.Record.Model, id: frame.Record.ID, value: value}
+			if frame.Record.DeletedAt != nil {
+				restored.deletedAt = *frame.Record.DeletedAt
+			}
+			records = append(records, restored)
+		default:
+			return errors.New("unknown frame")
+		}
+
+		sum.Write(newFrame(payload))
+		return nil
+	})
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidBackup)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+	}
+
+	switch {
+	case header == nil:
+		return nil, fmt.Errorf("%w: empty", ErrInvalidBackup)
+	case footer == nil:
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidBackup)
+	case footer.Count != len(records):
+		return nil, fmt.Errorf("%w: %d records instead of %d", ErrInvalidBackup, len(records), footer.Count)
+	case footer.Checksum != hex.EncodeToString(sum.Sum(nil)):
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidBackup)
+	}
+
+	return records, nil
+}
+
+// RestoreBackup verifies the whole backup, then creates its records in a single transaction,
+// so either every record is restored or none. Restored records start again at version 1,
+// the trashed ones go back to the trash, and policy handles the ids which are already used, live or trashed.
+func RestoreBackup(database Database, schema Schema, r io.Reader, policy ConflictPolicy) (RestoreResult, error) {
+	if err := policy.validate(); err != nil {
+		return RestoreResult{}, err
+	}
+
+	records, err := readBackup(r, schema)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+
+	var result RestoreResult
+	err = database.RunInTx(func(tx Tx) error {
+		result = RestoreResult{}
+		for _, item := range records {
+			restored, err := restoreRecord(tx, item, policy)
+			if err != nil {
+				return err
+			}
+
+			switch restored {
+			case opCreate:
+				result.Created++
+			case opUpdate:
+				result.Overwritten++
+			default:
+				result.Skipped++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RestoreResult{}, err
+	}
+
+	return result, nil
+}
+
+// restoreRecord returns opCreate or opUpdate for the write it did, empty when it skipped the record
+func restoreRecord(tx Tx, item restoredRecord, policy ConflictPolicy) (opKind, error) {
+	err := tx.Create(item.model, item.id, item.value)
+	if err == nil {
+		return opCreate, trashRestored(tx, item)
+	}
+	if !errors.Is(err, ErrAlreadyExists) {
+		return "", err
+	}
+
+	switch policy {
+	case ConflictSkip:
+		return "", nil
+	case ConflictFail:
+		return "", fmt.Errorf("%w: %s %s", ErrAlreadyExists, item.model, item.id)
+	}
+
+	// a record in the trash is restored first, so it keeps its position in the create order
+	if err := tx.Restore(item.model, item.id); err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	if err := tx.Update(item.model, item.id, item.value); err != nil {
+		return "", err
+	}
+	return opUpdate, trashRestored(tx, item)
+}
+
+// trashRestored puts a restored record back in the trash when it was trashed in the backup
+func trashRestored(tx Tx, item restoredRecord) error {
+	if item.deletedAt.IsZero() {
+		return nil
+	}
+
+	return tx.TrashAt(item.model, item.id, item.deletedAt)
+}
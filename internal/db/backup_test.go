@@ -0,0 +1,214 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestBackup(t *testing.T, db Snapshotter) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, WriteBackup(db, &buf))
+	return buf.Bytes()
+}
+
+// reframe rewrites the frames of a backup with edit, fixing their checksums but not the one of the footer.
+// The frames edit empties are dropped.
+func reframe(t *testing.T, backup []byte, edit func(frame *backupFrame)) []byte {
+	var out bytes.Buffer
+	_, err := readFrames(bytes.NewReader(backup), func(payload []byte) error {
+		var frame backupFrame
+		require.NoError(t, json.Unmarshal(payload, &frame))
+		edit(&frame)
+		if frame == (backupFrame{}) {
+			return nil
+		}
+
+		buf, err := encodeFrame(frame)
+		require.NoError(t, err)
+		out.Write(buf)
+		return nil
+	})
+	require.NoError(t, err)
+
+	return out.Bytes()
+}
+
+func TestBackup(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2, 3)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 10}))
+		require.NoError(t, db.Trash(Task, ids[1]))
+		backup := writeTestBackup(t, db)
+
+		expectedTrash, err := db.ListTrash(Task)
+		require.NoError(t, err)
+
+		// assert, the trashed record goes back to the trash
+		restored := New()
+		result, err := RestoreBackup(restored, testSchema, bytes.NewReader(backup), ConflictFail)
+		require.NoError(t, err)
+		require.Equal(t, RestoreResult{Created: 3}, result)
+		require.Equal(t, []int{10, 3}, counts(t, restored))
+
+		v, err := restored.Get(Task, ids[2])
+		require.NoError(t, err)
+		require.Equal(t, &testValue{Count: 3}, v)
+		_, err = restored.Get(Task, ids[1])
+		require.ErrorIs(t, err, ErrNotFound)
+
+		trashed, err := restored.ListTrash(Task)
+		require.NoError(t, err)
+		require.Len(t, trashed, 1)
+		require.Equal(t, ids[1], trashed[0].ID)
+		require.True(t, expectedTrash[0].DeletedAt.Equal(trashed[0].DeletedAt))
+
+		// and it comes back to its place in the create order
+		require.NoError(t, restored.Restore(Task, ids[1]))
+		require.Equal(t, []int{10, 2, 3}, counts(t, restored))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		backup := writeTestBackup(t, New())
+
+		// assert
+		result, err := RestoreBackup(New(), testSchema, bytes.NewReader(backup), ConflictFail)
+		require.NoError(t, err)
+		require.Equal(t, RestoreResult{}, result)
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2, 3)
+		backup := writeTestBackup(t, db)
+
+		prepare := func(t *testing.T) Database {
+			target := New()
+			require.NoError(t, target.Create(Task, ids[0], &testValue{Count: 10}))
+			require.NoError(t, target.Create(Task, ids[1], &testValue{Count: 20}))
+			require.NoError(t, target.Trash(Task, ids[1]))
+			createCounts(t, target, 40)
+			return target
+		}
+
+		// assert
+		target := prepare(t)
+		result, err := RestoreBackup(target, testSchema, bytes.NewReader(backup), ConflictSkip)
+		require.NoError(t, err)
+		require.Equal(t, RestoreResult{Created: 1, Skipped: 2}, result)
+		require.Equal(t, []int{10, 40, 3}, counts(t, target))
+
+		// the trashed record is taken out of the trash at its position
+		target = prepare(t)
+		result, err = RestoreBackup(target, testSchema, bytes.NewReader(backup), ConflictOverwrite)
+		require.NoError(t, err)
+		require.Equal(t, RestoreResult{Created: 1, Overwritten: 2}, result)
+		require.Equal(t, []int{1, 2, 40, 3}, counts(t, target))
+		trashed, err := target.ListTrash(Task)
+		require.NoError(t, err)
+		require.Empty(t, trashed)
+
+		// nothing is restored when a record fails
+		target = prepare(t)
+		_, err = RestoreBackup(target, testSchema, bytes.NewReader(backup), ConflictFail)
+		require.ErrorIs(t, err, ErrAlreadyExists)
+		require.ErrorIs(t, err, ErrConflict)
+		require.Equal(t, []int{10, 40}, counts(t, target))
+
+		_, err = RestoreBackup(target, testSchema, bytes.NewReader(backup), "merge")
+		require.ErrorIs(t, err, ErrInvalidPolicy)
+	})
+
+	t.Run("overwrite with trashed", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[1]))
+		backup := writeTestBackup(t, db)
+		expectedTrash, err := db.ListTrash(Task)
+		require.NoError(t, err)
+
+		target := New()
+		require.NoError(t, target.Create(Task, ids[0], &testValue{Count: 10}))
+		require.NoError(t, target.Create(Task, ids[1], &testValue{Count: 20}))
+		require.NoError(t, target.Trash(Task, ids[1]))
+		createCounts(t, target, 30)
+
+		// assert, both go to the trash as of the backup and keep their position
+		result, err := RestoreBackup(target, testSchema, bytes.NewReader(backup), ConflictOverwrite)
+		require.NoError(t, err)
+		require.Equal(t, RestoreResult{Overwritten: 2}, result)
+		require.Equal(t, []int{30}, counts(t, target))
+
+		trashed, err := target.ListTrash(Task)
+		require.NoError(t, err)
+		require.Len(t, trashed, 2)
+		for i, item := range trashed {
+			require.Equal(t, expectedTrash[i].ID, item.ID)
+			require.Equal(t, expectedTrash[i].Value.(*testValue).Count, item.Value.(*testValue).Count)
+			require.True(t, expectedTrash[i].DeletedAt.Equal(item.DeletedAt))
+		}
+
+		require.NoError(t, target.Restore(Task, ids[0]))
+		require.NoError(t, target.Restore(Task, ids[1]))
+		require.Equal(t, []int{1, 2, 30}, counts(t, target))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		createCounts(t, db, 1, 2)
+		backup := writeTestBackup(t, db)
+
+		corrupted := bytes.Clone(backup)
+		corrupted[len(corrupted)/2] ^= 0xff
+
+		tampered := reframe(t, backup, func(frame *backupFrame) {
+			if frame.Record != nil {
+				frame.Record.Value = json.RawMessage(`{"count":100}`)
+			}
+		})
+		newer := reframe(t, backup, func(frame *backupFrame) {
+			if frame.Header != nil {
+				frame.Header.Version = backupVersion + 1
+			}
+		})
+		unknown := reframe(t, backup, func(frame *backupFrame) {
+			if frame.Record != nil {
+				frame.Record.Model = "unknown"
+			}
+		})
+		extra := append(bytes.Clone(backup), backup...)
+
+		// assert
+		cases := map[string][]byte{
+			"empty":     nil,
+			"truncated": backup[:len(backup)-1],
+			"no footer": reframe(t, backup, func(frame *backupFrame) { frame.Footer = nil }),
+			"corrupted": corrupted,
+			"tampered":  tampered,
+			"version":   newer,
+			"model":     unknown,
+			"extra":     extra,
+		}
+		for name, backup := range cases {
+			target := New()
+			require.NoError(t, target.Create(Task, uuid.New(), &testValue{Count: 5}))
+
+			_, err := RestoreBackup(target, testSchema, bytes.NewReader(backup), ConflictOverwrite)
+			require.ErrorIs(t, err, ErrInvalidBackup, name)
+			require.Equal(t, []int{5}, counts(t, target), name)
+		}
+	})
+}
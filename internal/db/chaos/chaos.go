@@ -0,0 +1,322 @@
+//go:build !production
+
+// Package chaos wraps a database to inject latency and failures into its calls,
+// so the layers above can be tested against a database which misbehaves.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInjected is the error of every injected failure
+	ErrInjected = errors.New("injected fault")
+)
+
+// faults decides the faults of every call
+type faults struct {
+	rules []Rule
+
+	// mu guards rand, which isn't safe for concurrent use
+	mu   sync.Mutex
+	rand *rand.Rand
+	seed int64
+}
+
+// fault is what a call goes through
+type fault struct {
+	latency time.Duration
+	fail    bool
+	after   bool
+}
+
+func (f *faults) decide(method string, model db.Model, id string) fault {
+	for _, rule := range f.rules {
+		if !rule.Matches(method, model, id) {
+			continue
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		decided := fault{latency: rule.Latency, after: rule.FailAfter}
+		if rule.Jitter > 0 {
+			decided.latency += time.Duration(f.rand.Int63n(int64(rule.Jitter) + 1))
+		}
+		if rule.ErrorRate > 0 {
+			decided.fail = f.rand.Float64() < rule.ErrorRate
+		}
+		return decided
+	}
+
+	return fault{}
+}
+
+// run calls fn through the fault of the call, id is empty for the calls without a record
+func (f *faults) run(method string, model db.Model, id string, fn func() error) error {
+	decided := f.decide(method, model, id)
+	if decided.latency > 0 {
+		time.Sleep(decided.latency)
+	}
+
+	injected := fmt.Errorf("%w: %s", ErrInjected, method)
+	if decided.fail && !decided.after {
+		return injected
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+	if decided.fail {
+		return injected
+	}
+	return nil
+}
+
+// Database injects the faults of the rules into the calls to a database,
+// and into the calls of its transactions. It has every capability of a database,
+// the ones the wrapped database lacks fail with db.ErrNotSupported.
+type Database struct {
+	*faultyTx
+
+	database db.Database
+}
+
+// New wraps database with the rules of config, whether it is enabled or not
+func New(database db.Database, config Config) *Database {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	faults := &faults{rules: config.Rules, rand: rand.New(rand.NewSource(seed)), seed: seed}
+	return &Database{
+		faultyTx: &faultyTx{Tx: database, faults: faults},
+		database: database,
+	}
+}
+
+// Seed replays the same faults once set in Config
+func (d *Database) Seed() int64 {
+	return d.faults.seed
+}
+
+// RunInTx injects faults into the transaction of fn as well. With FailAfter it fails once the transaction is committed.
+func (d *Database) RunInTx(fn func(tx db.Tx) error) error {
+	return d.faults.run("RunInTx", "", "", func() error {
+		return d.database.RunInTx(func(tx db.Tx) error {
+			return fn(&faultyTx{Tx: tx, faults: d.faults})
+		})
+	})
+}
+
+func (d *Database) RegisterIndex(model db.Model, name string, fn db.IndexFunc) error {
+	return d.database.RegisterIndex(model, name, fn)
+}
+
+func (d *Database) Watch(ctx context.Context, opts db.WatchOptions) (sub *db.Subscription, err error) {
+	watcher, ok := d.database.(db.Watcher)
+	if !ok {
+		return nil, db.ErrNotSupported
+	}
+	err = d.faults.run("Watch", "", "", func() error {
+		sub, err = watcher.Watch(ctx, opts)
+		return err
+	})
+
+	return sub, err
+}
+
+func (d *Database) RunSweeper(ctx context.Context) error {
+	sweeper, ok := d.database.(db.Sweeper)
+	if !ok {
+		return db.ErrNotSupported
+	}
+	return sweeper.RunSweeper(ctx)
+}
+
+func (d *Database) OpenSnapshot() (snapshot *db.ReadSnapshot, err error) {
+	snapshotter, ok := d.database.(db.Snapshotter)
+	if !ok {
+		return nil, db.ErrNotSupported
+	}
+	err = d.faults.run("OpenSnapshot", "", "", func() error {
+		snapshot, err = snapshotter.OpenSnapshot()
+		return err
+	})
+
+	return snapshot, err
+}
+
+func (d *Database) SetQuota(model db.Model, quota db.Quota) {
+	if limiter, ok := d.database.(db.Limiter); ok {
+		limiter.SetQuota(model, quota)
+	}
+}
+
+func (d *Database) Usage() []db.Usage {
+	limiter, ok := d.database.(db.Limiter)
+	if !ok {
+		return []db.Usage{}
+	}
+	return limiter.Usage()
+}
+
+func (d *Database) Replicate(ctx context.Context, after *uint64) (replication *db.Replication, err error) {
+	replicator, ok := d.database.(db.Replicator)
+	if !ok {
+		return nil, db.ErrNotSupported
+	}
+	err = d.faults.run("Replicate", "", "", func() error {
+		replication, err = replicator.Replicate(ctx, after)
+		return err
+	})
+
+	return replication, err
+}
+
+func (d *Database) LoadCopy(cp *db.Copy) error {
+	replicator, ok := d.database.(db.Replicator)
+	if !ok {
+		return db.ErrNotSupported
+	}
+	return d.faults.run("LoadCopy", "", "", func() error {
+		return replicator.LoadCopy(cp)
+	})
+}
+
+func (d *Database) ApplyEvents(events []db.Event) error {
+	replicator, ok := d.database.(db.Replicator)
+	if !ok {
+		return db.ErrNotSupported
+	}
+	return d.faults.run("ApplyEvents", "", "", func() error {
+		return replicator.ApplyEvents(events)
+	})
+}
+
+// faultyTx injects faults into the calls to a database or a transaction
+type faultyTx struct {
+	db.Tx
+
+	faults *faults
+}
+
+func (t *faultyTx) Get(model db.Model, id uuid.UUID) (value interface{}, err error) {
+	err = t.faults.run("Get", model, id.String(), func() error {
+		value, err = t.Tx.Get(model, id)
+		return err
+	})
+
+	return value, err
+}
+
+func (t *faultyTx) List(model db.Model) (list []interface{}, err error) {
+	err = t.faults.run("List", model, "", func() error {
+		list, err = t.Tx.List(model)
+		return err
+	})
+
+	return list, err
+}
+
+func (t *faultyTx) ListRange(model db.Model, opts db.ListOptions) (page db.Page, err error) {
+	err = t.faults.run("ListRange", model, "", func() error {
+		page, err = t.Tx.ListRange(model, opts)
+		return err
+	})
+
+	return page, err
+}
+
+func (t *faultyTx) FindIDs(model db.Model, name string, query db.IndexQuery) (ids []uuid.UUID, err error) {
+	err = t.faults.run("FindIDs", model, "", func() error {
+		ids, err = t.Tx.FindIDs(model, name, query)
+		return err
+	})
+
+	return ids, err
+}
+
+func (t *faultyTx) Find(model db.Model, name string, query db.IndexQuery) (list []interface{}, err error) {
+	err = t.faults.run("Find", model, "", func() error {
+		list, err = t.Tx.Find(model, name, query)
+		return err
+	})
+
+	return list, err
+}
+
+func (t *faultyTx) Create(model db.Model, id uuid.UUID, value interface{}) error {
+	return t.faults.run("Create", model, id.String(), func() error {
+		return t.Tx.Create(model, id, value)
+	})
+}
+
+func (t *faultyTx) Update(model db.Model, id uuid.UUID, value interface{}) error {
+	return t.faults.run("Update", model, id.String(), func() error {
+		return t.Tx.Update(model, id, value)
+	})
+}
+
+func (t *faultyTx) UpdateIfVersion(model db.Model, id uuid.UUID, version uint64, value interface{}) error {
+	return t.faults.run("UpdateIfVersion", model, id.String(), func() error {
+		return t.Tx.UpdateIfVersion(model, id, version, value)
+	})
+}
+
+func (t *faultyTx) Delete(model db.Model, id uuid.UUID) error {
+	return t.faults.run("Delete", model, id.String(), func() error {
+		return t.Tx.Delete(model, id)
+	})
+}
+
+func (t *faultyTx) Trash(model db.Model, id uuid.UUID) error {
+	return t.faults.run("Trash", model, id.String(), func() error {
+		return t.Tx.Trash(model, id)
+	})
+}
+
+func (t *faultyTx) TrashAt(model db.Model, id uuid.UUID, at time.Time) error {
+	return t.faults.run("TrashAt", model, id.String(), func() error {
+		return t.Tx.TrashAt(model, id, at)
+	})
+}
+
+func (t *faultyTx) Restore(model db.Model, id uuid.UUID) error {
+	return t.faults.run("Restore", model, id.String(), func() error {
+		return t.Tx.Restore(model, id)
+	})
+}
+
+func (t *faultyTx) Purge(model db.Model, id uuid.UUID) error {
+	return t.faults.run("Purge", model, id.String(), func() error {
+		return t.Tx.Purge(model, id)
+	})
+}
+
+func (t *faultyTx) ListTrash(model db.Model) (list []db.Trashed, err error) {
+	err = t.faults.run("ListTrash", model, "", func() error {
+		list, err = t.Tx.ListTrash(model)
+		return err
+	})
+
+	return list, err
+}
+
+func (t *faultyTx) FindTrash(model db.Model, name string, query db.IndexQuery) (list []db.Trashed, err error) {
+	err = t.faults.run("FindTrash", model, "", func() error {
+		list, err = t.Tx.FindTrash(model, name, query)
+		return err
+	})
+
+	return list, err
+}
@@ -0,0 +1,150 @@
+//go:build !production
+
+package chaos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/db/dbtest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Count int `json:"count"`
+}
+
+// failures reports which of n calls to Get fail
+func failures(t *testing.T, database db.Database, n int) []bool {
+	failed := make([]bool, n)
+	for i := range failed {
+		_, err := database.Get(db.Task, uuid.New())
+		failed[i] = err != db.ErrNotFound
+		if failed[i] {
+			require.ErrorIs(t, err, ErrInjected)
+		}
+	}
+
+	return failed
+}
+
+func TestDatabase(t *testing.T) {
+	t.Run("seed", func(t *testing.T) {
+		config := Config{Seed: 42, Rules: []Rule{{ErrorRate: 0.5}}}
+
+		// assert, the same seed fails the same calls
+		first := failures(t, New(db.New(), config), 100)
+		require.Equal(t, first, failures(t, New(db.New(), config), 100))
+		require.Contains(t, first, true)
+		require.Contains(t, first, false)
+
+		database := New(db.New(), Config{Rules: config.Rules})
+		require.NotZero(t, database.Seed())
+		replayed := New(db.New(), Config{Seed: database.Seed(), Rules: config.Rules})
+		require.Equal(t, failures(t, database, 100), failures(t, replayed, 100))
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		base := db.New()
+		target, other := uuid.New(), uuid.New()
+		require.NoError(t, base.Create(db.Task, target, &testValue{Count: 1}))
+		require.NoError(t, base.Create(db.Task, other, &testValue{Count: 2}))
+
+		database := New(base, Config{Rules: []Rule{
+			{Methods: []string{"Get"}, ID: target.String(), ErrorRate: 1},
+			{Methods: []string{"List"}, Model: "other", ErrorRate: 1},
+			// the first rule matching wins
+			{Methods: []string{"List", "Delete"}, ErrorRate: 1},
+		}})
+
+		// assert
+		_, err := database.Get(db.Task, target)
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = database.Get(db.Task, other)
+		require.NoError(t, err)
+
+		_, err = database.List("other")
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = database.List(db.Task)
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = database.ListTrash(db.Task)
+		require.NoError(t, err)
+
+		// a failed write isn't applied
+		err = database.Delete(db.Task, other)
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = base.Get(db.Task, other)
+		require.NoError(t, err)
+	})
+
+	t.Run("fail after", func(t *testing.T) {
+		base := db.New()
+		database := New(base, Config{Rules: []Rule{{Methods: []string{"Create"}, ErrorRate: 1, FailAfter: true}}})
+
+		// assert, the write is applied anyway
+		id := uuid.New()
+		err := database.Create(db.Task, id, &testValue{Count: 1})
+		require.ErrorIs(t, err, ErrInjected)
+		_, err = base.Get(db.Task, id)
+		require.NoError(t, err)
+
+		// the errors of the call come first
+		err = database.Create(db.Task, id, &testValue{Count: 1})
+		require.ErrorIs(t, err, db.ErrAlreadyExists)
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		base := db.New()
+		database := New(base, Config{Rules: []Rule{{Methods: []string{"Update"}, ErrorRate: 1}}})
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, database.Create(db.Task, id, &testValue{Count: 1}))
+
+		// assert, a failure inside the transaction rolls it back
+		err := database.RunInTx(func(tx db.Tx) error {
+			if err := tx.Create(db.Task, uuid.New(), &testValue{Count: 2}); err != nil {
+				return err
+			}
+			return tx.Update(db.Task, id, &testValue{Count: 3})
+		})
+		require.ErrorIs(t, err, ErrInjected)
+
+		list, err := base.List(db.Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{&testValue{Count: 1}}, list)
+
+		database = New(base, Config{Rules: []Rule{{Methods: []string{"RunInTx"}, ErrorRate: 1}}})
+		err = database.RunInTx(func(tx db.Tx) error {
+			t.Fatal("the transaction must not run")
+			return nil
+		})
+		require.ErrorIs(t, err, ErrInjected)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		database := New(db.New(), Config{Rules: []Rule{
+			{Methods: []string{"List"}, Latency: 20 * time.Millisecond, Jitter: 10 * time.Millisecond},
+		}})
+
+		// assert
+		start := time.Now()
+		_, err := database.List(db.Task)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+		start = time.Now()
+		_, err = database.ListTrash(db.Task)
+		require.NoError(t, err)
+		require.Less(t, time.Since(start), 20*time.Millisecond)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	// without faults the database behaves as the one it wraps
+	dbtest.Run(t, func(t *testing.T) db.Database {
+		return New(db.New(), Config{})
+	})
+}
@@ -0,0 +1,57 @@
+// Package chaosconfig holds the config of the chaos package, which builds under every tag
+// so production builds read it without linking the fault injection.
+package chaosconfig
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+)
+
+// Config of the fault injecting database of the chaos package, which only builds without the production tag
+type Config struct {
+	Enabled bool `koanf:"enabled"`
+	// Seed of the faults, the same seed injects the same faults into the same calls. 0 picks one at random.
+	Seed int64 `koanf:"seed"`
+	// Rules in order, a call follows the first rule it matches and goes through untouched otherwise
+	Rules []Rule `koanf:"rules" validate:"dive"`
+}
+
+func (Config) Default() Config {
+	return Config{}
+}
+
+// Rule injects faults into the calls it matches, its empty fields match every call
+type Rule struct {
+	// Methods of db.Tx, RunInTx, Watch and OpenSnapshot
+	Methods []string `koanf:"methods" validate:"dive,oneof=Get List ListRange FindIDs Find Create Update UpdateIfVersion Delete Trash TrashAt Restore Purge ListTrash FindTrash RunInTx Watch OpenSnapshot Replicate LoadCopy ApplyEvents"`
+	// Model and ID of the record, the calls without one only match the rules without one
+	Model db.Model `koanf:"model"`
+	ID    string   `koanf:"id" validate:"omitempty,uuid"`
+
+	// ErrorRate is the chance between 0 and 1 of a call to fail with ErrInjected
+	ErrorRate float64 `koanf:"error_rate" validate:"gte=0,lte=1"`
+	// FailAfter fails the calls once they are done, so a failed write is applied anyway
+	FailAfter bool `koanf:"fail_after"`
+
+	// Latency is added to every call, plus a random duration up to Jitter
+	Latency time.Duration `koanf:"latency" validate:"gte=0"`
+	Jitter  time.Duration `koanf:"jitter" validate:"gte=0"`
+}
+
+// Matches reports whether the rule applies to a call, id is empty for the calls without a record
+func (r Rule) Matches(method string, model db.Model, id string) bool {
+	if len(r.Methods) > 0 && !slices.Contains(r.Methods, method) {
+		return false
+	}
+	if r.Model != "" && r.Model != model {
+		return false
+	}
+	if r.ID != "" && !strings.EqualFold(r.ID, id) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,9 @@
+package chaos
+
+import "github.com/dragon-huang0403/todo-go/internal/db/chaos/chaosconfig"
+
+// Config and Rule live in chaosconfig, so production builds configure chaos without linking it
+type (
+	Config = chaosconfig.Config
+	Rule   = chaosconfig.Rule
+)
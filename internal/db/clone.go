@@ -0,0 +1,22 @@
+package db
+
+import "reflect"
+
+// Cloner is implemented by values holding references, e.g. pointers, maps or slices,
+// which a shallow copy would share with the stored record
+type Cloner interface {
+	Clone() interface{}
+}
+
+// clone returns an independent copy of the pointer v, so the database never shares
+// a record with its callers and committed data can't be changed behind its back
+func clone(v interface{}) interface{} {
+	if c, ok := v.(Cloner); ok {
+		return c.Clone()
+	}
+
+	src := reflect.ValueOf(v)
+	dst := reflect.New(src.Elem().Type())
+	dst.Elem().Set(src.Elem())
+	return dst.Interface()
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// bindings holds the type each model is bound to by its collection
+var bindings = struct {
+	mu    sync.Mutex
+	types map[Model]reflect.Type
+}{types: map[Model]reflect.Type{}}
+
+// Collection gives typed access to the records of a model.
+// It works on a Database as well as inside a transaction, since both are a Tx,
+// and its reads work on a ReadSnapshot too.
+type Collection[T any] struct {
+	model Model
+}
+
+// NewCollection binds T to model, every record of model must be a *T.
+// It panics when model is bound to another type already, collections are meant to be package variables.
+func NewCollection[T any](model Model) Collection[T] {
+	typ := reflect.TypeFor[T]()
+
+	bindings.mu.Lock()
+	defer bindings.mu.Unlock()
+
+	if bound, ok := bindings.types[model]; ok && bound != typ {
+		panic(fmt.Sprintf("db: %s is bound to %s, it can't hold %s", model, bound, typ))
+	}
+	bindings.types[model] = typ
+
+	return Collection[T]{model: model}
+}
+
+func (c Collection[T]) Model() Model {
+	return c.model
+}
+
+// New returns an empty record, it can be used in Schema
+func (c Collection[T]) New() interface{} {
+	return new(T)
+}
+
+func (c Collection[T]) Get(tx Reader, id uuid.UUID) (*T, error) {
+	v, err := tx.Get(c.model, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.cast(v), nil
+}
+
+// List by create order
+func (c Collection[T]) List(tx Reader) ([]*T, error) {
+	values, err := tx.List(c.model)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*T, 0, len(values))
+	for _, v := range values {
+		list = append(list, c.cast(v))
+	}
+
+	return list, nil
+}
+
+// ListRange returns a page of List and the cursor of the next page
+func (c Collection[T]) ListRange(tx Reader, opts ListOptions) ([]*T, Cursor, error) {
+	page, err := tx.ListRange(c.model, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list := make([]*T, 0, len(page.Values))
+	for _, v := range page.Values {
+		list = append(list, c.cast(v))
+	}
+
+	return list, page.Next, nil
+}
+
+func (c Collection[T]) Create(tx Tx, id uuid.UUID, value *T) error {
+	return tx.Create(c.model, id, value)
+}
+
+func (c Collection[T]) Update(tx Tx, id uuid.UUID, value *T) error {
+	return tx.Update(c.model, id, value)
+}
+
+func (c Collection[T]) UpdateIfVersion(tx Tx, id uuid.UUID, version uint64, value *T) error {
+	return tx.UpdateIfVersion(c.model, id, version, value)
+}
+
+func (c Collection[T]) Delete(tx Tx, id uuid.UUID) error {
+	return tx.Delete(c.model, id)
+}
+
+func (c Collection[T]) Trash(tx Tx, id uuid.UUID) error {
+	return tx.Trash(c.model, id)
+}
+
+func (c Collection[T]) Restore(tx Tx, id uuid.UUID) error {
+	return tx.Restore(c.model, id)
+}
+
+func (c Collection[T]) Purge(tx Tx, id uuid.UUID) error {
+	return tx.Purge(c.model, id)
+}
+
+// TrashedRecord is a soft deleted record of a collection
+type TrashedRecord[T any] struct {
+	ID        uuid.UUID
+	Value     *T
+	DeletedAt time.Time
+}
+
+// ListTrash by create order
+func (c Collection[T]) ListTrash(tx Tx) ([]TrashedRecord[T], error) {
+	trashed, err := tx.ListTrash(c.model)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]TrashedRecord[T], 0, len(trashed))
+	for _, item := range trashed {
+		list = append(list, TrashedRecord[T]{ID: item.ID, Value: c.cast(item.Value), DeletedAt: item.DeletedAt})
+	}
+
+	return list, nil
+}
+
+// Index is a typed index of a collection
+type Index[T any] struct {
+	collection Collection[T]
+	name       string
+	keys       func(value *T) []string
+}
+
+// Index declares the index name of the collection, keys must not modify the value
+func (c Collection[T]) Index(name string, keys func(value *T) []string) Index[T] {
+	return Index[T]{collection: c, name: name, keys: keys}
+}
+
+func (i Index[T]) Name() string {
+	return i.name
+}
+
+// Register the index on database, see Database.RegisterIndex
+func (i Index[T]) Register(database Database) error {
+	return database.RegisterIndex(i.collection.model, i.name, func(value interface{}) []string {
+		item, ok := value.(*T)
+		if !ok {
+			return nil
+		}
+		return i.keys(item)
+	})
+}
+
+func (i Index[T]) FindIDs(tx Reader, query IndexQuery) ([]uuid.UUID, error) {
+	return tx.FindIDs(i.collection.model, i.name, query)
+}
+
+// Find returns the records selected by query, by key then create order
+func (i Index[T]) Find(tx Reader, query IndexQuery) ([]*T, error) {
+	values, err := tx.Find(i.collection.model, i.name, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*T, 0, len(values))
+	for _, v := range values {
+		list = append(list, i.collection.cast(v))
+	}
+
+	return list, nil
+}
+
+// FindTrash returns the trashed records selected by query, by key then create order
+func (i Index[T]) FindTrash(tx Tx, query IndexQuery) ([]TrashedRecord[T], error) {
+	trashed, err := tx.FindTrash(i.collection.model, i.name, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]TrashedRecord[T], 0, len(trashed))
+	for _, item := range trashed {
+		list = append(list, TrashedRecord[T]{ID: item.ID, Value: i.collection.cast(item.Value), DeletedAt: item.DeletedAt})
+	}
+
+	return list, nil
+}
+
+// EventValue returns the value of an event of the model
+func (c Collection[T]) EventValue(e Event) *T {
+	return c.cast(e.Value)
+}
+
+// cast can't fail since the model is bound to T, writing another type to the model
+// without its collection is a programming error which panics here
+func (c Collection[T]) cast(v interface{}) *T {
+	return v.(*T)
+}
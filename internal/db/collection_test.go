@@ -0,0 +1,88 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollection(t *testing.T) {
+	values := NewCollection[testValue](Task)
+
+	t.Run("ok", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		n := gofakeit.Number(1, 10)
+		expected := make([]*testValue, 0, n)
+		for range n {
+			value := randomTestValue()
+			require.NoError(t, values.Create(db, uuid.New(), value))
+			expected = append(expected, value)
+		}
+
+		id := uuid.New()
+		value := randomTestValue()
+		require.NoError(t, values.Create(db, id, value))
+		updated := randomTestValue()
+		require.NoError(t, values.Update(db, id, updated))
+
+		// assert
+		v, err := values.Get(db, id)
+		require.NoError(t, err)
+		require.Equal(t, updated, v)
+
+		require.NoError(t, values.Delete(db, id))
+		_, err = values.Get(db, id)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		list, err := values.List(db)
+		require.NoError(t, err)
+		require.Equal(t, expected, list)
+
+		page, next, err := values.ListRange(db, ListOptions{Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, expected[:1], page)
+		if n > 1 {
+			require.NotEmpty(t, next)
+		}
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, values.Create(db, id, &testValue{}))
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			v, err := values.Get(tx, id)
+			if err != nil {
+				return err
+			}
+			v.Count++
+			return values.Update(tx, id, v)
+		})
+		require.NoError(t, err)
+
+		v, err := values.Get(db, id)
+		require.NoError(t, err)
+		require.Equal(t, 1, v.Count)
+	})
+
+	t.Run("bound to one type", func(t *testing.T) {
+		require.NotPanics(t, func() { NewCollection[testValue](Task) })
+		require.Panics(t, func() { NewCollection[testCloner](Task) })
+
+		// the model is still bound to its first type
+		require.NotPanics(t, func() { NewCollection[testValue](Task) })
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		require.Equal(t, Task, values.Model())
+		require.IsType(t, &testValue{}, values.New())
+	})
+}
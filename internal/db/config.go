@@ -0,0 +1,92 @@
+package db
+
+import "time"
+
+const (
+	DriverMemory = "memory"
+	DriverFile   = "file"
+	DriverSQL    = "sql"
+)
+
+type Config struct {
+	Driver string     `koanf:"driver" validate:"required,oneof=memory file sql"`
+	File   FileConfig `koanf:"file" validate:"required"`
+	SQL    SQLConfig  `koanf:"sql" validate:"required"`
+	// Quotas by model, the models left out have no limit
+	Quotas map[Model]Quota `koanf:"quotas" validate:"dive,keys,oneof=task project,endkeys"`
+}
+
+func (Config) Default() Config {
+	return Config{
+		Driver: DriverMemory,
+		File:   FileConfig{}.Default(),
+		SQL:    SQLConfig{}.Default(),
+	}
+}
+
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs the log after every write
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs the log periodically, a crash may lose the last interval of writes
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system
+	SyncNever SyncPolicy = "never"
+)
+
+type FileConfig struct {
+	// directory of the log segments and snapshots
+	Dir          string        `koanf:"dir" validate:"required"`
+	SyncPolicy   SyncPolicy    `koanf:"sync_policy" validate:"required,oneof=always interval never"`
+	SyncInterval time.Duration `koanf:"sync_interval" validate:"required_if=SyncPolicy interval"`
+
+	// a new log segment is started once the current one reaches this size in bytes
+	SegmentSize int64 `koanf:"segment_size" validate:"gte=0"`
+
+	// 0 disables periodic snapshots
+	SnapshotInterval time.Duration `koanf:"snapshot_interval" validate:"gte=0"`
+
+	Encryption EncryptionConfig `koanf:"encryption"`
+}
+
+func (FileConfig) Default() FileConfig {
+	return FileConfig{
+		Dir:              "data",
+		SyncPolicy:       SyncAlways,
+		SyncInterval:     time.Second,
+		SegmentSize:      64 << 20,
+		SnapshotInterval: 10 * time.Minute,
+	}
+}
+
+type EncryptionConfig struct {
+	// KeyFile holds the base64 encoded 32 bytes key the log and snapshots are encrypted with,
+	// they are written in plain text when it is empty
+	KeyFile string `koanf:"key_file"`
+	// PreviousKeyFiles hold the keys rotated out, which only read the data until it is re-encrypted
+	PreviousKeyFiles []string `koanf:"previous_key_files" validate:"dive,required"`
+	// MigratePlainData reads the data written in plain text once, so it is encrypted with KeyFile.
+	// It turns itself off once the data is encrypted, plain data is then a corrupted log again.
+	MigratePlainData bool `koanf:"migrate_plain_data"`
+}
+
+type SQLConfig struct {
+	// DSN of the SQLite database, its schema is created and upgraded by Migrate
+	DSN string `koanf:"dsn" validate:"required"`
+
+	// settings of the connection pool as in sql.DB, 0 is no limit except for MaxIdleConns which then keeps 2
+	MaxOpenConns    int           `koanf:"max_open_conns" validate:"gte=0"`
+	MaxIdleConns    int           `koanf:"max_idle_conns" validate:"gte=0"`
+	ConnMaxLifetime time.Duration `koanf:"conn_max_lifetime" validate:"gte=0"`
+	ConnMaxIdleTime time.Duration `koanf:"conn_max_idle_time" validate:"gte=0"`
+}
+
+func (SQLConfig) Default() SQLConfig {
+	return SQLConfig{
+		DSN:             "file:todo.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		MaxOpenConns:    4,
+		MaxIdleConns:    4,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+}
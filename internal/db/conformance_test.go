@@ -0,0 +1,62 @@
+package db_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/db/dbtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		dbtest.Run(t, func(t *testing.T) db.Database {
+			return db.New()
+		})
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dbtest.Run(t, func(t *testing.T) db.Database {
+			config := db.FileConfig{}.Default()
+			config.Dir = t.TempDir()
+
+			fileDB, err := db.NewFile(config, dbtest.Schema)
+			require.NoError(t, err)
+			t.Cleanup(func() { fileDB.Close() })
+			return fileDB
+		})
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		dbtest.Run(t, func(t *testing.T) db.Database {
+			config := db.SQLConfig{}.Default()
+			config.DSN = "file:" + filepath.Join(t.TempDir(), "todo.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+			_, err := db.Migrate(context.Background(), config)
+			require.NoError(t, err)
+
+			sqlDB, err := db.NewSQL(config, dbtest.Schema)
+			require.NoError(t, err)
+			t.Cleanup(func() { sqlDB.Close() })
+			return sqlDB
+		})
+	})
+
+	t.Run("encrypted file", func(t *testing.T) {
+		dbtest.Run(t, func(t *testing.T) db.Database {
+			config := db.FileConfig{}.Default()
+			config.Dir = t.TempDir()
+			config.Encryption.KeyFile = filepath.Join(t.TempDir(), "key")
+			err := os.WriteFile(config.Encryption.KeyFile, []byte(base64.StdEncoding.EncodeToString(make([]byte, 32))), 0o600)
+			require.NoError(t, err)
+
+			fileDB, err := db.NewFile(config, dbtest.Schema)
+			require.NoError(t, err)
+			t.Cleanup(func() { fileDB.Close() })
+			return fileDB
+		})
+	})
+}
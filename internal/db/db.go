@@ -1,43 +1,167 @@
 package db
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrNotFound      = errors.New("not found")
-	ErrAlreadyExists = errors.New("already exists")
-	ErrOnlyPointer   = errors.New("only pointer")
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is the parent of every error caused by the current state of a record
+	ErrConflict        = errors.New("conflict")
+	ErrAlreadyExists   = fmt.Errorf("%w: already exists", ErrConflict)
+	ErrVersionMismatch = fmt.Errorf("%w: version mismatch", ErrConflict)
+	ErrOnlyPointer     = errors.New("only pointer")
+	// ErrNotSupported is returned when a database lacks the capability a call needs
+	ErrNotSupported = errors.New("not supported")
 )
 
 type Model string
 
 const (
-	Task Model = "task"
+	Task    Model = "task"
+	Project Model = "project"
 )
 
+// Database is safe for concurrent use by multiple goroutines.
+// Values are copied on the way in and out, so mutating a value passed to or returned by
+// the database never changes the stored record. Implement Cloner for values holding references.
 type Database interface {
-	Get(model Model, id uuid.UUID) (interface{}, error)
-	// List by create order
-	List(model Model) ([]interface{}, error)
-	Create(model Model, id uuid.UUID, value interface{}) error
-	Update(model Model, id uuid.UUID, value interface{}) error
-	Delete(model Model, id uuid.UUID) error
+	Tx
+
+	// RunInTx runs fn in a transaction, which is committed when fn returns nil
+	// and rolled back otherwise. fn must only access the database through tx.
+	RunInTx(fn func(tx Tx) error) error
+
+	// RegisterIndex indexes the live records of model by the keys fn returns, and keeps
+	// the index up to date on every write. Registering a name again replaces the index.
+	RegisterIndex(model Model, name string, fn IndexFunc) error
+}
+
+// The optional capabilities of a Database, which callers type-assert.
+// Every database of this package has all of them.
+
+// Watcher is a Database which feeds its changes to subscribers
+type Watcher interface {
+	// Watch subscribes to every change committed from now on, or since opts.After.
+	// The subscription is closed when ctx is done.
+	Watch(ctx context.Context, opts WatchOptions) (*Subscription, error)
+}
+
+// Sweeper is a Database which deletes the records of Expiring values
+type Sweeper interface {
+	// RunSweeper deletes the records of Expiring values as they expire, until ctx is done
+	RunSweeper(ctx context.Context) error
+}
+
+// Snapshotter is a Database which reads consistent views of itself
+type Snapshotter interface {
+	// OpenSnapshot opens a consistent view of the live records, which writes don't block
+	OpenSnapshot() (*ReadSnapshot, error)
+}
+
+// Limiter is a Database which accounts for the size of its models
+type Limiter interface {
+	// SetQuota limits model, the creates and updates which would take it over the quota
+	// fail with ErrQuotaExceeded. Setting it again replaces the quota.
+	SetQuota(model Model, quota Quota)
+	// Usage returns the usage of every model by name
+	Usage() []Usage
+}
+
+// Replicator is a Database which ships its changes to replicas and applies those of a primary
+type Replicator interface {
+	Snapshotter
+
+	// Replicate streams the changes after `after` to a replica, starting with a copy
+	// of the database when they are gone. The subscription is closed when ctx is done.
+	Replicate(ctx context.Context, after *uint64) (*Replication, error)
+	// LoadCopy replaces every record with a copy of Replicate
+	LoadCopy(copy *Copy) error
+	// ApplyEvents commits the events of one write of Replicate, which must follow the last change
+	ApplyEvents(events []Event) error
+}
+
+// every database of this package has every capability
+var (
+	_ Watcher     = (*databaseManager)(nil)
+	_ Sweeper     = (*databaseManager)(nil)
+	_ Snapshotter = (*databaseManager)(nil)
+	_ Limiter     = (*databaseManager)(nil)
+	_ Replicator  = (*databaseManager)(nil)
+)
+
+// Versioned is implemented by values which want to know the version of their record.
+// A record starts at version 1 and every update increases it by one.
+type Versioned interface {
+	SetVersion(version uint64)
+}
+
+func setVersion(v interface{}, version uint64) {
+	if versioned, ok := v.(Versioned); ok {
+		versioned.SetVersion(version)
+	}
+}
+
+// journal persists operations before they are applied in memory.
+// The operations of one write are persisted atomically,
+// and a failed write must leave the in-memory state untouched.
+type journal interface {
+	write(ops ...operation) error
 }
 
 type databaseManager struct {
+	// txMu is held exclusively by transactions and shared by every other access
+	txMu sync.RWMutex
+
+	// mu guards the database map itself, each model guards its own data
+	mu       sync.RWMutex
 	database map[Model]*modelDatabase
+
+	// journal is nil for the pure in-memory database
+	journal journal
+
+	feed      *feed
+	expiries  *expiries
+	snapshots *snapshots
+}
+
+func newDatabaseManager() *databaseManager {
+	return &databaseManager{
+		database:  map[Model]*modelDatabase{},
+		feed:      newFeed(),
+		expiries:  newExpiries(),
+		snapshots: newSnapshots(),
+	}
 }
 
 func (db *databaseManager) getModelDB(model Model) *modelDatabase {
+	db.mu.RLock()
 	modelDB, ok := db.database[model]
+	db.mu.RUnlock()
+	if ok {
+		return modelDB
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// another goroutine may have created it while we were waiting for the lock
+	modelDB, ok = db.database[model]
 	if !ok {
 		modelDB = &modelDatabase{
-			dataMap: map[uuid.UUID]interface{}{},
-			orders:  []uuid.UUID{},
+			dataMap:       map[uuid.UUID]record{},
+			orders:        newSkipList[uint64, uuid.UUID](),
+			trashed:       map[uuid.UUID]record{},
+			versions:      map[uuid.UUID][]version{},
+			trashVersions: map[uuid.UUID][]version{},
 		}
 		db.database[model] = modelDB
 	}
@@ -45,85 +169,420 @@ func (db *databaseManager) getModelDB(model Model) *modelDatabase {
 	return modelDB
 }
 
+func (db *databaseManager) writeJournal(ops ...operation) error {
+	if db.journal == nil {
+		return nil
+	}
+
+	return db.journal.write(ops...)
+}
+
+// write persists and applies a single operation, the caller holds the lock of the model
+func (db *databaseManager) write(modelDB *modelDatabase, op operation) error {
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	if err := db.writeJournal(op); err != nil {
+		return err
+	}
+
+	db.feed.beginLocked(1)
+	db.applyLocked(modelDB, op)
+	return nil
+}
+
+// applyLocked writes op in memory without any check and publishes it,
+// the caller holds the lock of the model and of the feed
+func (db *databaseManager) applyLocked(modelDB *modelDatabase, op operation) {
+	seq := db.feed.nextSeqLocked()
+	previous, live := modelDB.dataMap[op.ID]
+	previousTrashed, trashed := modelDB.trashed[op.ID]
+
+	var item record
+	switch op.Kind {
+	case opCreate:
+		item = modelDB.create(op.ID, op.Value, op.size, seq)
+	case opUpdate:
+		item = modelDB.update(op.ID, op.Value, op.size, seq)
+	case opDelete:
+		item = modelDB.delete(op.ID)
+	case opTrash:
+		item = modelDB.trash(op.ID, op.At, seq)
+	case opRestore:
+		item = modelDB.restore(op.ID, seq)
+	case opPurge:
+		item = modelDB.purge(op.ID)
+	}
+
+	if live {
+		db.retain(modelDB, op.ID, previous, seq)
+	}
+	if trashed {
+		db.retainTrash(modelDB, op.ID, previousTrashed, seq)
+	}
+	db.trackExpiry(op, item)
+	db.feed.publishLocked(op.Kind, op.Model, op.ID, item)
+}
+
+type record struct {
+	value   interface{}
+	version uint64
+	// position in the create order of the model
+	position uint64
+	// deletedAt is only set on trashed records
+	deletedAt time.Time
+	// seq of the change which made the record live, or trashed it, zero when it was loaded
+	seq uint64
+	// size of the value in Usage
+	size int64
+}
+
+// Trashed is a soft deleted record
+type Trashed struct {
+	ID        uuid.UUID
+	Value     interface{}
+	DeletedAt time.Time
+}
+
+func (r record) readTrashed(id uuid.UUID) Trashed {
+	return Trashed{ID: id, Value: r.read(), DeletedAt: r.deletedAt}
+}
+
+// sortTrashed orders trashed records by their position in the create order
+func sortTrashed(records map[uuid.UUID]record, list []Trashed) {
+	sort.Slice(list, func(i, j int) bool {
+		return records[list[i].ID].position < records[list[j].ID].position
+	})
+}
+
+// read returns a copy of the value which knows its version
+func (r record) read() interface{} {
+	value := clone(r.value)
+	setVersion(value, r.version)
+	return value
+}
+
 type modelDatabase struct {
-	dataMap map[uuid.UUID]interface{}
+	mu sync.RWMutex
+
+	dataMap map[uuid.UUID]record
+
+	// orders maps the position of every record to its id
+	orders *skipList[uint64, uuid.UUID]
+	// lastPosition only grows, so positions are never reused
+	lastPosition uint64
+
+	// trashed records keep their position, so they are restored where they were
+	trashed map[uuid.UUID]record
+
+	// indexes by name, see RegisterIndex
+	indexes map[string]*index
+	// trashIndexes are the same indexes over the trashed records
+	trashIndexes map[string]*index
+
+	// versions are the live records replaced while a snapshot could see them
+	versions map[uuid.UUID][]version
+	// trashVersions are the trashed records restored or purged while a snapshot could see them
+	trashVersions map[uuid.UUID][]version
+
+	// usage doesn't count the versions, which only last as long as the snapshots
+	usage Usage
+	quota Quota
+}
+
+// exists reports whether id is used by a record, trashed or not
+func (m *modelDatabase) exists(id uuid.UUID) bool {
+	if _, ok := m.dataMap[id]; ok {
+		return true
+	}
+	_, ok := m.trashed[id]
+	return ok
+}
+
+func (m *modelDatabase) create(id uuid.UUID, value interface{}, size int64, seq uint64) record {
+	m.lastPosition++
+	item := record{value: value, version: 1, position: m.lastPosition, seq: seq, size: size}
+	m.account(item, 1)
+	m.dataMap[id] = item
+	m.orders.Set(m.lastPosition, id)
+	m.index(id, nil, item)
+	return item
+}
 
-	orders []uuid.UUID
+// load puts back a record as it was, e.g. from a snapshot
+func (m *modelDatabase) load(id uuid.UUID, item record) {
+	m.lastPosition = max(m.lastPosition, item.position)
+	m.account(item, 1)
+	if !item.deletedAt.IsZero() {
+		m.trashed[id] = item
+		m.indexTrash(id, item)
+		return
+	}
+
+	m.dataMap[id] = item
+	m.orders.Set(item.position, id)
+	m.index(id, nil, item)
 }
 
-func New() Database {
-	db := &databaseManager{
-		database: map[Model]*modelDatabase{},
+// reset removes every record, the indexes and the quota are kept
+func (m *modelDatabase) reset() {
+	m.dataMap = map[uuid.UUID]record{}
+	m.orders = newSkipList[uint64, uuid.UUID]()
+	m.lastPosition = 0
+	m.trashed = map[uuid.UUID]record{}
+	m.versions = map[uuid.UUID][]version{}
+	m.trashVersions = map[uuid.UUID][]version{}
+	m.usage = Usage{}
+	for name, i := range m.indexes {
+		m.indexes[name] = newIndex(i.fn)
+		m.trashIndexes[name] = newIndex(i.fn)
 	}
-	return db
+}
+
+func (m *modelDatabase) update(id uuid.UUID, value interface{}, size int64, seq uint64) record {
+	current := m.dataMap[id]
+	item := record{value: value, version: current.version + 1, position: current.position, seq: seq, size: size}
+	m.account(current, -1)
+	m.account(item, 1)
+	m.dataMap[id] = item
+	m.index(id, &current, item)
+	return item
+}
+
+// delete returns the deleted record
+func (m *modelDatabase) delete(id uuid.UUID) record {
+	item := m.dataMap[id]
+	m.account(item, -1)
+	m.orders.Delete(item.position)
+	delete(m.dataMap, id)
+	m.unindex(id, item)
+	return item
+}
+
+func (m *modelDatabase) trash(id uuid.UUID, at time.Time, seq uint64) record {
+	item := m.delete(id)
+	item.deletedAt = at
+	item.seq = seq
+	m.account(item, 1)
+	m.trashed[id] = item
+	m.indexTrash(id, item)
+	return item
+}
+
+func (m *modelDatabase) restore(id uuid.UUID, seq uint64) record {
+	item := m.trashed[id]
+	delete(m.trashed, id)
+	m.unindexTrash(id, item)
+	item.deletedAt = time.Time{}
+	item.seq = seq
+	m.dataMap[id] = item
+	m.orders.Set(item.position, id)
+	m.index(id, nil, item)
+	return item
+}
+
+// purge returns the purged record
+func (m *modelDatabase) purge(id uuid.UUID) record {
+	item := m.trashed[id]
+	m.account(item, -1)
+	delete(m.trashed, id)
+	m.unindexTrash(id, item)
+	return item
+}
+
+// MemoryDatabase keeps every record in memory only
+type MemoryDatabase struct {
+	*databaseManager
+}
+
+func New() *MemoryDatabase {
+	return &MemoryDatabase{databaseManager: newDatabaseManager()}
 }
 
 func (db *databaseManager) Get(model Model, id uuid.UUID) (interface{}, error) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
 	modelDB := db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
+
 	item, ok := modelDB.dataMap[id]
 	if !ok {
 		return nil, ErrNotFound
 	}
 
-	return item, nil
+	return item.read(), nil
 }
 
 func (db *databaseManager) List(model Model) ([]interface{}, error) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
 	modelDB := db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
 
-	list := make([]interface{}, 0, len(modelDB.orders))
-	for _, id := range modelDB.orders {
-		list = append(list, modelDB.dataMap[id])
+	list := make([]interface{}, 0, modelDB.orders.Len())
+	for node := modelDB.orders.First(); node != nil; node = node.Next() {
+		list = append(list, modelDB.dataMap[node.value].read())
 	}
 
 	return list, nil
 }
 
+func (db *databaseManager) ListRange(model Model, opts ListOptions) (Page, error) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
+
+	return modelDB.listRange(opts)
+}
+
+// Create sets the version of value to 1 when it is Versioned
 func (db *databaseManager) Create(model Model, id uuid.UUID, value interface{}) error {
 	if err := isPointer(value); err != nil {
 		return err
 	}
 
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
 	modelDB := db.getModelDB(model)
-	if _, ok := modelDB.dataMap[id]; ok {
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	if modelDB.exists(id) {
 		return ErrAlreadyExists
 	}
 
-	modelDB.dataMap[id] = value
-	modelDB.orders = append(modelDB.orders, id)
-	return nil
+	setVersion(value, 1)
+	value = clone(value)
+	size := sizeOf(value)
+	if err := modelDB.allow(model, 1, size); err != nil {
+		return err
+	}
+
+	return db.write(modelDB, operation{Kind: opCreate, Model: model, ID: id, Value: value, size: size})
 }
 
+// Update sets the version of value to the new version of the record when it is Versioned
 func (db *databaseManager) Update(model Model, id uuid.UUID, value interface{}) error {
+	return db.update(model, id, nil, value)
+}
+
+// UpdateIfVersion is Update which fails with ErrVersionMismatch
+// when the record is not at version anymore
+func (db *databaseManager) UpdateIfVersion(model Model, id uuid.UUID, version uint64, value interface{}) error {
+	return db.update(model, id, &version, value)
+}
+
+func (db *databaseManager) update(model Model, id uuid.UUID, version *uint64, value interface{}) error {
 	if err := isPointer(value); err != nil {
 		return err
 	}
 
-	if _, err := db.Get(model, id); err != nil {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	current, ok := modelDB.dataMap[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if version != nil && current.version != *version {
+		return ErrVersionMismatch
+	}
+
+	setVersion(value, current.version+1)
+	value = clone(value)
+	size := sizeOf(value)
+	if err := modelDB.allow(model, 0, size-current.size); err != nil {
 		return err
 	}
 
-	modelDB := db.getModelDB(model)
-	modelDB.dataMap[id] = value
-	return nil
+	return db.write(modelDB, operation{Kind: opUpdate, Model: model, ID: id, Value: value, size: size})
 }
 
 func (db *databaseManager) Delete(model Model, id uuid.UUID) error {
-	if _, err := db.Get(model, id); err != nil {
-		return err
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	if _, ok := modelDB.dataMap[id]; !ok {
+		return ErrNotFound
 	}
 
+	return db.write(modelDB, operation{Kind: opDelete, Model: model, ID: id})
+}
+
+// Trash soft deletes a record, it is restored to the same position in the create order
+func (db *databaseManager) Trash(model Model, id uuid.UUID) error {
+	return db.TrashAt(model, id, time.Now().UTC())
+}
+
+func (db *databaseManager) TrashAt(model Model, id uuid.UUID, at time.Time) error {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
 	modelDB := db.getModelDB(model)
-	delete(modelDB.dataMap, id)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
 
-	for i, item := range modelDB.orders {
-		if item == id {
-			modelDB.orders = append(modelDB.orders[:i], modelDB.orders[i+1:]...)
-			break
-		}
+	if _, ok := modelDB.dataMap[id]; !ok {
+		return ErrNotFound
 	}
 
-	return nil
+	return db.write(modelDB, operation{Kind: opTrash, Model: model, ID: id, At: at})
+}
+
+func (db *databaseManager) Restore(model Model, id uuid.UUID) error {
+	return db.writeTrashed(model, id, opRestore)
+}
+
+func (db *databaseManager) Purge(model Model, id uuid.UUID) error {
+	return db.writeTrashed(model, id, opPurge)
+}
+
+func (db *databaseManager) writeTrashed(model Model, id uuid.UUID, kind opKind) error {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	if _, ok := modelDB.trashed[id]; !ok {
+		return ErrNotFound
+	}
+
+	return db.write(modelDB, operation{Kind: kind, Model: model, ID: id})
+}
+
+func (db *databaseManager) ListTrash(model Model) ([]Trashed, error) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
+
+	list := make([]Trashed, 0, len(modelDB.trashed))
+	for id, item := range modelDB.trashed {
+		list = append(list, item.readTrashed(id))
+	}
+	sortTrashed(modelDB.trashed, list)
+
+	return list, nil
 }
 
 func isPointer(v interface{}) error {
@@ -1,6 +1,10 @@
 package db
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/brianvoe/gofakeit/v6"
@@ -162,3 +166,438 @@ func TestDelete(t *testing.T) {
 		require.ErrorIs(t, err, ErrNotFound)
 	})
 }
+
+func TestConcurrency(t *testing.T) {
+	t.Run("all methods in parallel", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		workers := 16
+		iterations := 200
+		models := []Model{Task, Model("other")}
+
+		// the workers report to the test goroutine, only it can stop the test
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				model := models[w%len(models)]
+				for range iterations {
+					id := uuid.New()
+					value := gofakeit.Map()
+					if err := db.Create(model, id, &value); err != nil {
+						errs <- err
+						return
+					}
+
+					v, err := db.Get(model, id)
+					if err != nil {
+						errs <- err
+						return
+					}
+					if !reflect.DeepEqual(&value, v) {
+						errs <- fmt.Errorf("got %v, want %v", v, &value)
+						return
+					}
+
+					newValue := gofakeit.Map()
+					if err := db.Update(model, id, &newValue); err != nil {
+						errs <- err
+						return
+					}
+
+					if _, err := db.List(model); err != nil {
+						errs <- err
+						return
+					}
+
+					if err := db.Delete(model, id); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// assert
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		for _, model := range models {
+			values, err := db.List(model)
+			require.NoError(t, err)
+			require.Len(t, values, 0)
+		}
+	})
+
+	t.Run("create same id only once", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		workers := 32
+
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		var created atomic.Int32
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value := gofakeit.Map()
+				err := db.Create(Task, id, &value)
+				if err == nil {
+					created.Add(1)
+					return
+				}
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// assert
+		for err := range errs {
+			require.ErrorIs(t, err, ErrAlreadyExists)
+		}
+		require.EqualValues(t, 1, created.Load())
+		values, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, values, 1)
+	})
+
+	t.Run("delete same id only once", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		value := gofakeit.Map()
+		require.NoError(t, db.Create(Task, id, &value))
+		workers := 32
+
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		var deleted atomic.Int32
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := db.Delete(Task, id)
+				if err == nil {
+					deleted.Add(1)
+					return
+				}
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// assert
+		for err := range errs {
+			require.ErrorIs(t, err, ErrNotFound)
+		}
+		require.EqualValues(t, 1, deleted.Load())
+	})
+
+	t.Run("update and delete race", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		n := 100
+		ids := make([]uuid.UUID, 0, n)
+		for range n {
+			id := uuid.New()
+			value := gofakeit.Map()
+			require.NoError(t, db.Create(Task, id, &value))
+			ids = append(ids, id)
+		}
+
+		updateErrs := make(chan error, n)
+		deleteErrs := make(chan error, n)
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				value := gofakeit.Map()
+				if err := db.Update(Task, id, &value); err != nil {
+					updateErrs <- err
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if err := db.Delete(Task, id); err != nil {
+					deleteErrs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(updateErrs)
+		close(deleteErrs)
+
+		// assert
+		for err := range updateErrs {
+			require.ErrorIs(t, err, ErrNotFound)
+		}
+		for err := range deleteErrs {
+			require.NoError(t, err)
+		}
+		values, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, values, 0)
+		for _, id := range ids {
+			_, err := db.Get(Task, id)
+			require.ErrorIs(t, err, ErrNotFound)
+		}
+	})
+}
+
+type testCloner struct {
+	Tags []string
+}
+
+func (c *testCloner) Clone() interface{} {
+	return &testCloner{Tags: append([]string{}, c.Tags...)}
+}
+
+func TestIsolation(t *testing.T) {
+	t.Run("mutate returned value", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		value := randomTestValue()
+		expected := *value
+		require.NoError(t, db.Create(Task, id, value))
+
+		// assert
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		v.(*testValue).Name = gofakeit.Name()
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		list[0].(*testValue).Count++
+
+		v, err = db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, &expected, v)
+	})
+
+	t.Run("mutate written value", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		value := randomTestValue()
+		expected := *value
+		require.NoError(t, db.Create(Task, id, value))
+		value.Name = gofakeit.Name()
+
+		// assert
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, &expected, v)
+
+		updated := randomTestValue()
+		expected = *updated
+		require.NoError(t, db.Update(Task, id, updated))
+		updated.Count++
+
+		v, err = db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, &expected, v)
+	})
+
+	t.Run("failed transaction", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		value := randomTestValue()
+		expected := *value
+		require.NoError(t, db.Create(Task, id, value))
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			v, err := tx.Get(Task, id)
+			if err != nil {
+				return err
+			}
+			v.(*testValue).Name = gofakeit.Name()
+			return gofakeit.Error()
+		})
+		require.Error(t, err)
+
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, &expected, v)
+	})
+
+	t.Run("cloner", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, db.Create(Task, id, &testCloner{Tags: []string{"a", "b"}}))
+
+		// assert
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		v.(*testCloner).Tags[0] = "c"
+
+		v, err = db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b"}, v.(*testCloner).Tags)
+	})
+}
+
+type testVersioned struct {
+	Name    string `json:"name"`
+	Version uint64 `json:"version"`
+}
+
+func (v *testVersioned) SetVersion(version uint64) {
+	v.Version = version
+}
+
+func TestUpdateIfVersion(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		value := &testVersioned{Name: gofakeit.Name()}
+		require.NoError(t, db.Create(Task, id, value))
+		require.EqualValues(t, 1, value.Version)
+
+		// assert
+		updated := &testVersioned{Name: gofakeit.Name()}
+		require.NoError(t, db.UpdateIfVersion(Task, id, 1, updated))
+		require.EqualValues(t, 2, updated.Version)
+
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, updated, v)
+
+		require.NoError(t, db.Update(Task, id, &testVersioned{}))
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, list[0].(*testVersioned).Version)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		value := &testVersioned{Name: gofakeit.Name()}
+		require.NoError(t, db.Create(Task, id, value))
+		require.NoError(t, db.Update(Task, id, &testVersioned{Name: gofakeit.Name()}))
+
+		// assert
+		err := db.UpdateIfVersion(Task, id, 1, &testVersioned{Name: gofakeit.Name()})
+		require.ErrorIs(t, err, ErrVersionMismatch)
+		require.ErrorIs(t, err, ErrConflict)
+
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, v.(*testVersioned).Version)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db := New()
+
+		// assert
+		err := db.UpdateIfVersion(Task, uuid.New(), 1, &testVersioned{})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("concurrent writers", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, db.Create(Task, id, &testVersioned{}))
+
+		workers := 32
+		var wg sync.WaitGroup
+		var succeeded atomic.Int32
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := db.UpdateIfVersion(Task, id, 1, &testVersioned{Name: gofakeit.Name()})
+				if err == nil {
+					succeeded.Add(1)
+					return
+				}
+				require.ErrorIs(t, err, ErrVersionMismatch)
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		require.EqualValues(t, 1, succeeded.Load())
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, db.Create(Task, id, &testVersioned{}))
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.UpdateIfVersion(Task, id, 1, &testVersioned{}))
+			require.ErrorIs(t, tx.UpdateIfVersion(Task, id, 1, &testVersioned{}), ErrVersionMismatch)
+
+			v, err := tx.Get(Task, id)
+			require.NoError(t, err)
+			require.EqualValues(t, 2, v.(*testVersioned).Version)
+			return nil
+		})
+		require.NoError(t, err)
+
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, v.(*testVersioned).Version)
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		config := testFileConfig(t)
+		schema := Schema{Task: func() interface{} { return &testVersioned{} }}
+		db, err := NewFile(config, schema)
+		require.NoError(t, err)
+
+		// prepare
+		snapshotted := uuid.New()
+		require.NoError(t, db.Create(Task, snapshotted, &testVersioned{}))
+		require.NoError(t, db.Update(Task, snapshotted, &testVersioned{}))
+		require.NoError(t, db.Snapshot())
+
+		replayed := uuid.New()
+		require.NoError(t, db.Create(Task, replayed, &testVersioned{}))
+		require.NoError(t, db.Update(Task, snapshotted, &testVersioned{}))
+		require.NoError(t, db.Close())
+
+		// assert
+		db, err = NewFile(config, schema)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		v, err := db.Get(Task, snapshotted)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, v.(*testVersioned).Version)
+
+		v, err = db.Get(Task, replayed)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, v.(*testVersioned).Version)
+	})
+}
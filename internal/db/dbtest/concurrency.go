@@ -0,0 +1,218 @@
+package dbtest
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func testTransactions(t *testing.T, open Opener) {
+	t.Run("commit", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		ids := create(t, database, db.Task, 1, 2, 3)
+
+		// assert, the transaction reads its own writes
+		require.NoError(t, database.RunInTx(func(tx db.Tx) error {
+			require.NoError(t, tx.Update(db.Task, ids[0], &Value{Count: 10}))
+			require.NoError(t, tx.Delete(db.Task, ids[1]))
+			require.NoError(t, tx.Trash(db.Task, ids[2]))
+			create(t, tx, db.Task, 4)
+
+			require.Equal(t, []int{10, 4}, counts(t, tx, db.Task))
+			trashed, err := tx.ListTrash(db.Task)
+			require.NoError(t, err)
+			require.Len(t, trashed, 1)
+			return nil
+		}))
+		require.Equal(t, []int{10, 4}, counts(t, database, db.Task))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		ids := create(t, database, db.Task, 1, 2)
+		failed := errors.New("failed")
+
+		// assert
+		err := database.RunInTx(func(tx db.Tx) error {
+			require.NoError(t, tx.Update(db.Task, ids[0], &Value{Count: 10}))
+			require.NoError(t, tx.Delete(db.Task, ids[1]))
+			create(t, tx, db.Task, 3)
+			return failed
+		})
+		require.ErrorIs(t, err, failed)
+		require.Equal(t, []int{1, 2}, counts(t, database, db.Task))
+
+		// nothing is left of the transaction, the next records go on in order
+		create(t, database, db.Task, 4)
+		require.Equal(t, []int{1, 2, 4}, counts(t, database, db.Task))
+	})
+
+	t.Run("serializable", func(t *testing.T) {
+		database := open(t)
+
+		// prepare, every transaction increments the counter it reads
+		id := create(t, database, db.Task, 0)[0]
+		workers, increments := 8, 25
+
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range increments {
+					require.NoError(t, database.RunInTx(func(tx db.Tx) error {
+						v, err := tx.Get(db.Task, id)
+						if err != nil {
+							return err
+						}
+						return tx.Update(db.Task, id, &Value{Count: v.(*Value).Count + 1})
+					}))
+				}
+			}()
+		}
+		wg.Wait()
+
+		// assert, no increment is lost
+		require.Equal(t, []int{workers * increments}, counts(t, database, db.Task))
+	})
+}
+
+func testConcurrency(t *testing.T, open Opener) {
+	t.Run("parallel writers", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		workers, iterations := 16, 50
+		models := []db.Model{db.Task, Other}
+
+		var wg sync.WaitGroup
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				model := models[w%len(models)]
+				for i := range iterations {
+					id := uuid.New()
+					require.NoError(t, database.Create(model, id, &Value{Count: i}))
+					require.NoError(t, database.Update(model, id, &Value{Count: i + 1}))
+
+					v, err := database.Get(model, id)
+					require.NoError(t, err)
+					require.Equal(t, i+1, v.(*Value).Count)
+
+					_, err = database.List(model)
+					require.NoError(t, err)
+					if i%2 == 0 {
+						require.NoError(t, database.Delete(model, id))
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		for _, model := range models {
+			list, err := database.List(model)
+			require.NoError(t, err)
+			require.Len(t, list, workers/len(models)*iterations/2)
+		}
+	})
+
+	t.Run("create same id only once", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		id := uuid.New()
+		workers := 32
+
+		var wg sync.WaitGroup
+		var created atomic.Int32
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := database.Create(db.Task, id, &Value{Count: w})
+				if err == nil {
+					created.Add(1)
+					return
+				}
+				require.ErrorIs(t, err, db.ErrAlreadyExists)
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		require.EqualValues(t, 1, created.Load())
+		require.Len(t, counts(t, database, db.Task), 1)
+	})
+
+	t.Run("one update per version", func(t *testing.T) {
+		database := open(t)
+
+		// prepare, every writer tries to move the record from version 1
+		id := create(t, database, db.Task, 0)[0]
+		workers := 32
+
+		var wg sync.WaitGroup
+		var updated atomic.Int32
+		for w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := database.UpdateIfVersion(db.Task, id, 1, &Value{Count: w})
+				if err == nil {
+					updated.Add(1)
+					return
+				}
+				require.ErrorIs(t, err, db.ErrVersionMismatch)
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		require.EqualValues(t, 1, updated.Load())
+		v, err := database.Get(db.Task, id)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, v.(*Value).Version)
+	})
+
+	t.Run("update and delete race", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		ids := create(t, database, db.Task, make([]int, 100)...)
+
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				err := database.Update(db.Task, id, &Value{Count: 1})
+				if err != nil {
+					require.ErrorIs(t, err, db.ErrNotFound)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				require.NoError(t, database.Delete(db.Task, id))
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		require.Empty(t, counts(t, database, db.Task))
+		for _, id := range ids {
+			_, err := database.Get(db.Task, id)
+			require.ErrorIs(t, err, db.ErrNotFound)
+		}
+	})
+}
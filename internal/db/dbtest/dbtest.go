@@ -0,0 +1,78 @@
+// Package dbtest is the conformance suite of db.Database, every implementation runs it
+// to behave the same as the others.
+package dbtest
+
+import (
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Other is a second model, the suite checks the models don't see each other
+const Other db.Model = "dbtest"
+
+// Value is the value of the records of the suite
+type Value struct {
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+	Version uint64 `json:"-"`
+}
+
+func (v *Value) SetVersion(version uint64) {
+	v.Version = version
+}
+
+// Schema decodes the records of the suite, for the implementations which persist them
+var Schema = db.Schema{
+	db.Task: func() interface{} { return &Value{} },
+	Other:   func() interface{} { return &Value{} },
+}
+
+// Opener opens an empty database, which is closed by the cleanups of t
+type Opener func(t *testing.T) db.Database
+
+// Run runs the whole suite, every test against a new database
+func Run(t *testing.T, open Opener) {
+	t.Run("errors", func(t *testing.T) { testErrors(t, open) })
+	t.Run("values", func(t *testing.T) { testValues(t, open) })
+	t.Run("order", func(t *testing.T) { testOrder(t, open) })
+	t.Run("pages", func(t *testing.T) { testPages(t, open) })
+	t.Run("trash", func(t *testing.T) { testTrash(t, open) })
+	t.Run("transactions", func(t *testing.T) { testTransactions(t, open) })
+	t.Run("concurrency", func(t *testing.T) { testConcurrency(t, open) })
+	t.Run("replication", func(t *testing.T) { testReplication(t, open) })
+	t.Run("large dataset", func(t *testing.T) { testLargeDataset(t, open) })
+}
+
+// create creates a record for every count, and returns their ids in order
+func create(t *testing.T, tx db.Tx, model db.Model, counts ...int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(counts))
+	for _, count := range counts {
+		id := uuid.New()
+		require.NoError(t, tx.Create(model, id, &Value{Count: count}))
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// counts lists the counts of the records of model in order
+func counts(t *testing.T, tx db.Reader, model db.Model) []int {
+	list, err := tx.List(model)
+	require.NoError(t, err)
+
+	return valueCounts(t, list)
+}
+
+func valueCounts(t *testing.T, list []interface{}) []int {
+	counts := make([]int, 0, len(list))
+	for _, v := range list {
+		value, ok := v.(*Value)
+		require.True(t, ok, "%T is not a *Value", v)
+		counts = append(counts, value.Count)
+	}
+
+	return counts
+}
@@ -0,0 +1,159 @@
+package dbtest
+
+import (
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func testErrors(t *testing.T, open Opener) {
+	t.Run("not found", func(t *testing.T) {
+		database := open(t)
+
+		// prepare, the id of another model isn't found either
+		id := create(t, database, Other, 1)[0]
+		value := &Value{Count: 2}
+
+		// assert
+		v, err := database.Get(db.Task, id)
+		require.Nil(t, v)
+		require.ErrorIs(t, err, db.ErrNotFound)
+
+		require.ErrorIs(t, database.Update(db.Task, id, value), db.ErrNotFound)
+		require.ErrorIs(t, database.UpdateIfVersion(db.Task, id, 1, value), db.ErrNotFound)
+		require.ErrorIs(t, database.Delete(db.Task, id), db.ErrNotFound)
+		require.ErrorIs(t, database.Trash(db.Task, id), db.ErrNotFound)
+
+		// only trashed records are restored or purged
+		require.ErrorIs(t, database.Restore(Other, id), db.ErrNotFound)
+		require.ErrorIs(t, database.Purge(Other, id), db.ErrNotFound)
+
+		require.Equal(t, []int{1}, counts(t, database, Other))
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		ids := create(t, database, db.Task, 1, 2)
+		require.NoError(t, database.Trash(db.Task, ids[1]))
+
+		// assert, a trashed id is still used
+		for _, id := range ids {
+			err := database.Create(db.Task, id, &Value{Count: 3})
+			require.ErrorIs(t, err, db.ErrAlreadyExists)
+			require.ErrorIs(t, err, db.ErrConflict)
+		}
+		require.Equal(t, []int{1}, counts(t, database, db.Task))
+
+		// ids are per model
+		require.NoError(t, database.Create(Other, ids[0], &Value{Count: 4}))
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		id := create(t, database, db.Task, 1)[0]
+		require.NoError(t, database.Update(db.Task, id, &Value{Count: 2}))
+
+		// assert
+		err := database.UpdateIfVersion(db.Task, id, 1, &Value{Count: 3})
+		require.ErrorIs(t, err, db.ErrVersionMismatch)
+		require.ErrorIs(t, err, db.ErrConflict)
+		require.NoError(t, database.UpdateIfVersion(db.Task, id, 2, &Value{Count: 4}))
+		require.Equal(t, []int{4}, counts(t, database, db.Task))
+	})
+
+	t.Run("only pointer", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		id := create(t, database, db.Task, 1)[0]
+
+		// assert
+		for _, value := range []interface{}{nil, Value{Count: 2}} {
+			require.ErrorIs(t, database.Create(db.Task, uuid.New(), value), db.ErrOnlyPointer)
+			require.ErrorIs(t, database.Update(db.Task, id, value), db.ErrOnlyPointer)
+			require.ErrorIs(t, database.UpdateIfVersion(db.Task, id, 1, value), db.ErrOnlyPointer)
+
+			err := database.RunInTx(func(tx db.Tx) error {
+				return tx.Create(db.Task, uuid.New(), value)
+			})
+			require.ErrorIs(t, err, db.ErrOnlyPointer)
+		}
+		require.Equal(t, []int{1}, counts(t, database, db.Task))
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		database := open(t)
+
+		// assert
+		_, err := database.ListRange(db.Task, db.ListOptions{After: "invalid"})
+		require.ErrorIs(t, err, db.ErrInvalidCursor)
+		_, err = database.ListRange(db.Task, db.ListOptions{Direction: "sideways"})
+		require.ErrorIs(t, err, db.ErrInvalidDirection)
+	})
+}
+
+func testValues(t *testing.T, open Opener) {
+	t.Run("versions", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		id := uuid.New()
+		value := &Value{Count: 1}
+		require.NoError(t, database.Create(db.Task, id, value))
+		require.EqualValues(t, 1, value.Version)
+
+		updated := &Value{Count: 2}
+		require.NoError(t, database.Update(db.Task, id, updated))
+		require.EqualValues(t, 2, updated.Version)
+
+		// assert
+		v, err := database.Get(db.Task, id)
+		require.NoError(t, err)
+		require.Equal(t, &Value{Count: 2, Version: 2}, v)
+
+		list, err := database.List(db.Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{&Value{Count: 2, Version: 2}}, list)
+	})
+
+	t.Run("copies", func(t *testing.T) {
+		database := open(t)
+
+		// prepare, the values written and read are not the stored ones
+		id := uuid.New()
+		value := &Value{Name: "written", Count: 1}
+		require.NoError(t, database.Create(db.Task, id, value))
+		value.Name = "mutated"
+
+		v, err := database.Get(db.Task, id)
+		require.NoError(t, err)
+		v.(*Value).Name = "mutated"
+
+		list, err := database.List(db.Task)
+		require.NoError(t, err)
+		list[0].(*Value).Name = "mutated"
+
+		// assert
+		v, err = database.Get(db.Task, id)
+		require.NoError(t, err)
+		require.Equal(t, "written", v.(*Value).Name)
+	})
+
+	t.Run("models", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		create(t, database, db.Task, 1, 2)
+		create(t, database, Other, 3)
+
+		// assert
+		require.Equal(t, []int{1, 2}, counts(t, database, db.Task))
+		require.Equal(t, []int{3}, counts(t, database, Other))
+	})
+}
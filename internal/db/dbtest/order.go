@@ -0,0 +1,191 @@
+package dbtest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func testOrder(t *testing.T, open Opener) {
+	t.Run("empty", func(t *testing.T) {
+		database := open(t)
+
+		// assert, an empty list isn't nil
+		list, err := database.List(db.Task)
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		require.Empty(t, list)
+	})
+
+	t.Run("create order", func(t *testing.T) {
+		database := open(t)
+
+		// prepare, updates keep the place of a record and deletes close the gap
+		ids := create(t, database, db.Task, 1, 2, 3, 4)
+		require.NoError(t, database.Update(db.Task, ids[0], &Value{Count: 10}))
+		require.NoError(t, database.Delete(db.Task, ids[1]))
+		create(t, database, db.Task, 5)
+
+		// assert
+		require.Equal(t, []int{10, 3, 4, 5}, counts(t, database, db.Task))
+	})
+
+	t.Run("ids aren't reused", func(t *testing.T) {
+		database := open(t)
+
+		// prepare, a record created again with a deleted id goes last
+		ids := create(t, database, db.Task, 1, 2)
+		require.NoError(t, database.Delete(db.Task, ids[0]))
+		require.NoError(t, database.Create(db.Task, ids[0], &Value{Count: 3}))
+
+		// assert
+		require.Equal(t, []int{2, 3}, counts(t, database, db.Task))
+		v, err := database.Get(db.Task, ids[0])
+		require.NoError(t, err)
+		require.Equal(t, &Value{Count: 3, Version: 1}, v)
+	})
+}
+
+// pages lists every page of model with limit
+func pages(t *testing.T, database db.Database, model db.Model, limit int, direction db.Direction) [][]int {
+	var pages [][]int
+	opts := db.ListOptions{Limit: limit, Direction: direction}
+	for {
+		page, err := database.ListRange(model, opts)
+		require.NoError(t, err)
+		pages = append(pages, valueCounts(t, page.Values))
+
+		if page.Next == "" {
+			return pages
+		}
+		opts.After = page.Next
+	}
+}
+
+func testPages(t *testing.T, open Opener) {
+	t.Run("forward and backward", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		create(t, database, db.Task, 1, 2, 3, 4, 5)
+
+		// assert
+		require.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, pages(t, database, db.Task, 2, db.DirectionForward))
+		require.Equal(t, [][]int{{5, 4}, {3, 2}, {1}}, pages(t, database, db.Task, 2, db.DirectionBackward))
+		require.Equal(t, [][]int{{1, 2, 3, 4, 5}}, pages(t, database, db.Task, 0, ""))
+		require.Equal(t, [][]int{{}}, pages(t, database, Other, 2, ""))
+	})
+
+	t.Run("cursor of a deleted record", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		ids := create(t, database, db.Task, 1, 2, 3, 4)
+		page, err := database.ListRange(db.Task, db.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, valueCounts(t, page.Values))
+
+		require.NoError(t, database.Delete(db.Task, ids[1]))
+		require.NoError(t, database.Delete(db.Task, ids[2]))
+
+		// assert, the cursor stays valid
+		page, err = database.ListRange(db.Task, db.ListOptions{After: page.Next, Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, []int{4}, valueCounts(t, page.Values))
+		require.Empty(t, page.Next)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		create(t, database, db.Task, 1, 2, 3, 4, 5, 6)
+		even := func(v interface{}) bool { return v.(*Value).Count%2 == 0 }
+
+		// assert, a page holds limit records which pass the filter
+		page, err := database.ListRange(db.Task, db.ListOptions{Limit: 2, Filter: even})
+		require.NoError(t, err)
+		require.Equal(t, []int{2, 4}, valueCounts(t, page.Values))
+
+		page, err = database.ListRange(db.Task, db.ListOptions{After: page.Next, Limit: 2, Filter: even})
+		require.NoError(t, err)
+		require.Equal(t, []int{6}, valueCounts(t, page.Values))
+		require.Empty(t, page.Next)
+	})
+}
+
+func testTrash(t *testing.T, open Opener) {
+	t.Run("restore to the same place", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		ids := create(t, database, db.Task, 1, 2, 3)
+		require.NoError(t, database.Trash(db.Task, ids[1]))
+		require.NoError(t, database.Trash(db.Task, ids[0]))
+		create(t, database, db.Task, 4)
+
+		// assert, the trash is in create order too
+		require.Equal(t, []int{3, 4}, counts(t, database, db.Task))
+		trashed, err := database.ListTrash(db.Task)
+		require.NoError(t, err)
+		require.Len(t, trashed, 2)
+		require.Equal(t, ids[0], trashed[0].ID)
+		require.Equal(t, &Value{Count: 1, Version: 1}, trashed[0].Value)
+		require.False(t, trashed[0].DeletedAt.IsZero())
+		require.Equal(t, ids[1], trashed[1].ID)
+
+		_, err = database.Get(db.Task, ids[0])
+		require.ErrorIs(t, err, db.ErrNotFound)
+
+		require.NoError(t, database.Restore(db.Task, ids[1]))
+		require.Equal(t, []int{2, 3, 4}, counts(t, database, db.Task))
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		database := open(t)
+
+		// prepare
+		ids := create(t, database, db.Task, 1, 2)
+		require.NoError(t, database.Trash(db.Task, ids[0]))
+		require.NoError(t, database.Purge(db.Task, ids[0]))
+
+		// assert, the id is free again
+		trashed, err := database.ListTrash(db.Task)
+		require.NoError(t, err)
+		require.Empty(t, trashed)
+		require.NoError(t, database.Create(db.Task, ids[0], &Value{Count: 3}))
+		require.Equal(t, []int{2, 3}, counts(t, database, db.Task))
+	})
+}
+
+func testLargeDataset(t *testing.T, open Opener) {
+	n := 10000
+	if testing.Short() {
+		n = 1000
+	}
+	database := open(t)
+
+	// prepare, in batches so persistent databases don't sync every record
+	expected := make([]int, 0, n)
+	for start := 0; start < n; start += 1000 {
+		require.NoError(t, database.RunInTx(func(tx db.Tx) error {
+			for i := start; i < min(start+1000, n); i++ {
+				create(t, tx, db.Task, i)
+				expected = append(expected, i)
+			}
+			return nil
+		}))
+	}
+
+	// assert
+	require.Equal(t, expected, counts(t, database, db.Task))
+
+	forward := slices.Concat(pages(t, database, db.Task, 97, db.DirectionForward)...)
+	require.Equal(t, expected, forward)
+
+	backward := slices.Concat(pages(t, database, db.Task, 101, db.DirectionBackward)...)
+	slices.Reverse(backward)
+	require.Equal(t, expected, backward)
+}
@@ -0,0 +1,212 @@
+package dbtest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+// replicated is a database which replicates
+type replicated interface {
+	db.Database
+	db.Replicator
+}
+
+// openReplicated opens a database, the test is skipped unless it replicates
+func openReplicated(t *testing.T, open Opener) replicated {
+	database, ok := open(t).(replicated)
+	if !ok {
+		t.Skip("the database doesn't replicate")
+	}
+	return database
+}
+
+// requireReplica checks replica holds the same records as primary, in the same order
+// and with the same versions, and hands out the same cursors
+func requireReplica(t *testing.T, primary, replica db.Database) {
+	for _, model := range []db.Model{db.Task, Other} {
+		expected, err := primary.List(model)
+		require.NoError(t, err)
+		list, err := replica.List(model)
+		require.NoError(t, err)
+		require.Equal(t, expected, list)
+
+		expectedTrash, err := primary.ListTrash(model)
+		require.NoError(t, err)
+		trashed, err := replica.ListTrash(model)
+		require.NoError(t, err)
+		require.Equal(t, expectedTrash, trashed)
+
+		expectedPage, err := primary.ListRange(model, db.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		page, err := replica.ListRange(model, db.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, expectedPage, page)
+	}
+}
+
+// applyUntil applies the events of replication to replica up to seq, a write at once
+func applyUntil(t *testing.T, replica db.Replicator, replication *db.Replication, seq uint64) {
+	pending := []db.Event{}
+	for {
+		select {
+		case e := <-replication.Events():
+			pending = append(pending, e)
+			if e.Seq < e.LastSeq {
+				continue
+			}
+
+			require.NoError(t, replica.ApplyEvents(pending))
+			pending = pending[:0]
+			if e.Seq >= seq {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event after %d", seq)
+		}
+	}
+}
+
+// writeAll runs every kind of write on database, a transaction included
+func writeAll(t *testing.T, database db.Database) {
+	ids := create(t, database, db.Task, 1, 2, 3, 4)
+	create(t, database, Other, 5)
+	require.NoError(t, database.Update(db.Task, ids[0], &Value{Count: 10}))
+	require.NoError(t, database.Delete(db.Task, ids[1]))
+	require.NoError(t, database.Trash(db.Task, ids[2]))
+	require.NoError(t, database.Trash(db.Task, ids[3]))
+	require.NoError(t, database.RunInTx(func(tx db.Tx) error {
+		create(t, tx, db.Task, 6)
+		require.NoError(t, tx.Restore(db.Task, ids[2]))
+		return tx.Purge(db.Task, ids[3])
+	}))
+}
+
+func testReplication(t *testing.T, open Opener) {
+	t.Run("copy then events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		primary, replica := openReplicated(t, open), openReplicated(t, open)
+
+		// prepare, the replica had records of its own
+		writeAll(t, primary)
+		create(t, replica, db.Task, 7)
+
+		replication, err := primary.Replicate(ctx, nil)
+		require.NoError(t, err)
+		require.NotNil(t, replication.Copy)
+		require.NoError(t, replica.LoadCopy(replication.Copy))
+
+		// assert
+		requireReplica(t, primary, replica)
+
+		writeAll(t, primary)
+		applyUntil(t, replica, replication, replication.Seq())
+		requireReplica(t, primary, replica)
+
+		// the next records go to the same places
+		create(t, primary, db.Task, 8)
+		create(t, replica, db.Task, 8)
+		requireReplica(t, primary, replica)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		primary, replica := openReplicated(t, open), openReplicated(t, open)
+
+		// prepare
+		writeAll(t, primary)
+		replication, err := primary.Replicate(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, replica.LoadCopy(replication.Copy))
+		seq := replication.Copy.Seq
+
+		// assert, the events after seq are still kept so there is no copy
+		writeAll(t, primary)
+		replication, err = primary.Replicate(ctx, &seq)
+		require.NoError(t, err)
+		require.Nil(t, replication.Copy)
+		applyUntil(t, replica, replication, replication.Seq())
+		requireReplica(t, primary, replica)
+	})
+
+	t.Run("gap", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		primary, replica := openReplicated(t, open), openReplicated(t, open)
+
+		// prepare
+		replication, err := primary.Replicate(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, replica.LoadCopy(replication.Copy))
+		create(t, primary, db.Task, 1, 2)
+		first := <-replication.Events()
+		second := <-replication.Events()
+
+		// assert, nothing is applied out of order
+		require.ErrorIs(t, replica.ApplyEvents([]db.Event{second}), db.ErrReplicationGap)
+		require.Empty(t, counts(t, replica, db.Task))
+
+		require.NoError(t, replica.ApplyEvents([]db.Event{first}))
+		require.ErrorIs(t, replica.ApplyEvents([]db.Event{first}), db.ErrReplicationGap)
+		require.NoError(t, replica.ApplyEvents([]db.Event{second}))
+		requireReplica(t, primary, replica)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		primary, replica := openReplicated(t, open), openReplicated(t, open)
+
+		// prepare
+		writeAll(t, primary)
+		r, w := io.Pipe()
+		written := make(chan error, 1)
+		go func() {
+			written <- db.WriteReplication(ctx, primary, w, nil)
+			w.Close()
+		}()
+
+		progress := make(chan db.ReplicaProgress, 100)
+		read := make(chan error, 1)
+		go func() {
+			read <- db.ReadReplication(replica, Schema, r, func(p db.ReplicaProgress) { progress <- p })
+		}()
+
+		waitFor := func(seq uint64) {
+			for {
+				select {
+				case p := <-progress:
+					if p.Seq >= seq {
+						return
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("replica didn't get to %d", seq)
+				}
+			}
+		}
+
+		// assert
+		snapshot, err := primary.OpenSnapshot()
+		require.NoError(t, err)
+		waitFor(snapshot.Seq())
+		snapshot.Close()
+		requireReplica(t, primary, replica)
+
+		writeAll(t, primary)
+		snapshot, err = primary.OpenSnapshot()
+		require.NoError(t, err)
+		waitFor(snapshot.Seq())
+		snapshot.Close()
+		requireReplica(t, primary, replica)
+
+		// the stream ends with ctx
+		cancel()
+		require.NoError(t, <-written)
+		require.NoError(t, <-read)
+	})
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dragon-huang0403/todo-go/pkg/config"
+)
+
+var (
+	ErrMissingKey = errors.New("missing encryption key")
+)
+
+// encryptedMarker is the file marking a data dir whose data is all encrypted,
+// its plain data isn't migrated anymore
+const encryptedMarker = "encrypted"
+
+// an encrypted frame payload is | magic byte | key id uint32 | nonce | sealed payload |,
+// while a plain one is JSON so it never starts with the magic byte
+const (
+	encryptedMagic   = 0xe1
+	encryptedHeader  = 5
+	encryptionKeyLen = 32
+)
+
+type dataKey struct {
+	id   uint32
+	aead cipher.AEAD
+}
+
+// keyring encrypts the frames persisted by the file database with AES-256-GCM
+type keyring struct {
+	// current seals the frames written, they are written in plain text when it is nil
+	current *dataKey
+	keys    map[uint32]*dataKey
+	// migrate reads the frames written in plain text despite the current key
+	migrate bool
+
+	// stale is set once a frame which wasn't written with the current key is opened
+	stale bool
+}
+
+// newKeyring loads the keys of encryption, the plain data of dir is only migrated until it is marked encrypted
+func newKeyring(dir string, encryption EncryptionConfig) (*keyring, error) {
+	k := &keyring{keys: map[uint32]*dataKey{}}
+
+	for i, path := range append([]string{encryption.KeyFile}, encryption.PreviousKeyFiles...) {
+		if path == "" {
+			continue
+		}
+
+		secret, err := config.ReadKeyFile(path, encryptionKeyLen)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newDataKey(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			k.current = key
+		}
+		k.keys[key.id] = key
+	}
+
+	if encryption.MigratePlainData && k.current != nil {
+		_, err := os.Stat(filepath.Join(dir, encryptedMarker))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		k.migrate = err != nil
+	}
+
+	return k, nil
+}
+
+func newDataKey(secret []byte) (*dataKey, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// the id tells which key sealed a frame without giving the key away
+	sum := sha256.Sum256(secret)
+	return &dataKey{id: binary.BigEndian.Uint32(sum[:4]), aead: aead}, nil
+}
+
+// seal encrypts payload with the current key, if any
+func (k *keyring) seal(payload []byte) ([]byte, error) {
+	if k.current == nil {
+		return payload, nil
+	}
+
+	nonceSize := k.current.aead.NonceSize()
+	buf := make([]byte, encryptedHeader+nonceSize, encryptedHeader+nonceSize+len(payload)+k.current.aead.Overhead())
+	buf[0] = encryptedMagic
+	binary.BigEndian.PutUint32(buf[1:encryptedHeader], k.current.id)
+	nonce := buf[encryptedHeader:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// the header is authenticated along with the payload
+	return k.current.aead.Seal(buf, nonce, payload, buf[:encryptedHeader]), nil
+}
+
+// open decrypts a payload written by seal with any of the keys
+func (k *keyring) open(payload []byte) ([]byte, error) {
+	if len(payload) == 0 || payload[0] != encryptedMagic {
+		if k.current == nil {
+			return payload, nil
+		}
+		if !k.migrate {
+			return nil, fmt.Errorf("%w: frame isn't encrypted", ErrCorruptedLog)
+		}
+		k.stale = true
+		return payload, nil
+	}
+
+	if len(payload) < encryptedHeader {
+		return nil, fmt.Errorf("%w: truncated encrypted frame", ErrCorruptedLog)
+	}
+	id := binary.BigEndian.Uint32(payload[1:encryptedHeader])
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: the data is encrypted with key %08x", ErrMissingKey, id)
+	}
+
+	nonceSize := key.aead.NonceSize()
+	if len(payload) < encryptedHeader+nonceSize {
+		return nil, fmt.Errorf("%w: truncated encrypted frame", ErrCorruptedLog)
+	}
+	nonce := payload[encryptedHeader : encryptedHeader+nonceSize]
+	plain, err := key.aead.Open(nil, nonce, payload[encryptedHeader+nonceSize:], payload[:encryptedHeader])
+	if err != nil {
+		return nil, fmt.Errorf("%w: encrypted frame fails authentication", ErrCorruptedLog)
+	}
+
+	k.stale = k.stale || key != k.current
+	return plain, nil
+}
+
+// markEncrypted marks dir as encrypted once its data is all encrypted with the current key,
+// or unmarks it when there is no key
+func (k *keyring) markEncrypted(dir string) error {
+	path := filepath.Join(dir, encryptedMarker)
+	if k.current == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	k.migrate = false
+	return os.WriteFile(path, nil, 0o644)
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyFile(t *testing.T) string {
+	secret := make([]byte, encryptionKeyLen)
+	_, err := rand.Read(secret)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(secret)), 0o600))
+	return path
+}
+
+// requireUnreadable checks no file in dir holds the secret in plain text
+func requireUnreadable(t *testing.T, dir string, secret string) {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		require.False(t, bytes.Contains(content, []byte(secret)), "%s is in plain text in %s", secret, entry.Name())
+	}
+}
+
+// reencrypt runs the database until its data is rewritten with the current key
+func reencrypt(t *testing.T, db *FileDatabase) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- db.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		snapshots, err := listSnapshots(db.config.Dir)
+		require.NoError(t, err)
+		return len(snapshots) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NoError(t, db.Close())
+}
+
+func TestEncryption(t *testing.T) {
+	t.Run("unreadable without the key", func(t *testing.T) {
+		config := testFileConfig(t)
+		config.Encryption.KeyFile = testKeyFile(t)
+		db := openTestFileDB(t, config)
+
+		// prepare, the records are both in a snapshot and in the log
+		id := uuid.New()
+		require.NoError(t, db.Create(Task, id, &testValue{Name: "confidential customer"}))
+		require.NoError(t, db.Snapshot())
+		require.NoError(t, db.Create(Task, uuid.New(), &testValue{Name: "secret incident"}))
+		require.NoError(t, db.Trash(Task, id))
+		require.NoError(t, db.Close())
+
+		// assert
+		requireUnreadable(t, config.Dir, "confidential customer")
+		requireUnreadable(t, config.Dir, "secret incident")
+
+		reopened := openTestFileDB(t, config)
+		list, err := reopened.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{&testValue{Name: "secret incident"}}, list)
+		trashed, err := reopened.ListTrash(Task)
+		require.NoError(t, err)
+		require.Len(t, trashed, 1)
+		require.NoError(t, reopened.Close())
+
+		for _, keyFile := range []string{"", testKeyFile(t)} {
+			config.Encryption.KeyFile = keyFile
+			_, err = NewFile(config, testSchema)
+			require.ErrorIs(t, err, ErrMissingKey)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		config := testFileConfig(t)
+		config.Encryption.KeyFile = testKeyFile(t)
+		db := openTestFileDB(t, config)
+
+		// prepare, the frame checksum matches but the ciphertext was changed
+		require.NoError(t, db.Create(Task, uuid.New(), &testValue{Name: "a"}))
+		require.NoError(t, db.Close())
+
+		seg := lastSegment(t, config.Dir)
+		content, err := os.ReadFile(seg.path)
+		require.NoError(t, err)
+		payload := bytes.Clone(content[frameHeaderSize:])
+		payload[len(payload)-1] ^= 0xff
+		require.NoError(t, os.WriteFile(seg.path, newFrame(payload), 0o644))
+
+		// assert
+		_, err = NewFile(config, testSchema)
+		require.ErrorIs(t, err, ErrCorruptedLog)
+	})
+
+	t.Run("key rotation", func(t *testing.T) {
+		config := testFileConfig(t)
+		previous := testKeyFile(t)
+		config.Encryption.KeyFile = previous
+		db := openTestFileDB(t, config)
+
+		// prepare
+		ids := createCounts(t, db, 1, 2)
+		require.NoError(t, db.Snapshot())
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 3}))
+		require.NoError(t, db.Close())
+
+		config.Encryption.KeyFile = testKeyFile(t)
+		config.Encryption.PreviousKeyFiles = []string{previous}
+		db = openTestFileDB(t, config)
+		require.True(t, db.stale)
+		require.NoError(t, db.Create(Task, uuid.New(), &testValue{Count: 4}))
+		reencrypt(t, db)
+
+		// assert, the previous key isn't needed anymore
+		config.Encryption.PreviousKeyFiles = nil
+		reopened := openTestFileDB(t, config)
+		require.False(t, reopened.stale)
+		require.Equal(t, []int{3, 2, 4}, counts(t, reopened))
+	})
+
+	t.Run("plain data", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		require.NoError(t, db.Create(Task, uuid.New(), &testValue{Name: "written in plain text"}))
+		require.NoError(t, db.Close())
+
+		// assert, plain data is only read by a migration
+		config.Encryption.KeyFile = testKeyFile(t)
+		_, err := NewFile(config, testSchema)
+		require.ErrorIs(t, err, ErrCorruptedLog)
+
+		config.Encryption.MigratePlainData = true
+		db = openTestFileDB(t, config)
+		require.True(t, db.stale)
+		reencrypt(t, db)
+
+		requireUnreadable(t, config.Dir, "written in plain text")
+		reopened := openTestFileDB(t, config)
+		require.False(t, reopened.keys.migrate)
+		list, err := reopened.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{&testValue{Name: "written in plain text"}}, list)
+	})
+
+	t.Run("migration turns itself off", func(t *testing.T) {
+		config := testFileConfig(t)
+		config.Encryption.KeyFile = testKeyFile(t)
+		config.Encryption.MigratePlainData = true
+
+		// prepare, the data is encrypted from the start
+		db := openTestFileDB(t, config)
+		require.NoError(t, db.Create(Task, uuid.New(), &testValue{Count: 1}))
+		require.NoError(t, db.Close())
+
+		// assert, a plain frame written afterwards isn't taken
+		reopened := openTestFileDB(t, config)
+		require.False(t, reopened.keys.migrate)
+		_, err := reopened.keys.open([]byte(`{"seq":2}`))
+		require.ErrorIs(t, err, ErrCorruptedLog)
+	})
+
+	t.Run("invalid key file", func(t *testing.T) {
+		config := testFileConfig(t)
+		config.Encryption.KeyFile = filepath.Join(t.TempDir(), "key")
+		require.NoError(t, os.WriteFile(config.Encryption.KeyFile, []byte("c2hvcnQ="), 0o600))
+
+		// assert
+		_, err := NewFile(config, testSchema)
+		require.ErrorContains(t, err, "holds 5 bytes instead of 32")
+	})
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Expiring is implemented by values whose record expires. Once Expiry is passed,
+// RunSweeper deletes the record. The zero time never expires.
+type Expiring interface {
+	Expiry() time.Time
+}
+
+func expiryOf(v interface{}) time.Time {
+	if expiring, ok := v.(Expiring); ok {
+		return expiring.Expiry()
+	}
+	return time.Time{}
+}
+
+type expiryKey struct {
+	model Model
+	id    uuid.UUID
+}
+
+type expiryEntry struct {
+	key expiryKey
+	at  time.Time
+	// index in the heap, maintained by the heap methods
+	index int
+}
+
+// expiryHeap is a min heap of expiries, implementing heap.Interface
+type expiryHeap []*expiryEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	entry := x.(*expiryEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// expiries holds the expiry of every live record which has one, so the sweeper only
+// looks at the records which are due instead of scanning every model
+type expiries struct {
+	mu      sync.Mutex
+	heap    expiryHeap
+	entries map[expiryKey]*expiryEntry
+
+	// wake tells the sweeper the next expiry may be sooner than it waits for
+	wake chan struct{}
+}
+
+func newExpiries() *expiries {
+	return &expiries{
+		entries: map[expiryKey]*expiryEntry{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// set the expiry of a record, the zero time removes it
+func (e *expiries) set(model Model, id uuid.UUID, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := expiryKey{model: model, id: id}
+	entry, ok := e.entries[key]
+	switch {
+	case at.IsZero() && ok:
+		heap.Remove(&e.heap, entry.index)
+		delete(e.entries, key)
+		return
+	case at.IsZero():
+		return
+	case ok:
+		entry.at = at
+		heap.Fix(&e.heap, entry.index)
+	default:
+		entry = &expiryEntry{key: key, at: at}
+		heap.Push(&e.heap, entry)
+		e.entries[key] = entry
+	}
+
+	if e.heap[0] == entry {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// next returns the earliest expiry, ok is false when nothing expires
+func (e *expiries) next() (at time.Time, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.heap) == 0 {
+		return time.Time{}, false
+	}
+	return e.heap[0].at, true
+}
+
+// due takes out the records expired at now, by expiry. A record which changes
+// afterwards gets a new entry, and expire checks it is still due.
+func (e *expiries) due(now time.Time) []expiryKey {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keys := []expiryKey{}
+	for len(e.heap) > 0 && !e.heap[0].at.After(now) {
+		entry := heap.Pop(&e.heap).(*expiryEntry)
+		delete(e.entries, entry.key)
+		keys = append(keys, entry.key)
+	}
+
+	return keys
+}
+
+// trackExpiry keeps the expiries up to date after op, the caller holds the lock of the feed
+func (db *databaseManager) trackExpiry(op operation, item record) {
+	switch op.Kind {
+	case opCreate, opUpdate, opRestore:
+		db.expiries.set(op.Model, op.ID, expiryOf(item.value))
+	case opDelete, opTrash:
+		db.expiries.set(op.Model, op.ID, time.Time{})
+	}
+}
+
+func (db *databaseManager) RunSweeper(ctx context.Context) error {
+	for {
+		if err := db.sweep(time.Now()); err != nil {
+			return err
+		}
+
+		// with nothing to expire, only a new expiry wakes the sweeper up
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if at, ok := db.expiries.next(); ok {
+			timer = time.NewTimer(time.Until(at))
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-timeout:
+		case <-db.expiries.wake:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// sweep deletes the records expired at now
+func (db *databaseManager) sweep(now time.Time) error {
+	for _, key := range db.expiries.due(now) {
+		if err := db.expire(key, now); err != nil {
+			return fmt.Errorf("failed to delete expired %s %s: %w", key.model, key.id, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *databaseManager) expire(key expiryKey, now time.Time) error {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(key.model)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	// the record may have changed since the sweeper looked at it
+	item, ok := modelDB.dataMap[key.id]
+	if !ok {
+		return nil
+	}
+	if at := expiryOf(item.value); at.IsZero() || at.After(now) {
+		return nil
+	}
+
+	return db.write(modelDB, operation{Kind: opDelete, Model: key.model, ID: key.id})
+}
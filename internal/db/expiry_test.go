@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type testExpiring struct {
+	Name      string    `json:"name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (v *testExpiring) Expiry() time.Time {
+	return v.ExpiresAt
+}
+
+var testExpiringSchema = Schema{
+	Task: func() interface{} { return &testExpiring{} },
+}
+
+func TestExpiries(t *testing.T) {
+	e := newExpiries()
+	now := time.Now()
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	// prepare
+	e.set(Task, ids[0], now.Add(3*time.Second))
+	e.set(Task, ids[1], now.Add(time.Second))
+	e.set(Task, ids[2], now.Add(2*time.Second))
+	e.set(Task, uuid.New(), time.Time{})
+
+	// assert
+	at, ok := e.next()
+	require.True(t, ok)
+	require.Equal(t, now.Add(time.Second), at)
+
+	// an earlier expiry moves up, the zero time removes it
+	e.set(Task, ids[0], now)
+	e.set(Task, ids[2], time.Time{})
+	require.Len(t, e.entries, 2)
+
+	require.Empty(t, e.due(now.Add(-time.Second)))
+	require.Equal(t, []expiryKey{{Task, ids[0]}, {Task, ids[1]}}, e.due(now.Add(time.Second)))
+	require.Empty(t, e.entries)
+
+	_, ok = e.next()
+	require.False(t, ok)
+}
+
+func TestSweep(t *testing.T) {
+	t.Run("deletes expired records", func(t *testing.T) {
+		db := newDatabaseManager()
+		now := time.Now()
+
+		// prepare
+		expired, later, forever := uuid.New(), uuid.New(), uuid.New()
+		require.NoError(t, db.Create(Task, expired, &testExpiring{ExpiresAt: now.Add(-time.Second)}))
+		require.NoError(t, db.Create(Task, later, &testExpiring{ExpiresAt: now.Add(time.Hour)}))
+		require.NoError(t, db.Create(Task, forever, &testExpiring{}))
+
+		// assert
+		require.NoError(t, db.sweep(now))
+		_, err := db.Get(Task, expired)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		require.Len(t, db.expiries.entries, 1)
+	})
+
+	t.Run("follows writes", func(t *testing.T) {
+		db := newDatabaseManager()
+		now := time.Now()
+
+		// prepare
+		extended, cleared, trashed := uuid.New(), uuid.New(), uuid.New()
+		for _, id := range []uuid.UUID{extended, cleared, trashed} {
+			require.NoError(t, db.Create(Task, id, &testExpiring{ExpiresAt: now}))
+		}
+		require.NoError(t, db.Update(Task, extended, &testExpiring{ExpiresAt: now.Add(time.Hour)}))
+		require.NoError(t, db.Update(Task, cleared, &testExpiring{}))
+		require.NoError(t, db.Trash(Task, trashed))
+
+		// assert
+		require.NoError(t, db.sweep(now))
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+
+		// a trashed record expires again once restored
+		require.NoError(t, db.Restore(Task, trashed))
+		require.NoError(t, db.sweep(now))
+		_, err = db.Get(Task, trashed)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("in a transaction", func(t *testing.T) {
+		db := newDatabaseManager()
+		now := time.Now()
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, db.RunInTx(func(tx Tx) error {
+			return tx.Create(Task, id, &testExpiring{ExpiresAt: now})
+		}))
+
+		// assert
+		require.NoError(t, db.sweep(now))
+		_, err := db.Get(Task, id)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRunSweeper(t *testing.T) {
+	db := newDatabaseManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- db.RunSweeper(ctx) }()
+
+	// prepare, the sweeper waits for nothing until the record is created
+	id := uuid.New()
+	require.NoError(t, db.Create(Task, id, &testExpiring{ExpiresAt: time.Now().Add(50 * time.Millisecond)}))
+
+	// assert
+	require.Eventually(t, func() bool {
+		_, err := db.Get(Task, id)
+		return err == ErrNotFound
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestExpiryReopen(t *testing.T) {
+	for _, snapshot := range []bool{false, true} {
+		config := testFileConfig(t)
+		db, err := NewFile(config, testExpiringSchema)
+		require.NoError(t, err)
+
+		// prepare
+		now := time.Now()
+		expired, later := uuid.New(), uuid.New()
+		require.NoError(t, db.Create(Task, expired, &testExpiring{ExpiresAt: now.Add(time.Minute)}))
+		require.NoError(t, db.Create(Task, later, &testExpiring{ExpiresAt: now.Add(time.Hour)}))
+		if snapshot {
+			require.NoError(t, db.Snapshot())
+		}
+		require.NoError(t, db.Close())
+
+		// assert, records which expired while closed are deleted by the first sweep
+		reopened, err := NewFile(config, testExpiringSchema)
+		require.NoError(t, err)
+		t.Cleanup(func() { reopened.Close() })
+
+		require.NoError(t, reopened.sweep(now.Add(2*time.Minute)))
+		_, err = reopened.Get(Task, expired)
+		require.ErrorIs(t, err, ErrNotFound, snapshot)
+		_, err = reopened.Get(Task, later)
+		require.NoError(t, err, snapshot)
+	}
+}
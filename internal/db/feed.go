@@ -0,0 +1,295 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrFeedTruncated means the events to resume from are not kept anymore,
+	// the subscriber has to read the current state again
+	ErrFeedTruncated = errors.New("feed truncated")
+	// ErrSlowSubscriber closes a subscription whose buffer is full
+	ErrSlowSubscriber = errors.New("slow subscriber")
+)
+
+const (
+	// feedHistorySize is the number of events kept to resume subscriptions
+	feedHistorySize = 4096
+	// defaultWatchBuffer is the buffer of a subscription when WatchOptions.Buffer is zero
+	defaultWatchBuffer = 64
+)
+
+type EventKind string
+
+const (
+	EventCreate  EventKind = "create"
+	EventUpdate  EventKind = "update"
+	EventDelete  EventKind = "delete"
+	EventTrash   EventKind = "trash"
+	EventRestore EventKind = "restore"
+	EventPurge   EventKind = "purge"
+)
+
+// Event is a committed change of a record. Seq starts at 1 and increases by one
+// on every change of any model, the changes of a transaction get consecutive sequences.
+type Event struct {
+	Seq uint64
+	// LastSeq is the Seq of the last change of the same write, the changes up to it were committed together
+	LastSeq uint64
+	Kind    EventKind
+	Model   Model
+	ID      uuid.UUID
+	// Value is the record after the change, or the deleted record
+	Value interface{}
+	// DeletedAt is set on trash events
+	DeletedAt time.Time
+}
+
+type WatchOptions struct {
+	// After resumes from the event after this sequence, nil only streams new events
+	After *uint64
+	// Models filters the events, empty watches every model
+	Models []Model
+	// Buffer is the number of events a subscriber can fall behind before it is closed
+	Buffer int
+}
+
+// Subscription streams events until its context is done, the subscriber falls too far
+// behind, or the database is closed. Err tells why Events was closed.
+type Subscription struct {
+	events chan Event
+	models []Model
+
+	// err is set before events is closed
+	err  error
+	done chan struct{}
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns nil until Events is closed
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *Subscription) wants(model Model) bool {
+	return len(s.models) == 0 || slices.Contains(s.models, model)
+}
+
+// historyEvent keeps the stored record, which is copied for every subscriber
+type historyEvent struct {
+	seq   uint64
+	last  uint64
+	kind  EventKind
+	model Model
+	id    uuid.UUID
+	item  record
+}
+
+func (e historyEvent) event() Event {
+	return Event{Seq: e.seq, LastSeq: e.last, Kind: e.kind, Model: e.model, ID: e.id, Value: e.item.read(), DeletedAt: e.item.deletedAt}
+}
+
+// feed orders every write of the database. Its lock is held from the journal write
+// to the in-memory apply, so the sequences match the order of the log.
+type feed struct {
+	mu  sync.Mutex
+	seq uint64
+	// last is the sequence of the last event of the write being applied
+	last uint64
+
+	// history is a ring buffer of the last events
+	history []historyEvent
+	start   int
+
+	subscriptions map[*Subscription]struct{}
+	closed        error
+}
+
+func newFeed() *feed {
+	return &feed{
+		history:       make([]historyEvent, 0, feedHistorySize),
+		subscriptions: map[*Subscription]struct{}{},
+	}
+}
+
+// nextSeqLocked is the sequence of the next event
+func (f *feed) nextSeqLocked() uint64 {
+	return f.seq + 1
+}
+
+// beginLocked starts a write of n events
+func (f *feed) beginLocked(n int) {
+	f.last = f.seq + uint64(n)
+}
+
+// publishLocked adds an event to the history and sends it to the subscribers
+func (f *feed) publishLocked(kind opKind, model Model, id uuid.UUID, item record) {
+	f.seq++
+	e := historyEvent{seq: f.seq, last: max(f.last, f.seq), model: model, id: id, item: item}
+	switch kind {
+	case opCreate:
+		e.kind = EventCreate
+	case opUpdate:
+		e.kind = EventUpdate
+	case opDelete:
+		e.kind = EventDelete
+	case opTrash:
+		e.kind = EventTrash
+	case opRestore:
+		e.kind = EventRestore
+	case opPurge:
+		e.kind = EventPurge
+	}
+
+	if len(f.history) < cap(f.history) {
+		f.history = append(f.history, e)
+	} else {
+		f.history[f.start] = e
+		f.start = (f.start + 1) % len(f.history)
+	}
+
+	for s := range f.subscriptions {
+		if !s.wants(model) {
+			continue
+		}
+
+		// publishing must never block a write
+		select {
+		case s.events <- e.event():
+		default:
+			f.closeLocked(s, ErrSlowSubscriber)
+		}
+	}
+}
+
+// since returns the events after seq in order, ok is false when some were dropped
+func (f *feed) since(seq uint64) ([]historyEvent, bool) {
+	if seq > f.seq {
+		return nil, false
+	}
+
+	events := []historyEvent{}
+	for i := range f.history {
+		e := f.history[(f.start+i)%len(f.history)]
+		if e.seq > seq {
+			events = append(events, e)
+		}
+	}
+
+	// the first event after seq must still be there
+	if seq < f.seq && (len(events) == 0 || events[0].seq != seq+1) {
+		return nil, false
+	}
+
+	return events, true
+}
+
+func (f *feed) subscribe(ctx context.Context, opts WatchOptions) (*Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.subscribeLocked(ctx, opts)
+}
+
+func (f *feed) subscribeLocked(ctx context.Context, opts WatchOptions) (*Subscription, error) {
+	if f.closed != nil {
+		return nil, f.closed
+	}
+
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = defaultWatchBuffer
+	}
+
+	s := &Subscription{
+		models: slices.Clone(opts.Models),
+		done:   make(chan struct{}),
+	}
+
+	backlog := []historyEvent{}
+	if opts.After != nil {
+		events, ok := f.since(*opts.After)
+		if !ok {
+			return nil, ErrFeedTruncated
+		}
+		for _, e := range events {
+			if s.wants(e.model) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	// the backlog is bounded by the history, so it doesn't count against the buffer
+	s.events = make(chan Event, buffer+len(backlog))
+	for _, e := range backlog {
+		s.events <- e.event()
+	}
+	f.subscriptions[s] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.mu.Lock()
+			f.closeLocked(s, ctx.Err())
+			f.mu.Unlock()
+		case <-s.done:
+		}
+	}()
+
+	return s, nil
+}
+
+func (f *feed) closeLocked(s *Subscription, err error) {
+	if _, ok := f.subscriptions[s]; !ok {
+		return
+	}
+
+	delete(f.subscriptions, s)
+	s.err = err
+	// done first, so Err is set once a receiver sees events closed
+	close(s.done)
+	close(s.events)
+}
+
+// resetLocked starts the feed over at seq, the subscriptions end with ErrFeedTruncated
+// as the events they got don't lead there
+func (f *feed) resetLocked(seq uint64) {
+	f.seq = seq
+	f.last = seq
+	f.history = f.history[:0]
+	f.start = 0
+
+	for s := range f.subscriptions {
+		f.closeLocked(s, ErrFeedTruncated)
+	}
+}
+
+// close ends every subscription with err and refuses new ones
+func (f *feed) close(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = err
+	for s := range f.subscriptions {
+		f.closeLocked(s, err)
+	}
+}
+
+// Watch subscribes to the changes of the database
+func (db *databaseManager) Watch(ctx context.Context, opts WatchOptions) (*Subscription, error) {
+	return db.feed.subscribe(ctx, opts)
+}
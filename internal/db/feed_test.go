@@ -0,0 +1,257 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveEvent(t *testing.T, s *Subscription) Event {
+	select {
+	case e, ok := <-s.Events():
+		require.True(t, ok, "subscription closed: %v", s.Err())
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event")
+		return Event{}
+	}
+}
+
+func requireClosed(t *testing.T, s *Subscription, err error) {
+	select {
+	case _, ok := <-s.Events():
+		require.False(t, ok)
+		require.ErrorIs(t, s.Err(), err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "subscription not closed")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		db := New()
+		ctx := context.Background()
+
+		// prepare
+		s, err := db.Watch(ctx, WatchOptions{})
+		require.NoError(t, err)
+		require.Nil(t, s.Err())
+
+		id := uuid.New()
+		created := randomTestValue()
+		require.NoError(t, db.Create(Task, id, created))
+		updated := randomTestValue()
+		require.NoError(t, db.Update(Task, id, updated))
+		require.NoError(t, db.Delete(Task, id))
+
+		// assert
+		e := receiveEvent(t, s)
+		require.Equal(t, Event{Seq: 1, LastSeq: 1, Kind: EventCreate, Model: Task, ID: id, Value: created}, e)
+
+		// events hold copies
+		e.Value.(*testValue).Count++
+
+		e = receiveEvent(t, s)
+		require.Equal(t, Event{Seq: 2, LastSeq: 2, Kind: EventUpdate, Model: Task, ID: id, Value: updated}, e)
+		e = receiveEvent(t, s)
+		require.Equal(t, Event{Seq: 3, LastSeq: 3, Kind: EventDelete, Model: Task, ID: id, Value: updated}, e)
+	})
+
+	t.Run("multiple subscribers and filter", func(t *testing.T) {
+		db := New()
+		ctx := context.Background()
+		other := Model("other")
+
+		// prepare
+		all, err := db.Watch(ctx, WatchOptions{})
+		require.NoError(t, err)
+		tasks, err := db.Watch(ctx, WatchOptions{Models: []Model{Task}})
+		require.NoError(t, err)
+
+		require.NoError(t, db.Create(other, uuid.New(), randomTestValue()))
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+
+		// assert
+		require.Equal(t, uint64(1), receiveEvent(t, all).Seq)
+		require.Equal(t, uint64(2), receiveEvent(t, all).Seq)
+
+		e := receiveEvent(t, tasks)
+		require.Equal(t, uint64(2), e.Seq)
+		require.Equal(t, Task, e.Model)
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		db := New()
+		ctx := context.Background()
+
+		// prepare
+		s, err := db.Watch(ctx, WatchOptions{})
+		require.NoError(t, err)
+
+		ids := []uuid.UUID{uuid.New(), uuid.New()}
+		err = db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.Create(Task, ids[0], randomTestValue()))
+			require.NoError(t, tx.Create(Task, ids[1], randomTestValue()))
+			return tx.Delete(Task, ids[0])
+		})
+		require.NoError(t, err)
+
+		// rolled back transactions publish nothing
+		err = db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.Create(Task, uuid.New(), randomTestValue()))
+			return ErrConflict
+		})
+		require.ErrorIs(t, err, ErrConflict)
+		require.NoError(t, db.Delete(Task, ids[1]))
+
+		// assert, the events of the transaction end at the same LastSeq
+		expected := []Event{
+			{Seq: 1, LastSeq: 3, Kind: EventCreate, ID: ids[0]},
+			{Seq: 2, LastSeq: 3, Kind: EventCreate, ID: ids[1]},
+			{Seq: 3, LastSeq: 3, Kind: EventDelete, ID: ids[0]},
+			{Seq: 4, LastSeq: 4, Kind: EventDelete, ID: ids[1]},
+		}
+		for _, expectedEvent := range expected {
+			e := receiveEvent(t, s)
+			require.Equal(t, expectedEvent.Seq, e.Seq)
+			require.Equal(t, expectedEvent.LastSeq, e.LastSeq)
+			require.Equal(t, expectedEvent.Kind, e.Kind)
+			require.Equal(t, expectedEvent.ID, e.ID)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		db := New()
+		ctx := context.Background()
+
+		// prepare
+		ids := createTestValues(t, db, 5)
+
+		// assert
+		after := uint64(2)
+		s, err := db.Watch(ctx, WatchOptions{After: &after, Buffer: 1})
+		require.NoError(t, err)
+		for i := 2; i < 5; i++ {
+			e := receiveEvent(t, s)
+			require.Equal(t, uint64(i+1), e.Seq)
+			require.Equal(t, ids[i], e.ID)
+		}
+
+		// caught up
+		after = 5
+		s, err = db.Watch(ctx, WatchOptions{After: &after})
+		require.NoError(t, err)
+		require.NoError(t, db.Delete(Task, ids[0]))
+		require.Equal(t, uint64(6), receiveEvent(t, s).Seq)
+
+		// in the future
+		after = 7
+		_, err = db.Watch(ctx, WatchOptions{After: &after})
+		require.ErrorIs(t, err, ErrFeedTruncated)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		db := New()
+		ctx := context.Background()
+
+		// prepare
+		createTestValues(t, db, feedHistorySize+2)
+
+		// assert
+		after := uint64(1)
+		_, err := db.Watch(ctx, WatchOptions{After: &after})
+		require.ErrorIs(t, err, ErrFeedTruncated)
+
+		after = 2
+		s, err := db.Watch(ctx, WatchOptions{After: &after})
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), receiveEvent(t, s).Seq)
+	})
+
+	t.Run("slow subscriber", func(t *testing.T) {
+		db := New()
+		ctx := context.Background()
+
+		// prepare
+		slow, err := db.Watch(ctx, WatchOptions{Buffer: 2})
+		require.NoError(t, err)
+		fast, err := db.Watch(ctx, WatchOptions{Buffer: 2})
+		require.NoError(t, err)
+
+		for range 3 {
+			require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+			receiveEvent(t, fast)
+		}
+
+		// assert
+		receiveEvent(t, slow)
+		receiveEvent(t, slow)
+		requireClosed(t, slow, ErrSlowSubscriber)
+
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.Equal(t, uint64(4), receiveEvent(t, fast).Seq)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		db := New()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// prepare
+		s, err := db.Watch(ctx, WatchOptions{})
+		require.NoError(t, err)
+		cancel()
+
+		// assert
+		requireClosed(t, s, context.Canceled)
+
+		db.feed.mu.Lock()
+		require.Empty(t, db.feed.subscriptions)
+		db.feed.mu.Unlock()
+	})
+
+	t.Run("sequences survive a restart", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+		ctx := context.Background()
+
+		// prepare
+		s, err := db.Watch(ctx, WatchOptions{})
+		require.NoError(t, err)
+
+		ids := createTestValues(t, db, 3)
+		require.NoError(t, db.Snapshot())
+		require.NoError(t, db.Update(Task, ids[0], randomTestValue()))
+		require.NoError(t, db.Delete(Task, ids[1]))
+		require.NoError(t, db.Close())
+
+		for range 5 {
+			receiveEvent(t, s)
+		}
+		requireClosed(t, s, ErrClosed)
+
+		// assert
+		db = openTestFileDB(t, config)
+
+		// the events replayed from the log can be resumed
+		after := uint64(3)
+		s, err = db.Watch(ctx, WatchOptions{After: &after})
+		require.NoError(t, err)
+
+		e := receiveEvent(t, s)
+		require.Equal(t, uint64(4), e.Seq)
+		require.Equal(t, EventUpdate, e.Kind)
+		require.Equal(t, ids[0], e.ID)
+		require.Equal(t, uint64(5), receiveEvent(t, s).Seq)
+
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.Equal(t, uint64(6), receiveEvent(t, s).Seq)
+
+		// the events before the snapshot are gone
+		after = 2
+		_, err = db.Watch(ctx, WatchOptions{After: &after})
+		require.ErrorIs(t, err, ErrFeedTruncated)
+	})
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileDatabase keeps every model in memory like the default database,
+// and appends every write to a segmented log on disk. On startup the latest
+// snapshot is loaded and only the log written after it is replayed.
+// The log and snapshots are encrypted when a key is configured.
+type FileDatabase struct {
+	*databaseManager
+
+	config FileConfig
+	wal    *wal
+	keys   *keyring
+	// stale is set when some data on disk isn't encrypted with the current key
+	stale bool
+
+	snapshotMu sync.Mutex
+}
+
+// NewFile opens the database in config.Dir, creating it when it doesn't exist.
+func NewFile(config FileConfig, schema Schema) (*FileDatabase, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data dir: %w", err)
+	}
+
+	keys, err := newKeyring(config.Dir, config.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption keys: %w", err)
+	}
+
+	manager := newDatabaseManager()
+
+	seq, err := manager.loadSnapshot(config.Dir, schema, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	segments, err := listSegments(config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log segments: %w", err)
+	}
+
+	for i, seg := range segments {
+		tail := i == len(segments)-1
+		size, err := readSegment(seg, tail, keys, func(record logRecord) error {
+			// already part of the snapshot
+			if record.Seq <= seq {
+				return nil
+			}
+			if record.Seq != seq+1 {
+				return fmt.Errorf("%w: expected seq %d but got %d", ErrCorruptedLog, seq+1, record.Seq)
+			}
+
+			seq = record.Seq
+			return manager.replay(schema, record)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to replay log: %w", err)
+		}
+
+		// drop the torn write left by a crash, if any
+		if tail {
+			if err := truncateFile(seg.path, size); err != nil {
+				return nil, fmt.Errorf("failed to truncate log: %w", err)
+			}
+		}
+	}
+
+	var last *segment
+	if len(segments) > 0 {
+		last = &segments[len(segments)-1]
+	}
+
+	// the data already is as the keys write it
+	if !keys.stale {
+		if err := keys.markEncrypted(config.Dir); err != nil {
+			return nil, fmt.Errorf("failed to mark encryption: %w", err)
+		}
+	}
+
+	wal, err := openWAL(config.Dir, last, seq, config, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	manager.journal = wal
+
+	return &FileDatabase{
+		databaseManager: manager,
+		config:          config,
+		wal:             wal,
+		keys:            keys,
+		stale:           keys.stale,
+	}, nil
+}
+
+// Run flushes the log periodically when the sync policy is interval,
+// and takes a snapshot every snapshot interval. It blocks until the context is done.
+//
+// The data which isn't encrypted with the current key, after a key rotation or a migration of
+// plain data for instance, is rewritten at once by a snapshot, after which the previous keys
+// aren't needed anymore and plain data isn't migrated anymore.
+func (db *FileDatabase) Run(ctx context.Context) error {
+	if db.stale {
+		if err := db.Snapshot(); err != nil {
+			return fmt.Errorf("failed to re-encrypt: %w", err)
+		}
+		if err := db.keys.markEncrypted(db.config.Dir); err != nil {
+			return fmt.Errorf("failed to mark encryption: %w", err)
+		}
+	}
+
+	var syncCh, snapshotCh <-chan time.Time
+
+	if db.config.SyncPolicy == SyncInterval {
+		ticker := time.NewTicker(db.config.SyncInterval)
+		defer ticker.Stop()
+		syncCh = ticker.C
+	}
+
+	if db.config.SnapshotInterval > 0 {
+		ticker := time.NewTicker(db.config.SnapshotInterval)
+		defer ticker.Stop()
+		snapshotCh = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-syncCh:
+			if err := db.wal.sync(); err != nil {
+				return fmt.Errorf("failed to sync log: %w", err)
+			}
+		case <-snapshotCh:
+			if err := db.Snapshot(); err != nil {
+				return fmt.Errorf("failed to take snapshot: %w", err)
+			}
+		}
+	}
+}
+
+// Close flushes the log and ends every subscription,
+// writes and subscriptions after Close return ErrClosed
+func (db *FileDatabase) Close() error {
+	db.feed.close(ErrClosed)
+	return db.wal.close()
+}
+
+// replay decodes every operation of the record before applying any of them
+func (db *databaseManager) replay(schema Schema, record logRecord) error {
+	ops := make([]operation, 0, len(record.Ops))
+	for _, logOp := range record.Ops {
+		op := operation{Kind: logOp.Kind, Model: logOp.Model, ID: logOp.ID}
+
+		switch logOp.Kind {
+		case opCreate, opUpdate:
+			value, err := schema.decode(logOp.Model, logOp.Value)
+			if err != nil {
+				return err
+			}
+			op.Value = value
+			op.size = int64(len(logOp.Value))
+		case opTrash:
+			if logOp.At == nil {
+				return fmt.Errorf("%w: trash without time at seq %d", ErrCorruptedLog, record.Seq)
+			}
+			op.At = *logOp.At
+		case opDelete, opRestore, opPurge:
+		default:
+			return fmt.Errorf("%w: unknown operation %q at seq %d", ErrCorruptedLog, logOp.Kind, record.Seq)
+		}
+
+		ops = append(ops, op)
+	}
+
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	db.feed.beginLocked(len(ops))
+	for _, op := range ops {
+		db.apply(op)
+	}
+
+	return nil
+}
+
+func truncateFile(path string, size int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.Size() == size {
+		return nil
+	}
+
+	return os.Truncate(path, size)
+}
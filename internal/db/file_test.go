@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+var testSchema = Schema{
+	Task: func() interface{} { return &testValue{} },
+}
+
+func randomTestValue() *testValue {
+	return &testValue{
+		Name:  gofakeit.Name(),
+		Count: gofakeit.Number(0, 100),
+	}
+}
+
+func openTestFileDB(t *testing.T, config FileConfig) *FileDatabase {
+	db, err := NewFile(config, testSchema)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testFileConfig(t *testing.T) FileConfig {
+	config := FileConfig{}.Default()
+	config.Dir = t.TempDir()
+	return config
+}
+
+func lastSegment(t *testing.T, dir string) segment {
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, segments)
+	return segments[len(segments)-1]
+}
+
+// faultyFile fails the writes and syncs of a segment, a write which fails only writes half of its data
+type faultyFile struct {
+	segmentFile
+	writeErr error
+	syncErr  error
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.writeErr != nil {
+		n, _ := f.segmentFile.Write(p[:len(p)/2])
+		return n, f.writeErr
+	}
+	return f.segmentFile.Write(p)
+}
+
+func (f *faultyFile) Sync() error {
+	if f.syncErr != nil {
+		return f.syncErr
+	}
+	return f.segmentFile.Sync()
+}
+
+func TestFileDatabase(t *testing.T) {
+	t.Run("replay", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		n := gofakeit.Number(3, 10)
+		ids := make([]uuid.UUID, 0, n)
+		values := map[uuid.UUID]*testValue{}
+		for range n {
+			id := uuid.New()
+			value := randomTestValue()
+			require.NoError(t, db.Create(Task, id, value))
+			ids = append(ids, id)
+			values[id] = value
+		}
+
+		updated := randomTestValue()
+		require.NoError(t, db.Update(Task, ids[0], updated))
+		values[ids[0]] = updated
+
+		require.NoError(t, db.Delete(Task, ids[1]))
+		delete(values, ids[1])
+		ids = append(ids[:1], ids[2:]...)
+
+		require.NoError(t, db.Close())
+
+		// assert
+		reopened := openTestFileDB(t, config)
+		list, err := reopened.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, len(ids))
+		for i, id := range ids {
+			require.Equal(t, values[id], list[i])
+		}
+
+		_, err = reopened.Get(Task, ids[0])
+		require.NoError(t, err)
+	})
+
+	t.Run("write after replay", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		first := uuid.New()
+		require.NoError(t, db.Create(Task, first, randomTestValue()))
+		require.NoError(t, db.Close())
+
+		db = openTestFileDB(t, config)
+		second := uuid.New()
+		require.NoError(t, db.Create(Task, second, randomTestValue()))
+		require.NoError(t, db.Close())
+
+		// assert
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+	})
+
+	t.Run("torn write", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		id := uuid.New()
+		value := randomTestValue()
+		require.NoError(t, db.Create(Task, id, value))
+		require.NoError(t, db.Close())
+
+		// simulate a crash in the middle of appending a record
+		path := lastSegment(t, config.Dir).path
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+		require.NoError(t, err)
+		_, err = file.Write([]byte{42, 0, 0, 0, 1, 2})
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		// assert
+		db = openTestFileDB(t, config)
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, value, v)
+
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.NoError(t, db.Close())
+
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+	})
+
+	t.Run("short write", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		id := uuid.New()
+		value := randomTestValue()
+		require.NoError(t, db.Create(Task, id, value))
+		file := &faultyFile{segmentFile: db.wal.file, writeErr: syscall.ENOSPC}
+		db.wal.file = file
+
+		// assert, the half written record is cut off and the log goes on
+		require.ErrorIs(t, db.Create(Task, uuid.New(), randomTestValue()), syscall.ENOSPC)
+		file.writeErr = nil
+		other := uuid.New()
+		require.NoError(t, db.Create(Task, other, randomTestValue()))
+		require.NoError(t, db.Close())
+
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, value, v)
+		_, err = db.Get(Task, other)
+		require.NoError(t, err)
+	})
+
+	t.Run("sync error", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, db.Create(Task, id, randomTestValue()))
+		db.wal.file = &faultyFile{segmentFile: db.wal.file, syncErr: syscall.EIO}
+
+		// assert, the log fails for good and the failed write isn't replayed
+		require.ErrorIs(t, db.Create(Task, uuid.New(), randomTestValue()), ErrLogFailed)
+		require.ErrorIs(t, db.Update(Task, id, randomTestValue()), ErrLogFailed)
+		require.ErrorIs(t, db.Snapshot(), ErrLogFailed)
+		require.ErrorIs(t, db.Close(), ErrLogFailed)
+
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		_, err = db.Get(Task, id)
+		require.NoError(t, err)
+	})
+
+	t.Run("corrupted log", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.NoError(t, db.Close())
+
+		path := lastSegment(t, config.Dir).path
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[len(data)-2] ^= 0xff
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+
+		// assert
+		_, err = NewFile(config, testSchema)
+		require.ErrorIs(t, err, ErrCorruptedLog)
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		require.NoError(t, db.Create(Model("unknown"), uuid.New(), randomTestValue()))
+		require.NoError(t, db.Close())
+
+		// assert
+		_, err := NewFile(config, testSchema)
+		require.ErrorIs(t, err, ErrUnknownModel)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		db := openTestFileDB(t, testFileConfig(t))
+		require.NoError(t, db.Close())
+
+		// assert
+		err := db.Create(Task, uuid.New(), randomTestValue())
+		require.ErrorIs(t, err, ErrClosed)
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 0)
+	})
+
+	t.Run("sync interval", func(t *testing.T) {
+		config := testFileConfig(t)
+		config.SyncPolicy = SyncInterval
+		config.SyncInterval = 10 * time.Millisecond
+		db := openTestFileDB(t, config)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- db.Run(ctx)
+		}()
+
+		// prepare
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+
+		// assert
+		require.Eventually(t, func() bool {
+			db.wal.mu.Lock()
+			defer db.wal.mu.Unlock()
+			return !db.wal.dirty
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,388 @@
+package db
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownIndex = errors.New("unknown index")
+)
+
+// IndexFunc returns the keys of a value in an index, a value can have no key or several.
+// It receives the stored value, so it must not modify or keep it.
+type IndexFunc func(value interface{}) []string
+
+// IndexQuery selects the records with a key in [From, To)
+type IndexQuery struct {
+	From string
+	// To is exclusive, empty means no upper bound
+	To string
+	// Limit is the max number of records, zero means no limit
+	Limit int
+}
+
+// KeyEquals selects the records with exactly key
+func KeyEquals(key string) IndexQuery {
+	// key+"\x00" is the smallest key after key
+	return IndexQuery{From: key, To: key + "\x00"}
+}
+
+// KeyPrefix selects the records with a key starting with prefix
+func KeyPrefix(prefix string) IndexQuery {
+	// the keys with the prefix end before the prefix with its last byte incremented,
+	// trailing 0xff bytes can't be incremented and are dropped
+	end := []byte(prefix)
+	for len(end) > 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+	if len(end) == 0 {
+		return IndexQuery{From: prefix}
+	}
+	end[len(end)-1]++
+	return IndexQuery{From: prefix, To: string(end)}
+}
+
+func (q IndexQuery) contains(key string) bool {
+	return key >= q.From && (q.To == "" || key < q.To)
+}
+
+// IntKey encodes n so that keys sort like the numbers
+func IntKey(n int64) string {
+	return fmt.Sprintf("%016x", uint64(n)^(1<<63))
+}
+
+// indexEntry orders the records by key, then by create order
+type indexEntry struct {
+	key      string
+	position uint64
+}
+
+func compareIndexEntries(a, b indexEntry) int {
+	if c := strings.Compare(a.key, b.key); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.position, b.position)
+}
+
+// index holds either the live records or the trashed ones, a record moves from one to the other
+type index struct {
+	fn      IndexFunc
+	entries *skipList[indexEntry, uuid.UUID]
+	// keys of every indexed record, to remove its entries
+	keys map[uuid.UUID][]string
+}
+
+func newIndex(fn IndexFunc) *index {
+	return &index{
+		fn:      fn,
+		entries: newSkipListFunc[indexEntry, uuid.UUID](compareIndexEntries),
+		keys:    map[uuid.UUID][]string{},
+	}
+}
+
+func (i *index) add(id uuid.UUID, item record) {
+	keys := i.fn(item.value)
+	for _, key := range keys {
+		i.entries.Set(indexEntry{key: key, position: item.position}, id)
+	}
+	i.keys[id] = keys
+}
+
+func (i *index) remove(id uuid.UUID, item record) {
+	for _, key := range i.keys[id] {
+		i.entries.Delete(indexEntry{key: key, position: item.position})
+	}
+	delete(i.keys, id)
+}
+
+// find returns the ids by key then create order, a record with several keys in range is only returned once
+func (i *index) find(query IndexQuery) []uuid.UUID {
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for node := i.entries.SeekGE(indexEntry{key: query.From}); node != nil; node = node.Next() {
+		if query.To != "" && node.key.key >= query.To {
+			break
+		}
+		if query.Limit > 0 && len(ids) == query.Limit {
+			break
+		}
+		if seen[node.value] {
+			continue
+		}
+		seen[node.value] = true
+		ids = append(ids, node.value)
+	}
+
+	return ids
+}
+
+// index updates every index of the model for a live record, the caller holds the lock of the model
+func (m *modelDatabase) index(id uuid.UUID, previous *record, item record) {
+	for _, i := range m.indexes {
+		if previous != nil {
+			i.remove(id, *previous)
+		}
+		i.add(id, item)
+	}
+}
+
+func (m *modelDatabase) unindex(id uuid.UUID, item record) {
+	for _, i := range m.indexes {
+		i.remove(id, item)
+	}
+}
+
+// indexTrash adds a trashed record to every trash index of the model, the caller holds the lock of the model
+func (m *modelDatabase) indexTrash(id uuid.UUID, item record) {
+	for _, i := range m.trashIndexes {
+		i.add(id, item)
+	}
+}
+
+func (m *modelDatabase) unindexTrash(id uuid.UUID, item record) {
+	for _, i := range m.trashIndexes {
+		i.remove(id, item)
+	}
+}
+
+func (db *databaseManager) RegisterIndex(model Model, name string, fn IndexFunc) error {
+	if fn == nil {
+		return fmt.Errorf("index %s of %s has no IndexFunc", name, model)
+	}
+
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	i := newIndex(fn)
+	for id, item := range modelDB.dataMap {
+		i.add(id, item)
+	}
+	trash := newIndex(fn)
+	for id, item := range modelDB.trashed {
+		trash.add(id, item)
+	}
+
+	if modelDB.indexes == nil {
+		modelDB.indexes = map[string]*index{}
+		modelDB.trashIndexes = map[string]*index{}
+	}
+	modelDB.indexes[name] = i
+	modelDB.trashIndexes[name] = trash
+	return nil
+}
+
+func (db *databaseManager) FindIDs(model Model, name string, query IndexQuery) ([]uuid.UUID, error) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
+
+	i, ok := modelDB.indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %s", ErrUnknownIndex, name, model)
+	}
+
+	return i.find(query), nil
+}
+
+func (db *databaseManager) Find(model Model, name string, query IndexQuery) ([]interface{}, error) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
+
+	i, ok := modelDB.indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %s", ErrUnknownIndex, name, model)
+	}
+
+	ids := i.find(query)
+	list := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, modelDB.dataMap[id].read())
+	}
+
+	return list, nil
+}
+
+func (db *databaseManager) FindTrash(model Model, name string, query IndexQuery) ([]Trashed, error) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
+
+	i, ok := modelDB.trashIndexes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %s", ErrUnknownIndex, name, model)
+	}
+
+	ids := i.find(query)
+	list := make([]Trashed, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, modelDB.trashed[id].readTrashed(id))
+	}
+
+	return list, nil
+}
+
+// indexMatch is an entry selected by a query
+type indexMatch struct {
+	entry indexEntry
+	id    uuid.UUID
+}
+
+// distinctIDs returns the ids of matches by key then create order, a record is only returned once
+func distinctIDs(matches []indexMatch, limit int) []uuid.UUID {
+	sort.Slice(matches, func(a, b int) bool {
+		return compareIndexEntries(matches[a].entry, matches[b].entry) < 0
+	})
+
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, match := range matches {
+		if limit > 0 && len(ids) == limit {
+			break
+		}
+		if seen[match.id] {
+			continue
+		}
+		seen[match.id] = true
+		ids = append(ids, match.id)
+	}
+
+	return ids
+}
+
+// match runs the IndexFunc over records, for the reads the entries of the index don't reflect
+func (i *index) match(records map[uuid.UUID]record, query IndexQuery) []uuid.UUID {
+	matches := []indexMatch{}
+	for id, item := range records {
+		matches = i.appendMatches(matches, id, item, query)
+	}
+
+	return distinctIDs(matches, query.Limit)
+}
+
+// appendMatches appends the keys of item in the range of query
+func (i *index) appendMatches(matches []indexMatch, id uuid.UUID, item record, query IndexQuery) []indexMatch {
+	for _, key := range i.fn(item.value) {
+		if query.contains(key) {
+			matches = append(matches, indexMatch{entry: indexEntry{key: key, position: item.position}, id: id})
+		}
+	}
+
+	return matches
+}
+
+// find queries the index of the base and puts the writes of the transaction on top. The entries of
+// the records the transaction wrote or removed are stale, so only the records it wrote are matched again.
+func (m *txModel) find(model Model, name string, query IndexQuery) ([]uuid.UUID, error) {
+	i, ok := m.base.indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %s", ErrUnknownIndex, name, model)
+	}
+
+	matches := []indexMatch{}
+	// once limit records of the base are found, its later entries can't be returned
+	seen := map[uuid.UUID]bool{}
+	for node := i.entries.SeekGE(indexEntry{key: query.From}); node != nil; node = node.Next() {
+		if query.To != "" && node.key.key >= query.To {
+			break
+		}
+		if _, written := m.values[node.value]; written || m.removed[node.value] {
+			continue
+		}
+		if !seen[node.value] && query.Limit > 0 && len(seen) == query.Limit {
+			break
+		}
+		seen[node.value] = true
+		matches = append(matches, indexMatch{entry: node.key, id: node.value})
+	}
+	for id, item := range m.values {
+		matches = i.appendMatches(matches, id, item, query)
+	}
+
+	return distinctIDs(matches, query.Limit), nil
+}
+
+// findTrash is find over the trash: the records the transaction trashed are matched again,
+// and the entries of the base trash it restored or purged are left out
+func (m *txModel) findTrash(model Model, name string, query IndexQuery) ([]uuid.UUID, error) {
+	i, ok := m.base.trashIndexes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %s", ErrUnknownIndex, name, model)
+	}
+
+	matches := []indexMatch{}
+	seen := map[uuid.UUID]bool{}
+	for node := i.entries.SeekGE(indexEntry{key: query.From}); node != nil; node = node.Next() {
+		if query.To != "" && node.key.key >= query.To {
+			break
+		}
+		if _, trashed := m.trashed[node.value]; trashed || m.untrashed[node.value] {
+			continue
+		}
+		if !seen[node.value] && query.Limit > 0 && len(seen) == query.Limit {
+			break
+		}
+		seen[node.value] = true
+		matches = append(matches, indexMatch{entry: node.key, id: node.value})
+	}
+	for id, item := range m.trashed {
+		matches = i.appendMatches(matches, id, item, query)
+	}
+
+	return distinctIDs(matches, query.Limit), nil
+}
+
+func (tx *transaction) FindIDs(model Model, name string, query IndexQuery) ([]uuid.UUID, error) {
+	return tx.getModel(model).find(model, name, query)
+}
+
+func (tx *transaction) Find(model Model, name string, query IndexQuery) ([]interface{}, error) {
+	m := tx.getModel(model)
+	ids, err := m.find(model, name, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		item, _ := m.get(id)
+		list = append(list, item.read())
+	}
+
+	return list, nil
+}
+
+func (tx *transaction) FindTrash(model Model, name string, query IndexQuery) ([]Trashed, error) {
+	m := tx.getModel(model)
+	ids, err := m.findTrash(model, name, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Trashed, 0, len(ids))
+	for _, id := range ids {
+		item, _ := m.getTrashed(id)
+		list = append(list, item.readTrashed(id))
+	}
+
+	return list, nil
+}
@@ -0,0 +1,379 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// countIndex indexes test values by their count
+func countIndex(value interface{}) []string {
+	return []string{IntKey(int64(value.(*testValue).Count))}
+}
+
+// nameIndex indexes test values by every word of their name
+func nameIndex(value interface{}) []string {
+	return strings.Fields(value.(*testValue).Name)
+}
+
+func createCounts(t *testing.T, tx Tx, counts ...int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(counts))
+	for _, count := range counts {
+		id := uuid.New()
+		require.NoError(t, tx.Create(Task, id, &testValue{Count: count}))
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// foundTrashIDs returns the ids of the trashed records found in an index
+func foundTrashIDs(list []Trashed) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, item := range list {
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
+
+func TestIntKey(t *testing.T) {
+	numbers := []int64{-1 << 63, -100, -1, 0, 1, 9, 10, 100, 1<<63 - 1}
+	for i := 1; i < len(numbers); i++ {
+		require.Less(t, IntKey(numbers[i-1]), IntKey(numbers[i]))
+	}
+}
+
+func TestIndex(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createCounts(t, db, 10, 2, 10, 9)
+		require.NoError(t, db.RegisterIndex(Task, "count", countIndex))
+
+		// maintained after the registration
+		created := createCounts(t, db, 2)
+		ids = append(ids, created...)
+
+		// assert
+		found, err := db.FindIDs(Task, "count", KeyEquals(IntKey(10)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[2]}, found)
+
+		// by key then create order
+		found, err = db.FindIDs(Task, "count", IndexQuery{From: IntKey(2), To: IntKey(10)})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[1], ids[4], ids[3]}, found)
+
+		found, err = db.FindIDs(Task, "count", IndexQuery{From: IntKey(9)})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[3], ids[0], ids[2]}, found)
+
+		found, err = db.FindIDs(Task, "count", IndexQuery{Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[1], ids[4]}, found)
+
+		values, err := db.Find(Task, "count", KeyEquals(IntKey(9)))
+		require.NoError(t, err)
+		value, err := db.Get(Task, ids[3])
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{value}, values)
+
+		found, err = db.FindIDs(Task, "count", KeyEquals(IntKey(3)))
+		require.NoError(t, err)
+		require.Empty(t, found)
+	})
+
+	t.Run("maintained on write", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		require.NoError(t, db.RegisterIndex(Task, "count", countIndex))
+		ids := createCounts(t, db, 1, 1, 1, 1)
+
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 2}))
+		require.NoError(t, db.Delete(Task, ids[1]))
+		require.NoError(t, db.Trash(Task, ids[2]))
+		require.NoError(t, db.Trash(Task, ids[3]))
+		require.NoError(t, db.Restore(Task, ids[3]))
+
+		// assert
+		found, err := db.FindIDs(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[3]}, found)
+
+		found, err = db.FindIDs(Task, "count", KeyEquals(IntKey(2)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0]}, found)
+	})
+
+	t.Run("several keys", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		require.NoError(t, db.RegisterIndex(Task, "name", nameIndex))
+		ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+		require.NoError(t, db.Create(Task, ids[0], &testValue{Name: "buy milk"}))
+		require.NoError(t, db.Create(Task, ids[1], &testValue{Name: "milk milk"}))
+		require.NoError(t, db.Create(Task, ids[2], &testValue{}))
+
+		// assert
+		found, err := db.FindIDs(Task, "name", KeyEquals("milk"))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[1]}, found)
+
+		// a record is only returned once
+		found, err = db.FindIDs(Task, "name", IndexQuery{})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[1]}, found)
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		require.NoError(t, db.RegisterIndex(Task, "name", nameIndex))
+		ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+		require.NoError(t, db.Create(Task, ids[0], &testValue{Name: "milkshake"}))
+		require.NoError(t, db.Create(Task, ids[1], &testValue{Name: "mil"}))
+		require.NoError(t, db.Create(Task, ids[2], &testValue{Name: "milk"}))
+		require.NoError(t, db.Create(Task, ids[3], &testValue{Name: "mim"}))
+
+		// assert
+		found, err := db.FindIDs(Task, "name", KeyPrefix("milk"))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[2], ids[0]}, found)
+
+		found, err = db.FindIDs(Task, "name", KeyPrefix(""))
+		require.NoError(t, err)
+		require.Len(t, found, 4)
+
+		require.Equal(t, IndexQuery{From: "a\xff", To: "b"}, KeyPrefix("a\xff"))
+		require.Equal(t, IndexQuery{From: "\xff"}, KeyPrefix("\xff"))
+	})
+
+	t.Run("replaced", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createCounts(t, db, 1)
+		require.NoError(t, db.RegisterIndex(Task, "key", countIndex))
+		require.NoError(t, db.RegisterIndex(Task, "key", func(value interface{}) []string {
+			return []string{"same"}
+		}))
+
+		// assert
+		found, err := db.FindIDs(Task, "key", KeyEquals("same"))
+		require.NoError(t, err)
+		require.Equal(t, ids, found)
+	})
+
+	t.Run("unknown index", func(t *testing.T) {
+		db := New()
+
+		// assert
+		_, err := db.FindIDs(Task, "count", IndexQuery{})
+		require.ErrorIs(t, err, ErrUnknownIndex)
+		_, err = db.Find(Task, "count", IndexQuery{})
+		require.ErrorIs(t, err, ErrUnknownIndex)
+		err = db.RunInTx(func(tx Tx) error {
+			_, err := tx.FindIDs(Task, "count", IndexQuery{})
+			return err
+		})
+		require.ErrorIs(t, err, ErrUnknownIndex)
+		require.Error(t, db.RegisterIndex(Task, "count", nil))
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		require.NoError(t, db.RegisterIndex(Task, "count", countIndex))
+		ids := createCounts(t, db, 1, 1, 2)
+		require.NoError(t, db.Trash(Task, ids[2]))
+
+		// assert
+		var created []uuid.UUID
+		err := db.RunInTx(func(tx Tx) error {
+			created = createCounts(t, tx, 1)
+			require.NoError(t, tx.Update(Task, ids[0], &testValue{Count: 2}))
+			require.NoError(t, tx.Delete(Task, ids[1]))
+			require.NoError(t, tx.Restore(Task, ids[2]))
+
+			// the writes of the transaction are visible
+			found, err := tx.FindIDs(Task, "count", KeyEquals(IntKey(1)))
+			require.NoError(t, err)
+			require.Equal(t, created, found)
+
+			found, err = tx.FindIDs(Task, "count", IndexQuery{From: IntKey(2), Limit: 1})
+			require.NoError(t, err)
+			require.Equal(t, []uuid.UUID{ids[0]}, found)
+
+			values, err := tx.Find(Task, "count", KeyEquals(IntKey(2)))
+			require.NoError(t, err)
+			require.Len(t, values, 2)
+			require.Equal(t, 2, values[0].(*testValue).Count)
+			return nil
+		})
+		require.NoError(t, err)
+
+		// and committed
+		found, err := db.FindIDs(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, created, found)
+		found, err = db.FindIDs(Task, "count", KeyEquals(IntKey(2)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[2]}, found)
+	})
+
+	t.Run("transaction uses the index", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		calls := 0
+		require.NoError(t, db.RegisterIndex(Task, "count", func(value interface{}) []string {
+			calls++
+			return countIndex(value)
+		}))
+		ids := createCounts(t, db, 2, 1, 1, 1, 1, 1, 1, 1, 1, 2)
+
+		// assert, only the record written by the transaction is indexed again
+		err := db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.Update(Task, ids[1], &testValue{Count: 2}))
+			calls = 0
+
+			found, err := tx.FindIDs(Task, "count", KeyEquals(IntKey(2)))
+			require.NoError(t, err)
+			require.Equal(t, []uuid.UUID{ids[0], ids[1], ids[9]}, found)
+			require.Equal(t, 1, calls)
+
+			found, err = tx.FindIDs(Task, "count", IndexQuery{From: IntKey(2), Limit: 2})
+			require.NoError(t, err)
+			require.Equal(t, []uuid.UUID{ids[0], ids[1]}, found)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		require.NoError(t, db.RegisterIndex(Task, "count", countIndex))
+		ids := createCounts(t, db, 1, 2, 1, 1)
+		require.NoError(t, db.Trash(Task, ids[2]))
+		require.NoError(t, db.Trash(Task, ids[0]))
+
+		// assert
+		trashed, err := db.FindTrash(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[2]}, foundTrashIDs(trashed))
+		require.Equal(t, &testValue{Count: 1}, trashed[0].Value)
+		require.False(t, trashed[0].DeletedAt.IsZero())
+
+		found, err := db.FindIDs(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[3]}, found)
+
+		require.NoError(t, db.Restore(Task, ids[0]))
+		require.NoError(t, db.Purge(Task, ids[2]))
+		trashed, err = db.FindTrash(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Empty(t, trashed)
+
+		_, err = db.FindTrash(Task, "name", KeyEquals("a"))
+		require.ErrorIs(t, err, ErrUnknownIndex)
+	})
+
+	t.Run("trash in transaction", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		calls := 0
+		require.NoError(t, db.RegisterIndex(Task, "count", func(value interface{}) []string {
+			calls++
+			return countIndex(value)
+		}))
+		ids := createCounts(t, db, 1, 2, 1, 1, 2, 1)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[1]))
+		require.NoError(t, db.Trash(Task, ids[2]))
+
+		// assert, only the record trashed by the transaction is indexed again
+		err := db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.Trash(Task, ids[3]))
+			require.NoError(t, tx.Restore(Task, ids[0]))
+			require.NoError(t, tx.Purge(Task, ids[1]))
+			calls = 0
+
+			trashed, err := tx.FindTrash(Task, "count", KeyEquals(IntKey(1)))
+			require.NoError(t, err)
+			require.Equal(t, []uuid.UUID{ids[2], ids[3]}, foundTrashIDs(trashed))
+			require.Equal(t, 1, calls)
+
+			trashed, err = tx.FindTrash(Task, "count", IndexQuery{From: IntKey(1), Limit: 1})
+			require.NoError(t, err)
+			require.Equal(t, []uuid.UUID{ids[2]}, foundTrashIDs(trashed))
+
+			trashed, err = tx.FindTrash(Task, "count", KeyEquals(IntKey(2)))
+			require.NoError(t, err)
+			require.Empty(t, trashed)
+			return nil
+		})
+		require.NoError(t, err)
+
+		trashed, err := db.FindTrash(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[2], ids[3]}, foundTrashIDs(trashed))
+	})
+
+	t.Run("file database", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		ids := createCounts(t, db, 1, 2)
+		require.NoError(t, db.Snapshot())
+		ids = append(ids, createCounts(t, db, 1, 1)...)
+		require.NoError(t, db.Trash(Task, ids[3]))
+		require.NoError(t, db.Close())
+
+		// assert
+		db = openTestFileDB(t, config)
+		require.NoError(t, db.RegisterIndex(Task, "count", countIndex))
+		found, err := db.FindIDs(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[2]}, found)
+
+		trashed, err := db.FindTrash(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[3]}, foundTrashIDs(trashed))
+	})
+}
+
+func TestCollectionIndex(t *testing.T) {
+	values := NewCollection[testValue](Task)
+	counts := values.Index("count", func(value *testValue) []string {
+		return []string{IntKey(int64(value.Count))}
+	})
+
+	db := New()
+
+	// prepare
+	require.NoError(t, counts.Register(db))
+	ids := createCounts(t, db, 3, 1, 3)
+
+	// assert
+	require.Equal(t, "count", counts.Name())
+
+	found, err := counts.FindIDs(db, KeyEquals(IntKey(3)))
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{ids[0], ids[2]}, found)
+
+	list, err := counts.Find(db, IndexQuery{To: IntKey(3)})
+	require.NoError(t, err)
+	require.Equal(t, []*testValue{{Count: 1}}, list)
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSchemaOutdated is returned when opening a SQL database which misses migrations, see Migrate
+	ErrSchemaOutdated = errors.New("schema outdated")
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a versioned change of the schema of the SQL database
+type Migration struct {
+	Version int
+	Name    string
+
+	statements string
+}
+
+// migrations are ordered by version, which starts at 1 and has no gap
+var migrations = func() []Migration {
+	list, err := loadMigrations(migrationFiles)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}()
+
+// loadMigrations reads the migrations named like 0001_create_records.sql
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(files, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Migration, 0, len(names))
+	for i, name := range names {
+		prefix, rest, ok := strings.Cut(strings.TrimSuffix(path.Base(name), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s is not named like 0001_name.sql", name)
+		}
+		if version != i+1 {
+			return nil, fmt.Errorf("migration %s should be version %d", name, i+1)
+		}
+
+		statements, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Migration{Version: version, Name: rest, statements: string(statements)})
+	}
+
+	return list, nil
+}
+
+// Migrate applies the migrations the SQL database misses, each in a transaction, and returns them
+func Migrate(ctx context.Context, config SQLConfig) ([]Migration, error) {
+	sqlDB, err := openSQL(config)
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDB.Close()
+
+	return migrate(ctx, sqlDB, migrations)
+}
+
+func migrate(ctx context.Context, sqlDB *sql.DB, migrations []Migration) ([]Migration, error) {
+	if _, err := sqlDB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT    NOT NULL,
+		applied_at TEXT    NOT NULL
+	)`); err != nil {
+		return nil, err
+	}
+
+	current, err := schemaVersion(ctx, sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	if current > len(migrations) {
+		return nil, fmt.Errorf("schema at version %d is newer than the server, which knows %d migrations", current, len(migrations))
+	}
+
+	applied := []Migration{}
+	for _, m := range migrations[current:] {
+		if err := applyMigration(ctx, sqlDB, m); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d %s: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+func applyMigration(ctx context.Context, sqlDB *sql.DB, m Migration) error {
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.statements); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// schemaVersion returns the version of the last migration applied, zero when there is none
+func schemaVersion(ctx context.Context, sqlDB *sql.DB) (int, error) {
+	var tables int
+	if err := sqlDB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`,
+	).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var version int
+	if err := sqlDB.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Run("applies once", func(t *testing.T) {
+		ctx := context.Background()
+		config := testSQLConfig(t)
+
+		// assert, testSQLConfig applied every migration already
+		applied, err := Migrate(ctx, config)
+		require.NoError(t, err)
+		require.Empty(t, applied)
+
+		sqlDB, err := openSQL(config)
+		require.NoError(t, err)
+		defer sqlDB.Close()
+		version, err := schemaVersion(ctx, sqlDB)
+		require.NoError(t, err)
+		require.Equal(t, len(migrations), version)
+	})
+
+	t.Run("in order", func(t *testing.T) {
+		ctx := context.Background()
+		config := SQLConfig{}.Default()
+		config.DSN = "file:" + filepath.Join(t.TempDir(), "todo.db")
+		sqlDB, err := openSQL(config)
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		// prepare
+		applied, err := migrate(ctx, sqlDB, migrations[:1])
+		require.NoError(t, err)
+		require.Equal(t, migrations[:1], applied)
+
+		// assert
+		applied, err = migrate(ctx, sqlDB, migrations)
+		require.NoError(t, err)
+		require.Equal(t, migrations[1:], applied)
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		ctx := context.Background()
+		config := testSQLConfig(t)
+		sqlDB, err := openSQL(config)
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		// assert
+		_, err = migrate(ctx, sqlDB, migrations[:1])
+		require.ErrorContains(t, err, "is newer than the server")
+	})
+
+	t.Run("failed migration", func(t *testing.T) {
+		ctx := context.Background()
+		config := testSQLConfig(t)
+		sqlDB, err := openSQL(config)
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		// prepare
+		broken := append(slices.Clone(migrations), Migration{
+			Version:    len(migrations) + 1,
+			Name:       "broken",
+			statements: `CREATE TABLE broken (id INTEGER); INSERT INTO missing VALUES (1);`,
+		})
+
+		// assert, nothing of the migration is left
+		_, err = migrate(ctx, sqlDB, broken)
+		require.ErrorContains(t, err, "failed to apply migration")
+		version, err := schemaVersion(ctx, sqlDB)
+		require.NoError(t, err)
+		require.Equal(t, len(migrations), version)
+
+		var tables int
+		require.NoError(t, sqlDB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'broken'`).Scan(&tables))
+		require.Zero(t, tables)
+	})
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		err   string
+	}{
+		{
+			name: "valid",
+			files: fstest.MapFS{
+				"migrations/0001_first.sql":  {Data: []byte("CREATE TABLE a (id INTEGER);")},
+				"migrations/0002_second.sql": {Data: []byte("CREATE TABLE b (id INTEGER);")},
+			},
+		},
+		{
+			name: "gap",
+			files: fstest.MapFS{
+				"migrations/0001_first.sql": {Data: []byte("")},
+				"migrations/0003_third.sql": {Data: []byte("")},
+			},
+			err: "should be version 2",
+		},
+		{
+			name: "invalid name",
+			files: fstest.MapFS{
+				"migrations/first.sql": {Data: []byte("")},
+			},
+			err: "is not named like",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := loadMigrations(tt.files)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []Migration{
+				{Version: 1, Name: "first", statements: "CREATE TABLE a (id INTEGER);"},
+				{Version: 2, Name: "second", statements: "CREATE TABLE b (id INTEGER);"},
+			}, list)
+		})
+	}
+}
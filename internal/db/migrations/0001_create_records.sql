@@ -0,0 +1,27 @@
+-- every record of every model, value is the JSON of the record
+CREATE TABLE records (
+    model      TEXT    NOT NULL,
+    id         TEXT    NOT NULL,
+    version    INTEGER NOT NULL,
+    position   INTEGER NOT NULL,
+    value      TEXT    NOT NULL,
+    -- only set on trashed records
+    deleted_at TEXT,
+    PRIMARY KEY (model, id)
+);
+
+CREATE UNIQUE INDEX records_position ON records (model, position);
+
+-- the last position given in every model, positions are never reused
+CREATE TABLE positions (
+    model         TEXT    PRIMARY KEY,
+    last_position INTEGER NOT NULL
+);
+
+-- seq of the last change of the database, in a single row
+CREATE TABLE changes (
+    id  INTEGER PRIMARY KEY CHECK (id = 1),
+    seq INTEGER NOT NULL
+);
+
+INSERT INTO changes (id, seq) VALUES (1, 0);
@@ -0,0 +1,14 @@
+-- the tasks with a column for every field, for reporting
+CREATE VIEW tasks AS
+SELECT
+    id,
+    json_extract(value, '$.name')       AS name,
+    json_extract(value, '$.status')     AS status,
+    json_extract(value, '$.created_at') AS created_at,
+    json_extract(value, '$.updated_at') AS updated_at,
+    json_extract(value, '$.expires_at') AS expires_at,
+    version,
+    position,
+    deleted_at
+FROM records
+WHERE model = 'task';
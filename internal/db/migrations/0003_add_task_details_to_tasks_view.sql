@@ -0,0 +1,20 @@
+-- the description, priority, start and due dates of the tasks
+DROP VIEW tasks;
+
+CREATE VIEW tasks AS
+SELECT
+    id,
+    json_extract(value, '$.name')        AS name,
+    json_extract(value, '$.status')      AS status,
+    json_extract(value, '$.created_at')  AS created_at,
+    json_extract(value, '$.updated_at')  AS updated_at,
+    json_extract(value, '$.expires_at')  AS expires_at,
+    json_extract(value, '$.description') AS description,
+    json_extract(value, '$.priority')    AS priority,
+    json_extract(value, '$.start_at')    AS start_at,
+    json_extract(value, '$.due_at')      AS due_at,
+    version,
+    position,
+    deleted_at
+FROM records
+WHERE model = 'task';
@@ -0,0 +1,40 @@
+-- the projects, and the project each task belongs to
+CREATE VIEW projects AS
+SELECT
+    id,
+    json_extract(value, '$.name')        AS name,
+    json_extract(value, '$.description') AS description,
+    json_extract(value, '$.created_at')  AS created_at,
+    json_extract(value, '$.updated_at')  AS updated_at,
+    version,
+    position,
+    deleted_at
+FROM records
+WHERE model = 'project';
+
+DROP VIEW tasks;
+
+CREATE VIEW tasks AS
+SELECT
+    id,
+    json_extract(value, '$.name')         AS name,
+    json_extract(value, '$.status')       AS status,
+    COALESCE(
+        json_extract(value, '$.state'),
+        CASE json_extract(value, '$.status') WHEN 1 THEN 'done' ELSE 'todo' END
+    )                                     AS state,
+    json_extract(value, '$.project_id')   AS project_id,
+    json_extract(value, '$.created_at')   AS created_at,
+    json_extract(value, '$.updated_at')   AS updated_at,
+    json_extract(value, '$.expires_at')   AS expires_at,
+    json_extract(value, '$.description')  AS description,
+    json_extract(value, '$.priority')     AS priority,
+    json_extract(value, '$.start_at')     AS start_at,
+    json_extract(value, '$.due_at')       AS due_at,
+    json_extract(value, '$.completed_at') AS completed_at,
+    json_extract(value, '$.cancelled_at') AS cancelled_at,
+    version,
+    position,
+    deleted_at
+FROM records
+WHERE model = 'task';
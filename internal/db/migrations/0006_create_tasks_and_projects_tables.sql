@@ -0,0 +1,113 @@
+-- the tasks and projects in typed tables for reporting, which triggers keep in step with records
+DROP VIEW tasks;
+DROP VIEW projects;
+
+-- the columns of a task record, the tasks written before the states only have a status
+CREATE VIEW task_records AS
+SELECT
+    id,
+    json_extract(value, '$.name')         AS name,
+    json_extract(value, '$.status')       AS status,
+    COALESCE(
+        json_extract(value, '$.state'),
+        CASE json_extract(value, '$.status') WHEN 1 THEN 'done' ELSE 'todo' END
+    )                                     AS state,
+    json_extract(value, '$.project_id')   AS project_id,
+    json_extract(value, '$.created_at')   AS created_at,
+    json_extract(value, '$.updated_at')   AS updated_at,
+    json_extract(value, '$.expires_at')   AS expires_at,
+    json_extract(value, '$.description')  AS description,
+    json_extract(value, '$.priority')     AS priority,
+    json_extract(value, '$.start_at')     AS start_at,
+    json_extract(value, '$.due_at')       AS due_at,
+    json_extract(value, '$.completed_at') AS completed_at,
+    json_extract(value, '$.cancelled_at') AS cancelled_at,
+    version,
+    position,
+    deleted_at
+FROM records
+WHERE model = 'task';
+
+-- the columns of a project record
+CREATE VIEW project_records AS
+SELECT
+    id,
+    json_extract(value, '$.name')        AS name,
+    json_extract(value, '$.description') AS description,
+    json_extract(value, '$.created_at')  AS created_at,
+    json_extract(value, '$.updated_at')  AS updated_at,
+    version,
+    position,
+    deleted_at
+FROM records
+WHERE model = 'project';
+
+-- a write whose record doesn't fit the types fails as a whole
+CREATE TABLE tasks (
+    id           TEXT    PRIMARY KEY,
+    name         TEXT,
+    status       INTEGER CHECK (status IN (0, 1)),
+    state        TEXT    CHECK (state IN ('todo', 'in_progress', 'in_review', 'blocked', 'done', 'cancelled')),
+    project_id   TEXT,
+    created_at   TEXT,
+    updated_at   TEXT,
+    expires_at   TEXT,
+    description  TEXT,
+    priority     INTEGER,
+    start_at     TEXT,
+    due_at       TEXT,
+    completed_at TEXT,
+    cancelled_at TEXT,
+    version      INTEGER NOT NULL,
+    position     INTEGER NOT NULL,
+    -- only set on trashed tasks
+    deleted_at   TEXT
+) STRICT;
+
+CREATE INDEX tasks_project_id ON tasks (project_id);
+CREATE INDEX tasks_state ON tasks (state);
+
+CREATE TABLE projects (
+    id          TEXT    PRIMARY KEY,
+    name        TEXT,
+    description TEXT,
+    created_at  TEXT,
+    updated_at  TEXT,
+    version     INTEGER NOT NULL,
+    position    INTEGER NOT NULL,
+    -- only set on trashed projects
+    deleted_at  TEXT
+) STRICT;
+
+INSERT INTO tasks SELECT * FROM task_records;
+INSERT INTO projects SELECT * FROM project_records;
+
+CREATE TRIGGER records_insert_task AFTER INSERT ON records WHEN NEW.model = 'task'
+BEGIN
+    INSERT INTO tasks SELECT * FROM task_records WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER records_update_task AFTER UPDATE ON records WHEN NEW.model = 'task'
+BEGIN
+    REPLACE INTO tasks SELECT * FROM task_records WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER records_delete_task AFTER DELETE ON records WHEN OLD.model = 'task'
+BEGIN
+    DELETE FROM tasks WHERE id = OLD.id;
+END;
+
+CREATE TRIGGER records_insert_project AFTER INSERT ON records WHEN NEW.model = 'project'
+BEGIN
+    INSERT INTO projects SELECT * FROM project_records WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER records_update_project AFTER UPDATE ON records WHEN NEW.model = 'project'
+BEGIN
+    REPLACE INTO projects SELECT * FROM project_records WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER records_delete_project AFTER DELETE ON records WHEN OLD.model = 'project'
+BEGIN
+    DELETE FROM projects WHERE id = OLD.id;
+END;
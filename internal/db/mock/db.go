@@ -1,16 +1,18 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/dragon-huang0403/todo-go/internal/db (interfaces: Database)
+// Source: github.com/dragon-huang0403/todo-go/internal/db (interfaces: Database,Tx,Watcher,Limiter)
 //
 // Generated by this command:
 //
-//	mockgen -destination ./internal/db/mock/db.go github.com/dragon-huang0403/todo-go/internal/db Database
+//	mockgen -destination ./internal/db/mock/db.go github.com/dragon-huang0403/todo-go/internal/db Database,Tx,Watcher,Limiter
 //
 
 // Package mock_db is a generated GoMock package.
 package mock_db
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	db "github.com/dragon-huang0403/todo-go/internal/db"
 	uuid "github.com/google/uuid"
@@ -68,6 +70,51 @@ func (mr *MockDatabaseMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDatabase)(nil).Delete), arg0, arg1)
 }
 
+// Find mocks base method.
+func (m *MockDatabase) Find(arg0 db.Model, arg1 string, arg2 db.IndexQuery) ([]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockDatabaseMockRecorder) Find(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockDatabase)(nil).Find), arg0, arg1, arg2)
+}
+
+// FindIDs mocks base method.
+func (m *MockDatabase) FindIDs(arg0 db.Model, arg1 string, arg2 db.IndexQuery) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindIDs", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindIDs indicates an expected call of FindIDs.
+func (mr *MockDatabaseMockRecorder) FindIDs(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIDs", reflect.TypeOf((*MockDatabase)(nil).FindIDs), arg0, arg1, arg2)
+}
+
+// FindTrash mocks base method.
+func (m *MockDatabase) FindTrash(arg0 db.Model, arg1 string, arg2 db.IndexQuery) ([]db.Trashed, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTrash", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]db.Trashed)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTrash indicates an expected call of FindTrash.
+func (mr *MockDatabaseMockRecorder) FindTrash(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTrash", reflect.TypeOf((*MockDatabase)(nil).FindTrash), arg0, arg1, arg2)
+}
+
 // Get mocks base method.
 func (m *MockDatabase) Get(arg0 db.Model, arg1 uuid.UUID) (any, error) {
 	m.ctrl.T.Helper()
@@ -98,6 +145,120 @@ func (mr *MockDatabaseMockRecorder) List(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDatabase)(nil).List), arg0)
 }
 
+// ListRange mocks base method.
+func (m *MockDatabase) ListRange(arg0 db.Model, arg1 db.ListOptions) (db.Page, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRange", arg0, arg1)
+	ret0, _ := ret[0].(db.Page)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRange indicates an expected call of ListRange.
+func (mr *MockDatabaseMockRecorder) ListRange(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRange", reflect.TypeOf((*MockDatabase)(nil).ListRange), arg0, arg1)
+}
+
+// ListTrash mocks base method.
+func (m *MockDatabase) ListTrash(arg0 db.Model) ([]db.Trashed, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrash", arg0)
+	ret0, _ := ret[0].([]db.Trashed)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrash indicates an expected call of ListTrash.
+func (mr *MockDatabaseMockRecorder) ListTrash(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrash", reflect.TypeOf((*MockDatabase)(nil).ListTrash), arg0)
+}
+
+// Purge mocks base method.
+func (m *MockDatabase) Purge(arg0 db.Model, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockDatabaseMockRecorder) Purge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockDatabase)(nil).Purge), arg0, arg1)
+}
+
+// RegisterIndex mocks base method.
+func (m *MockDatabase) RegisterIndex(arg0 db.Model, arg1 string, arg2 db.IndexFunc) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterIndex", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterIndex indicates an expected call of RegisterIndex.
+func (mr *MockDatabaseMockRecorder) RegisterIndex(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterIndex", reflect.TypeOf((*MockDatabase)(nil).RegisterIndex), arg0, arg1, arg2)
+}
+
+// Restore mocks base method.
+func (m *MockDatabase) Restore(arg0 db.Model, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockDatabaseMockRecorder) Restore(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockDatabase)(nil).Restore), arg0, arg1)
+}
+
+// RunInTx mocks base method.
+func (m *MockDatabase) RunInTx(arg0 func(db.Tx) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunInTx", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RunInTx indicates an expected call of RunInTx.
+func (mr *MockDatabaseMockRecorder) RunInTx(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunInTx", reflect.TypeOf((*MockDatabase)(nil).RunInTx), arg0)
+}
+
+// Trash mocks base method.
+func (m *MockDatabase) Trash(arg0 db.Model, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockDatabaseMockRecorder) Trash(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockDatabase)(nil).Trash), arg0, arg1)
+}
+
+// TrashAt mocks base method.
+func (m *MockDatabase) TrashAt(arg0 db.Model, arg1 uuid.UUID, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrashAt", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TrashAt indicates an expected call of TrashAt.
+func (mr *MockDatabaseMockRecorder) TrashAt(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashAt", reflect.TypeOf((*MockDatabase)(nil).TrashAt), arg0, arg1, arg2)
+}
+
 // Update mocks base method.
 func (m *MockDatabase) Update(arg0 db.Model, arg1 uuid.UUID, arg2 any) error {
 	m.ctrl.T.Helper()
@@ -111,3 +272,344 @@ func (mr *MockDatabaseMockRecorder) Update(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockDatabase)(nil).Update), arg0, arg1, arg2)
 }
+
+// UpdateIfVersion mocks base method.
+func (m *MockDatabase) UpdateIfVersion(arg0 db.Model, arg1 uuid.UUID, arg2 uint64, arg3 any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIfVersion", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateIfVersion indicates an expected call of UpdateIfVersion.
+func (mr *MockDatabaseMockRecorder) UpdateIfVersion(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIfVersion", reflect.TypeOf((*MockDatabase)(nil).UpdateIfVersion), arg0, arg1, arg2, arg3)
+}
+
+// MockTx is a mock of Tx interface.
+type MockTx struct {
+	ctrl     *gomock.Controller
+	recorder *MockTxMockRecorder
+}
+
+// MockTxMockRecorder is the mock recorder for MockTx.
+type MockTxMockRecorder struct {
+	mock *MockTx
+}
+
+// NewMockTx creates a new mock instance.
+func NewMockTx(ctrl *gomock.Controller) *MockTx {
+	mock := &MockTx{ctrl: ctrl}
+	mock.recorder = &MockTxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTx) EXPECT() *MockTxMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockTx) Create(arg0 db.Model, arg1 uuid.UUID, arg2 any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockTxMockRecorder) Create(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTx)(nil).Create), arg0, arg1, arg2)
+}
+
+// Delete mocks base method.
+func (m *MockTx) Delete(arg0 db.Model, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockTxMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTx)(nil).Delete), arg0, arg1)
+}
+
+// Find mocks base method.
+func (m *MockTx) Find(arg0 db.Model, arg1 string, arg2 db.IndexQuery) ([]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockTxMockRecorder) Find(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockTx)(nil).Find), arg0, arg1, arg2)
+}
+
+// FindIDs mocks base method.
+func (m *MockTx) FindIDs(arg0 db.Model, arg1 string, arg2 db.IndexQuery) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindIDs", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindIDs indicates an expected call of FindIDs.
+func (mr *MockTxMockRecorder) FindIDs(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIDs", reflect.TypeOf((*MockTx)(nil).FindIDs), arg0, arg1, arg2)
+}
+
+// FindTrash mocks base method.
+func (m *MockTx) FindTrash(arg0 db.Model, arg1 string, arg2 db.IndexQuery) ([]db.Trashed, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTrash", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]db.Trashed)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTrash indicates an expected call of FindTrash.
+func (mr *MockTxMockRecorder) FindTrash(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTrash", reflect.TypeOf((*MockTx)(nil).FindTrash), arg0, arg1, arg2)
+}
+
+// Get mocks base method.
+func (m *MockTx) Get(arg0 db.Model, arg1 uuid.UUID) (any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockTxMockRecorder) Get(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTx)(nil).Get), arg0, arg1)
+}
+
+// List mocks base method.
+func (m *MockTx) List(arg0 db.Model) ([]any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockTxMockRecorder) List(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTx)(nil).List), arg0)
+}
+
+// ListRange mocks base method.
+func (m *MockTx) ListRange(arg0 db.Model, arg1 db.ListOptions) (db.Page, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRange", arg0, arg1)
+	ret0, _ := ret[0].(db.Page)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRange indicates an expected call of ListRange.
+func (mr *MockTxMockRecorder) ListRange(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRange", reflect.TypeOf((*MockTx)(nil).ListRange), arg0, arg1)
+}
+
+// ListTrash mocks base method.
+func (m *MockTx) ListTrash(arg0 db.Model) ([]db.Trashed, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrash", arg0)
+	ret0, _ := ret[0].([]db.Trashed)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrash indicates an expected call of ListTrash.
+func (mr *MockTxMockRecorder) ListTrash(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrash", reflect.TypeOf((*MockTx)(nil).ListTrash), arg0)
+}
+
+// Purge mocks base method.
+func (m *MockTx) Purge(arg0 db.Model, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockTxMockRecorder) Purge(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockTx)(nil).Purge), arg0, arg1)
+}
+
+// Restore mocks base method.
+func (m *MockTx) Restore(arg0 db.Model, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockTxMockRecorder) Restore(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockTx)(nil).Restore), arg0, arg1)
+}
+
+// Trash mocks base method.
+func (m *MockTx) Trash(arg0 db.Model, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockTxMockRecorder) Trash(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockTx)(nil).Trash), arg0, arg1)
+}
+
+// TrashAt mocks base method.
+func (m *MockTx) TrashAt(arg0 db.Model, arg1 uuid.UUID, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrashAt", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TrashAt indicates an expected call of TrashAt.
+func (mr *MockTxMockRecorder) TrashAt(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashAt", reflect.TypeOf((*MockTx)(nil).TrashAt), arg0, arg1, arg2)
+}
+
+// Update mocks base method.
+func (m *MockTx) Update(arg0 db.Model, arg1 uuid.UUID, arg2 any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockTxMockRecorder) Update(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTx)(nil).Update), arg0, arg1, arg2)
+}
+
+// UpdateIfVersion mocks base method.
+func (m *MockTx) UpdateIfVersion(arg0 db.Model, arg1 uuid.UUID, arg2 uint64, arg3 any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIfVersion", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateIfVersion indicates an expected call of UpdateIfVersion.
+func (mr *MockTxMockRecorder) UpdateIfVersion(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIfVersion", reflect.TypeOf((*MockTx)(nil).UpdateIfVersion), arg0, arg1, arg2, arg3)
+}
+
+// MockWatcher is a mock of Watcher interface.
+type MockWatcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockWatcherMockRecorder
+}
+
+// MockWatcherMockRecorder is the mock recorder for MockWatcher.
+type MockWatcherMockRecorder struct {
+	mock *MockWatcher
+}
+
+// NewMockWatcher creates a new mock instance.
+func NewMockWatcher(ctrl *gomock.Controller) *MockWatcher {
+	mock := &MockWatcher{ctrl: ctrl}
+	mock.recorder = &MockWatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWatcher) EXPECT() *MockWatcherMockRecorder {
+	return m.recorder
+}
+
+// Watch mocks base method.
+func (m *MockWatcher) Watch(arg0 context.Context, arg1 db.WatchOptions) (*db.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Watch", arg0, arg1)
+	ret0, _ := ret[0].(*db.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockWatcherMockRecorder) Watch(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockWatcher)(nil).Watch), arg0, arg1)
+}
+
+// MockLimiter is a mock of Limiter interface.
+type MockLimiter struct {
+	ctrl     *gomock.Controller
+	recorder *MockLimiterMockRecorder
+}
+
+// MockLimiterMockRecorder is the mock recorder for MockLimiter.
+type MockLimiterMockRecorder struct {
+	mock *MockLimiter
+}
+
+// NewMockLimiter creates a new mock instance.
+func NewMockLimiter(ctrl *gomock.Controller) *MockLimiter {
+	mock := &MockLimiter{ctrl: ctrl}
+	mock.recorder = &MockLimiterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLimiter) EXPECT() *MockLimiterMockRecorder {
+	return m.recorder
+}
+
+// SetQuota mocks base method.
+func (m *MockLimiter) SetQuota(arg0 db.Model, arg1 db.Quota) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetQuota", arg0, arg1)
+}
+
+// SetQuota indicates an expected call of SetQuota.
+func (mr *MockLimiterMockRecorder) SetQuota(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetQuota", reflect.TypeOf((*MockLimiter)(nil).SetQuota), arg0, arg1)
+}
+
+// Usage mocks base method.
+func (m *MockLimiter) Usage() []db.Usage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Usage")
+	ret0, _ := ret[0].([]db.Usage)
+	return ret0
+}
+
+// Usage indicates an expected call of Usage.
+func (mr *MockLimiterMockRecorder) Usage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Usage", reflect.TypeOf((*MockLimiter)(nil).Usage))
+}
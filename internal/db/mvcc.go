@@ -0,0 +1,398 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSnapshotClosed = errors.New("snapshot closed")
+)
+
+// version is a live record which was replaced by the change at until
+type version struct {
+	record
+	until uint64
+}
+
+// visibleAt reports whether the record is the one a snapshot at seq sees
+func (v version) visibleAt(seq uint64) bool {
+	return v.seq <= seq && seq < v.until
+}
+
+// snapshots counts the open snapshots by seq
+type snapshots struct {
+	mu   sync.Mutex
+	open map[uint64]int
+}
+
+func newSnapshots() *snapshots {
+	return &snapshots{open: map[uint64]int{}}
+}
+
+func (s *snapshots) add(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.open[seq]++
+}
+
+func (s *snapshots) remove(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.open[seq]--; s.open[seq] == 0 {
+		delete(s.open, seq)
+	}
+}
+
+// visible reports whether an open snapshot sees a version
+func (s *snapshots) visible(v version) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for seq := range s.open {
+		if v.visibleAt(seq) {
+			return true
+		}
+	}
+	return false
+}
+
+// retain keeps the live record replaced by the change at seq while a snapshot sees it,
+// the caller holds the lock of the model and of the feed
+func (db *databaseManager) retain(modelDB *modelDatabase, id uuid.UUID, previous record, seq uint64) {
+	v := version{record: previous, until: seq}
+	if db.snapshots.visible(v) {
+		modelDB.versions[id] = append(modelDB.versions[id], v)
+	}
+}
+
+// retainTrash keeps the trashed record restored or purged by the change at seq while a snapshot sees it,
+// the caller holds the lock of the model and of the feed
+func (db *databaseManager) retainTrash(modelDB *modelDatabase, id uuid.UUID, previous record, seq uint64) {
+	v := version{record: previous, until: seq}
+	if db.snapshots.visible(v) {
+		modelDB.trashVersions[id] = append(modelDB.trashVersions[id], v)
+	}
+}
+
+// collectVersions drops the versions no open snapshot sees anymore
+func (db *databaseManager) collectVersions() {
+	db.mu.RLock()
+	models := make([]*modelDatabase, 0, len(db.database))
+	for _, modelDB := range db.database {
+		models = append(models, modelDB)
+	}
+	db.mu.RUnlock()
+
+	for _, modelDB := range models {
+		modelDB.mu.Lock()
+		db.collect(modelDB.versions)
+		db.collect(modelDB.trashVersions)
+		modelDB.mu.Unlock()
+	}
+}
+
+// collect drops the versions of a model no open snapshot sees anymore, the caller holds the lock of the model
+func (db *databaseManager) collect(versions map[uuid.UUID][]version) {
+	for id, list := range versions {
+		kept := list[:0]
+		for _, v := range list {
+			if db.snapshots.visible(v) {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 {
+			delete(versions, id)
+		} else {
+			versions[id] = kept
+		}
+	}
+}
+
+// readAt returns the live record at seq, the caller holds the read lock of the model
+func (m *modelDatabase) readAt(id uuid.UUID, seq uint64) (record, bool) {
+	if item, ok := m.dataMap[id]; ok && item.seq <= seq {
+		return item, true
+	}
+	for _, v := range m.versions[id] {
+		if v.visibleAt(seq) {
+			return v.record, true
+		}
+	}
+
+	return record{}, false
+}
+
+// idRecord is a record along with its id
+type idRecord struct {
+	id uuid.UUID
+	record
+}
+
+// entriesAt returns the live records at seq by position
+func (m *modelDatabase) entriesAt(seq uint64) []idRecord {
+	entries := make([]idRecord, 0, m.orders.Len())
+	seen := map[uuid.UUID]bool{}
+	for node := m.orders.First(); node != nil; node = node.Next() {
+		if item, ok := m.readAt(node.value, seq); ok {
+			entries = append(entries, idRecord{id: node.value, record: item})
+		}
+		seen[node.value] = true
+	}
+
+	// records deleted or trashed since seq are only left in the versions
+	deleted := false
+	for id := range m.versions {
+		if seen[id] {
+			continue
+		}
+		if item, ok := m.readAt(id, seq); ok {
+			entries = append(entries, idRecord{id: id, record: item})
+			deleted = true
+		}
+	}
+	if deleted {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].position < entries[j].position })
+	}
+
+	return entries
+}
+
+// trashAt returns the trashed records at seq by position
+func (m *modelDatabase) trashAt(seq uint64) []idRecord {
+	entries := make([]idRecord, 0, len(m.trashed))
+	for id, item := range m.trashed {
+		if item.seq <= seq {
+			entries = append(entries, idRecord{id: id, record: item})
+		}
+	}
+	// records restored or purged since seq are only left in the trash versions
+	for id, versions := range m.trashVersions {
+		for _, v := range versions {
+			if v.visibleAt(seq) {
+				entries = append(entries, idRecord{id: id, record: v.record})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].position < entries[j].position })
+
+	return entries
+}
+
+func (m *modelDatabase) recordsAt(seq uint64) []record {
+	entries := m.entriesAt(seq)
+	records := make([]record, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, entry.record)
+	}
+
+	return records
+}
+
+// ReadSnapshot is a consistent view of the live records as of Seq, writes go on while
+// it is open and it never sees them. It keeps the records it sees alive, so it must be closed.
+type ReadSnapshot struct {
+	db  *databaseManager
+	seq uint64
+
+	// mu is held by reads, so Close waits for them
+	mu     sync.RWMutex
+	closed bool
+}
+
+// OpenSnapshot opens a snapshot at the last committed change. Watching after its Seq
+// follows the snapshot with every change it doesn't see.
+func (db *databaseManager) OpenSnapshot() (*ReadSnapshot, error) {
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	if db.feed.closed != nil {
+		return nil, db.feed.closed
+	}
+
+	seq := db.feed.seq
+	db.snapshots.add(seq)
+	return &ReadSnapshot{db: db, seq: seq}, nil
+}
+
+// Seq is the sequence of the last change the snapshot sees
+func (s *ReadSnapshot) Seq() uint64 {
+	return s.seq
+}
+
+// Close releases the versions only the snapshot sees, closing again does nothing
+func (s *ReadSnapshot) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	s.db.snapshots.remove(s.seq)
+	s.db.collectVersions()
+}
+
+// read runs fn with the read lock of model while the snapshot is open
+func (s *ReadSnapshot) read(model Model, fn func(modelDB *modelDatabase) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrSnapshotClosed
+	}
+
+	modelDB := s.db.getModelDB(model)
+	modelDB.mu.RLock()
+	defer modelDB.mu.RUnlock()
+
+	return fn(modelDB)
+}
+
+// models returns every model of the database by name
+func (s *ReadSnapshot) models() []Model {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	models := make([]Model, 0, len(s.db.database))
+	for model := range s.db.database {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i] < models[j] })
+
+	return models
+}
+
+// entries copies the live records of model, the stored values are never modified
+// so they can be read once the lock is released
+func (s *ReadSnapshot) entries(model Model) ([]idRecord, error) {
+	var entries []idRecord
+	err := s.read(model, func(modelDB *modelDatabase) error {
+		entries = modelDB.entriesAt(s.seq)
+		return nil
+	})
+
+	return entries, err
+}
+
+// trash copies the trashed records of model like entries
+func (s *ReadSnapshot) trash(model Model) ([]idRecord, error) {
+	var entries []idRecord
+	err := s.read(model, func(modelDB *modelDatabase) error {
+		entries = modelDB.trashAt(s.seq)
+		return nil
+	})
+
+	return entries, err
+}
+
+func (s *ReadSnapshot) Get(model Model, id uuid.UUID) (interface{}, error) {
+	var value interface{}
+	err := s.read(model, func(modelDB *modelDatabase) error {
+		item, ok := modelDB.readAt(id, s.seq)
+		if !ok {
+			return ErrNotFound
+		}
+		value = item.read()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (s *ReadSnapshot) List(model Model) ([]interface{}, error) {
+	var list []interface{}
+	err := s.read(model, func(modelDB *modelDatabase) error {
+		records := modelDB.recordsAt(s.seq)
+		list = make([]interface{}, 0, len(records))
+		for _, item := range records {
+			list = append(list, item.read())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (s *ReadSnapshot) ListRange(model Model, opts ListOptions) (Page, error) {
+	var page Page
+	err := s.read(model, func(modelDB *modelDatabase) error {
+		var err error
+		page, err = listRecords(modelDB.recordsAt(s.seq), opts)
+		return err
+	})
+
+	return page, err
+}
+
+// findAt matches the records the snapshot sees, a record it sees is either still indexed
+// or one of the versions
+func (s *ReadSnapshot) findAt(modelDB *modelDatabase, model Model, name string, query IndexQuery) ([]uuid.UUID, error) {
+	i, ok := modelDB.indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %s", ErrUnknownIndex, name, model)
+	}
+
+	candidates := i.find(IndexQuery{From: query.From, To: query.To})
+	for id := range modelDB.versions {
+		candidates = append(candidates, id)
+	}
+
+	records := make(map[uuid.UUID]record, len(candidates))
+	for _, id := range candidates {
+		if item, ok := modelDB.readAt(id, s.seq); ok {
+			records[id] = item
+		}
+	}
+
+	return i.match(records, query), nil
+}
+
+func (s *ReadSnapshot) FindIDs(model Model, name string, query IndexQuery) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := s.read(model, func(modelDB *modelDatabase) error {
+		var err error
+		ids, err = s.findAt(modelDB, model, name, query)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *ReadSnapshot) Find(model Model, name string, query IndexQuery) ([]interface{}, error) {
+	var list []interface{}
+	err := s.read(model, func(modelDB *modelDatabase) error {
+		ids, err := s.findAt(modelDB, model, name, query)
+		if err != nil {
+			return err
+		}
+
+		list = make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			item, _ := modelDB.readAt(id, s.seq)
+			list = append(list, item.read())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
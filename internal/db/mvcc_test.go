@@ -0,0 +1,242 @@
+package db
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// counts reads the counts of the test values by create order
+func counts(t *testing.T, tx Reader) []int {
+	list, err := NewCollection[testValue](Task).List(tx)
+	require.NoError(t, err)
+
+	counts := make([]int, 0, len(list))
+	for _, v := range list {
+		counts = append(counts, v.Count)
+	}
+	return counts
+}
+
+func TestReadSnapshot(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2, 3, 4)
+		snapshot, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		defer snapshot.Close()
+
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 10}))
+		require.NoError(t, db.Delete(Task, ids[1]))
+		require.NoError(t, db.Trash(Task, ids[2]))
+		createCounts(t, db, 5)
+
+		// assert
+		require.Equal(t, []int{10, 4, 5}, counts(t, db))
+		require.Equal(t, []int{1, 2, 3, 4}, counts(t, snapshot))
+
+		v, err := snapshot.Get(Task, ids[1])
+		require.NoError(t, err)
+		require.Equal(t, &testValue{Count: 2}, v)
+
+		page, err := snapshot.ListRange(Task, ListOptions{Limit: 2, Direction: DirectionBackward})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{&testValue{Count: 4}, &testValue{Count: 3}}, page.Values)
+		page, err = snapshot.ListRange(Task, ListOptions{After: page.Next, Direction: DirectionBackward})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{&testValue{Count: 2}, &testValue{Count: 1}}, page.Values)
+	})
+
+	t.Run("restored", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		snapshot, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		defer snapshot.Close()
+
+		require.NoError(t, db.Restore(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[1]))
+		require.NoError(t, db.Restore(Task, ids[1]))
+
+		// assert
+		require.Equal(t, []int{2}, counts(t, snapshot))
+		_, err = snapshot.Get(Task, ids[0])
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Equal(t, []int{1, 2}, counts(t, db))
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2, 3, 4)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[1]))
+		snapshot, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		defer snapshot.Close()
+
+		require.NoError(t, db.Restore(Task, ids[0]))
+		require.NoError(t, db.Purge(Task, ids[1]))
+		require.NoError(t, db.Trash(Task, ids[2]))
+		require.NoError(t, db.Trash(Task, ids[0]))
+
+		// assert, the snapshot sees the trash as it was
+		trashed, err := snapshot.trash(Task)
+		require.NoError(t, err)
+		require.Len(t, trashed, 2)
+		require.Equal(t, ids[0], trashed[0].id)
+		require.Equal(t, ids[1], trashed[1].id)
+		require.Equal(t, []int{3, 4}, counts(t, snapshot))
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2)
+		snapshot, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		defer snapshot.Close()
+
+		require.NoError(t, db.RunInTx(func(tx Tx) error {
+			if err := tx.Update(Task, ids[0], &testValue{Count: 2}); err != nil {
+				return err
+			}
+			return tx.Update(Task, ids[1], &testValue{Count: 1})
+		}))
+
+		// assert
+		require.Equal(t, []int{1, 2}, counts(t, snapshot))
+		require.Equal(t, []int{2, 1}, counts(t, db))
+	})
+
+	t.Run("index", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		require.NoError(t, db.RegisterIndex(Task, "count", countIndex))
+		ids := createCounts(t, db, 1, 2, 1)
+		snapshot, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		defer snapshot.Close()
+
+		require.NoError(t, db.Update(Task, ids[1], &testValue{Count: 1}))
+		require.NoError(t, db.Delete(Task, ids[2]))
+
+		// assert
+		found, err := snapshot.FindIDs(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[2]}, found)
+
+		values, err := snapshot.Find(Task, "count", IndexQuery{From: IntKey(1), Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{&testValue{Count: 1}, &testValue{Count: 1}}, values)
+
+		found, err = db.FindIDs(Task, "count", KeyEquals(IntKey(1)))
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ids[0], ids[1]}, found)
+
+		_, err = snapshot.FindIDs(Task, "unknown", IndexQuery{})
+		require.ErrorIs(t, err, ErrUnknownIndex)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		snapshot, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		snapshot.Close()
+		snapshot.Close()
+
+		// assert
+		_, err = snapshot.List(Task)
+		require.ErrorIs(t, err, ErrSnapshotClosed)
+		_, err = snapshot.Get(Task, uuid.New())
+		require.ErrorIs(t, err, ErrSnapshotClosed)
+
+		fileDB := openTestFileDB(t, testFileConfig(t))
+		require.NoError(t, fileDB.Close())
+		_, err = fileDB.OpenSnapshot()
+		require.ErrorIs(t, err, ErrClosed)
+	})
+}
+
+func TestCollectVersions(t *testing.T) {
+	t.Run("only while visible", func(t *testing.T) {
+		db := newDatabaseManager()
+		modelDB := db.getModelDB(Task)
+
+		// prepare, nothing is kept without a snapshot
+		ids := createCounts(t, db, 1, 2)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 10}))
+		require.Empty(t, modelDB.versions)
+
+		first, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 20}))
+		second, err := db.OpenSnapshot()
+		require.NoError(t, err)
+		require.NoError(t, db.Update(Task, ids[1], &testValue{Count: 30}))
+
+		// a version replaced twice while the same snapshots were open is only kept once
+		require.NoError(t, db.Update(Task, ids[1], &testValue{Count: 40}))
+		require.Len(t, modelDB.versions[ids[0]], 1)
+		require.Len(t, modelDB.versions[ids[1]], 1)
+
+		// assert
+		first.Close()
+		require.Equal(t, []int{20, 2}, counts(t, second))
+		require.NotContains(t, modelDB.versions, ids[0])
+		require.Len(t, modelDB.versions[ids[1]], 1)
+
+		second.Close()
+		require.Empty(t, modelDB.versions)
+	})
+
+	t.Run("concurrent writes", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		ids := createCounts(t, db, 0, 0, 0)
+		snapshot, err := db.OpenSnapshot()
+		require.NoError(t, err)
+
+		errs := make(chan error, len(ids))
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 1; i <= 100; i++ {
+					if err := db.Update(Task, id, &testValue{Count: i}); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+
+		// assert, the snapshot never sees a write
+		for range 100 {
+			require.Equal(t, []int{0, 0, 0}, counts(t, snapshot))
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		snapshot.Close()
+		require.Equal(t, []int{100, 100, 100}, counts(t, db))
+		require.Empty(t, db.getModelDB(Task).versions)
+	})
+}
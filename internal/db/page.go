@@ -0,0 +1,174 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidDirection = errors.New("invalid direction")
+)
+
+// Cursor is an opaque position in the create order of a model.
+// It stays valid after the record it points at is deleted.
+type Cursor string
+
+type Direction string
+
+const (
+	DirectionForward  Direction = "forward"
+	DirectionBackward Direction = "backward"
+)
+
+// ListOptions selects a page of records in create order
+type ListOptions struct {
+	// After is the Next cursor of the previous page, empty starts from the first record,
+	// or from the last one when going backward
+	After Cursor
+	// Limit is the max number of records in the page, zero means no limit
+	Limit int
+	// Direction is forward when empty
+	Direction Direction
+	// Filter skips the records it returns false for, nil keeps every record.
+	// It receives the stored value, so it must not modify or keep it.
+	Filter func(value interface{}) bool
+}
+
+type Page struct {
+	Values []interface{}
+	// Next is empty on the last page
+	Next Cursor
+}
+
+func newCursor(position uint64) Cursor {
+	return Cursor(base64.RawURLEncoding.EncodeToString(binary.BigEndian.AppendUint64(nil, position)))
+}
+
+// position returns zero for the empty cursor, records start at position 1
+func (c Cursor) position() (uint64, error) {
+	if c == "" {
+		return 0, nil
+	}
+
+	buf, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil || len(buf) != 8 {
+		return 0, ErrInvalidCursor
+	}
+
+	position := binary.BigEndian.Uint64(buf)
+	if position == 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return position, nil
+}
+
+func (o ListOptions) validate() (uint64, error) {
+	if o.Direction != "" && o.Direction != DirectionForward && o.Direction != DirectionBackward {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDirection, o.Direction)
+	}
+
+	return o.After.position()
+}
+
+func (o ListOptions) backward() bool {
+	return o.Direction == DirectionBackward
+}
+
+func (o ListOptions) keep(item record) bool {
+	return o.Filter == nil || o.Filter(item.value)
+}
+
+// full reports whether a page of size records is complete
+func (o ListOptions) full(size int) bool {
+	return o.Limit > 0 && size >= o.Limit
+}
+
+// listRange reads a page, the caller must hold the read lock of the model
+func (m *modelDatabase) listRange(opts ListOptions) (Page, error) {
+	after, err := opts.validate()
+	if err != nil {
+		return Page{}, err
+	}
+
+	var node *skipNode[uint64, uuid.UUID]
+	switch {
+	case !opts.backward():
+		node = m.orders.SeekGE(after + 1)
+	case after == 0:
+		node = m.orders.Last()
+	default:
+		node = m.orders.SeekLT(after)
+	}
+
+	page := Page{Values: []interface{}{}}
+	var last uint64
+	for ; node != nil; node = m.next(node, opts) {
+		item := m.dataMap[node.value]
+		if !opts.keep(item) {
+			continue
+		}
+
+		// there is a next page only when a record is left after the filter
+		if opts.full(len(page.Values)) {
+			page.Next = newCursor(last)
+			break
+		}
+
+		page.Values = append(page.Values, item.read())
+		last = node.key
+	}
+
+	return page, nil
+}
+
+func (m *modelDatabase) next(node *skipNode[uint64, uuid.UUID], opts ListOptions) *skipNode[uint64, uuid.UUID] {
+	if opts.backward() {
+		return node.Prev()
+	}
+	return node.Next()
+}
+
+// listRecords reads a page out of records sorted by position
+func listRecords(records []record, opts ListOptions) (Page, error) {
+	after, err := opts.validate()
+	if err != nil {
+		return Page{}, err
+	}
+
+	if opts.backward() {
+		if after != 0 {
+			records = records[:sort.Search(len(records), func(i int) bool { return records[i].position >= after })]
+		}
+	} else {
+		records = records[sort.Search(len(records), func(i int) bool { return records[i].position > after }):]
+	}
+
+	page := Page{Values: []interface{}{}}
+	var last uint64
+	for i := range records {
+		item := records[i]
+		if opts.backward() {
+			item = records[len(records)-1-i]
+		}
+		if !opts.keep(item) {
+			continue
+		}
+
+		if opts.full(len(page.Values)) {
+			page.Next = newCursor(last)
+			break
+		}
+
+		page.Values = append(page.Values, item.read())
+		last = item.position
+	}
+
+	return page, nil
+}
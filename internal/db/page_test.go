@@ -0,0 +1,201 @@
+package db
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// listPages reads every page starting from opts
+func listPages(t *testing.T, tx Tx, opts ListOptions) []interface{} {
+	values := []interface{}{}
+	for {
+		page, err := tx.ListRange(Task, opts)
+		require.NoError(t, err)
+		if opts.Limit > 0 {
+			require.LessOrEqual(t, len(page.Values), opts.Limit)
+		}
+
+		values = append(values, page.Values...)
+		if page.Next == "" {
+			return values
+		}
+		opts.After = page.Next
+	}
+}
+
+func createTestValues(t *testing.T, tx Tx, n int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, n)
+	for range n {
+		id := uuid.New()
+		require.NoError(t, tx.Create(Task, id, randomTestValue()))
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func TestListRange(t *testing.T) {
+	t.Run("pages", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createTestValues(t, db, gofakeit.Number(10, 50))
+		require.NoError(t, db.Delete(Task, ids[gofakeit.Number(0, len(ids)-1)]))
+
+		expected, err := db.List(Task)
+		require.NoError(t, err)
+		reversed := slices.Clone(expected)
+		slices.Reverse(reversed)
+
+		// assert
+		for _, limit := range []int{0, 1, gofakeit.Number(2, 9), len(expected), len(expected) + 1} {
+			require.Equal(t, expected, listPages(t, db, ListOptions{Limit: limit}))
+			require.Equal(t, expected, listPages(t, db, ListOptions{Limit: limit, Direction: DirectionForward}))
+			require.Equal(t, reversed, listPages(t, db, ListOptions{Limit: limit, Direction: DirectionBackward}))
+		}
+	})
+
+	t.Run("last page has no cursor", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		createTestValues(t, db, 4)
+
+		// assert
+		page, err := db.ListRange(Task, ListOptions{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Values, 2)
+		require.NotEmpty(t, page.Next)
+
+		page, err = db.ListRange(Task, ListOptions{After: page.Next, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Values, 2)
+		require.Empty(t, page.Next)
+	})
+
+	t.Run("cursor of a deleted record", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createTestValues(t, db, 3)
+		page, err := db.ListRange(Task, ListOptions{Limit: 2})
+		require.NoError(t, err)
+		require.NoError(t, db.Delete(Task, ids[1]))
+
+		// assert
+		page, err = db.ListRange(Task, ListOptions{After: page.Next})
+		require.NoError(t, err)
+		require.Len(t, page.Values, 1)
+
+		expected, err := db.Get(Task, ids[2])
+		require.NoError(t, err)
+		require.Equal(t, expected, page.Values[0])
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		db := New()
+
+		// assert
+		for _, cursor := range []Cursor{"abc", "!", newCursor(0)} {
+			_, err := db.ListRange(Task, ListOptions{After: cursor})
+			require.ErrorIs(t, err, ErrInvalidCursor)
+		}
+
+		_, err := db.ListRange(Task, ListOptions{Direction: "up"})
+		require.ErrorIs(t, err, ErrInvalidDirection)
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createTestValues(t, db, 10)
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.Delete(Task, ids[3]))
+			require.NoError(t, tx.Update(Task, ids[5], randomTestValue()))
+			created := createTestValues(t, tx, 5)
+			require.NoError(t, tx.Delete(Task, created[2]))
+
+			expected, err := tx.List(Task)
+			require.NoError(t, err)
+			reversed := slices.Clone(expected)
+			slices.Reverse(reversed)
+
+			for _, limit := range []int{0, 1, 3} {
+				require.Equal(t, expected, listPages(t, tx, ListOptions{Limit: limit}))
+				require.Equal(t, reversed, listPages(t, tx, ListOptions{Limit: limit, Direction: DirectionBackward}))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		createCounts(t, db, 1, 2, 3, 4, 5, 6, 7)
+		even := func(value interface{}) bool { return value.(*testValue).Count%2 == 0 }
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		expected := []interface{}{list[1], list[3], list[5]}
+		reversed := slices.Clone(expected)
+		slices.Reverse(reversed)
+
+		// assert
+		for _, limit := range []int{0, 1, 2, 3} {
+			require.Equal(t, expected, listPages(t, db, ListOptions{Limit: limit, Filter: even}))
+			require.Equal(t, reversed, listPages(t, db, ListOptions{Limit: limit, Filter: even, Direction: DirectionBackward}))
+		}
+
+		// no empty page after the last match
+		page, err := db.ListRange(Task, ListOptions{Limit: 3, Filter: even})
+		require.NoError(t, err)
+		require.Len(t, page.Values, 3)
+		require.Empty(t, page.Next)
+
+		err = db.RunInTx(func(tx Tx) error {
+			createCounts(t, tx, 8)
+			list, err := tx.List(Task)
+			require.NoError(t, err)
+			require.Equal(t, append(expected, list[7]), listPages(t, tx, ListOptions{Limit: 2, Filter: even}))
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("cursor survives a restart", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		ids := createTestValues(t, db, 4)
+		page, err := db.ListRange(Task, ListOptions{Limit: 3})
+		require.NoError(t, err)
+
+		// the newest records are gone before the snapshot, their positions must not be reused
+		require.NoError(t, db.Delete(Task, ids[2]))
+		require.NoError(t, db.Delete(Task, ids[3]))
+		require.NoError(t, db.Snapshot())
+		require.NoError(t, db.Close())
+
+		db = openTestFileDB(t, config)
+		created := createTestValues(t, db, 1)
+
+		// assert
+		page, err = db.ListRange(Task, ListOptions{After: page.Next})
+		require.NoError(t, err)
+		require.Len(t, page.Values, 1)
+
+		v, err := db.Get(Task, created[0])
+		require.NoError(t, err)
+		require.Equal(t, v, page.Values[0])
+	})
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// ErrQuotaExceeded is returned by the writes which would take a model over its Quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Quota limits what the records of a model take, trashed records included. Zero is no limit.
+type Quota struct {
+	MaxRecords int `koanf:"max_records" validate:"gte=0"`
+	// MaxBytes limits the approximate size of the records, see Usage
+	MaxBytes int64 `koanf:"max_bytes" validate:"gte=0"`
+}
+
+// Usage is what the records of a model take, trashed records included.
+// Bytes is the size of their JSON encoding, an approximation of their memory.
+type Usage struct {
+	Model   Model
+	Records int
+	Bytes   int64
+	Quota   Quota
+}
+
+// sizeOf is the size of value in Usage
+func sizeOf(value interface{}) int64 {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+
+	return int64(len(buf))
+}
+
+// allow fails when adding records and bytes to usage goes over quota,
+// so shrinking a model over a lowered quota is still allowed
+func (q Quota) allow(model Model, usage Usage, records int, bytes int64) error {
+	if records > 0 && q.MaxRecords > 0 && usage.Records+records > q.MaxRecords {
+		return fmt.Errorf("%w: %s is limited to %d records", ErrQuotaExceeded, model, q.MaxRecords)
+	}
+	if bytes > 0 && q.MaxBytes > 0 && usage.Bytes+bytes > q.MaxBytes {
+		return fmt.Errorf("%w: %s is limited to %d bytes", ErrQuotaExceeded, model, q.MaxBytes)
+	}
+
+	return nil
+}
+
+// account adds n times item to the usage of the model
+func (m *modelDatabase) account(item record, n int) {
+	m.usage.Records += n
+	m.usage.Bytes += int64(n) * item.size
+}
+
+// allow checks the quota before adding records and bytes, the caller holds the lock of the model
+func (m *modelDatabase) allow(model Model, records int, bytes int64) error {
+	return m.quota.allow(model, m.usage, records, bytes)
+}
+
+// SetQuota limits model from now on, the records over a lower quota are kept
+func (db *databaseManager) SetQuota(model Model, quota Quota) {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	modelDB := db.getModelDB(model)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	modelDB.quota = quota
+}
+
+// Usage returns the usage of every model by name
+func (db *databaseManager) Usage() []Usage {
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	db.mu.RLock()
+	list := make([]Usage, 0, len(db.database))
+	for model, modelDB := range db.database {
+		modelDB.mu.RLock()
+		usage := modelDB.usage
+		usage.Model = model
+		usage.Quota = modelDB.quota
+		modelDB.mu.RUnlock()
+
+		list = append(list, usage)
+	}
+	db.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Model < list[j].Model })
+	return list
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuota(t *testing.T) {
+	size := sizeOf(&testValue{Count: 1})
+
+	t.Run("records", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		db.SetQuota(Task, Quota{MaxRecords: 2})
+		ids := createCounts(t, db, 1, 2)
+
+		// assert, trashed records count until they are purged
+		err := db.Create(Task, uuid.New(), &testValue{Count: 3})
+		require.ErrorIs(t, err, ErrQuotaExceeded)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		err = db.Create(Task, uuid.New(), &testValue{Count: 3})
+		require.ErrorIs(t, err, ErrQuotaExceeded)
+
+		require.NoError(t, db.Purge(Task, ids[0]))
+		createCounts(t, db, 3)
+		require.NoError(t, db.Delete(Task, ids[1]))
+		createCounts(t, db, 4)
+		require.Equal(t, []int{3, 4}, counts(t, db))
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		db.SetQuota(Task, Quota{MaxBytes: 2 * size})
+		ids := createCounts(t, db, 1, 2)
+
+		// assert
+		err := db.Update(Task, ids[0], &testValue{Name: "longer", Count: 1})
+		require.ErrorIs(t, err, ErrQuotaExceeded)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 3}))
+		require.Equal(t, []int{3, 2}, counts(t, db))
+	})
+
+	t.Run("lowered", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2, 3)
+		db.SetQuota(Task, Quota{MaxRecords: 1, MaxBytes: size})
+
+		// assert, the records over the quota are kept and can shrink
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 4}))
+		require.NoError(t, db.Delete(Task, ids[1]))
+		err := db.Create(Task, uuid.New(), &testValue{Count: 5})
+		require.ErrorIs(t, err, ErrQuotaExceeded)
+		require.Equal(t, []int{4, 3}, counts(t, db))
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		db := newDatabaseManager()
+
+		// prepare
+		db.SetQuota(Task, Quota{MaxRecords: 2})
+		ids := createCounts(t, db, 1, 2)
+
+		// assert, a record deleted in the transaction makes room
+		require.NoError(t, db.RunInTx(func(tx Tx) error {
+			if err := tx.Delete(Task, ids[0]); err != nil {
+				return err
+			}
+			return tx.Create(Task, uuid.New(), &testValue{Count: 3})
+		}))
+		require.Equal(t, []int{2, 3}, counts(t, db))
+
+		err := db.RunInTx(func(tx Tx) error {
+			return tx.Create(Task, uuid.New(), &testValue{Count: 4})
+		})
+		require.ErrorIs(t, err, ErrQuotaExceeded)
+		require.Equal(t, []int{2, 3}, counts(t, db))
+	})
+}
+
+func TestUsage(t *testing.T) {
+	size := sizeOf(&testValue{Count: 1})
+
+	for _, snapshot := range []bool{false, true} {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		ids := createCounts(t, db, 1, 2, 3)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Name: "a", Count: 1}))
+		require.NoError(t, db.Delete(Task, ids[1]))
+		require.NoError(t, db.Trash(Task, ids[2]))
+		db.SetQuota(Task, Quota{MaxRecords: 10})
+
+		// assert
+		expected := Usage{Model: Task, Records: 2, Bytes: 2*size + int64(len("a")), Quota: Quota{MaxRecords: 10}}
+		require.Equal(t, []Usage{expected}, db.Usage(), snapshot)
+
+		// the usage is the same once reopened, the quota is set again by the caller
+		if snapshot {
+			require.NoError(t, db.Snapshot())
+		}
+		require.NoError(t, db.Close())
+
+		expected.Quota = Quota{}
+		reopened := openTestFileDB(t, config)
+		require.Equal(t, []Usage{expected}, reopened.Usage(), snapshot)
+	}
+}
@@ -0,0 +1,518 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrReplicationGap means the events don't follow the last change of the replica,
+	// it has to start over from a copy
+	ErrReplicationGap = errors.New("replication gap")
+	// ErrInvalidReplication means a replication stream is corrupted or of an unknown version
+	ErrInvalidReplication = errors.New("invalid replication stream")
+)
+
+const (
+	replicationFormat  = "todo-replication"
+	replicationVersion = 1
+
+	// replicationBuffer is the number of events a replica can fall behind before it has to resume
+	replicationBuffer = 1024
+	// heartbeatInterval is how often a primary tells its replicas its last change
+	heartbeatInterval = time.Second
+)
+
+// Copy is every record of a database, trashed ones included, as of the change Seq
+type Copy struct {
+	Seq uint64
+	// Positions is the last position given in every model,
+	// the records after it may have been deleted already
+	Positions map[Model]uint64
+	Records   []CopyRecord
+}
+
+// CopyRecord is a record as it is stored, so a replica gives the same versions and order
+type CopyRecord struct {
+	Model    Model
+	ID       uuid.UUID
+	Value    interface{}
+	Version  uint64
+	Position uint64
+	// DeletedAt is only set on trashed records
+	DeletedAt time.Time
+}
+
+// Replication streams the changes of a database to a replica
+type Replication struct {
+	*Subscription
+
+	// Copy is nil when the replica resumes from the events kept, the events follow it otherwise
+	Copy *Copy
+
+	feed *feed
+}
+
+// Seq is the sequence of the last change committed, the replica is behind until it gets there
+func (r *Replication) Seq() uint64 {
+	r.feed.mu.Lock()
+	defer r.feed.mu.Unlock()
+
+	return r.feed.seq
+}
+
+// Replicate resumes from the events after `after` when they are still kept, and starts
+// with a copy of the database otherwise. The copy and the subscription are taken at once,
+// so the events follow it without a gap. Writes are blocked while the models are being copied.
+func (db *databaseManager) Replicate(ctx context.Context, after *uint64) (*Replication, error) {
+	opts := WatchOptions{After: after, Buffer: replicationBuffer}
+	if after != nil {
+		subscription, err := db.Watch(ctx, opts)
+		if err == nil {
+			return &Replication{Subscription: subscription, feed: db.feed}, nil
+		}
+		if !errors.Is(err, ErrFeedTruncated) {
+			return nil, err
+		}
+	}
+
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	models := make([]Model, 0, len(db.database))
+	for model := range db.database {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i] < models[j] })
+
+	for _, model := range models {
+		modelDB := db.database[model]
+		modelDB.mu.RLock()
+		defer modelDB.mu.RUnlock()
+	}
+
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	cp := &Copy{Seq: db.feed.seq, Positions: map[Model]uint64{}, Records: []CopyRecord{}}
+	for _, model := range models {
+		modelDB := db.database[model]
+		cp.Positions[model] = modelDB.lastPosition
+
+		for node := modelDB.orders.First(); node != nil; node = node.Next() {
+			cp.Records = append(cp.Records, copyRecord(model, node.value, modelDB.dataMap[node.value]))
+		}
+
+		trashed := make([]CopyRecord, 0, len(modelDB.trashed))
+		for id, item := range modelDB.trashed {
+			trashed = append(trashed, copyRecord(model, id, item))
+		}
+		sort.Slice(trashed, func(i, j int) bool { return trashed[i].Position < trashed[j].Position })
+		cp.Records = append(cp.Records, trashed...)
+	}
+
+	opts.After = &cp.Seq
+	subscription, err := db.feed.subscribeLocked(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Replication{Subscription: subscription, Copy: cp, feed: db.feed}, nil
+}
+
+func copyRecord(model Model, id uuid.UUID, item record) CopyRecord {
+	return CopyRecord{
+		Model:     model,
+		ID:        id,
+		Value:     item.read(),
+		Version:   item.version,
+		Position:  item.position,
+		DeletedAt: item.deletedAt,
+	}
+}
+
+// LoadCopy replaces every record with a copy from Replicate, and the next change is the one
+// after its Seq. The subscriptions end with ErrFeedTruncated, and the snapshots already
+// open see the copy as well.
+func (db *databaseManager) LoadCopy(cp *Copy) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	// nothing else holds a model lock while txMu is held exclusively,
+	// so they can be taken after the feed lock here
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	if db.feed.closed != nil {
+		return db.feed.closed
+	}
+
+	db.loadCopyLocked(cp)
+	return nil
+}
+
+// loadCopyLocked replaces the records in memory, the caller holds txMu exclusively and the lock of the feed
+func (db *databaseManager) loadCopyLocked(cp *Copy) {
+	db.mu.RLock()
+	for model, modelDB := range db.database {
+		modelDB.mu.Lock()
+		for id := range modelDB.dataMap {
+			db.expiries.set(model, id, time.Time{})
+		}
+		modelDB.reset()
+		modelDB.mu.Unlock()
+	}
+	db.mu.RUnlock()
+
+	for _, item := range cp.Records {
+		value := clone(item.Value)
+		modelDB := db.getModelDB(item.Model)
+		modelDB.mu.Lock()
+		modelDB.load(item.ID, record{
+			value:     value,
+			version:   item.Version,
+			position:  item.Position,
+			deletedAt: item.DeletedAt,
+			size:      sizeOf(value),
+		})
+		modelDB.mu.Unlock()
+
+		if item.DeletedAt.IsZero() {
+			db.expiries.set(item.Model, item.ID, expiryOf(value))
+		}
+	}
+
+	for model, position := range cp.Positions {
+		modelDB := db.getModelDB(model)
+		modelDB.mu.Lock()
+		modelDB.lastPosition = max(modelDB.lastPosition, position)
+		modelDB.mu.Unlock()
+	}
+
+	db.feed.resetLocked(cp.Seq)
+}
+
+var eventOps = map[EventKind]opKind{
+	EventCreate:  opCreate,
+	EventUpdate:  opUpdate,
+	EventDelete:  opDelete,
+	EventTrash:   opTrash,
+	EventRestore: opRestore,
+	EventPurge:   opPurge,
+}
+
+// ApplyEvents commits the events of one write of a primary at once, the first one must follow
+// the last change of the database. They are applied without any check, quotas included,
+// as the database holds the same records as the primary did.
+func (db *databaseManager) ApplyEvents(events []Event) error {
+	ops := make([]operation, 0, len(events))
+	for _, e := range events {
+		kind, ok := eventOps[e.Kind]
+		if !ok {
+			return fmt.Errorf("unknown event %q at seq %d", e.Kind, e.Seq)
+		}
+
+		op := operation{Kind: kind, Model: e.Model, ID: e.ID}
+		switch kind {
+		case opCreate, opUpdate:
+			if err := isPointer(e.Value); err != nil {
+				return err
+			}
+			op.Value = clone(e.Value)
+			op.size = sizeOf(op.Value)
+		case opTrash:
+			op.At = e.DeletedAt
+		}
+		ops = append(ops, op)
+	}
+
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	db.feed.mu.Lock()
+	seq := db.feed.seq
+	db.feed.mu.Unlock()
+
+	for i, e := range events {
+		if e.Seq != seq+uint64(i)+1 {
+			return fmt.Errorf("%w: got seq %d after %d", ErrReplicationGap, e.Seq, seq+uint64(i))
+		}
+	}
+
+	return db.commit(ops)
+}
+
+// a replication stream is a header frame, then a copy frame followed by its records when the
+// replica starts over, then a frame for every event and a heartbeat every heartbeatInterval
+type replicationFrame struct {
+	Header    *replicationHeader    `json:"header,omitempty"`
+	Copy      *replicationCopy      `json:"copy,omitempty"`
+	Record    *snapshotRecord       `json:"record,omitempty"`
+	Event     *replicationEvent     `json:"event,omitempty"`
+	Heartbeat *replicationHeartbeat `json:"heartbeat,omitempty"`
+}
+
+type replicationHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+type replicationCopy struct {
+	Seq       uint64           `json:"seq"`
+	Positions map[Model]uint64 `json:"positions"`
+	// Count is the number of record frames which follow
+	Count int `json:"count"`
+}
+
+type replicationEvent struct {
+	Seq     uint64    `json:"seq"`
+	LastSeq uint64    `json:"last_seq"`
+	Kind    EventKind `json:"kind"`
+	Model   Model     `json:"model"`
+	ID      uuid.UUID `json:"id"`
+	// Value is only set on creates and updates
+	Value     json.RawMessage `json:"value,omitempty"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
+}
+
+type replicationHeartbeat struct {
+	// Seq is the last change of the primary
+	Seq uint64 `json:"seq"`
+}
+
+// flusher is implemented by the writers which buffer, like an http.ResponseWriter
+type flusher interface {
+	Flush()
+}
+
+// WriteReplication streams the changes of database after `after` to w until ctx is done,
+// starting with a copy of every record when after is nil or those changes are gone.
+// w is flushed whenever the stream catches up, when it is a flusher.
+func WriteReplication(ctx context.Context, database Replicator, w io.Writer, after *uint64) error {
+	replication, err := database.Replicate(ctx, after)
+	if err != nil {
+		return err
+	}
+
+	write := func(frame replicationFrame) error {
+		buf, err := encodeFrame(frame)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(buf)
+		return err
+	}
+	flush := func() {
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+	}
+
+	if err := write(replicationFrame{Header: &replicationHeader{Format: replicationFormat, Version: replicationVersion}}); err != nil {
+		return err
+	}
+
+	if cp := replication.Copy; cp != nil {
+		header := replicationCopy{Seq: cp.Seq, Positions: cp.Positions, Count: len(cp.Records)}
+		if err := write(replicationFrame{Copy: &header}); err != nil {
+			return err
+		}
+
+		for _, item := range cp.Records {
+			value, err := json.Marshal(item.Value)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s %s: %w", item.Model, item.ID, err)
+			}
+
+			frame := &snapshotRecord{
+				Model:    item.Model,
+				ID:       item.ID,
+				Version:  item.Version,
+				Position: item.Position,
+				Value:    value,
+			}
+			if !item.DeletedAt.IsZero() {
+				deletedAt := item.DeletedAt
+				frame.DeletedAt = &deletedAt
+			}
+			if err := write(replicationFrame{Record: frame}); err != nil {
+				return err
+			}
+		}
+	}
+	flush()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-replication.Events():
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return replication.Err()
+			}
+
+			frame := &replicationEvent{Seq: e.Seq, LastSeq: e.LastSeq, Kind: e.Kind, Model: e.Model, ID: e.ID}
+			switch e.Kind {
+			case EventCreate, EventUpdate:
+				value, err := json.Marshal(e.Value)
+				if err != nil {
+					return fmt.Errorf("failed to encode %s %s: %w", e.Model, e.ID, err)
+				}
+				frame.Value = value
+			case EventTrash:
+				frame.DeletedAt = &e.DeletedAt
+			}
+			if err := write(replicationFrame{Event: frame}); err != nil {
+				return err
+			}
+
+			if len(replication.Events()) == 0 {
+				flush()
+			}
+		case <-ticker.C:
+			if err := write(replicationFrame{Heartbeat: &replicationHeartbeat{Seq: replication.Seq()}}); err != nil {
+				return err
+			}
+			flush()
+		}
+	}
+}
+
+// ReplicaProgress is how far a replica got in the changes of its primary
+type ReplicaProgress struct {
+	// Seq is the last change applied
+	Seq uint64
+	// PrimarySeq is the last change of the primary the replica heard of
+	PrimarySeq uint64
+}
+
+// ReadReplication applies a stream of WriteReplication to database until it ends, and calls
+// progress after every copy or write applied and every heartbeat. The changes of one write
+// of the primary are applied at once.
+func ReadReplication(database Replicator, schema Schema, r io.Reader, progress func(ReplicaProgress)) error {
+	snapshot, err := database.OpenSnapshot()
+	if err != nil {
+		return err
+	}
+	state := ReplicaProgress{Seq: snapshot.Seq()}
+	snapshot.Close()
+
+	var header *replicationHeader
+	var cp *Copy
+	var count int
+	pending := []Event{}
+
+	loadCopy := func() error {
+		if err := database.LoadCopy(cp); err != nil {
+			return err
+		}
+
+		state.Seq = cp.Seq
+		state.PrimarySeq = max(state.PrimarySeq, cp.Seq)
+		cp = nil
+		progress(state)
+		return nil
+	}
+
+	_, err = readFrames(r, func(payload []byte) error {
+		var frame replicationFrame
+		if err := json.Unmarshal(payload, &frame); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidReplication, err)
+		}
+
+		switch {
+		case header == nil:
+			if frame.Header == nil || frame.Header.Format != replicationFormat {
+				return fmt.Errorf("%w: missing header", ErrInvalidReplication)
+			}
+			if frame.Header.Version != replicationVersion {
+				return fmt.Errorf("%w: unsupported version %d", ErrInvalidReplication, frame.Header.Version)
+			}
+			header = frame.Header
+		case frame.Copy != nil:
+			if cp != nil || len(pending) > 0 {
+				return fmt.Errorf("%w: copy in the middle of a write", ErrInvalidReplication)
+			}
+			cp = &Copy{Seq: frame.Copy.Seq, Positions: frame.Copy.Positions, Records: make([]CopyRecord, 0, frame.Copy.Count)}
+			count = frame.Copy.Count
+			if count == 0 {
+				return loadCopy()
+			}
+		case frame.Record != nil:
+			if cp == nil {
+				return fmt.Errorf("%w: record outside of a copy", ErrInvalidReplication)
+			}
+			value, err := schema.decode(frame.Record.Model, frame.Record.Value)
+			if err != nil {
+				return fmt.Errorf("%w: failed to decode %s %s: %w", ErrInvalidReplication, frame.Record.Model, frame.Record.ID, err)
+			}
+			item := CopyRecord{
+				Model:    frame.Record.Model,
+				ID:       frame.Record.ID,
+				Value:    value,
+				Version:  frame.Record.Version,
+				Position: frame.Record.Position,
+			}
+			if frame.Record.DeletedAt != nil {
+				item.DeletedAt = *frame.Record.DeletedAt
+			}
+			cp.Records = append(cp.Records, item)
+			if len(cp.Records) == count {
+				return loadCopy()
+			}
+		case frame.Event != nil:
+			if cp != nil {
+				return fmt.Errorf("%w: event in the middle of a copy", ErrInvalidReplication)
+			}
+			e := Event{Seq: frame.Event.Seq, LastSeq: frame.Event.LastSeq, Kind: frame.Event.Kind, Model: frame.Event.Model, ID: frame.Event.ID}
+			if frame.Event.Value != nil {
+				value, err := schema.decode(e.Model, frame.Event.Value)
+				if err != nil {
+					return fmt.Errorf("%w: failed to decode %s %s: %w", ErrInvalidReplication, e.Model, e.ID, err)
+				}
+				e.Value = value
+			}
+			if frame.Event.DeletedAt != nil {
+				e.DeletedAt = *frame.Event.DeletedAt
+			}
+
+			pending = append(pending, e)
+			state.PrimarySeq = max(state.PrimarySeq, e.LastSeq)
+			if e.Seq < e.LastSeq {
+				return nil
+			}
+
+			if err := database.ApplyEvents(pending); err != nil {
+				return err
+			}
+			pending = pending[:0]
+			state.Seq = e.Seq
+			progress(state)
+		case frame.Heartbeat != nil:
+			state.PrimarySeq = max(state.PrimarySeq, frame.Heartbeat.Seq)
+			progress(state)
+		default:
+			return fmt.Errorf("%w: unknown frame", ErrInvalidReplication)
+		}
+
+		return nil
+	})
+	if errors.Is(err, ErrCorruptedLog) {
+		return fmt.Errorf("%w: %w", ErrInvalidReplication, err)
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicate(t *testing.T) {
+	t.Run("truncated feed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		db := New()
+
+		// prepare
+		for range feedHistorySize + 1 {
+			createCounts(t, db, 1)
+		}
+
+		// assert, the replica starts over from a copy when the events are gone
+		var zero uint64
+		replication, err := db.Replicate(ctx, &zero)
+		require.NoError(t, err)
+		require.NotNil(t, replication.Copy)
+		require.EqualValues(t, feedHistorySize+1, replication.Copy.Seq)
+		require.Len(t, replication.Copy.Records, feedHistorySize+1)
+
+		// or when it is ahead of the database
+		ahead := uint64(feedHistorySize + 2)
+		replication, err = db.Replicate(ctx, &ahead)
+		require.NoError(t, err)
+		require.NotNil(t, replication.Copy)
+	})
+
+	t.Run("copy is consistent", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		db := New()
+
+		// prepare
+		ids := createCounts(t, db, 1, 2)
+		replication, err := db.Replicate(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 3}))
+
+		// assert, the copy is as of its seq and the events follow it
+		require.EqualValues(t, 2, replication.Copy.Seq)
+		require.Equal(t, map[Model]uint64{Task: 2}, replication.Copy.Positions)
+		require.Equal(t, &testValue{Count: 1}, replication.Copy.Records[0].Value)
+		require.EqualValues(t, 1, replication.Copy.Records[0].Version)
+
+		e := receiveEvent(t, replication.Subscription)
+		require.EqualValues(t, 3, e.Seq)
+		require.Equal(t, EventUpdate, e.Kind)
+		require.EqualValues(t, 3, replication.Seq())
+	})
+}
+
+func TestLoadCopy(t *testing.T) {
+	t.Run("subscriptions", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		primary, replica := New(), New()
+
+		// prepare
+		createCounts(t, primary, 1, 2, 3)
+		createCounts(t, replica, 4)
+		s, err := replica.Watch(ctx, WatchOptions{})
+		require.NoError(t, err)
+
+		replication, err := primary.Replicate(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, replica.LoadCopy(replication.Copy))
+
+		// assert, the events the subscribers got don't lead to the copy
+		requireClosed(t, s, ErrFeedTruncated)
+
+		after := uint64(3)
+		s, err = replica.Watch(ctx, WatchOptions{After: &after})
+		require.NoError(t, err)
+		createCounts(t, replica, 5)
+		require.EqualValues(t, 4, receiveEvent(t, s).Seq)
+	})
+
+	t.Run("file database", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		config := testFileConfig(t)
+		primary, replica := New(), openTestFileDB(t, config)
+
+		// prepare, the copy replaces the log of the replica
+		createCounts(t, replica, 10, 11)
+		ids := createCounts(t, primary, 1, 2, 3)
+		require.NoError(t, primary.Update(Task, ids[0], &testValue{Count: 4}))
+		require.NoError(t, primary.Trash(Task, ids[1]))
+
+		replication, err := primary.Replicate(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, replica.LoadCopy(replication.Copy))
+		require.NoError(t, primary.Restore(Task, ids[1]))
+		require.NoError(t, replica.ApplyEvents([]Event{receiveEvent(t, replication.Subscription)}))
+		require.NoError(t, replica.Close())
+
+		// assert
+		reopened := openTestFileDB(t, config)
+		require.Equal(t, []int{4, 2, 3}, counts(t, reopened))
+
+		snapshot, err := reopened.OpenSnapshot()
+		require.NoError(t, err)
+		defer snapshot.Close()
+		require.EqualValues(t, 6, snapshot.Seq())
+
+		v, err := reopened.Get(Task, ids[0])
+		require.NoError(t, err)
+		expected, err := primary.Get(Task, ids[0])
+		require.NoError(t, err)
+		require.Equal(t, expected, v)
+	})
+}
+
+func TestReadReplication(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		event, err := encodeFrame(replicationFrame{Event: &replicationEvent{Seq: 1, LastSeq: 1, Kind: EventDelete}})
+		require.NoError(t, err)
+		header, err := encodeFrame(replicationFrame{Header: &replicationHeader{Format: replicationFormat, Version: replicationVersion}})
+		require.NoError(t, err)
+		unknown, err := encodeFrame(replicationFrame{Header: &replicationHeader{Format: replicationFormat, Version: 2}})
+		require.NoError(t, err)
+		record, err := encodeFrame(replicationFrame{Record: &snapshotRecord{Model: Task, Value: []byte(`{}`)}})
+		require.NoError(t, err)
+
+		corrupted := bytes.Clone(header)
+		corrupted[len(corrupted)-2] = 'x'
+
+		for name, stream := range map[string][]byte{
+			"missing header":   event,
+			"unknown version":  unknown,
+			"record alone":     append(bytes.Clone(header), record...),
+			"checksum":         corrupted,
+			"unknown frame":    append(bytes.Clone(header), header...),
+			"garbage in frame": append(bytes.Clone(header), newFrame([]byte("{"))...),
+		} {
+			t.Run(name, func(t *testing.T) {
+				db := New()
+
+				// assert
+				err := ReadReplication(db, testSchema, bytes.NewReader(stream), func(ReplicaProgress) {})
+				require.ErrorIs(t, err, ErrInvalidReplication)
+				require.Empty(t, counts(t, db))
+			})
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		var buf bytes.Buffer
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.NoError(t, WriteReplication(ctx, New(), &buf, nil))
+		stream := buf.Bytes()
+
+		// assert, a stream cut short is not invalid, it ends
+		err := ReadReplication(db, testSchema, bytes.NewReader(stream[:len(stream)-1]), func(ReplicaProgress) {})
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"cmp"
+	"math/rand/v2"
+)
+
+const (
+	skipListMaxLevel = 32
+	// the chance of a node to be promoted to the next level
+	skipListP = 0.25
+)
+
+// skipList is an ordered map with O(log n) insert, delete and seek.
+// It is not safe for concurrent use.
+type skipList[K any, V any] struct {
+	head    *skipNode[K, V]
+	tail    *skipNode[K, V]
+	level   int
+	len     int
+	compare func(a, b K) int
+}
+
+type skipNode[K any, V any] struct {
+	key   K
+	value V
+
+	next []*skipNode[K, V]
+	prev *skipNode[K, V]
+}
+
+func newSkipList[K cmp.Ordered, V any]() *skipList[K, V] {
+	return newSkipListFunc[K, V](cmp.Compare[K])
+}
+
+// newSkipListFunc orders the keys with compare, which returns -1, 0 or +1 like cmp.Compare
+func newSkipListFunc[K any, V any](compare func(a, b K) int) *skipList[K, V] {
+	return &skipList[K, V]{
+		head:    &skipNode[K, V]{next: make([]*skipNode[K, V], skipListMaxLevel)},
+		level:   1,
+		compare: compare,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.Float64() < skipListP {
+		level++
+	}
+	return level
+}
+
+// findPath fills path with the last node before key on every level
+func (s *skipList[K, V]) findPath(key K, path []*skipNode[K, V]) *skipNode[K, V] {
+	node := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && s.compare(node.next[i].key, key) < 0 {
+			node = node.next[i]
+		}
+		if path != nil {
+			path[i] = node
+		}
+	}
+	return node.next[0]
+}
+
+func (s *skipList[K, V]) Len() int {
+	return s.len
+}
+
+func (s *skipList[K, V]) Get(key K) (V, bool) {
+	node := s.findPath(key, nil)
+	if node == nil || s.compare(node.key, key) != 0 {
+		var zero V
+		return zero, false
+	}
+	return node.value, true
+}
+
+// Set inserts key or replaces its value
+func (s *skipList[K, V]) Set(key K, value V) {
+	path := make([]*skipNode[K, V], skipListMaxLevel)
+	node := s.findPath(key, path)
+	if node != nil && s.compare(node.key, key) == 0 {
+		node.value = value
+		return
+	}
+
+	level := randomLevel()
+	if level > s.level {
+		for i := s.level; i < level; i++ {
+			path[i] = s.head
+		}
+		s.level = level
+	}
+
+	node = &skipNode[K, V]{key: key, value: value, next: make([]*skipNode[K, V], level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = path[i].next[i]
+		path[i].next[i] = node
+	}
+
+	if path[0] != s.head {
+		node.prev = path[0]
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node
+	} else {
+		s.tail = node
+	}
+	s.len++
+}
+
+func (s *skipList[K, V]) Delete(key K) bool {
+	path := make([]*skipNode[K, V], skipListMaxLevel)
+	node := s.findPath(key, path)
+	if node == nil || s.compare(node.key, key) != 0 {
+		return false
+	}
+
+	for i := 0; i < len(node.next); i++ {
+		path[i].next[i] = node.next[i]
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node.prev
+	} else {
+		s.tail = node.prev
+	}
+
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+	s.len--
+	return true
+}
+
+// First returns the node with the smallest key
+func (s *skipList[K, V]) First() *skipNode[K, V] {
+	return s.head.next[0]
+}
+
+// Last returns the node with the largest key
+func (s *skipList[K, V]) Last() *skipNode[K, V] {
+	return s.tail
+}
+
+// SeekGE returns the first node with a key greater than or equal to key
+func (s *skipList[K, V]) SeekGE(key K) *skipNode[K, V] {
+	return s.findPath(key, nil)
+}
+
+// SeekLT returns the last node with a key less than key
+func (s *skipList[K, V]) SeekLT(key K) *skipNode[K, V] {
+	node := s.findPath(key, nil)
+	if node == nil {
+		return s.tail
+	}
+	return node.prev
+}
+
+func (n *skipNode[K, V]) Next() *skipNode[K, V] {
+	return n.next[0]
+}
+
+func (n *skipNode[K, V]) Prev() *skipNode[K, V] {
+	return n.prev
+}
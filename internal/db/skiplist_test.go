@@ -0,0 +1,89 @@
+package db
+
+import (
+	"math/rand/v2"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipList(t *testing.T) {
+	t.Run("ordered like a sorted map", func(t *testing.T) {
+		list := newSkipList[int, int]()
+		expected := map[int]int{}
+
+		// prepare
+		for range 2000 {
+			key := rand.IntN(500)
+			if rand.IntN(3) == 0 {
+				_, ok := expected[key]
+				require.Equal(t, ok, list.Delete(key))
+				delete(expected, key)
+				continue
+			}
+			value := rand.Int()
+			list.Set(key, value)
+			expected[key] = value
+		}
+
+		// assert
+		keys := make([]int, 0, len(expected))
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		sort.Ints(keys)
+		require.Equal(t, len(keys), list.Len())
+
+		forward := []int{}
+		for node := list.First(); node != nil; node = node.Next() {
+			forward = append(forward, node.key)
+			require.Equal(t, expected[node.key], node.value)
+		}
+		require.Equal(t, keys, forward)
+
+		backward := []int{}
+		for node := list.Last(); node != nil; node = node.Prev() {
+			backward = append([]int{node.key}, backward...)
+		}
+		require.Equal(t, keys, backward)
+
+		for _, key := range keys {
+			v, ok := list.Get(key)
+			require.True(t, ok)
+			require.Equal(t, expected[key], v)
+		}
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		list := newSkipList[int, string]()
+		for _, key := range []int{10, 20, 30} {
+			list.Set(key, "")
+		}
+
+		// assert
+		require.Equal(t, 10, list.SeekGE(5).key)
+		require.Equal(t, 20, list.SeekGE(20).key)
+		require.Equal(t, 30, list.SeekGE(21).key)
+		require.Nil(t, list.SeekGE(31))
+
+		require.Nil(t, list.SeekLT(10))
+		require.Equal(t, 10, list.SeekLT(20).key)
+		require.Equal(t, 20, list.SeekLT(21).key)
+		require.Equal(t, 30, list.SeekLT(100).key)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		list := newSkipList[int, int]()
+
+		// assert
+		require.Nil(t, list.First())
+		require.Nil(t, list.Last())
+		require.Nil(t, list.SeekGE(0))
+		require.Nil(t, list.SeekLT(0))
+		require.False(t, list.Delete(0))
+
+		_, ok := list.Get(0)
+		require.False(t, ok)
+	})
+}
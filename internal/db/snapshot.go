@@ -0,0 +1,417 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	snapshotPrefix  = "snapshot-"
+	snapshotSuffix  = ".snap"
+	snapshotTmpName = "snapshot.tmp"
+	snapshotVersion = 1
+)
+
+// a snapshot file is a header frame, a frame for every record in create order
+// followed by the trashed ones, and a footer frame
+type snapshotFrame struct {
+	Header *snapshotHeader `json:"header,omitempty"`
+	Record *snapshotRecord `json:"record,omitempty"`
+	Footer *snapshotFooter `json:"footer,omitempty"`
+}
+
+type snapshotHeader struct {
+	Version int `json:"version"`
+	// Seq is the last log record included in the snapshot
+	Seq uint64 `json:"seq"`
+	// Events is the sequence of the last event included in the snapshot
+	Events uint64 `json:"events"`
+	// Positions is the last position given in every model,
+	// the records after it may have been deleted already
+	Positions map[Model]uint64 `json:"positions"`
+}
+
+type snapshotRecord struct {
+	Model    Model           `json:"model"`
+	ID       uuid.UUID       `json:"id"`
+	Version  uint64          `json:"version"`
+	Position uint64          `json:"position"`
+	Value    json.RawMessage `json:"value"`
+	// DeletedAt is only set on trashed records
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type snapshotFooter struct {
+	Count int `json:"count"`
+}
+
+func snapshotPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", snapshotPrefix, seq, snapshotSuffix))
+}
+
+// listSnapshots returns the sequences of the snapshots in dir in ascending order
+func listSnapshots(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := []uint64{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// Snapshot writes every model to a new snapshot file and removes the log segments
+// and snapshots it supersedes. Writes are blocked while the models are being copied.
+func (db *FileDatabase) Snapshot() error {
+	db.snapshotMu.Lock()
+	defer db.snapshotMu.Unlock()
+
+	header, records, err := db.freeze()
+	if err != nil {
+		return err
+	}
+
+	if err := writeSnapshot(db.config.Dir, db.keys, header, records); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return db.compact(header.Seq)
+}
+
+// freeze encodes every record and starts a new log segment at a point where no write is in flight
+func (db *FileDatabase) freeze() (snapshotHeader, []snapshotRecord, error) {
+	// a transaction applies its operations to several models one after another
+	db.txMu.RLock()
+	defer db.txMu.RUnlock()
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	models := make([]Model, 0, len(db.database))
+	for model := range db.database {
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i] < models[j] })
+
+	// writers hold the model lock while appending to the log,
+	// so once every model is read locked the log can't move
+	for _, model := range models {
+		modelDB := db.database[model]
+		modelDB.mu.RLock()
+		defer modelDB.mu.RUnlock()
+	}
+
+	// no write can be between the log and the feed while every model is locked
+	db.feed.mu.Lock()
+	events := db.feed.seq
+	db.feed.mu.Unlock()
+
+	db.wal.mu.Lock()
+	if db.wal.closed {
+		db.wal.mu.Unlock()
+		return snapshotHeader{}, nil, ErrClosed
+	}
+	seq := db.wal.seq
+	err := db.wal.rotateLocked()
+	db.wal.mu.Unlock()
+	if err != nil {
+		return snapshotHeader{}, nil, fmt.Errorf("failed to rotate log: %w", err)
+	}
+
+	header := snapshotHeader{Version: snapshotVersion, Seq: seq, Events: events, Positions: map[Model]uint64{}}
+	records := []snapshotRecord{}
+	for _, model := range models {
+		modelDB := db.database[model]
+		header.Positions[model] = modelDB.lastPosition
+		ids := make([]uuid.UUID, 0, len(modelDB.dataMap)+len(modelDB.trashed))
+		for node := modelDB.orders.First(); node != nil; node = node.Next() {
+			ids = append(ids, node.value)
+		}
+		trashed := make([]uuid.UUID, 0, len(modelDB.trashed))
+		for id := range modelDB.trashed {
+			trashed = append(trashed, id)
+		}
+		sort.Slice(trashed, func(i, j int) bool {
+			return modelDB.trashed[trashed[i]].position < modelDB.trashed[trashed[j]].position
+		})
+		ids = append(ids, trashed...)
+
+		for _, id := range ids {
+			item, ok := modelDB.dataMap[id]
+			if !ok {
+				item = modelDB.trashed[id]
+			}
+
+			value, err := json.Marshal(item.value)
+			if err != nil {
+				return snapshotHeader{}, nil, err
+			}
+
+			snapshot := snapshotRecord{
+				Model:    model,
+				ID:       id,
+				Version:  item.version,
+				Position: item.position,
+				Value:    value,
+			}
+			if !item.deletedAt.IsZero() {
+				deletedAt := item.deletedAt
+				snapshot.DeletedAt = &deletedAt
+			}
+			records = append(records, snapshot)
+		}
+	}
+
+	return header, records, nil
+}
+
+// LoadCopy writes the copy to a new snapshot file, which supersedes the whole log, before loading it
+func (db *FileDatabase) LoadCopy(cp *Copy) error {
+	db.snapshotMu.Lock()
+	defer db.snapshotMu.Unlock()
+
+	records := make([]snapshotRecord, 0, len(cp.Records))
+	for _, item := range cp.Records {
+		value, err := json.Marshal(item.Value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s %s: %w", item.Model, item.ID, err)
+		}
+
+		snapshot := snapshotRecord{
+			Model:    item.Model,
+			ID:       item.ID,
+			Version:  item.Version,
+			Position: item.Position,
+			Value:    value,
+		}
+		if !item.DeletedAt.IsZero() {
+			deletedAt := item.DeletedAt
+			snapshot.DeletedAt = &deletedAt
+		}
+		records = append(records, snapshot)
+	}
+
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	if db.feed.closed != nil {
+		return db.feed.closed
+	}
+
+	db.wal.mu.Lock()
+	seq := db.wal.seq
+	err := db.wal.rotateLocked()
+	db.wal.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to rotate log: %w", err)
+	}
+
+	header := snapshotHeader{Version: snapshotVersion, Seq: seq, Events: cp.Seq, Positions: cp.Positions}
+	if err := writeSnapshot(db.config.Dir, db.keys, header, records); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := db.compact(seq); err != nil {
+		return err
+	}
+
+	db.loadCopyLocked(cp)
+	return nil
+}
+
+// writeSnapshot writes to a temporary file first and renames it,
+// so a crash never leaves a partial snapshot behind
+func writeSnapshot(dir string, keys *keyring, header snapshotHeader, records []snapshotRecord) (err error) {
+	tmpPath := filepath.Join(dir, snapshotTmpName)
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	frames := make([]snapshotFrame, 0, len(records)+2)
+	frames = append(frames, snapshotFrame{Header: &header})
+	for i := range records {
+		frames = append(frames, snapshotFrame{Record: &records[i]})
+	}
+	frames = append(frames, snapshotFrame{Footer: &snapshotFooter{Count: len(records)}})
+
+	for _, frame := range frames {
+		payload, err := json.Marshal(frame)
+		if err != nil {
+			return err
+		}
+		payload, err = keys.seal(payload)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(newFrame(payload)); err != nil {
+			return err
+		}
+	}
+
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, snapshotPath(dir, header.Seq)); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// compact removes the snapshots older than seq and the log segments they cover
+func (db *FileDatabase) compact(seq uint64) error {
+	snapshots, err := listSnapshots(db.config.Dir)
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		if s < seq {
+			if err := os.Remove(snapshotPath(db.config.Dir, s)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the log was rotated at seq, so every segment starting before it only holds older records
+	segments, err := listSegments(db.config.Dir)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		if seg.firstSeq <= seq {
+			if err := os.Remove(seg.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return syncDir(db.config.Dir)
+}
+
+// loadSnapshot restores the latest snapshot in dir and returns its sequence,
+// or zero when there is no snapshot yet
+func (db *databaseManager) loadSnapshot(dir string, schema Schema, keys *keyring) (uint64, error) {
+	// leftover of a crash while writing a snapshot
+	if err := os.Remove(filepath.Join(dir, snapshotTmpName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+
+	path := snapshotPath(dir, snapshots[len(snapshots)-1])
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var header *snapshotHeader
+	var footer *snapshotFooter
+	count := 0
+	_, err = readFrames(file, func(payload []byte) error {
+		payload, err := keys.open(payload)
+		if err != nil {
+			return err
+		}
+
+		var frame snapshotFrame
+		if err := json.Unmarshal(payload, &frame); err != nil {
+			return fmt.Errorf("%w: %w", ErrCorruptedLog, err)
+		}
+
+		switch {
+		case header == nil:
+			if frame.Header == nil || frame.Header.Version != snapshotVersion {
+				return fmt.Errorf("%w: invalid snapshot header in %s", ErrCorruptedLog, path)
+			}
+			header = frame.Header
+			db.feed.seq = header.Events
+			for model, position := range header.Positions {
+				modelDB := db.getModelDB(model)
+				modelDB.lastPosition = max(modelDB.lastPosition, position)
+			}
+		case footer != nil:
+			return fmt.Errorf("%w: data after snapshot footer in %s", ErrCorruptedLog, path)
+		case frame.Footer != nil:
+			footer = frame.Footer
+		case frame.Record != nil:
+			value, err := schema.decode(frame.Record.Model, frame.Record.Value)
+			if err != nil {
+				return err
+			}
+			item := record{
+				value:    value,
+				version:  frame.Record.Version,
+				position: frame.Record.Position,
+				size:     int64(len(frame.Record.Value)),
+			}
+			if frame.Record.DeletedAt != nil {
+				item.deletedAt = *frame.Record.DeletedAt
+			}
+			db.getModelDB(frame.Record.Model).load(frame.Record.ID, item)
+			if item.deletedAt.IsZero() {
+				db.expiries.set(frame.Record.Model, frame.Record.ID, expiryOf(value))
+			}
+			count++
+		default:
+			return fmt.Errorf("%w: unknown snapshot frame in %s", ErrCorruptedLog, path)
+		}
+
+		return nil
+	})
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("%w: truncated snapshot %s", ErrCorruptedLog, path)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if header == nil || footer == nil || footer.Count != count {
+		return 0, fmt.Errorf("%w: incomplete snapshot %s", ErrCorruptedLog, path)
+	}
+
+	return header.Seq, nil
+}
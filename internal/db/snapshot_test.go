@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Run("load snapshot and replay tail", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		n := gofakeit.Number(3, 10)
+		ids := make([]uuid.UUID, 0, n)
+		for range n {
+			id := uuid.New()
+			require.NoError(t, db.Create(Task, id, randomTestValue()))
+			ids = append(ids, id)
+		}
+		require.NoError(t, db.Delete(Task, ids[0]))
+		require.NoError(t, db.Snapshot())
+
+		// written after the snapshot
+		tail := uuid.New()
+		require.NoError(t, db.Create(Task, tail, randomTestValue()))
+		updated := randomTestValue()
+		require.NoError(t, db.Update(Task, ids[1], updated))
+
+		expected, err := db.List(Task)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		// assert
+		snapshots, err := listSnapshots(config.Dir)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{uint64(n + 1)}, snapshots)
+
+		segments, err := listSegments(config.Dir)
+		require.NoError(t, err)
+		require.Len(t, segments, 1)
+		require.Equal(t, uint64(n+2), segments[0].firstSeq)
+
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, expected, list)
+
+		v, err := db.Get(Task, ids[1])
+		require.NoError(t, err)
+		require.Equal(t, updated, v)
+	})
+
+	t.Run("replaces previous snapshot", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.NoError(t, db.Snapshot())
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.NoError(t, db.Snapshot())
+
+		// nothing written since the last snapshot
+		require.NoError(t, db.Snapshot())
+		require.NoError(t, db.Close())
+
+		// assert
+		snapshots, err := listSnapshots(config.Dir)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{2}, snapshots)
+
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+	})
+
+	t.Run("segment rotation", func(t *testing.T) {
+		config := testFileConfig(t)
+		config.SegmentSize = 1
+		db := openTestFileDB(t, config)
+
+		// prepare
+		n := gofakeit.Number(3, 10)
+		for range n {
+			require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		}
+		require.NoError(t, db.Close())
+
+		// assert
+		segments, err := listSegments(config.Dir)
+		require.NoError(t, err)
+		require.Len(t, segments, n)
+
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, n)
+	})
+
+	t.Run("leftover temporary file", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.NoError(t, db.Close())
+
+		// simulate a crash while writing a snapshot
+		tmpPath := filepath.Join(config.Dir, snapshotTmpName)
+		require.NoError(t, os.WriteFile(tmpPath, []byte("partial"), 0o644))
+
+		// assert
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+
+		_, err = os.Stat(tmpPath)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("corrupted snapshot", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		require.NoError(t, db.Snapshot())
+		require.NoError(t, db.Close())
+
+		path := snapshotPath(config.Dir, 1)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data[:len(data)-1], 0o644))
+
+		// assert
+		_, err = NewFile(config, testSchema)
+		require.ErrorIs(t, err, ErrCorruptedLog)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		db := openTestFileDB(t, testFileConfig(t))
+		require.NoError(t, db.Close())
+
+		// assert
+		require.ErrorIs(t, db.Snapshot(), ErrClosed)
+	})
+
+	t.Run("periodic", func(t *testing.T) {
+		config := testFileConfig(t)
+		config.SnapshotInterval = 10 * time.Millisecond
+		db := openTestFileDB(t, config)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- db.Run(ctx)
+		}()
+
+		// prepare
+		require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+
+		// assert
+		require.Eventually(t, func() bool {
+			snapshots, err := listSnapshots(config.Dir)
+			require.NoError(t, err)
+			return len(snapshots) > 0 && snapshots[len(snapshots)-1] == 1
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("concurrent writes", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					done <- nil
+					return
+				default:
+				}
+				if err := db.Snapshot(); err != nil {
+					done <- err
+					return
+				}
+			}
+		}()
+
+		n := 200
+		for range n {
+			require.NoError(t, db.Create(Task, uuid.New(), randomTestValue()))
+		}
+		cancel()
+		require.NoError(t, <-done)
+
+		expected, err := db.List(Task)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		// assert
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, expected, list)
+	})
+}
@@ -0,0 +1,307 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+// SQLDatabase keeps every model in memory like the default database, and writes every
+// change to the records table of a SQLite database in the same SQL transaction.
+// On startup every record is loaded from the table. The triggers of the schema keep the
+// typed tasks and projects tables in step with it for reporting. Nothing is encrypted.
+type SQLDatabase struct {
+	*databaseManager
+
+	journal *sqlJournal
+}
+
+// NewSQL opens the database of config, whose schema must be up to date, see Migrate
+func NewSQL(config SQLConfig, schema Schema) (*SQLDatabase, error) {
+	sqlDB, err := openSQL(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	version, err := schemaVersion(ctx, sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version != len(migrations) {
+		sqlDB.Close()
+		return nil, fmt.Errorf("%w: schema at version %d instead of %d, run todo migrate", ErrSchemaOutdated, version, len(migrations))
+	}
+
+	manager := newDatabaseManager()
+	if err := manager.loadSQL(ctx, sqlDB, schema); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to load records: %w", err)
+	}
+
+	journal := &sqlJournal{db: sqlDB}
+	manager.journal = journal
+
+	return &SQLDatabase{
+		databaseManager: manager,
+		journal:         journal,
+	}, nil
+}
+
+func openSQL(config SQLConfig) (*sql.DB, error) {
+	sqlDB, err := sql.Open("sqlite", config.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to sql database: %w", err)
+	}
+
+	return sqlDB, nil
+}
+
+// Close ends every subscription and closes the connections,
+// writes and subscriptions after Close return ErrClosed
+func (db *SQLDatabase) Close() error {
+	db.feed.close(ErrClosed)
+	if db.journal.closed.Swap(true) {
+		return nil
+	}
+
+	return db.journal.db.Close()
+}
+
+// loadSQL loads every record along with the last position of every model and the last change
+func (db *databaseManager) loadSQL(ctx context.Context, sqlDB *sql.DB, schema Schema) error {
+	if err := sqlDB.QueryRowContext(ctx, `SELECT seq FROM changes WHERE id = 1`).Scan(&db.feed.seq); err != nil {
+		return err
+	}
+
+	rows, err := sqlDB.QueryContext(ctx, `SELECT model, id, version, position, value, deleted_at FROM records ORDER BY model, position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			model     Model
+			id        uuid.UUID
+			item      record
+			value     []byte
+			deletedAt sql.NullString
+		)
+		if err := rows.Scan(&model, &id, &item.version, &item.position, &value, &deletedAt); err != nil {
+			return err
+		}
+
+		item.value, err = schema.decode(model, value)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s %s: %w", model, id, err)
+		}
+		item.size = int64(len(value))
+		if deletedAt.Valid {
+			item.deletedAt, err = time.Parse(time.RFC3339Nano, deletedAt.String)
+			if err != nil {
+				return fmt.Errorf("failed to decode %s %s: %w", model, id, err)
+			}
+		}
+
+		db.getModelDB(model).load(id, item)
+		if item.deletedAt.IsZero() {
+			db.expiries.set(model, id, expiryOf(item.value))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	positions, err := sqlDB.QueryContext(ctx, `SELECT model, last_position FROM positions`)
+	if err != nil {
+		return err
+	}
+	defer positions.Close()
+
+	for positions.Next() {
+		var model Model
+		var position uint64
+		if err := positions.Scan(&model, &position); err != nil {
+			return err
+		}
+
+		modelDB := db.getModelDB(model)
+		modelDB.lastPosition = max(modelDB.lastPosition, position)
+	}
+
+	return positions.Err()
+}
+
+// LoadCopy replaces every row with the copy before loading it
+func (db *SQLDatabase) LoadCopy(cp *Copy) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	if db.feed.closed != nil {
+		return db.feed.closed
+	}
+
+	err := db.journal.inTx(func(tx *sql.Tx) error {
+		for _, statement := range []string{`DELETE FROM records`, `DELETE FROM positions`} {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+
+		positions := map[Model]uint64{}
+		for model, position := range cp.Positions {
+			positions[model] = position
+		}
+		for _, item := range cp.Records {
+			value, err := json.Marshal(item.Value)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s %s: %w", item.Model, item.ID, err)
+			}
+
+			var deletedAt *string
+			if !item.DeletedAt.IsZero() {
+				at := formatSQLTime(item.DeletedAt)
+				deletedAt = &at
+			}
+			if _, err := tx.Exec(`INSERT INTO records (model, id, version, position, value, deleted_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				item.Model, item.ID.String(), item.Version, item.Position, string(value), deletedAt); err != nil {
+				return err
+			}
+			positions[item.Model] = max(positions[item.Model], item.Position)
+		}
+
+		for model, position := range positions {
+			if _, err := tx.Exec(`INSERT INTO positions (model, last_position) VALUES (?, ?)`, model, position); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`UPDATE changes SET seq = ? WHERE id = 1`, cp.Seq)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write copy: %w", err)
+	}
+
+	db.loadCopyLocked(cp)
+	return nil
+}
+
+type sqlJournal struct {
+	db     *sql.DB
+	closed atomic.Bool
+}
+
+// inTx runs fn in a SQL transaction, which is committed when fn returns nil
+func (j *sqlJournal) inTx(fn func(tx *sql.Tx) error) error {
+	if j.closed.Load() {
+		return ErrClosed
+	}
+
+	tx, err := j.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// write changes the rows as the operations change the records in memory,
+// a version or position is worked out by the SQL database from the rows
+func (j *sqlJournal) write(ops ...operation) error {
+	return j.inTx(func(tx *sql.Tx) error {
+		for _, op := range ops {
+			if err := writeSQL(tx, op); err != nil {
+				return fmt.Errorf("failed to %s %s %s: %w", op.Kind, op.Model, op.ID, err)
+			}
+		}
+
+		_, err := tx.Exec(`UPDATE changes SET seq = seq + ? WHERE id = 1`, len(ops))
+		return err
+	})
+}
+
+func writeSQL(tx *sql.Tx, op operation) error {
+	id := op.ID.String()
+
+	var result sql.Result
+	var err error
+	switch op.Kind {
+	case opCreate:
+		value, err := json.Marshal(op.Value)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO positions (model, last_position) VALUES (?, 1)
+			ON CONFLICT (model) DO UPDATE SET last_position = last_position + 1`, op.Model); err != nil {
+			return err
+		}
+		result, err = tx.Exec(`INSERT INTO records (model, id, version, position, value)
+			SELECT model, ?, 1, last_position, ? FROM positions WHERE model = ?`, id, string(value), op.Model)
+		if err != nil {
+			return err
+		}
+	case opUpdate:
+		value, err := json.Marshal(op.Value)
+		if err != nil {
+			return err
+		}
+		result, err = tx.Exec(`UPDATE records SET value = ?, version = version + 1 WHERE model = ? AND id = ?`,
+			string(value), op.Model, id)
+		if err != nil {
+			return err
+		}
+	case opDelete, opPurge:
+		result, err = tx.Exec(`DELETE FROM records WHERE model = ? AND id = ?`, op.Model, id)
+	case opTrash:
+		result, err = tx.Exec(`UPDATE records SET deleted_at = ? WHERE model = ? AND id = ?`, formatSQLTime(op.At), op.Model, id)
+	case opRestore:
+		result, err = tx.Exec(`UPDATE records SET deleted_at = NULL WHERE model = ? AND id = ?`, op.Model, id)
+	default:
+		return fmt.Errorf("unknown operation %q", op.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the checks in memory passed, so a missing row means the table was changed behind the database
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("%d rows changed instead of 1", n)
+	}
+
+	return nil
+}
+
+func formatSQLTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
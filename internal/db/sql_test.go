@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func testSQLConfig(t *testing.T) SQLConfig {
+	config := SQLConfig{}.Default()
+	config.DSN = "file:" + filepath.Join(t.TempDir(), "todo.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+	_, err := Migrate(context.Background(), config)
+	require.NoError(t, err)
+	return config
+}
+
+func openTestSQLDB(t *testing.T, config SQLConfig) *SQLDatabase {
+	db, err := NewSQL(config, testSchema)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLDatabase(t *testing.T) {
+	t.Run("reopen", func(t *testing.T) {
+		config := testSQLConfig(t)
+		db := openTestSQLDB(t, config)
+
+		// prepare
+		ids := createCounts(t, db, 1, 2, 3, 4, 5)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Count: 6}))
+		require.NoError(t, db.Trash(Task, ids[1]))
+		require.NoError(t, db.RunInTx(func(tx Tx) error {
+			if err := tx.Delete(Task, ids[2]); err != nil {
+				return err
+			}
+			return tx.Update(Task, ids[0], &testValue{Count: 7})
+		}))
+		// the last position isn't given again
+		require.NoError(t, db.Delete(Task, ids[4]))
+
+		expectedTrash, err := db.ListTrash(Task)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		// assert
+		reopened := openTestSQLDB(t, config)
+		require.Equal(t, []int{7, 4}, counts(t, reopened))
+
+		v, err := reopened.Get(Task, ids[0])
+		require.NoError(t, err)
+		require.Equal(t, &testValue{Count: 7}, v)
+		require.NoError(t, reopened.UpdateIfVersion(Task, ids[0], 3, &testValue{Count: 8}))
+
+		trashed, err := reopened.ListTrash(Task)
+		require.NoError(t, err)
+		require.Equal(t, expectedTrash, trashed)
+		require.NoError(t, reopened.Restore(Task, ids[1]))
+
+		snapshot, err := reopened.OpenSnapshot()
+		require.NoError(t, err)
+		require.EqualValues(t, 12, snapshot.Seq())
+		snapshot.Close()
+
+		createCounts(t, reopened, 9)
+		require.Equal(t, []int{8, 2, 4, 9}, counts(t, reopened))
+		require.EqualValues(t, 6, reopened.getModelDB(Task).lastPosition)
+	})
+
+	t.Run("outdated schema", func(t *testing.T) {
+		config := SQLConfig{}.Default()
+		config.DSN = "file:" + filepath.Join(t.TempDir(), "todo.db")
+
+		// assert
+		_, err := NewSQL(config, testSchema)
+		require.ErrorIs(t, err, ErrSchemaOutdated)
+	})
+
+	t.Run("reporting", func(t *testing.T) {
+		config := testSQLConfig(t)
+		db := openTestSQLDB(t, config)
+
+		// prepare
+		ids := createCounts(t, db, 1, 2)
+		require.NoError(t, db.Update(Task, ids[0], &testValue{Name: "report", Count: 3}))
+		require.NoError(t, db.Trash(Task, ids[1]))
+
+		// assert, the tasks are plain rows to a SQL client
+		client, err := sql.Open("sqlite", config.DSN)
+		require.NoError(t, err)
+		defer client.Close()
+
+		var name string
+		var version int
+		err = client.QueryRow(`SELECT name, version FROM tasks WHERE deleted_at IS NULL`).Scan(&name, &version)
+		require.NoError(t, err)
+		require.Equal(t, "report", name)
+		require.Equal(t, 2, version)
+
+		var trashed int
+		require.NoError(t, client.QueryRow(`SELECT COUNT(*) FROM tasks WHERE deleted_at IS NOT NULL`).Scan(&trashed))
+		require.Equal(t, 1, trashed)
+	})
+
+	t.Run("typed tables", func(t *testing.T) {
+		config := testSQLConfig(t)
+		db := openTestSQLDB(t, config)
+		type reportedTask struct {
+			Name      string `json:"name"`
+			Status    int    `json:"status"`
+			State     string `json:"state"`
+			ProjectID string `json:"project_id"`
+		}
+
+		// prepare
+		projectID := uuid.New()
+		require.NoError(t, db.Create(Project, projectID, &testValue{Name: "boxes"}))
+		taskID := uuid.New()
+		require.NoError(t, db.Create(Task, taskID, &reportedTask{Name: "pack", Status: 1, State: "done", ProjectID: projectID.String()}))
+
+		// assert, the rows follow the records
+		client, err := sql.Open("sqlite", config.DSN)
+		require.NoError(t, err)
+		defer client.Close()
+
+		var name, state, statusType string
+		err = client.QueryRow(`SELECT tasks.name, state, typeof(status) FROM tasks JOIN projects ON projects.id = tasks.project_id
+			WHERE projects.name = 'boxes'`).Scan(&name, &state, &statusType)
+		require.NoError(t, err)
+		require.Equal(t, []string{"pack", "done", "integer"}, []string{name, state, statusType})
+
+		require.NoError(t, db.Delete(Task, taskID))
+		var count int
+		require.NoError(t, client.QueryRow(`SELECT COUNT(*) FROM tasks`).Scan(&count))
+		require.Zero(t, count)
+
+		// a record which doesn't fit the columns isn't written
+		require.Error(t, db.Create(Task, uuid.New(), &reportedTask{Name: "lost", State: "lost"}))
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Empty(t, list)
+	})
+
+	t.Run("failed write", func(t *testing.T) {
+		config := testSQLConfig(t)
+		db := openTestSQLDB(t, config)
+
+		// prepare, the row is removed behind the database
+		ids := createCounts(t, db, 1)
+		_, err := db.journal.db.Exec(`DELETE FROM records`)
+		require.NoError(t, err)
+
+		// assert, nothing is applied in memory either
+		require.Error(t, db.Update(Task, ids[0], &testValue{Count: 2}))
+		require.Equal(t, []int{1}, counts(t, db))
+
+		require.NoError(t, db.Close())
+		require.ErrorIs(t, db.Create(Task, uuid.New(), &testValue{}), ErrClosed)
+	})
+
+	t.Run("load copy", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		config := testSQLConfig(t)
+		primary, replica := New(), openTestSQLDB(t, config)
+
+		// prepare
+		createCounts(t, replica, 10)
+		ids := createCounts(t, primary, 1, 2, 3)
+		require.NoError(t, primary.Trash(Task, ids[1]))
+
+		replication, err := primary.Replicate(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, replica.LoadCopy(replication.Copy))
+		require.NoError(t, primary.Update(Task, ids[0], &testValue{Count: 4}))
+		require.NoError(t, replica.ApplyEvents([]Event{receiveEvent(t, replication.Subscription)}))
+		require.NoError(t, replica.Close())
+
+		// assert
+		reopened := openTestSQLDB(t, config)
+		require.Equal(t, []int{4, 3}, counts(t, reopened))
+
+		trashed, err := reopened.ListTrash(Task)
+		require.NoError(t, err)
+		expected, err := primary.ListTrash(Task)
+		require.NoError(t, err)
+		require.Equal(t, expected, trashed)
+
+		snapshot, err := reopened.OpenSnapshot()
+		require.NoError(t, err)
+		defer snapshot.Close()
+		require.EqualValues(t, 5, snapshot.Seq())
+	})
+}
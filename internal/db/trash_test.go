@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func trashedIDs(t *testing.T, tx Tx) []uuid.UUID {
+	trashed, err := tx.ListTrash(Task)
+	require.NoError(t, err)
+
+	ids := make([]uuid.UUID, 0, len(trashed))
+	for _, item := range trashed {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestTrash(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createTestValues(t, db, 4)
+		expected, err := db.List(Task)
+		require.NoError(t, err)
+
+		value, err := db.Get(Task, ids[1])
+		require.NoError(t, err)
+
+		before := time.Now().UTC()
+		require.NoError(t, db.Trash(Task, ids[2]))
+		require.NoError(t, db.Trash(Task, ids[1]))
+
+		// assert
+		_, err = db.Get(Task, ids[1])
+		require.ErrorIs(t, err, ErrNotFound)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{expected[0], expected[3]}, list)
+
+		trashed, err := db.ListTrash(Task)
+		require.NoError(t, err)
+		require.Len(t, trashed, 2)
+		require.Equal(t, ids[1], trashed[0].ID)
+		require.Equal(t, value, trashed[0].Value)
+		require.WithinDuration(t, before, trashed[0].DeletedAt, time.Second)
+		require.Equal(t, ids[2], trashed[1].ID)
+
+		// restored to its position
+		require.NoError(t, db.Restore(Task, ids[1]))
+		list, err = db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{expected[0], expected[1], expected[3]}, list)
+
+		require.NoError(t, db.Purge(Task, ids[2]))
+		require.Empty(t, trashedIDs(t, db))
+		require.ErrorIs(t, db.Restore(Task, ids[2]), ErrNotFound)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createTestValues(t, db, 2)
+		require.NoError(t, db.Trash(Task, ids[0]))
+
+		// assert
+		require.ErrorIs(t, db.Trash(Task, ids[0]), ErrNotFound)
+		require.ErrorIs(t, db.Trash(Task, uuid.New()), ErrNotFound)
+		require.ErrorIs(t, db.Update(Task, ids[0], randomTestValue()), ErrNotFound)
+		require.ErrorIs(t, db.Delete(Task, ids[0]), ErrNotFound)
+		require.ErrorIs(t, db.Create(Task, ids[0], randomTestValue()), ErrAlreadyExists)
+
+		require.ErrorIs(t, db.Restore(Task, ids[1]), ErrNotFound)
+		require.ErrorIs(t, db.Purge(Task, ids[1]), ErrNotFound)
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createTestValues(t, db, 4)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[3]))
+
+		// assert
+		var expected []interface{}
+		err := db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.Restore(Task, ids[0]))
+			require.NoError(t, tx.Purge(Task, ids[3]))
+			require.NoError(t, tx.Trash(Task, ids[1]))
+			require.ErrorIs(t, tx.Create(Task, ids[1], randomTestValue()), ErrAlreadyExists)
+
+			created := createTestValues(t, tx, 1)
+			require.NoError(t, tx.Trash(Task, created[0]))
+			require.NoError(t, tx.Restore(Task, created[0]))
+
+			require.Equal(t, []uuid.UUID{ids[1]}, trashedIDs(t, tx))
+
+			var err error
+			expected, err = tx.List(Task)
+			require.NoError(t, err)
+			require.Len(t, expected, 3)
+
+			value, err := tx.Get(Task, ids[0])
+			require.NoError(t, err)
+			require.Equal(t, value, expected[0])
+			return nil
+		})
+		require.NoError(t, err)
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, expected, list)
+		require.Equal(t, []uuid.UUID{ids[1]}, trashedIDs(t, db))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		ids := createTestValues(t, db, 2)
+		require.NoError(t, db.Trash(Task, ids[0]))
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			require.NoError(t, tx.Purge(Task, ids[0]))
+			require.NoError(t, tx.Trash(Task, ids[1]))
+			return ErrConflict
+		})
+		require.ErrorIs(t, err, ErrConflict)
+		require.Equal(t, []uuid.UUID{ids[0]}, trashedIDs(t, db))
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		ids := createTestValues(t, db, 5)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[1]))
+		require.NoError(t, db.Snapshot())
+
+		// in the log after the snapshot
+		require.NoError(t, db.Purge(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[2]))
+		require.NoError(t, db.Trash(Task, ids[3]))
+		require.NoError(t, db.Restore(Task, ids[3]))
+
+		expectedList, err := db.List(Task)
+		require.NoError(t, err)
+		expectedTrash, err := db.ListTrash(Task)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		// assert
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, expectedList, list)
+
+		trashed, err := db.ListTrash(Task)
+		require.NoError(t, err)
+		require.Len(t, trashed, len(expectedTrash))
+		for i := range trashed {
+			require.Equal(t, expectedTrash[i].ID, trashed[i].ID)
+			require.Equal(t, expectedTrash[i].Value, trashed[i].Value)
+			require.True(t, expectedTrash[i].DeletedAt.Equal(trashed[i].DeletedAt))
+		}
+
+		// restored after a restart
+		require.NoError(t, db.Restore(Task, ids[1]))
+		list, err = db.List(Task)
+		require.NoError(t, err)
+		value, err := db.Get(Task, ids[1])
+		require.NoError(t, err)
+		require.Equal(t, value, list[0])
+	})
+
+	t.Run("events", func(t *testing.T) {
+		db := New()
+		ctx := context.Background()
+
+		// prepare
+		s, err := db.Watch(ctx, WatchOptions{})
+		require.NoError(t, err)
+
+		ids := createTestValues(t, db, 1)
+		require.NoError(t, db.Trash(Task, ids[0]))
+		require.NoError(t, db.Restore(Task, ids[0]))
+		require.NoError(t, db.Trash(Task, ids[0]))
+		require.NoError(t, db.Purge(Task, ids[0]))
+
+		// assert
+		require.Equal(t, EventCreate, receiveEvent(t, s).Kind)
+		e := receiveEvent(t, s)
+		require.Equal(t, EventTrash, e.Kind)
+		require.False(t, e.DeletedAt.IsZero())
+		e = receiveEvent(t, s)
+		require.Equal(t, EventRestore, e.Kind)
+		require.True(t, e.DeletedAt.IsZero())
+		require.Equal(t, EventTrash, receiveEvent(t, s).Kind)
+		require.Equal(t, EventPurge, receiveEvent(t, s).Kind)
+	})
+}
@@ -0,0 +1,381 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reader reads the live records
+type Reader interface {
+	Get(model Model, id uuid.UUID) (interface{}, error)
+	// List by create order
+	List(model Model) ([]interface{}, error)
+	// ListRange returns a page of List
+	ListRange(model Model, opts ListOptions) (Page, error)
+
+	// FindIDs returns the ids of the live records selected by query in the index name,
+	// by key then create order. It fails with ErrUnknownIndex when name is not registered.
+	FindIDs(model Model, name string, query IndexQuery) ([]uuid.UUID, error)
+	// Find is FindIDs returning the records
+	Find(model Model, name string, query IndexQuery) ([]interface{}, error)
+}
+
+// Tx reads and writes records. Inside a transaction the writes are only visible
+// to the transaction itself until it commits.
+type Tx interface {
+	Reader
+
+	Create(model Model, id uuid.UUID, value interface{}) error
+	Update(model Model, id uuid.UUID, value interface{}) error
+	// UpdateIfVersion is Update which fails with ErrVersionMismatch
+	// when the record is not at version anymore
+	UpdateIfVersion(model Model, id uuid.UUID, version uint64, value interface{}) error
+	// Delete removes a record for good
+	Delete(model Model, id uuid.UUID) error
+
+	// Trash soft deletes a record, which is only visible to ListTrash and FindTrash until it is
+	// restored to its position in the create order or purged
+	Trash(model Model, id uuid.UUID) error
+	// TrashAt is Trash as of at, to put back a record trashed elsewhere like in a backup
+	TrashAt(model Model, id uuid.UUID, at time.Time) error
+	Restore(model Model, id uuid.UUID) error
+	Purge(model Model, id uuid.UUID) error
+	// ListTrash by create order
+	ListTrash(model Model) ([]Trashed, error)
+	// FindTrash is FindIDs over the trashed records, by key then create order
+	FindTrash(model Model, name string, query IndexQuery) ([]Trashed, error)
+}
+
+// RunInTx holds the database exclusively while fn runs, so the transaction is serializable.
+// Writes are buffered and applied all together on commit.
+func (db *databaseManager) RunInTx(fn func(tx Tx) error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	tx := &transaction{
+		db:     db,
+		models: map[Model]*txModel{},
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return db.commit(tx.ops)
+}
+
+// commit persists and applies ops, the caller must hold txMu exclusively
+func (db *databaseManager) commit(ops []operation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	// nothing else holds a model lock while txMu is held exclusively,
+	// so they can be taken after the feed lock here
+	db.feed.mu.Lock()
+	defer db.feed.mu.Unlock()
+
+	if err := db.writeJournal(ops...); err != nil {
+		return err
+	}
+
+	db.feed.beginLocked(len(ops))
+	for _, op := range ops {
+		db.apply(op)
+	}
+
+	return nil
+}
+
+// apply writes op in memory without any check, the caller holds the lock of the feed
+func (db *databaseManager) apply(op operation) {
+	modelDB := db.getModelDB(op.Model)
+	modelDB.mu.Lock()
+	defer modelDB.mu.Unlock()
+
+	db.applyLocked(modelDB, op)
+}
+
+type transaction struct {
+	db *databaseManager
+
+	models map[Model]*txModel
+	ops    []operation
+}
+
+// txModel is the overlay of the writes of a transaction on top of a model
+type txModel struct {
+	base *modelDatabase
+
+	// records written in the transaction which currently exist
+	values map[uuid.UUID]record
+	// deleted at least once in the transaction, so the base order doesn't apply anymore
+	removed map[uuid.UUID]bool
+	// created or restored in the transaction, they may come before base records
+	appended []uuid.UUID
+	// positions are given the same way the base does on commit
+	lastPosition uint64
+
+	// records trashed in the transaction, and the base trash which was restored or purged
+	trashed   map[uuid.UUID]record
+	untrashed map[uuid.UUID]bool
+
+	// usage is the usage of the base with the writes of the transaction
+	usage Usage
+}
+
+func (tx *transaction) getModel(model Model) *txModel {
+	m, ok := tx.models[model]
+	if !ok {
+		base := tx.db.getModelDB(model)
+		m = &txModel{
+			base:         base,
+			values:       map[uuid.UUID]record{},
+			removed:      map[uuid.UUID]bool{},
+			lastPosition: base.lastPosition,
+			trashed:      map[uuid.UUID]record{},
+			untrashed:    map[uuid.UUID]bool{},
+			usage:        base.usage,
+		}
+		tx.models[model] = m
+	}
+
+	return m
+}
+
+// the transaction holds the database exclusively, so the base can be read without its lock
+func (m *txModel) get(id uuid.UUID) (record, bool) {
+	if item, ok := m.values[id]; ok {
+		return item, true
+	}
+	if m.removed[id] {
+		return record{}, false
+	}
+
+	item, ok := m.base.dataMap[id]
+	return item, ok
+}
+
+func (m *txModel) getTrashed(id uuid.UUID) (record, bool) {
+	if item, ok := m.trashed[id]; ok {
+		return item, true
+	}
+	if m.untrashed[id] {
+		return record{}, false
+	}
+
+	item, ok := m.base.trashed[id]
+	return item, ok
+}
+
+// account adds n times item to the usage of the transaction
+func (m *txModel) account(item record, n int) {
+	m.usage.Records += n
+	m.usage.Bytes += int64(n) * item.size
+}
+
+// remove takes a record out of the live records of the transaction
+func (m *txModel) remove(id uuid.UUID) {
+	delete(m.values, id)
+	m.removed[id] = true
+	for i, item := range m.appended {
+		if item == id {
+			m.appended = append(m.appended[:i], m.appended[i+1:]...)
+			break
+		}
+	}
+}
+
+func (tx *transaction) Get(model Model, id uuid.UUID) (interface{}, error) {
+	item, ok := tx.getModel(model).get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return item.read(), nil
+}
+
+// records returns every record by position, created records come after the base ones
+func (m *txModel) records() []record {
+	records := make([]record, 0, m.base.orders.Len()+len(m.appended))
+	for node := m.base.orders.First(); node != nil; node = node.Next() {
+		if m.removed[node.value] {
+			continue
+		}
+		item, _ := m.get(node.value)
+		records = append(records, item)
+	}
+	for _, id := range m.appended {
+		records = append(records, m.values[id])
+	}
+
+	// restored records go back between the base ones
+	sort.Slice(records, func(i, j int) bool { return records[i].position < records[j].position })
+	return records
+}
+
+func (tx *transaction) List(model Model) ([]interface{}, error) {
+	records := tx.getModel(model).records()
+
+	list := make([]interface{}, 0, len(records))
+	for _, item := range records {
+		list = append(list, item.read())
+	}
+
+	return list, nil
+}
+
+func (tx *transaction) ListRange(model Model, opts ListOptions) (Page, error) {
+	return listRecords(tx.getModel(model).records(), opts)
+}
+
+func (tx *transaction) Create(model Model, id uuid.UUID, value interface{}) error {
+	if err := isPointer(value); err != nil {
+		return err
+	}
+
+	m := tx.getModel(model)
+	if _, ok := m.get(id); ok {
+		return ErrAlreadyExists
+	}
+	if _, ok := m.getTrashed(id); ok {
+		return ErrAlreadyExists
+	}
+
+	setVersion(value, 1)
+	value = clone(value)
+	size := sizeOf(value)
+	if err := m.base.quota.allow(model, m.usage, 1, size); err != nil {
+		return err
+	}
+
+	m.lastPosition++
+	item := record{value: value, version: 1, position: m.lastPosition, size: size}
+	m.account(item, 1)
+	m.values[id] = item
+	m.appended = append(m.appended, id)
+	tx.ops = append(tx.ops, operation{Kind: opCreate, Model: model, ID: id, Value: value, size: size})
+	return nil
+}
+
+func (tx *transaction) Update(model Model, id uuid.UUID, value interface{}) error {
+	return tx.update(model, id, nil, value)
+}
+
+func (tx *transaction) UpdateIfVersion(model Model, id uuid.UUID, version uint64, value interface{}) error {
+	return tx.update(model, id, &version, value)
+}
+
+func (tx *transaction) update(model Model, id uuid.UUID, version *uint64, value interface{}) error {
+	if err := isPointer(value); err != nil {
+		return err
+	}
+
+	m := tx.getModel(model)
+	current, ok := m.get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	if version != nil && current.version != *version {
+		return ErrVersionMismatch
+	}
+
+	setVersion(value, current.version+1)
+	value = clone(value)
+	size := sizeOf(value)
+	if err := m.base.quota.allow(model, m.usage, 0, size-current.size); err != nil {
+		return err
+	}
+
+	item := record{value: value, version: current.version + 1, position: current.position, size: size}
+	m.account(current, -1)
+	m.account(item, 1)
+	m.values[id] = item
+	tx.ops = append(tx.ops, operation{Kind: opUpdate, Model: model, ID: id, Value: value, size: size})
+	return nil
+}
+
+func (tx *transaction) Delete(model Model, id uuid.UUID) error {
+	m := tx.getModel(model)
+	item, ok := m.get(id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	m.remove(id)
+	m.account(item, -1)
+	tx.ops = append(tx.ops, operation{Kind: opDelete, Model: model, ID: id})
+	return nil
+}
+
+func (tx *transaction) Trash(model Model, id uuid.UUID) error {
+	return tx.TrashAt(model, id, time.Now().UTC())
+}
+
+func (tx *transaction) TrashAt(model Model, id uuid.UUID, at time.Time) error {
+	m := tx.getModel(model)
+	item, ok := m.get(id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	m.remove(id)
+	item.deletedAt = at
+	m.trashed[id] = item
+	tx.ops = append(tx.ops, operation{Kind: opTrash, Model: model, ID: id, At: item.deletedAt})
+	return nil
+}
+
+func (tx *transaction) Restore(model Model, id uuid.UUID) error {
+	m := tx.getModel(model)
+	item, ok := m.getTrashed(id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(m.trashed, id)
+	m.untrashed[id] = true
+	item.deletedAt = time.Time{}
+	m.values[id] = item
+	m.appended = append(m.appended, id)
+	tx.ops = append(tx.ops, operation{Kind: opRestore, Model: model, ID: id})
+	return nil
+}
+
+func (tx *transaction) Purge(model Model, id uuid.UUID) error {
+	m := tx.getModel(model)
+	item, ok := m.getTrashed(id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(m.trashed, id)
+	m.untrashed[id] = true
+	m.account(item, -1)
+	tx.ops = append(tx.ops, operation{Kind: opPurge, Model: model, ID: id})
+	return nil
+}
+
+func (tx *transaction) ListTrash(model Model) ([]Trashed, error) {
+	m := tx.getModel(model)
+
+	records := map[uuid.UUID]record{}
+	for id, item := range m.base.trashed {
+		if !m.untrashed[id] {
+			records[id] = item
+		}
+	}
+	for id, item := range m.trashed {
+		records[id] = item
+	}
+
+	list := make([]Trashed, 0, len(records))
+	for id, item := range records {
+		list = append(list, item.readTrashed(id))
+	}
+	sortTrashed(records, list)
+
+	return list, nil
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInTx(t *testing.T) {
+	t.Run("commit", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		existing := uuid.New()
+		require.NoError(t, db.Create(Task, existing, randomTestValue()))
+
+		created := uuid.New()
+		createdValue := randomTestValue()
+		updatedValue := randomTestValue()
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			if err := tx.Create(Task, created, createdValue); err != nil {
+				return err
+			}
+			if err := tx.Update(Task, existing, updatedValue); err != nil {
+				return err
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{updatedValue, createdValue}, list)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		existing := uuid.New()
+		value := randomTestValue()
+		require.NoError(t, db.Create(Task, existing, value))
+
+		// assert
+		expectedErr := gofakeit.Error()
+		err := db.RunInTx(func(tx Tx) error {
+			if err := tx.Create(Task, uuid.New(), randomTestValue()); err != nil {
+				return err
+			}
+			if err := tx.Delete(Task, existing); err != nil {
+				return err
+			}
+			return expectedErr
+		})
+		require.ErrorIs(t, err, expectedErr)
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{value}, list)
+	})
+
+	t.Run("read your writes", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		first := uuid.New()
+		firstValue := randomTestValue()
+		require.NoError(t, db.Create(Task, first, firstValue))
+		second := uuid.New()
+		require.NoError(t, db.Create(Task, second, randomTestValue()))
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			created := uuid.New()
+			createdValue := randomTestValue()
+			require.NoError(t, tx.Create(Task, created, createdValue))
+
+			v, err := tx.Get(Task, created)
+			require.NoError(t, err)
+			require.Equal(t, createdValue, v)
+
+			require.NoError(t, tx.Delete(Task, second))
+			_, err = tx.Get(Task, second)
+			require.ErrorIs(t, err, ErrNotFound)
+
+			list, err := tx.List(Task)
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{firstValue, createdValue}, list)
+
+			// created again goes to the end of the order
+			recreatedValue := randomTestValue()
+			require.NoError(t, tx.Create(Task, second, recreatedValue))
+			list, err = tx.List(Task)
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{firstValue, createdValue, recreatedValue}, list)
+
+			require.NoError(t, tx.Delete(Task, created))
+			list, err = tx.List(Task)
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{firstValue, recreatedValue}, list)
+			return nil
+		})
+		require.NoError(t, err)
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		existing := uuid.New()
+		require.NoError(t, db.Create(Task, existing, randomTestValue()))
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			require.ErrorIs(t, tx.Create(Task, existing, randomTestValue()), ErrAlreadyExists)
+			require.ErrorIs(t, tx.Create(Task, uuid.New(), *randomTestValue()), ErrOnlyPointer)
+			require.ErrorIs(t, tx.Update(Task, uuid.New(), randomTestValue()), ErrNotFound)
+			require.ErrorIs(t, tx.Delete(Task, uuid.New()), ErrNotFound)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("read modify write is atomic", func(t *testing.T) {
+		db := New()
+
+		// prepare
+		id := uuid.New()
+		require.NoError(t, db.Create(Task, id, &testValue{}))
+
+		workers := 50
+		errs := make(chan error, workers)
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- db.RunInTx(func(tx Tx) error {
+					v, err := tx.Get(Task, id)
+					if err != nil {
+						return err
+					}
+					next := *v.(*testValue)
+					next.Count++
+					return tx.Update(Task, id, &next)
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// assert
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		v, err := db.Get(Task, id)
+		require.NoError(t, err)
+		require.Equal(t, workers, v.(*testValue).Count)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		config := testFileConfig(t)
+		db := openTestFileDB(t, config)
+
+		// prepare
+		first := uuid.New()
+		second := uuid.New()
+		err := db.RunInTx(func(tx Tx) error {
+			if err := tx.Create(Task, first, randomTestValue()); err != nil {
+				return err
+			}
+			if err := tx.Create(Task, second, randomTestValue()); err != nil {
+				return err
+			}
+			return tx.Delete(Task, first)
+		})
+		require.NoError(t, err)
+
+		expected, err := db.List(Task)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		// assert
+		segment := lastSegment(t, config.Dir)
+		records := 0
+		_, err = readSegment(segment, true, &keyring{}, func(record logRecord) error {
+			records++
+			require.Len(t, record.Ops, 3)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, records)
+
+		db = openTestFileDB(t, config)
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Equal(t, expected, list)
+	})
+
+	t.Run("journal failure", func(t *testing.T) {
+		db := openTestFileDB(t, testFileConfig(t))
+		require.NoError(t, db.Close())
+
+		// assert
+		err := db.RunInTx(func(tx Tx) error {
+			return tx.Create(Task, uuid.New(), randomTestValue())
+		})
+		require.ErrorIs(t, err, ErrClosed)
+
+		list, err := db.List(Task)
+		require.NoError(t, err)
+		require.Len(t, list, 0)
+	})
+}
@@ -0,0 +1,460 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrClosed       = errors.New("database closed")
+	ErrCorruptedLog = errors.New("corrupted log")
+	// ErrLogFailed is returned by every write after the log couldn't tell what reached the disk
+	ErrLogFailed    = errors.New("log failed")
+	ErrUnknownModel = errors.New("unknown model")
+)
+
+type opKind string
+
+const (
+	opCreate  opKind = "create"
+	opUpdate  opKind = "update"
+	opDelete  opKind = "delete"
+	opTrash   opKind = "trash"
+	opRestore opKind = "restore"
+	opPurge   opKind = "purge"
+)
+
+type operation struct {
+	Kind  opKind
+	Model Model
+	ID    uuid.UUID
+	Value interface{}
+	// At is the time of a trash
+	At time.Time
+
+	// size of Value in Usage, it is not persisted
+	size int64
+}
+
+// Schema tells the persistent database how to decode the values of each model
+type Schema map[Model]func() interface{}
+
+func (s Schema) decode(model Model, data []byte) (interface{}, error) {
+	newValue, ok := s[model]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownModel, model)
+	}
+
+	value := newValue()
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// logRecord holds every operation of one write, so a transaction is replayed all or nothing
+type logRecord struct {
+	Seq uint64  `json:"seq"`
+	Ops []logOp `json:"ops"`
+}
+
+type logOp struct {
+	Kind  opKind          `json:"op"`
+	Model Model           `json:"model"`
+	ID    uuid.UUID       `json:"id"`
+	Value json.RawMessage `json:"value,omitempty"`
+	At    *time.Time      `json:"at,omitempty"`
+}
+
+// every frame is | length uint32 | crc32 uint32 | json payload |
+const (
+	frameHeaderSize = 8
+	maxFrameSize    = 64 << 20
+)
+
+func encodeFrame(v interface{}) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFrame(payload), nil
+}
+
+// newFrame prefixes payload with its size and checksum
+func newFrame(payload []byte) []byte {
+	buf := make([]byte, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[frameHeaderSize:], payload)
+	return buf
+}
+
+// readFrames calls fn for every frame in r and returns the size of the valid prefix.
+// io.ErrUnexpectedEOF is returned along with the size when the last frame is incomplete,
+// while a checksum mismatch means the data is corrupted.
+func readFrames(r io.Reader, fn func(payload []byte) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, frameHeaderSize)
+	var offset int64
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return offset, nil
+			}
+			return offset, err
+		}
+
+		size := binary.LittleEndian.Uint32(header[0:4])
+		checksum := binary.LittleEndian.Uint32(header[4:8])
+		if size > maxFrameSize {
+			return offset, fmt.Errorf("%w: frame size %d at offset %d", ErrCorruptedLog, size, offset)
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if errors.Is(err, io.EOF) {
+				return offset, io.ErrUnexpectedEOF
+			}
+			return offset, err
+		}
+
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return offset, fmt.Errorf("%w: checksum mismatch at offset %d", ErrCorruptedLog, offset)
+		}
+
+		if err := fn(payload); err != nil {
+			return offset, err
+		}
+
+		offset += int64(frameHeaderSize) + int64(size)
+	}
+}
+
+const (
+	segmentPrefix = "wal-"
+	segmentSuffix = ".log"
+)
+
+// segment is a log file holding the records from firstSeq onwards
+type segment struct {
+	firstSeq uint64
+	path     string
+}
+
+func segmentPath(dir string, firstSeq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", segmentPrefix, firstSeq, segmentSuffix))
+}
+
+// listSegments returns the segments in dir ordered by their first sequence
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []segment{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		firstSeq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		segments = append(segments, segment{firstSeq: firstSeq, path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].firstSeq < segments[j].firstSeq
+	})
+	return segments, nil
+}
+
+// readSegment calls fn for every record in the segment and returns the size of the valid prefix.
+// An incomplete record is tolerated only when tail is set, as it is the torn write from a crash.
+func readSegment(seg segment, tail bool, keys *keyring, fn func(logRecord) error) (int64, error) {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	size, err := readFrames(file, func(payload []byte) error {
+		payload, err := keys.open(payload)
+		if err != nil {
+			return err
+		}
+
+		var record logRecord
+		if err := json.Unmarshal(payload, &record); err != nil {
+			return fmt.Errorf("%w: %w", ErrCorruptedLog, err)
+		}
+		return fn(record)
+	})
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		if !tail {
+			return 0, fmt.Errorf("%w: incomplete record in %s", ErrCorruptedLog, seg.path)
+		}
+		return size, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// segmentFile is the part of *os.File the log writes segments through
+type segmentFile interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+type wal struct {
+	mu sync.Mutex
+
+	dir         string
+	policy      SyncPolicy
+	segmentSize int64
+	keys        *keyring
+
+	file segmentFile
+	// size of the current segment
+	size int64
+
+	seq    uint64
+	dirty  bool
+	closed bool
+	// failed is set once a write or a sync fails in a way the log can't recover from,
+	// the database has to be opened again to find out what was persisted
+	failed error
+}
+
+// openWAL appends to the last segment, or starts a new one after seq
+func openWAL(dir string, last *segment, seq uint64, config FileConfig, keys *keyring) (*wal, error) {
+	w := &wal{
+		dir:         dir,
+		policy:      config.SyncPolicy,
+		segmentSize: config.SegmentSize,
+		keys:        keys,
+		seq:         seq,
+	}
+
+	if last == nil {
+		if err := w.createSegment(); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	file, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return w, nil
+}
+
+func (w *wal) createSegment() error {
+	file, err := os.OpenFile(segmentPath(w.dir, w.seq+1), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err := syncDir(w.dir); err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = 0
+	return nil
+}
+
+// rotateLocked closes the current segment and starts a new one after the last sequence
+func (w *wal) rotateLocked() error {
+	if w.failed != nil {
+		return w.failed
+	}
+	if w.size == 0 {
+		return nil
+	}
+
+	// the closed segment must be durable before any record lands in the next one
+	w.dirty = true
+	if err := w.syncLocked(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	return w.createSegment()
+}
+
+func (w *wal) write(ops ...operation) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if w.failed != nil {
+		return w.failed
+	}
+
+	if w.segmentSize > 0 && w.size >= w.segmentSize {
+		if err := w.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	record := logRecord{
+		Seq: w.seq + 1,
+		Ops: make([]logOp, 0, len(ops)),
+	}
+	for _, op := range ops {
+		logOp := logOp{
+			Kind:  op.Kind,
+			Model: op.Model,
+			ID:    op.ID,
+		}
+		if op.Value != nil {
+			value, err := json.Marshal(op.Value)
+			if err != nil {
+				return err
+			}
+			logOp.Value = value
+		}
+		if !op.At.IsZero() {
+			logOp.At = &op.At
+		}
+		record.Ops = append(record.Ops, logOp)
+	}
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	payload, err = w.keys.seal(payload)
+	if err != nil {
+		return err
+	}
+	buf := newFrame(payload)
+
+	// a partial frame is cut off, as the next records would be appended after it
+	if _, err := w.file.Write(buf); err != nil {
+		w.truncateLocked()
+		return err
+	}
+	w.dirty = true
+
+	if w.policy == SyncAlways {
+		if err := w.syncLocked(); err != nil {
+			// the caller is told the write failed, so it shouldn't come back on replay
+			w.truncateLocked()
+			return err
+		}
+	}
+
+	w.size += int64(len(buf))
+	w.seq = record.Seq
+	return nil
+}
+
+// truncateLocked drops what was written after the last complete record, the log fails when it can't
+func (w *wal) truncateLocked() {
+	if err := w.file.Truncate(w.size); err != nil && w.failed == nil {
+		w.failed = fmt.Errorf("%w: %w", ErrLogFailed, err)
+	}
+}
+
+func (w *wal) sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if w.failed != nil {
+		return w.failed
+	}
+
+	return w.syncLocked()
+}
+
+func (w *wal) syncLocked() error {
+	if !w.dirty {
+		return nil
+	}
+
+	// the kernel may drop the pages which failed to be written, so a later sync
+	// succeeding wouldn't mean they are on disk
+	if err := w.file.Sync(); err != nil {
+		w.failed = fmt.Errorf("%w: %w", ErrLogFailed, err)
+		return w.failed
+	}
+	w.dirty = false
+	return nil
+}
+
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.failed != nil {
+		w.file.Close()
+		return w.failed
+	}
+	if err := w.syncLocked(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
+
+// syncDir makes file creations, renames and removals in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -5,6 +5,8 @@ import "time"
 type Config struct {
 	AddrPort        string        `koanf:"addr_port" validate:"required,tcp4_addr"`
 	ShutdownTimeout time.Duration `koanf:"shutdown_timeout" validate:"required"`
+	// AdminToken is the bearer token of the /admin routes, they aren't served without one
+	AdminToken string `koanf:"admin_token"`
 }
 
 func (Config) Default() Config {
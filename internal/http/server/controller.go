@@ -15,7 +15,7 @@ import (
 // Start will block until the server is shutdown
 // And will start graceful shutdown when the context is done
 func Start(ctx context.Context, config Config, ctl *controller.Controller, validator *validator.Validator) error {
-	server := NewServer(ctx, ctl, validator)
+	server := NewServer(ctx, config, ctl, validator)
 
 	// start server
 	go func() {
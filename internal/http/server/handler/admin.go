@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	httpserver "github.com/dragon-huang0403/todo-go/pkg/http/server"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"github.com/dragon-huang0403/todo-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// @Summary		Backup
+// @Description	Stream a versioned and checksummed archive of every record, the trashed ones included.
+// @Description	Writes go on meanwhile and the archive holds the records as of its start.
+// @Tags			Admin
+// @Produce		application/octet-stream
+// @Success		200	{file}		file	"OK"
+// @Failure		401	{object}	Failure	"Unauthorized"
+// @Security		AdminToken
+// @Router			/admin/backup [get]
+func (h *Handler) Backup() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		header := c.Response().Header()
+		header.Set(echo.HeaderContentType, echo.MIMEOctetStream)
+		header.Set(echo.HeaderContentDisposition,
+			fmt.Sprintf(`attachment; filename="todo-%s.backup"`, time.Now().UTC().Format("20060102T150405Z")))
+		c.Response().WriteHeader(http.StatusOK)
+
+		// the status is sent already, an archive cut short has no footer so a restore rejects it
+		if err := h.controller.Admin.Backup(ctx, c.Response()); err != nil {
+			logger.Debug(ctx, "failed to write backup", zap.Error(err))
+		}
+
+		return nil
+	}
+}
+
+// @Summary		Restore backup
+// @Description	Restore an archive of Backup. The whole archive is verified first, then every record is restored
+// @Description	at once at version 1. policy handles the ids already used, fail aborts the restore with 409.
+// @Tags			Admin
+// @Accept			application/octet-stream
+// @Produce		json
+// @Param			policy	query		string					false	"what to do with a record whose id is used, fail by default"	Enums(skip, overwrite, fail)
+// @Param			request	body		string					true	"archive of Backup"	Format(binary)
+// @Success		200		{object}	handler.RestoreBackup.response	"OK"
+// @Failure		400		{object}	Failure					"Bad Request"
+// @Failure		401		{object}	Failure					"Unauthorized"
+// @Failure		409		{object}	Failure					"Conflict"
+// @Failure		503		{object}	Failure					"Service Unavailable"
+// @Failure		507		{object}	Failure					"Insufficient Storage"
+// @Security		AdminToken
+// @Router			/admin/restore [post]
+func (h *Handler) RestoreBackup() echo.HandlerFunc {
+	type request struct {
+		Policy string `query:"policy" validate:"omitempty,oneof=skip overwrite fail"`
+	}
+	type response struct {
+		Data models.RestoreResult `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		// the body is the archive, so only the query is bound
+		req := &request{Policy: c.QueryParam("policy")}
+		if err := c.Validate(req); err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: validator.Explain(req, err).Error()})
+		}
+		policy := controller.ConflictPolicy(req.Policy)
+		if policy == "" {
+			policy = controller.ConflictFail
+		}
+
+		result, err := h.controller.Admin.Restore(ctx, c.Request().Body, policy)
+		if err != nil {
+			if errors.Is(err, controller.ErrInvalidBackup) {
+				return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+			}
+			if errors.Is(err, controller.ErrConflict) {
+				return c.JSON(http.StatusConflict, Failure{Message: err.Error()})
+			}
+			if errors.Is(err, controller.ErrQuotaExceeded) {
+				return c.JSON(http.StatusInsufficientStorage, Failure{Message: err.Error()})
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: *result})
+	}
+}
+
+// @Summary		Usage
+// @Description	What the records of every model take, trashed records included, along with their quota.
+// @Description	bytes is the size of their JSON encoding, 0 is no limit.
+// @Tags			Admin
+// @Produce		json
+// @Success		200	{object}	handler.Usage.response	"OK"
+// @Failure		401	{object}	Failure					"Unauthorized"
+// @Security		AdminToken
+// @Router			/admin/usage [get]
+func (h *Handler) Usage() echo.HandlerFunc {
+	type response struct {
+		Data []*models.Usage `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		return c.JSON(http.StatusOK, response{Data: h.controller.Admin.Usage(ctx)})
+	}
+}
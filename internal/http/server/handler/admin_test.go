@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBackup(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+
+		// stubs
+		m.mockAdminCtl.EXPECT().Backup(gomock.Any(), gomock.Any()).DoAndReturn(func(_ any, w io.Writer) error {
+			_, err := w.Write([]byte("archive"))
+			return err
+		})
+
+		// assert
+		err := m.handler.Backup()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+		require.Equal(t, "archive", rec.Body.String())
+	})
+}
+
+func TestRestoreBackup(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader("archive"))
+		c.Request().URL.RawQuery = "policy=skip"
+		result := &models.RestoreResult{Created: 2, Skipped: 1}
+
+		// stubs
+		m.mockAdminCtl.EXPECT().Restore(gomock.Any(), gomock.Any(), controller.ConflictSkip).
+			DoAndReturn(func(_ any, r io.Reader, _ controller.ConflictPolicy) (*models.RestoreResult, error) {
+				body, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.Equal(t, "archive", string(body))
+				return result, nil
+			})
+
+		// assert
+		err := m.handler.RestoreBackup()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"data":{"created":2,"overwritten":0,"skipped":1}}`, rec.Body.String())
+	})
+
+	t.Run("fails by default", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader("archive"))
+
+		// stubs
+		m.mockAdminCtl.EXPECT().Restore(gomock.Any(), gomock.Any(), controller.ConflictFail).
+			Return(nil, fmt.Errorf("%w: task", controller.ErrConflict))
+
+		// assert
+		err := m.handler.RestoreBackup()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader("archive"))
+		c.Request().URL.RawQuery = "policy=merge"
+
+		// assert
+		err := m.handler.RestoreBackup()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var failure Failure
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &failure))
+		require.Contains(t, failure.Message, "policy must be one of [skip overwrite fail]")
+	})
+
+	t.Run("invalid backup", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader("archive"))
+
+		// stubs
+		m.mockAdminCtl.EXPECT().Restore(gomock.Any(), gomock.Any(), controller.ConflictFail).
+			Return(nil, fmt.Errorf("%w: checksum mismatch", controller.ErrInvalidBackup))
+
+		// assert
+		err := m.handler.RestoreBackup()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var failure Failure
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &failure))
+		require.Equal(t, "invalid backup: checksum mismatch", failure.Message)
+	})
+}
+
+func TestUsage(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		usage := []*models.Usage{{Model: "task", Records: 2, Bytes: 100, MaxRecords: 10}}
+
+		// stubs
+		m.mockAdminCtl.EXPECT().Usage(gomock.Any()).Return(usage)
+
+		// assert
+		err := m.handler.Usage()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t,
+			`{"data":[{"model":"task","records":2,"bytes":100,"max_records":10,"max_bytes":0}]}`, rec.Body.String())
+	})
+}
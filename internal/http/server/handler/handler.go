@@ -1,6 +1,9 @@
 package handler
 
-import "github.com/dragon-huang0403/todo-go/internal/controller"
+import (
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+)
 
 type Handler struct {
 	controller *controller.Controller
@@ -19,3 +22,10 @@ type Failure struct {
 type Success struct {
 	Success bool `json:"success" validate:"required"`
 }
+
+// TransitionFailure is a move of a task the workflow doesn't allow
+type TransitionFailure struct {
+	Message string `json:"message" validate:"required"`
+	// the states the task can move to
+	Allowed []models.TaskState `json:"allowed" validate:"required"`
+}
@@ -1,6 +1,25 @@
 package handler
 
-import "github.com/labstack/echo/v4"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/dragon-huang0403/todo-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+	headerLastEventID = "Last-Event-ID"
+)
 
 func bindAndValidate[T any](c echo.Context) (*T, error) {
 	t := new(T)
@@ -13,3 +32,178 @@ func bindAndValidate[T any](c echo.Context) (*T, error) {
 
 	return t, nil
 }
+
+// bindQuery is bindAndValidate with errors a client can act on, naming the query parameters
+func bindQuery[T any](c echo.Context) (*T, error) {
+	t := new(T)
+	if err := c.Bind(t); err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			return nil, fmt.Errorf("%v", httpErr.Message)
+		}
+		return nil, err
+	}
+	if err := c.Validate(t); err != nil {
+		return nil, validator.Explain(t, err)
+	}
+
+	return t, nil
+}
+
+// etag is a strong entity tag of a record version
+func etag(version uint64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+func setETag(c echo.Context, version uint64) {
+	c.Response().Header().Set(headerETag, etag(version))
+}
+
+// entityTag is an entity tag of a list in a conditional header
+type entityTag struct {
+	weak bool
+	// opaque is the tag without its quotes
+	opaque string
+}
+
+// entityTags parses a comma separated list of entity tags (RFC 9110 §8.8.3),
+// empty elements are skipped and ok is false when the list is malformed
+func entityTags(header string) (tags []entityTag, ok bool) {
+	for rest := header; ; {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return tags, true
+		}
+		if rest[0] == ',' {
+			rest = rest[1:]
+			continue
+		}
+
+		tag := entityTag{}
+		if strings.HasPrefix(rest, "W/") {
+			tag.weak = true
+			rest = rest[2:]
+		}
+		// the opaque tag can hold commas, so it ends at the closing quote
+		if rest == "" || rest[0] != '"' {
+			return nil, false
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, false
+		}
+		tag.opaque = rest[1 : end+1]
+		tags = append(tags, tag)
+
+		rest = strings.TrimLeft(rest[end+2:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, false
+		}
+	}
+}
+
+// ifMatch returns the versions the If-Match header allows, none when there is no
+// header or it is "*". Entity tags are compared strongly (RFC 9110 §13.1.1), so weak
+// tags never match, and ok is false when no tag of the header can match a version.
+func ifMatch(c echo.Context) (versions []uint64, ok bool) {
+	header := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	tags, ok := entityTags(header)
+	if !ok {
+		return nil, false
+	}
+	for _, tag := range tags {
+		if tag.weak {
+			continue
+		}
+		if v, err := strconv.ParseUint(tag.opaque, 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, len(versions) > 0
+}
+
+// ifNoneMatch reports whether the If-None-Match header matches the version,
+// entity tags are compared weakly (RFC 9110 §13.1.2)
+func ifNoneMatch(c echo.Context, version uint64) bool {
+	header := strings.TrimSpace(c.Request().Header.Get(headerIfNoneMatch))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	tags, ok := entityTags(header)
+	if !ok {
+		return false
+	}
+	current := strconv.FormatUint(version, 10)
+	for _, tag := range tags {
+		if tag.opaque == current {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeVersions returns the versions a write may apply to, ok is false when it must answer 412.
+// The current version is only read when there is an If-None-Match header, the write is then pinned
+// to the version the header was evaluated against, so a concurrent write fails it with a conflict.
+func writeVersions(c echo.Context, current func() (uint64, error)) (versions []uint64, ok bool, err error) {
+	versions, ok = ifMatch(c)
+	if !ok || strings.TrimSpace(c.Request().Header.Get(headerIfNoneMatch)) == "" {
+		return versions, ok, nil
+	}
+
+	version, err := current()
+	if err != nil {
+		return nil, false, err
+	}
+	if ifNoneMatch(c, version) || (len(versions) > 0 && !slices.Contains(versions, version)) {
+		return nil, false, nil
+	}
+
+	return []uint64{version}, true, nil
+}
+
+// writeEvent writes a server-sent event and flushes it to the client
+func writeEvent(c echo.Context, id uint64, event string, data interface{}) error {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	w := c.Response()
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, buf); err != nil {
+		return err
+	}
+	w.Flush()
+
+	return nil
+}
+
+// transitionFailure lists the allowed states of the TransitionError in err, an empty list when there is none
+func transitionFailure(err error) TransitionFailure {
+	failure := TransitionFailure{Message: err.Error(), Allowed: []models.TaskState{}}
+
+	var transition *controller.TransitionError
+	if errors.As(err, &transition) && transition.Allowed != nil {
+		failure.Allowed = transition.Allowed
+	}
+	return failure
+}
+
+// valueOf is the zero value when v is nil
+func valueOf[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
@@ -20,7 +20,10 @@ import (
 type testMain struct {
 	handler *Handler
 
-	mockTaskCtl *mock_controller.MockTask
+	mockTaskCtl        *mock_controller.MockTask
+	mockProjectCtl     *mock_controller.MockProject
+	mockAdminCtl       *mock_controller.MockAdmin
+	mockReplicationCtl *mock_controller.MockReplication
 }
 
 func setup(t *testing.T) *testMain {
@@ -28,14 +31,23 @@ func setup(t *testing.T) *testMain {
 	t.Cleanup(ctl.Finish)
 
 	mockTaskCtl := mock_controller.NewMockTask(ctl)
+	mockProjectCtl := mock_controller.NewMockProject(ctl)
+	mockAdminCtl := mock_controller.NewMockAdmin(ctl)
+	mockReplicationCtl := mock_controller.NewMockReplication(ctl)
 
 	controller := &controller.Controller{
-		Task: mockTaskCtl,
+		Task:        mockTaskCtl,
+		Project:     mockProjectCtl,
+		Admin:       mockAdminCtl,
+		Replication: mockReplicationCtl,
 	}
 
 	return &testMain{
-		handler:     New(controller),
-		mockTaskCtl: mockTaskCtl,
+		handler:            New(controller),
+		mockTaskCtl:        mockTaskCtl,
+		mockProjectCtl:     mockProjectCtl,
+		mockAdminCtl:       mockAdminCtl,
+		mockReplicationCtl: mockReplicationCtl,
 	}
 }
 
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	httpserver "github.com/dragon-huang0403/todo-go/pkg/http/server"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// @Summary		List Projects
+// @Description	List every project in create order
+// @Tags			Project
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	handler.ListProjects.response	"OK"
+// @Router			/projects [get]
+func (h *Handler) ListProjects() echo.HandlerFunc {
+	type response struct {
+		Data []*models.Project `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		projects, err := h.controller.Project.List(ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: projects})
+	}
+}
+
+// @Summary		Get Project
+// @Description	Get Project, answers 304 when If-None-Match matches the ETag of the project
+// @Tags			Project
+// @Accept			json
+// @Produce		json
+// @Param			projectId		path		string						true	"project id"
+// @Param			If-None-Match	header		string						false	"ETag of a cached project"
+// @Success		200				{object}	handler.GetProject.response	"OK"
+// @Success		304				"Not Modified"
+// @Failure		400				{object}	Failure	"Bad Request"
+// @Failure		404				{object}	Failure	"Not Found"
+// @Router			/projects/{projectId} [get]
+func (h *Handler) GetProject() echo.HandlerFunc {
+	type response struct {
+		Data models.Project `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		projectId, err := uuid.Parse(c.Param("projectId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid project id"})
+		}
+
+		project, err := h.controller.Project.Get(ctx, projectId)
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		setETag(c, project.Version)
+		if ifNoneMatch(c, project.Version) {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: *project})
+	}
+}
+
+// @Summary		Create Project
+// @Description	Create Project, tasks are put in it with project_id
+// @Tags			Project
+// @Accept			json
+// @Produce		json
+// @Param			request	body		handler.CreateProject.request	true	"request body"
+// @Success		200		{object}	handler.CreateProject.response	"OK"
+// @Failure		400		{object}	Failure							"Bad Request"
+// @Failure		503		{object}	Failure							"Service Unavailable"
+// @Failure		507		{object}	Failure							"Insufficient Storage"
+// @Router			/projects [post]
+func (h *Handler) CreateProject() echo.HandlerFunc {
+	type request struct {
+		Name string `json:"name" validate:"required"`
+		// markdown text
+		Description string `json:"description" validate:"max=10000"`
+	}
+	type response struct {
+		Data models.Project `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		req, err := bindAndValidate[request](c)
+		if err != nil {
+			logger.Debug(ctx, "failed to bind and validate request", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+		}
+
+		project, err := h.controller.Project.Create(ctx, controller.CreateProjectParams{
+			Name:        req.Name,
+			Description: req.Description,
+		})
+		if err != nil {
+			if errors.Is(err, controller.ErrQuotaExceeded) {
+				return c.JSON(http.StatusInsufficientStorage, Failure{Message: err.Error()})
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		setETag(c, project.Version)
+		return c.JSON(http.StatusOK, response{Data: *project})
+	}
+}
+
+// @Summary		Update Project
+// @Description	Update Project, answers 412 when If-Match doesn't match the ETag of the project or If-None-Match does
+// @Tags			Project
+// @Accept			json
+// @Produce		json
+// @Param			projectId	path		string							true	"project id"
+// @Param			If-Match	header		string							false	"ETags the project being updated must have, weak ones never match"
+// @Param			If-None-Match	header		string							false	"ETags the project being updated must not have"
+// @Param			request		body		handler.UpdateProject.request	true	"request body"
+// @Success		200			{object}	handler.UpdateProject.response	"OK"
+// @Failure		400			{object}	Failure							"Bad Request"
+// @Failure		404			{object}	Failure							"Not Found"
+// @Failure		412			{object}	Failure							"Precondition Failed"
+// @Failure		503			{object}	Failure							"Service Unavailable"
+// @Failure		507			{object}	Failure							"Insufficient Storage"
+// @Router			/projects/{projectId} [put]
+func (h *Handler) UpdateProject() echo.HandlerFunc {
+	type request struct {
+		Name string `json:"name" validate:"required"`
+		// markdown text
+		Description string `json:"description" validate:"max=10000"`
+	}
+	type response struct {
+		Data models.Project `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		projectId, err := uuid.Parse(c.Param("projectId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid project id"})
+		}
+
+		req, err := bindAndValidate[request](c)
+		if err != nil {
+			logger.Debug(ctx, "failed to bind and validate request", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+		}
+
+		versions, ok, err := writeVersions(c, h.projectVersion(ctx, projectId))
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+		if !ok {
+			return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+		}
+
+		project, err := h.controller.Project.Update(ctx, controller.UpdateProjectParams{
+			ID:          projectId,
+			Name:        req.Name,
+			Description: req.Description,
+			Versions:    versions,
+		})
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			if errors.Is(err, controller.ErrConflict) {
+				return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+			}
+			if errors.Is(err, controller.ErrQuotaExceeded) {
+				return c.JSON(http.StatusInsufficientStorage, Failure{Message: err.Error()})
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		setETag(c, project.Version)
+		return c.JSON(http.StatusOK, response{Data: *project})
+	}
+}
+
+// @Summary		Delete Project
+// @Description	Move Project to the trash along with its tasks, answers 412 when If-Match doesn't match the ETag of the project or If-None-Match does
+// @Tags			Project
+// @Accept			json
+// @Produce		json
+// @Param			projectId	path		string	true	"project id"
+// @Param			If-Match	header		string	false	"ETags the project being deleted must have, weak ones never match"
+// @Param			If-None-Match	header		string	false	"ETags the project being deleted must not have"
+// @Success		200			{object}	Success	"OK"
+// @Failure		400			{object}	Failure	"Bad Request"
+// @Failure		404			{object}	Failure	"Not Found"
+// @Failure		412			{object}	Failure	"Precondition Failed"
+// @Failure		503			{object}	Failure	"Service Unavailable"
+// @Router			/projects/{projectId} [delete]
+func (h *Handler) DeleteProject() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		projectId, err := uuid.Parse(c.Param("projectId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid project id"})
+		}
+
+		versions, ok, err := writeVersions(c, h.projectVersion(ctx, projectId))
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+		if !ok {
+			return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+		}
+
+		err = h.controller.Project.Delete(ctx, controller.DeleteProjectParams{
+			ID:       projectId,
+			Versions: versions,
+		})
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			if errors.Is(err, controller.ErrConflict) {
+				return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, Success{Success: true})
+	}
+}
+
+// @Summary		List Project Tasks
+// @Description	List the tasks of Project, like List Tasks
+// @Tags			Project
+// @Accept			json
+// @Produce		json
+// @Param			projectId		path		string						true	"project id"
+// @Param			limit			query		int							false	"max number of tasks"	minimum(0)	maximum(1000)
+// @Param			cursor			query		string						false	"next_cursor of the previous page"
+// @Param			direction		query		string						false	"list order, backward reverses sort"	Enums(forward, backward)
+// @Param			status			query		int							false	"only tasks with this status"	Enums(0, 1)
+// @Param			state			query		string						false	"only tasks in this state"	Enums(todo, in_progress, in_review, blocked, done, cancelled)
+// @Param			name			query		string						false	"only tasks whose name contains it, case insensitive"
+// @Param			created_after	query		string						false	"only tasks created at or after"	Format(date-time)
+// @Param			created_before	query		string						false	"only tasks created before"	Format(date-time)
+// @Param			updated_after	query		string						false	"only tasks updated at or after"	Format(date-time)
+// @Param			updated_before	query		string						false	"only tasks updated before"	Format(date-time)
+// @Param			priority		query		int							false	"only tasks with this priority"	Enums(0, 1, 2, 3, 4)
+// @Param			start_after		query		string						false	"only tasks starting at or after"	Format(date-time)
+// @Param			start_before	query		string						false	"only tasks starting before"	Format(date-time)
+// @Param			due_after		query		string						false	"only tasks due at or after"	Format(date-time)
+// @Param			due_before		query		string						false	"only tasks due before"	Format(date-time)
+// @Param			sort			query		string						false	"comma separated fields, prefixed by - for descending order"	example(status,-updated_at)
+// @Param			fields			query		string						false	"comma separated fields to return"	example(id,name)
+// @Success		200				{object}	handler.ListTasks.response	"OK"
+// @Failure		400				{object}	Failure						"Bad Request"
+// @Failure		404				{object}	Failure						"Not Found"
+// @Router			/projects/{projectId}/tasks [get]
+func (h *Handler) ListProjectTasks() echo.HandlerFunc {
+	// ListTasks only keeps the tasks of the project in the path
+	return h.ListTasks()
+}
+
+// projectVersion reads the current version of a project for writeVersions
+func (h *Handler) projectVersion(ctx context.Context, id uuid.UUID) func() (uint64, error) {
+	return func() (uint64, error) {
+		project, err := h.controller.Project.Get(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return project.Version, nil
+	}
+}
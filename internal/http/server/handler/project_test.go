@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListProjects(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+
+		project := models.Project{}
+		require.NoError(t, gofakeit.Struct(&project))
+		data := []*models.Project{&project}
+
+		// stubs
+		m.mockProjectCtl.EXPECT().List(gomock.Any()).Return(data, nil)
+
+		// assert
+		err := m.handler.ListProjects()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		expectedData, err := json.Marshal(data)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, expectedData), rec.Body.String())
+	})
+}
+
+func TestGetProject(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		project := models.Project{}
+		require.NoError(t, gofakeit.Struct(&project))
+
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("projectId")
+		c.SetParamValues(project.ID.String())
+
+		// stubs
+		m.mockProjectCtl.EXPECT().Get(gomock.Any(), project.ID).Return(&project, nil)
+
+		// assert
+		err := m.handler.GetProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, etag(project.Version), rec.Header().Get(headerETag))
+
+		expectedData, err := json.Marshal(project)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, expectedData), rec.Body.String())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("projectId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockProjectCtl.EXPECT().Get(gomock.Any(), id).Return(nil, controller.ErrNotFound)
+
+		// assert
+		err := m.handler.GetProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestCreateProject(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		project := models.Project{}
+		require.NoError(t, gofakeit.Struct(&project))
+		payload := fmt.Sprintf(`{"name":%q,"description":%q}`, project.Name, project.Description)
+		c, rec := m.prepareContext(strings.NewReader(payload))
+
+		// stubs
+		m.mockProjectCtl.EXPECT().Create(gomock.Any(), controller.CreateProjectParams{
+			Name:        project.Name,
+			Description: project.Description,
+		}).Return(&project, nil)
+
+		// assert
+		err := m.handler.CreateProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		expectedData, err := json.Marshal(project)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, expectedData), rec.Body.String())
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			payload string
+		}{
+			{name: "empty name", payload: `{"description":"test"}`},
+			{name: "description too long", payload: fmt.Sprintf(`{"name":"test","description":%q}`, strings.Repeat("a", 10001))},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				c, rec := m.prepareContext(strings.NewReader(tc.payload))
+
+				// assert
+				err := m.handler.CreateProject()(c)
+				require.NoError(t, err)
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		}
+	})
+}
+
+func TestUpdateProject(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		project := models.Project{}
+		require.NoError(t, gofakeit.Struct(&project))
+		c, rec := m.prepareContext(strings.NewReader(fmt.Sprintf(`{"name":%q}`, project.Name)))
+		c.Request().Header.Set(headerIfMatch, `"3"`)
+		c.SetParamNames("projectId")
+		c.SetParamValues(project.ID.String())
+
+		// stubs
+		version := uint64(3)
+		m.mockProjectCtl.EXPECT().Update(gomock.Any(), controller.UpdateProjectParams{
+			ID:       project.ID,
+			Name:     project.Name,
+			Versions: []uint64{version},
+		}).Return(&project, nil)
+
+		// assert
+		err := m.handler.UpdateProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, etag(project.Version), rec.Header().Get(headerETag))
+	})
+
+	t.Run("precondition failed", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(strings.NewReader(`{"name":"test"}`))
+		c.Request().Header.Set(headerIfMatch, `"1"`)
+		c.SetParamNames("projectId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockProjectCtl.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, controller.ErrConflict)
+
+		// assert
+		err := m.handler.UpdateProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	})
+
+	t.Run("if none match", func(t *testing.T) {
+		for _, tc := range ifNoneMatchCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				project := models.Project{ID: uuid.New(), Name: "test", Version: 3}
+				c, rec := m.prepareContext(strings.NewReader(`{"name":"test"}`))
+				c.Request().Header.Set(headerIfMatch, tc.ifMatch)
+				c.Request().Header.Set(headerIfNoneMatch, tc.ifNoneMatch)
+				c.SetParamNames("projectId")
+				c.SetParamValues(project.ID.String())
+
+				// stubs
+				m.mockProjectCtl.EXPECT().Get(gomock.Any(), project.ID).Return(&project, nil)
+				if tc.versions != nil {
+					m.mockProjectCtl.EXPECT().Update(gomock.Any(), controller.UpdateProjectParams{
+						ID:       project.ID,
+						Name:     project.Name,
+						Versions: tc.versions,
+					}).Return(&project, nil)
+				}
+
+				// assert
+				err := m.handler.UpdateProject()(c)
+				require.NoError(t, err)
+				if tc.versions == nil {
+					require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+					return
+				}
+				require.Equal(t, http.StatusOK, rec.Code)
+			})
+		}
+	})
+}
+
+func TestDeleteProject(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("projectId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockProjectCtl.EXPECT().Delete(gomock.Any(), controller.DeleteProjectParams{ID: id}).Return(nil)
+
+		// assert
+		err := m.handler.DeleteProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"success":true}`, rec.Body.String())
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("projectId")
+		c.SetParamValues("invalid")
+
+		// assert
+		err := m.handler.DeleteProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("if none match", func(t *testing.T) {
+		for _, tc := range ifNoneMatchCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				project := models.Project{ID: uuid.New(), Version: 3}
+				c, rec := m.prepareContext(nil)
+				c.Request().Header.Set(headerIfMatch, tc.ifMatch)
+				c.Request().Header.Set(headerIfNoneMatch, tc.ifNoneMatch)
+				c.SetParamNames("projectId")
+				c.SetParamValues(project.ID.String())
+
+				// stubs
+				m.mockProjectCtl.EXPECT().Get(gomock.Any(), project.ID).Return(&project, nil)
+				if tc.versions != nil {
+					m.mockProjectCtl.EXPECT().Delete(gomock.Any(), controller.DeleteProjectParams{ID: project.ID, Versions: tc.versions}).Return(nil)
+				}
+
+				// assert
+				err := m.handler.DeleteProject()(c)
+				require.NoError(t, err)
+				if tc.versions == nil {
+					require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+					return
+				}
+				require.Equal(t, http.StatusOK, rec.Code)
+			})
+		}
+	})
+}
+
+func TestListProjectTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		projectID := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.Request().URL.RawQuery = "status=1"
+		c.SetParamNames("projectId")
+		c.SetParamValues(projectID.String())
+
+		status := models.TaskStatusCompleted
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+		task.ProjectID = &projectID
+
+		// stubs
+		m.mockTaskCtl.EXPECT().
+			List(gomock.Any(), controller.ListTasksParams{
+				Filter: controller.TaskFilter{Status: &status, ProjectID: &projectID},
+			}).
+			Return(&models.TaskPage{Tasks: []*models.Task{&task}}, nil)
+
+		// assert
+		err := m.handler.ListProjectTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		expectedData, err := json.Marshal(task)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":[%s]}`, expectedData), rec.Body.String())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.SetParamNames("projectId")
+		c.SetParamValues(uuid.NewString())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, controller.ErrProjectNotFound)
+
+		// assert
+		err := m.handler.ListProjectTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.JSONEq(t, `{"message":"project not found"}`, rec.Body.String())
+	})
+
+	t.Run("invalid project id", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.SetParamNames("projectId")
+		c.SetParamValues("invalid")
+
+		// assert
+		err := m.handler.ListProjectTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
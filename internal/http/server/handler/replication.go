@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	httpserver "github.com/dragon-huang0403/todo-go/pkg/http/server"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ReadOnly refuses the writes of a follower, they go to its primary
+func (h *Handler) ReadOnly() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if h.controller.Replication.ReadOnly() {
+				return c.JSON(http.StatusServiceUnavailable, Failure{Message: "read-only follower, write to the primary"})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// @Summary		Replicate
+// @Description	Stream the ordered changes of every model to a follower, along with a heartbeat every second.
+// @Description	The stream starts with a copy of every record, trash included, unless it resumes after a change which is still kept.
+// @Tags			Admin
+// @Produce		application/octet-stream
+// @Param			after	query		int		false	"seq of the last change the follower applied"
+// @Success		200		{file}		file	"OK"
+// @Failure		400		{object}	Failure	"Bad Request"
+// @Failure		401		{object}	Failure	"Unauthorized"
+// @Security		AdminToken
+// @Router			/admin/replication [get]
+func (h *Handler) Replicate() echo.HandlerFunc {
+	type request struct {
+		After *uint64 `query:"after"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		req, err := bindQuery[request](c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+		}
+
+		header := c.Response().Header()
+		header.Set(echo.HeaderContentType, echo.MIMEOctetStream)
+		header.Set(echo.HeaderCacheControl, "no-cache")
+		c.Response().WriteHeader(http.StatusOK)
+		c.Response().Flush()
+
+		// the stream ends when the follower goes away, which then reconnects
+		if err := h.controller.Replication.Stream(c.Request().Context(), req.After, c.Response()); err != nil {
+			logger.Debug(ctx, "failed to write replication", zap.Error(err))
+		}
+
+		return nil
+	}
+}
+
+// @Summary		Replication status
+// @Description	The role of the server, the seq of its last change and, on a follower, how far behind its primary it is.
+// @Tags			Admin
+// @Produce		json
+// @Success		200	{object}	handler.ReplicationStatus.response	"OK"
+// @Failure		401	{object}	Failure								"Unauthorized"
+// @Security		AdminToken
+// @Router			/admin/replication/status [get]
+func (h *Handler) ReplicationStatus() echo.HandlerFunc {
+	type response struct {
+		Data models.ReplicationStatus `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		status, err := h.controller.Replication.Status(ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: *status})
+	}
+}
+
+// @Summary		Promote
+// @Description	Make a follower stop following its primary and take writes. The changes it didn't get are lost,
+// @Description	so the former primary must not take writes anymore.
+// @Tags			Admin
+// @Produce		json
+// @Success		200	{object}	handler.Promote.response	"OK"
+// @Failure		401	{object}	Failure						"Unauthorized"
+// @Failure		409	{object}	Failure						"Conflict"
+// @Security		AdminToken
+// @Router			/admin/promote [post]
+func (h *Handler) Promote() echo.HandlerFunc {
+	type response struct {
+		Data models.ReplicationStatus `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		status, err := h.controller.Replication.Promote(ctx)
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFollower) {
+				return c.JSON(http.StatusConflict, Failure{Message: "the server is the primary already"})
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: *status})
+	}
+}
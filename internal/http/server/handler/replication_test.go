@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReadOnly(t *testing.T) {
+	t.Run("follower", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		next := func(echo.Context) error {
+			t.Fatal("a follower must not take writes")
+			return nil
+		}
+
+		// stubs
+		m.mockReplicationCtl.EXPECT().ReadOnly().Return(true)
+
+		// assert
+		err := m.handler.ReadOnly()(next)(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("primary", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		next := func(c echo.Context) error {
+			return c.NoContent(http.StatusCreated)
+		}
+
+		// stubs
+		m.mockReplicationCtl.EXPECT().ReadOnly().Return(false)
+
+		// assert
+		err := m.handler.ReadOnly()(next)(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	})
+}
+
+func TestReplicate(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.Request().URL.RawQuery = "after=3"
+
+		// stubs
+		m.mockReplicationCtl.EXPECT().Stream(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, after *uint64, w io.Writer) error {
+				require.EqualValues(t, 3, *after)
+				_, err := w.Write([]byte("changes"))
+				return err
+			})
+
+		// assert
+		err := m.handler.Replicate()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+		require.Equal(t, "changes", rec.Body.String())
+	})
+
+	t.Run("invalid after", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.Request().URL.RawQuery = "after=x"
+
+		// assert
+		err := m.handler.Replicate()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestReplicationStatus(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		status := &models.ReplicationStatus{
+			Role:       models.ReplicationFollower,
+			Seq:        3,
+			Primary:    "http://127.0.0.1:8080",
+			Connected:  true,
+			PrimarySeq: 5,
+			Lag:        2,
+		}
+
+		// stubs
+		m.mockReplicationCtl.EXPECT().Status(gomock.Any()).Return(status, nil)
+
+		// assert
+		err := m.handler.ReplicationStatus()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"data":{"role":"follower","seq":3,"primary":"http://127.0.0.1:8080","connected":true,"primary_seq":5,"lag":2}}`, rec.Body.String())
+	})
+}
+
+func TestPromote(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+
+		// stubs
+		m.mockReplicationCtl.EXPECT().Promote(gomock.Any()).
+			Return(&models.ReplicationStatus{Role: models.ReplicationPrimary, Seq: 3}, nil)
+
+		// assert
+		err := m.handler.Promote()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"data":{"role":"primary","seq":3,"connected":false,"lag":0}}`, rec.Body.String())
+	})
+
+	t.Run("primary", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+
+		// stubs
+		m.mockReplicationCtl.EXPECT().Promote(gomock.Any()).Return(nil, controller.ErrNotFollower)
+
+		// assert
+		err := m.handler.Promote()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	httpserver "github.com/dragon-huang0403/todo-go/pkg/http/server"
+	"github.com/dragon-huang0403/todo-go/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// @Summary		Search Tasks
+// @Description	Search Tasks by name, every word of q has to start a word of the name, case insensitive.
+// @Description	Whole words rank before prefixes, then the most recently updated tasks come first.
+// @Tags			Task
+// @Accept			json
+// @Produce		json
+// @Param			q		query		string						true	"words to search"
+// @Param			limit	query		int							false	"max number of tasks"	minimum(0)	maximum(1000)
+// @Success		200		{object}	handler.SearchTasks.response	"OK"
+// @Failure		400		{object}	Failure						"Bad Request"
+// @Router			/tasks/search [get]
+func (h *Handler) SearchTasks() echo.HandlerFunc {
+	type request struct {
+		Query string `query:"q" validate:"required"`
+		Limit int    `query:"limit" validate:"gte=0,lte=1000"`
+	}
+	type response struct {
+		Data []*models.TaskMatch `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		req, err := bindQuery[request](c)
+		if err != nil {
+			logger.Debug(ctx, "failed to bind and validate request", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+		}
+
+		matches, err := h.controller.Task.Search(ctx, controller.SearchTasksParams{
+			Query: req.Query,
+			Limit: req.Limit,
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: matches})
+	}
+}
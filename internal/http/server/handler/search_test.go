@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSearchTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.Request().URL.RawQuery = "q=buy+mil&limit=5"
+
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+		data := []*models.TaskMatch{{
+			Task:       &task,
+			Score:      2,
+			Highlights: []models.Highlight{{Start: 0, End: 3}},
+		}}
+
+		// stubs
+		m.mockTaskCtl.EXPECT().
+			Search(gomock.Any(), controller.SearchTasksParams{Query: "buy mil", Limit: 5}).
+			Return(data, nil)
+
+		// assert
+		err := m.handler.SearchTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		expectedData, err := json.Marshal(data)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, string(expectedData)), rec.Body.String())
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		for _, query := range []string{"", "q=", "q=milk&limit=-1", "q=milk&limit=1001"} {
+			m := setup(t)
+			// prepare
+			c, rec := m.prepareContext(nil)
+			c.Request().Method = http.MethodGet
+			c.Request().URL.RawQuery = query
+
+			// assert
+			err := m.handler.SearchTasks()(c)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.Request().URL.RawQuery = "q=milk"
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Search(gomock.Any(), gomock.Any()).Return(nil, gofakeit.Error())
+
+		// assert
+		err := m.handler.SearchTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
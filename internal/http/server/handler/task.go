@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/dragon-huang0403/todo-go/internal/controller"
 	"github.com/dragon-huang0403/todo-go/internal/models"
@@ -14,40 +17,198 @@ import (
 )
 
 // @Summary		List Tasks
-// @Description	List Tasks
+// @Description	List Tasks in create order, every task at once unless limit is set.
+// @Description	The filters are combined, sort orders by each field in turn and fields only returns some fields of the tasks.
 // @Tags			Task
 // @Accept			json
 // @Produce		json
-// @Success		200	{object}	handler.ListTasks.response	"OK"
+// @Param			limit			query		int							false	"max number of tasks"	minimum(0)	maximum(1000)
+// @Param			cursor			query		string						false	"next_cursor of the previous page"
+// @Param			direction		query		string						false	"list order, backward reverses sort"	Enums(forward, backward)
+// @Param			status			query		int							false	"only tasks with this status"	Enums(0, 1)
+// @Param			state			query		string						false	"only tasks in this state"	Enums(todo, in_progress, in_review, blocked, done, cancelled)
+// @Param			name			query		string						false	"only tasks whose name contains it, case insensitive"
+// @Param			created_after	query		string						false	"only tasks created at or after"	Format(date-time)
+// @Param			created_before	query		string						false	"only tasks created before"	Format(date-time)
+// @Param			updated_after	query		string						false	"only tasks updated at or after"	Format(date-time)
+// @Param			updated_before	query		string						false	"only tasks updated before"	Format(date-time)
+// @Param			priority		query		int							false	"only tasks with this priority"	Enums(0, 1, 2, 3, 4)
+// @Param			start_after		query		string						false	"only tasks starting at or after"	Format(date-time)
+// @Param			start_before	query		string						false	"only tasks starting before"	Format(date-time)
+// @Param			due_after		query		string						false	"only tasks due at or after"	Format(date-time)
+// @Param			due_before		query		string						false	"only tasks due before"	Format(date-time)
+// @Param			sort			query		string						false	"comma separated fields, prefixed by - for descending order"	example(status,-updated_at)
+// @Param			fields			query		string						false	"comma separated fields to return"	example(id,name)
+// @Success		200				{object}	handler.ListTasks.response	"OK"
+// @Failure		400				{object}	Failure						"Bad Request"
 // @Router			/tasks [get]
 func (h *Handler) ListTasks() echo.HandlerFunc {
+	type request struct {
+		Limit     int    `query:"limit" validate:"gte=0,lte=1000"`
+		Cursor    string `query:"cursor"`
+		Direction string `query:"direction" validate:"omitempty,oneof=forward backward"`
+
+		Status        *models.TaskStatus `query:"status" validate:"omitempty,oneof=0 1"`
+		State         *models.TaskState  `query:"state" validate:"omitempty,oneof=todo in_progress in_review blocked done cancelled"`
+		Name          string             `query:"name"`
+		CreatedAfter  *time.Time         `query:"created_after"`
+		CreatedBefore *time.Time         `query:"created_before" validate:"omitempty,afterfield=CreatedAfter"`
+		UpdatedAfter  *time.Time         `query:"updated_after"`
+		UpdatedBefore *time.Time         `query:"updated_before" validate:"omitempty,afterfield=UpdatedAfter"`
+
+		Priority    *models.TaskPriority `query:"priority" validate:"omitempty,oneof=0 1 2 3 4"`
+		StartAfter  *time.Time           `query:"start_after"`
+		StartBefore *time.Time           `query:"start_before" validate:"omitempty,afterfield=StartAfter"`
+		DueAfter    *time.Time           `query:"due_after"`
+		DueBefore   *time.Time           `query:"due_before" validate:"omitempty,afterfield=DueAfter"`
+
+		Sort   string `query:"sort" validate:"omitempty,sortby=id name status created_at updated_at version expires_at description priority start_at due_at state completed_at cancelled_at project_id"`
+		Fields string `query:"fields" validate:"omitempty,csvoneof=id name status created_at updated_at version expires_at description priority start_at due_at state completed_at cancelled_at project_id"`
+	}
 	type response struct {
+		// the tasks, with only the selected fields when fields is set
 		Data []*models.Task `json:"data" validate:"required"`
+		// empty on the last page
+		NextCursor string `json:"next_cursor,omitempty"`
+	}
+	type partialResponse struct {
+		Data       interface{} `json:"data"`
+		NextCursor string      `json:"next_cursor,omitempty"`
 	}
 	return func(c echo.Context) error {
 		ctx := httpserver.TransformContext(c)
-		task, err := h.controller.Task.List(ctx)
+
+		req, err := bindQuery[request](c)
+		if err != nil {
+			logger.Debug(ctx, "failed to bind and validate request", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+		}
+
+		// set when listing the tasks of a project, see ListProjectTasks
+		var projectId *uuid.UUID
+		if param := c.Param("projectId"); param != "" {
+			id, err := uuid.Parse(param)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, Failure{Message: "invalid project id"})
+			}
+			projectId = &id
+		}
+
+		page, err := h.controller.Task.List(ctx, controller.ListTasksParams{
+			Cursor:   req.Cursor,
+			Limit:    req.Limit,
+			Backward: req.Direction == "backward",
+			Filter: controller.TaskFilter{
+				Status:        req.Status,
+				State:         req.State,
+				Name:          req.Name,
+				CreatedAfter:  req.CreatedAfter,
+				CreatedBefore: req.CreatedBefore,
+				UpdatedAfter:  req.UpdatedAfter,
+				UpdatedBefore: req.UpdatedBefore,
+				Priority:      req.Priority,
+				StartAfter:    req.StartAfter,
+				StartBefore:   req.StartBefore,
+				DueAfter:      req.DueAfter,
+				DueBefore:     req.DueBefore,
+				ProjectID:     projectId,
+			},
+			Sort:   req.Sort,
+			Fields: req.Fields,
+		})
 		if err != nil {
+			if errors.Is(err, controller.ErrInvalidCursor) {
+				return c.JSON(http.StatusBadRequest, Failure{Message: "invalid cursor"})
+			}
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, Failure{Message: err.Error()})
+			}
 			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
 		}
 
-		return c.JSON(http.StatusOK, response{Data: task})
+		if len(page.Fields) > 0 {
+			return c.JSON(http.StatusOK, partialResponse{Data: page.Data(), NextCursor: page.NextCursor})
+		}
+		return c.JSON(http.StatusOK, response{Data: page.Tasks, NextCursor: page.NextCursor})
+	}
+}
+
+// @Summary		Get Task
+// @Description	Get Task, answers 304 when If-None-Match matches the ETag of the task
+// @Tags			Task
+// @Accept			json
+// @Produce		json
+// @Param			taskId			path		string						true	"task id"
+// @Param			If-None-Match	header		string						false	"ETag of a cached task"
+// @Success		200				{object}	handler.GetTask.response	"OK"
+// @Success		304				"Not Modified"
+// @Failure		400				{object}	Failure	"Bad Request"
+// @Failure		404				{object}	Failure	"Not Found"
+// @Router			/tasks/{taskId} [get]
+func (h *Handler) GetTask() echo.HandlerFunc {
+	type response struct {
+		Data models.Task `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		taskId, err := uuid.Parse(c.Param("taskId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid task id"})
+		}
+
+		task, err := h.controller.Task.Get(ctx, taskId)
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		setETag(c, task.Version)
+		if ifNoneMatch(c, task.Version) {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: *task})
 	}
 }
 
 // @Summary		Create Task
-// @Description	Create Task
+// @Description	Create Task, deleted for good once expires_at is passed.
+// @Description	A task is created in todo or in a state todo can move to, otherwise it answers 409 with the allowed states.
+// @Description	A task created in a project which doesn't exist answers 404.
 // @Tags			Task
 // @Accept			json
 // @Produce		json
 // @Param			request	body		handler.CreateTask.request	true	"request body"
 // @Success		200		{object}	handler.CreateTask.response	"OK"
 // @Failure		400		{object}	Failure						"Bad Request"
+// @Failure		404		{object}	Failure						"Not Found"
+// @Failure		409		{object}	TransitionFailure			"Conflict"
+// @Failure		503		{object}	Failure						"Service Unavailable"
+// @Failure		507		{object}	Failure						"Insufficient Storage"
 // @Router			/tasks [post]
 func (h *Handler) CreateTask() echo.HandlerFunc {
 	type request struct {
-		Name   string             `json:"name" validate:"required"`
-		Status *models.TaskStatus `json:"status" validate:"required,oneof=0 1"`
+		Name string `json:"name" validate:"required"`
+		// status of the clients which predate the states, required without state
+		Status *models.TaskStatus `json:"status" validate:"required_without=State,omitempty,oneof=0 1" swaggertype:"integer" enums:"0,1"`
+		// takes precedence over status
+		State *models.TaskState `json:"state" validate:"omitempty,oneof=todo in_progress in_review blocked done cancelled"`
+		// the task is deleted for good once it expires, it never expires when empty
+		ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,future" format:"date-time"`
+
+		// markdown text
+		Description string `json:"description" validate:"max=10000"`
+		// 0 none, 1 low, 2 medium, 3 high, 4 urgent
+		Priority models.TaskPriority `json:"priority" validate:"oneof=0 1 2 3 4" swaggertype:"integer"`
+		StartAt  *time.Time          `json:"start_at" format:"date-time"`
+		// must be after start_at
+		DueAt *time.Time `json:"due_at" validate:"omitempty,afterfield=StartAt" format:"date-time"`
+
+		// the project of the task, it belongs to none when empty
+		ProjectID *uuid.UUID `json:"project_id" format:"uuid"`
 	}
 	type response struct {
 		Data models.Task `json:"data" validate:"required"`
@@ -62,32 +223,74 @@ func (h *Handler) CreateTask() echo.HandlerFunc {
 		}
 
 		task, err := h.controller.Task.Create(ctx, controller.CreateTaskParams{
-			Name:   req.Name,
-			Status: *req.Status,
+			Name:      req.Name,
+			Status:    valueOf(req.Status),
+			State:     valueOf(req.State),
+			ExpiresAt: req.ExpiresAt,
+
+			Description: req.Description,
+			Priority:    req.Priority,
+			StartAt:     req.StartAt,
+			DueAt:       req.DueAt,
+
+			ProjectID: req.ProjectID,
 		})
 		if err != nil {
+			if errors.Is(err, controller.ErrProjectNotFound) {
+				return c.JSON(http.StatusNotFound, Failure{Message: err.Error()})
+			}
+			if errors.Is(err, controller.ErrInvalidTransition) {
+				return c.JSON(http.StatusConflict, transitionFailure(err))
+			}
+			if errors.Is(err, controller.ErrQuotaExceeded) {
+				return c.JSON(http.StatusInsufficientStorage, Failure{Message: err.Error()})
+			}
 			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
 		}
 
+		setETag(c, task.Version)
 		return c.JSON(http.StatusOK, response{Data: *task})
 	}
 }
 
 // @Summary		Update Task
-// @Description	Update Task
+// @Description	Update Task, answers 412 when If-Match doesn't match the ETag of the task or If-None-Match does.
+// @Description	expires_at replaces the expiry of the task, leaving it out keeps the task forever.
+// @Description	The details replace the ones of the task, leaving start_at or due_at out clears it.
+// @Description	A move the workflow doesn't allow answers 409 with the states the task can move to.
+// @Description	The task stays in its project, see Move Task.
 // @Tags			Task
 // @Accept			json
 // @Produce		json
-// @Param			taskId	path		string						true	"task id"
-// @Param			request	body		handler.UpdateTask.request	true	"request body"
-// @Success		200		{object}	handler.UpdateTask.response	"OK"
-// @Failure		400		{object}	Failure						"Bad Request"
-// @Failure		404		{object}	Failure						"Not Found"
+// @Param			taskId		path		string						true	"task id"
+// @Param			If-Match	header		string						false	"ETags the task being updated must have, weak ones never match"
+// @Param			If-None-Match	header		string						false	"ETags the task being updated must not have"
+// @Param			request		body		handler.UpdateTask.request	true	"request body"
+// @Success		200			{object}	handler.UpdateTask.response	"OK"
+// @Failure		400			{object}	Failure						"Bad Request"
+// @Failure		404			{object}	Failure						"Not Found"
+// @Failure		409			{object}	TransitionFailure			"Conflict"
+// @Failure		412			{object}	Failure						"Precondition Failed"
+// @Failure		503			{object}	Failure						"Service Unavailable"
+// @Failure		507			{object}	Failure						"Insufficient Storage"
 // @Router			/tasks/{taskId} [put]
 func (h *Handler) UpdateTask() echo.HandlerFunc {
 	type request struct {
-		Name   string             `json:"name" validate:"required"`
-		Status *models.TaskStatus `json:"status" validate:"required,oneof=0 1"`
+		Name string `json:"name" validate:"required"`
+		// status of the clients which predate the states, required without state
+		Status *models.TaskStatus `json:"status" validate:"required_without=State,omitempty,oneof=0 1" swaggertype:"integer" enums:"0,1"`
+		// takes precedence over status
+		State *models.TaskState `json:"state" validate:"omitempty,oneof=todo in_progress in_review blocked done cancelled"`
+		// the task is deleted for good once it expires, it never expires when empty
+		ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,future" format:"date-time"`
+
+		// markdown text
+		Description string `json:"description" validate:"max=10000"`
+		// 0 none, 1 low, 2 medium, 3 high, 4 urgent
+		Priority models.TaskPriority `json:"priority" validate:"oneof=0 1 2 3 4" swaggertype:"integer"`
+		StartAt  *time.Time          `json:"start_at" format:"date-time"`
+		// must be after start_at
+		DueAt *time.Time `json:"due_at" validate:"omitempty,afterfield=StartAt" format:"date-time"`
 	}
 	type response struct {
 		Data models.Task `json:"data" validate:"required"`
@@ -106,31 +309,140 @@ func (h *Handler) UpdateTask() echo.HandlerFunc {
 			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
 		}
 
+		versions, ok, err := writeVersions(c, h.taskVersion(ctx, taskId))
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+		if !ok {
+			return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+		}
+
 		task, err := h.controller.Task.Update(ctx, controller.UpdateTaskParams{
-			ID:     taskId,
-			Name:   req.Name,
-			Status: *req.Status,
+			ID:        taskId,
+			Name:      req.Name,
+			Status:    valueOf(req.Status),
+			State:     valueOf(req.State),
+			ExpiresAt: req.ExpiresAt,
+
+			Description: req.Description,
+			Priority:    req.Priority,
+			StartAt:     req.StartAt,
+			DueAt:       req.DueAt,
+
+			Versions: versions,
+		})
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			if errors.Is(err, controller.ErrConflict) {
+				return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+			}
+			if errors.Is(err, controller.ErrInvalidTransition) {
+				return c.JSON(http.StatusConflict, transitionFailure(err))
+			}
+			if errors.Is(err, controller.ErrQuotaExceeded) {
+				return c.JSON(http.StatusInsufficientStorage, Failure{Message: err.Error()})
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		setETag(c, task.Version)
+		return c.JSON(http.StatusOK, response{Data: *task})
+	}
+}
+
+// @Summary		Move Task
+// @Description	Move Task to another project, answers 412 when If-Match doesn't match the ETag of the task or If-None-Match does
+// @Tags			Task
+// @Accept			json
+// @Produce		json
+// @Param			taskId		path		string						true	"task id"
+// @Param			If-Match	header		string						false	"ETags the task being moved must have, weak ones never match"
+// @Param			If-None-Match	header		string						false	"ETags the task being moved must not have"
+// @Param			request		body		handler.MoveTask.request	true	"request body"
+// @Success		200			{object}	handler.MoveTask.response	"OK"
+// @Failure		400			{object}	Failure						"Bad Request"
+// @Failure		404			{object}	Failure						"Not Found"
+// @Failure		412			{object}	Failure						"Precondition Failed"
+// @Failure		503			{object}	Failure						"Service Unavailable"
+// @Failure		507			{object}	Failure						"Insufficient Storage"
+// @Router			/tasks/{taskId}/move [post]
+func (h *Handler) MoveTask() echo.HandlerFunc {
+	type request struct {
+		// the project the task moves to, empty takes it out of its project
+		ProjectID *uuid.UUID `json:"project_id" format:"uuid"`
+	}
+	type response struct {
+		Data models.Task `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		taskId, err := uuid.Parse(c.Param("taskId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid task id"})
+		}
+
+		req, err := bindAndValidate[request](c)
+		if err != nil {
+			logger.Debug(ctx, "failed to bind and validate request", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+		}
+
+		versions, ok, err := writeVersions(c, h.taskVersion(ctx, taskId))
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+		if !ok {
+			return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+		}
+
+		task, err := h.controller.Task.Move(ctx, controller.MoveTaskParams{
+			ID:        taskId,
+			ProjectID: req.ProjectID,
+			Versions:  versions,
 		})
 		if err != nil {
+			if errors.Is(err, controller.ErrProjectNotFound) {
+				return c.JSON(http.StatusNotFound, Failure{Message: err.Error()})
+			}
 			if errors.Is(err, controller.ErrNotFound) {
 				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
 			}
+			if errors.Is(err, controller.ErrConflict) {
+				return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+			}
+			if errors.Is(err, controller.ErrQuotaExceeded) {
+				return c.JSON(http.StatusInsufficientStorage, Failure{Message: err.Error()})
+			}
 			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
 		}
 
+		setETag(c, task.Version)
 		return c.JSON(http.StatusOK, response{Data: *task})
 	}
 }
 
 // @Summary		Delete Task
-// @Description	Delete Task
+// @Description	Move Task to the trash, answers 412 when If-Match doesn't match the ETag of the task or If-None-Match does
 // @Tags			Task
 // @Accept			json
 // @Produce		json
-// @Param			taskId	path		string	true	"task id"
-// @Success		200		{object}	Success	"OK"
-// @Failure		400		{object}	Failure	"Bad Request"
-// @Failure		404		{object}	Failure	"Not Found"
+// @Param			taskId		path		string	true	"task id"
+// @Param			If-Match	header		string	false	"ETags the task being deleted must have, weak ones never match"
+// @Param			If-None-Match	header		string	false	"ETags the task being deleted must not have"
+// @Success		200			{object}	Success	"OK"
+// @Failure		400			{object}	Failure	"Bad Request"
+// @Failure		404			{object}	Failure	"Not Found"
+// @Failure		412			{object}	Failure	"Precondition Failed"
+// @Failure		503			{object}	Failure	"Service Unavailable"
 // @Router			/tasks/{taskId} [delete]
 func (h *Handler) DeleteTask() echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -141,14 +453,101 @@ func (h *Handler) DeleteTask() echo.HandlerFunc {
 			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid task id"})
 		}
 
-		err = h.controller.Task.Delete(ctx, taskId)
+		versions, ok, err := writeVersions(c, h.taskVersion(ctx, taskId))
 		if err != nil {
 			if errors.Is(err, controller.ErrNotFound) {
 				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
 			}
 			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
 		}
+		if !ok {
+			return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+		}
+
+		err = h.controller.Task.Delete(ctx, controller.DeleteTaskParams{
+			ID:       taskId,
+			Versions: versions,
+		})
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			if errors.Is(err, controller.ErrConflict) {
+				return c.JSON(http.StatusPreconditionFailed, echo.ErrPreconditionFailed)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
 
 		return c.JSON(http.StatusOK, Success{Success: true})
 	}
 }
+
+// @Summary		Watch Tasks
+// @Description	Stream the changes of tasks as server-sent events, the id of an event is its seq.
+// @Description	A stream resumes after Last-Event-ID or after, and answers 410 when those changes are gone.
+// @Tags			Task
+// @Produce		text/event-stream
+// @Param			after			query		int					false	"seq of the last change received"
+// @Param			Last-Event-ID	header		string				false	"seq of the last change received, takes precedence over after"
+// @Success		200				{object}	models.TaskEvent	"OK"
+// @Failure		400				{object}	Failure				"Bad Request"
+// @Failure		410				{object}	Failure				"Gone"
+// @Router			/tasks/events [get]
+func (h *Handler) WatchTasks() echo.HandlerFunc {
+	type request struct {
+		After *uint64 `query:"after"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		req, err := bindAndValidate[request](c)
+		if err != nil {
+			logger.Debug(ctx, "failed to bind and validate request", zap.Error(err))
+			return c.JSON(http.StatusBadRequest, Failure{Message: err.Error()})
+		}
+
+		after := req.After
+		if lastEventID := c.Request().Header.Get(headerLastEventID); lastEventID != "" {
+			seq, err := strconv.ParseUint(lastEventID, 10, 64)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, Failure{Message: "invalid Last-Event-ID"})
+			}
+			after = &seq
+		}
+
+		// the stream ends when the client goes away
+		events, err := h.controller.Task.Watch(c.Request().Context(), controller.WatchTasksParams{After: after})
+		if err != nil {
+			if errors.Is(err, controller.ErrFeedTruncated) {
+				return c.JSON(http.StatusGone, Failure{Message: "changes after the given seq are gone, list the tasks again"})
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		header := c.Response().Header()
+		header.Set(echo.HeaderContentType, "text/event-stream")
+		header.Set(echo.HeaderCacheControl, "no-cache")
+		c.Response().WriteHeader(http.StatusOK)
+		c.Response().Flush()
+
+		for event := range events {
+			if err := writeEvent(c, event.Seq, string(event.Type), event); err != nil {
+				logger.Debug(ctx, "failed to write event", zap.Error(err))
+				return nil
+			}
+		}
+
+		return nil
+	}
+}
+
+// taskVersion reads the current version of a task for writeVersions
+func (h *Handler) taskVersion(ctx context.Context, id uuid.UUID) func() (uint64, error) {
+	return func() (uint64, error) {
+		task, err := h.controller.Task.Get(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return task.Version, nil
+	}
+}
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/dragon-huang0403/todo-go/internal/controller"
@@ -32,7 +34,9 @@ func TestListTasks(t *testing.T) {
 		}
 
 		// stubs
-		m.mockTaskCtl.EXPECT().List(gomock.Any()).Return(data, nil)
+		m.mockTaskCtl.EXPECT().
+			List(gomock.Any(), controller.ListTasksParams{}).
+			Return(&models.TaskPage{Tasks: data}, nil)
 
 		// assert
 		err := m.handler.ListTasks()(c)
@@ -49,6 +53,118 @@ func TestListTasks(t *testing.T) {
 		require.JSONEq(t, expectedBody, rec.Body.String())
 	})
 
+	t.Run("page", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.Request().URL.RawQuery = "limit=2&cursor=abc&direction=backward"
+
+		nextCursor := gofakeit.UUID()
+
+		// stubs
+		m.mockTaskCtl.EXPECT().
+			List(gomock.Any(), controller.ListTasksParams{Cursor: "abc", Limit: 2, Backward: true}).
+			Return(&models.TaskPage{Tasks: []*models.Task{}, NextCursor: nextCursor}, nil)
+
+		// assert
+		err := m.handler.ListTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, fmt.Sprintf(`{"data":[],"next_cursor":%q}`, nextCursor), rec.Body.String())
+	})
+
+	t.Run("query", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Method = http.MethodGet
+		c.Request().URL.RawQuery = "status=1&name=milk&created_after=2024-01-01T00:00:00Z&updated_before=2024-02-01T00:00:00Z" +
+			"&priority=3&due_before=2024-03-01T00:00:00Z&state=cancelled&sort=-name,id&fields=id,name"
+
+		status := models.TaskStatusCompleted
+		state := models.TaskStateCancelled
+		priority := models.TaskPriorityHigh
+		createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		updatedBefore := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		dueBefore := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+
+		// stubs
+		m.mockTaskCtl.EXPECT().
+			List(gomock.Any(), controller.ListTasksParams{
+				Filter: controller.TaskFilter{
+					Status:        &status,
+					State:         &state,
+					Name:          "milk",
+					CreatedAfter:  &createdAfter,
+					UpdatedBefore: &updatedBefore,
+					Priority:      &priority,
+					DueBefore:     &dueBefore,
+				},
+				Sort:   "-name,id",
+				Fields: "id,name",
+			}).
+			Return(&models.TaskPage{
+				Tasks:  []*models.Task{&task},
+				Fields: []models.TaskField{models.TaskFieldID, models.TaskFieldName},
+			}, nil)
+
+		// assert
+		err := m.handler.ListTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, fmt.Sprintf(`{"data":[{"id":%q,"name":%q}]}`, task.ID, task.Name), rec.Body.String())
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		tests := []struct {
+			query   string
+			message string
+		}{
+			{query: "limit=1001", message: "limit must be at most 1000"},
+			{query: "status=2", message: "status must be one of [0 1]"},
+			{query: "sort=name,-name", message: "sort must be a comma separated list of"},
+			{query: "sort=owner", message: "sort must be a comma separated list of"},
+			{query: "fields=id,owner", message: "fields must be a comma separated list of"},
+			{query: "created_after=2024-02-01T00:00:00Z&created_before=2024-01-01T00:00:00Z", message: "created_before must be after created_after"},
+			{query: "updated_before=yesterday", message: "yesterday"},
+			{query: "priority=5", message: "priority must be one of [0 1 2 3 4]"},
+			{query: "due_after=2024-02-01T00:00:00Z&due_before=2024-01-01T00:00:00Z", message: "due_before must be after due_after"},
+		}
+		for _, test := range tests {
+			m := setup(t)
+			// prepare
+			c, rec := m.prepareContext(nil)
+			c.Request().Method = http.MethodGet
+			c.Request().URL.RawQuery = test.query
+
+			// assert
+			err := m.handler.ListTasks()(c)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, rec.Code, test.query)
+
+			var failure Failure
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &failure))
+			require.Contains(t, failure.Message, test.message, test.query)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+
+		// stubs
+		m.mockTaskCtl.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, controller.ErrInvalidCursor)
+
+		// assert
+		err := m.handler.ListTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
 	t.Run("error", func(t *testing.T) {
 		m := setup(t)
 		// prepare
@@ -56,7 +172,7 @@ func TestListTasks(t *testing.T) {
 
 		// stubs
 		err := gofakeit.Error()
-		m.mockTaskCtl.EXPECT().List(gomock.Any()).Return(nil, err)
+		m.mockTaskCtl.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, err)
 
 		// assert
 		err = m.handler.ListTasks()(c)
@@ -65,25 +181,155 @@ func TestListTasks(t *testing.T) {
 	})
 }
 
+func TestGetTask(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		task := models.Task{}
+		err := gofakeit.Struct(&task)
+		require.NoError(t, err)
+
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues(task.ID.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Get(gomock.Any(), task.ID).Return(&task, nil)
+
+		// assert
+		err = m.handler.GetTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, fmt.Sprintf(`"%d"`, task.Version), rec.Header().Get("ETag"))
+
+		expectedData, err := json.Marshal(task)
+		require.NoError(t, err)
+
+		expectedBody := fmt.Sprintf(`{"data":%s}`, string(expectedData))
+		require.JSONEq(t, expectedBody, rec.Body.String())
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			ifNoneMatch string
+			code        int
+		}{{
+			name:        "same version",
+			ifNoneMatch: `"3"`,
+			code:        http.StatusNotModified,
+		}, {
+			name:        "weak tag in list",
+			ifNoneMatch: `"1", W/"3"`,
+			code:        http.StatusNotModified,
+		}, {
+			name:        "any",
+			ifNoneMatch: `*`,
+			code:        http.StatusNotModified,
+		}, {
+			name:        "other version",
+			ifNoneMatch: `"2"`,
+			code:        http.StatusOK,
+		}}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				task := models.Task{ID: uuid.New(), Version: 3}
+				c, rec := m.prepareContext(nil)
+				c.Request().Header.Set("If-None-Match", tc.ifNoneMatch)
+				c.SetParamNames("taskId")
+				c.SetParamValues(task.ID.String())
+
+				// stubs
+				m.mockTaskCtl.EXPECT().Get(gomock.Any(), task.ID).Return(&task, nil)
+
+				// assert
+				err := m.handler.GetTask()(c)
+				require.NoError(t, err)
+				require.Equal(t, tc.code, rec.Code)
+				require.Equal(t, `"3"`, rec.Header().Get("ETag"))
+			})
+		}
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues("invalid")
+
+		// assert
+		err := m.handler.GetTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), "invalid task id")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Get(gomock.Any(), id).Return(nil, controller.ErrNotFound)
+
+		// assert
+		err := m.handler.GetTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Get(gomock.Any(), id).Return(nil, gofakeit.Error())
+
+		// assert
+		err := m.handler.GetTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
 func TestCreateTask(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		m := setup(t)
 
 		// prepare
-		payload := fmt.Sprintf(`{"name":"%s","status":%d}`, gofakeit.Name(), gofakeit.Number(0, 1))
+		name, status := gofakeit.Name(), gofakeit.Number(0, 1)
+		payload := fmt.Sprintf(`{"name":"%s","status":%d}`, name, status)
 		c, rec := m.prepareContext(strings.NewReader(payload))
+		createParams := fmt.Sprintf(`{"name":"%s","status":%d,"state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"projectid":null}`, name, status)
 
 		task := models.Task{}
 		err := gofakeit.Struct(&task)
 		require.NoError(t, err)
 
 		// stubs
-		m.mockTaskCtl.EXPECT().Create(gomock.Any(), EqJSON(t, payload)).Return(&task, nil)
+		m.mockTaskCtl.EXPECT().Create(gomock.Any(), EqJSON(t, createParams)).Return(&task, nil)
 
 		// assert
 		err = m.handler.CreateTask()(c)
 		require.NoError(t, err)
 		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, fmt.Sprintf(`"%d"`, task.Version), rec.Header().Get("ETag"))
 
 		contentType := rec.Header().Get(echo.HeaderContentType)
 		require.Equal(t, echo.MIMEApplicationJSON, contentType)
@@ -95,6 +341,94 @@ func TestCreateTask(t *testing.T) {
 		require.JSONEq(t, string(expectedBody), rec.Body.String())
 	})
 
+	t.Run("expires", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		name, expiresAt := gofakeit.Name(), time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		payload := fmt.Sprintf(`{"name":"%s","status":0,"expires_at":"%s"}`, name, expiresAt)
+		c, rec := m.prepareContext(strings.NewReader(payload))
+		createParams := fmt.Sprintf(`{"name":"%s","status":0,"state":"","expiresat":"%s","description":"","priority":0,"startat":null,"dueat":null,"projectid":null}`, name, expiresAt)
+
+		task := models.Task{}
+		err := gofakeit.Struct(&task)
+		require.NoError(t, err)
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Create(gomock.Any(), EqJSON(t, createParams)).Return(&task, nil)
+
+		// assert
+		err = m.handler.CreateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("details", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		startAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		dueAt := startAt.Add(24 * time.Hour)
+		payload := `{"name":"Pay rent","status":0,"description":"Call the **bank** first","priority":4,` +
+			`"start_at":"2024-01-01T00:00:00Z","due_at":"2024-01-02T00:00:00Z"}`
+		c, rec := m.prepareContext(strings.NewReader(payload))
+
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Create(gomock.Any(), controller.CreateTaskParams{
+			Name:        "Pay rent",
+			Status:      models.TaskStatusIncomplete,
+			Description: "Call the **bank** first",
+			Priority:    models.TaskPriorityUrgent,
+			StartAt:     &startAt,
+			DueAt:       &dueAt,
+		}).Return(&task, nil)
+
+		// assert
+		err := m.handler.CreateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("invalid transition", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader(`{"name":"test","state":"in_review"}`))
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Create(gomock.Any(), controller.CreateTaskParams{Name: "test", State: models.TaskStateInReview}).
+			Return(nil, &controller.TransitionError{
+				To:      models.TaskStateInReview,
+				Allowed: []models.TaskState{models.TaskStateTodo, models.TaskStateInProgress},
+			})
+
+		// assert
+		err := m.handler.CreateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.JSONEq(t, `{"message":"invalid transition: a task can't be created in in_review","allowed":["todo","in_progress"]}`, rec.Body.String())
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader(`{"name":"test","status":0}`))
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Create(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("%w: task is limited to 10 records", controller.ErrQuotaExceeded))
+
+		// assert
+		err := m.handler.CreateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInsufficientStorage, rec.Code)
+		require.JSONEq(t, `{"message":"quota exceeded: task is limited to 10 records"}`, rec.Body.String())
+	})
+
 	t.Run("bad request", func(t *testing.T) {
 		testCases := []struct {
 			name        string
@@ -107,7 +441,7 @@ func TestCreateTask(t *testing.T) {
 		}, {
 			name:        "empty status",
 			payload:     `{"name":"test"}`,
-			errContains: `'request.Status' Error:Field validation for 'Status' failed on the 'required' tag`,
+			errContains: `'request.Status' Error:Field validation for 'Status' failed on the 'required_without' tag`,
 		}, {
 			name:        "invalid json",
 			payload:     `{"name":}`,
@@ -116,6 +450,22 @@ func TestCreateTask(t *testing.T) {
 			name:        "invalid status",
 			payload:     fmt.Sprintf(`{"name":"%s","status":2}`, gofakeit.Name()),
 			errContains: `'request.Status' Error:Field validation for 'Status' failed on the 'oneof' tag`,
+		}, {
+			name:        "expired",
+			payload:     fmt.Sprintf(`{"name":"test","status":0,"expires_at":"%s"}`, time.Now().Add(-time.Minute).Format(time.RFC3339)),
+			errContains: `'request.ExpiresAt' Error:Field validation for 'ExpiresAt' failed on the 'future' tag`,
+		}, {
+			name:        "invalid priority",
+			payload:     `{"name":"test","status":0,"priority":5}`,
+			errContains: `'request.Priority' Error:Field validation for 'Priority' failed on the 'oneof' tag`,
+		}, {
+			name:        "due before start",
+			payload:     `{"name":"test","status":0,"start_at":"2024-01-02T00:00:00Z","due_at":"2024-01-01T00:00:00Z"}`,
+			errContains: `'request.DueAt' Error:Field validation for 'DueAt' failed on the 'afterfield' tag`,
+		}, {
+			name:        "description too long",
+			payload:     fmt.Sprintf(`{"name":"test","status":0,"description":"%s"}`, strings.Repeat("a", 10001)),
+			errContains: `'request.Description' Error:Field validation for 'Description' failed on the 'max' tag`,
 		}}
 
 		for _, tc := range testCases {
@@ -138,12 +488,14 @@ func TestCreateTask(t *testing.T) {
 		m := setup(t)
 
 		// prepare
-		payload := fmt.Sprintf(`{"name":"%s","status":%d}`, gofakeit.Name(), gofakeit.Number(0, 1))
+		name, status := gofakeit.Name(), gofakeit.Number(0, 1)
+		payload := fmt.Sprintf(`{"name":"%s","status":%d}`, name, status)
 		c, rec := m.prepareContext(strings.NewReader(payload))
+		createParams := fmt.Sprintf(`{"name":"%s","status":%d,"state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"projectid":null}`, name, status)
 
 		// stubs
 		err := gofakeit.Error()
-		m.mockTaskCtl.EXPECT().Create(gomock.Any(), EqJSON(t, payload)).Return(nil, err)
+		m.mockTaskCtl.EXPECT().Create(gomock.Any(), EqJSON(t, createParams)).Return(nil, err)
 
 		// assert
 		err = m.handler.CreateTask()(c)
@@ -152,6 +504,37 @@ func TestCreateTask(t *testing.T) {
 	})
 }
 
+// ifNoneMatchCases are the If-None-Match headers of a write to a record at version 3,
+// versions are the ones the write is pinned to and none means it answers 412
+var ifNoneMatchCases = []struct {
+	name        string
+	ifMatch     string
+	ifNoneMatch string
+	versions    []uint64
+}{{
+	name:        "any",
+	ifNoneMatch: `*`,
+}, {
+	name:        "same version",
+	ifNoneMatch: `"3"`,
+}, {
+	name:        "weak tag in list",
+	ifNoneMatch: `"1", W/"3"`,
+}, {
+	name:        "other version",
+	ifNoneMatch: `"2"`,
+	versions:    []uint64{3},
+}, {
+	name:        "if match of another version",
+	ifMatch:     `"2"`,
+	ifNoneMatch: `"1"`,
+}, {
+	name:        "if match of the version",
+	ifMatch:     `"2", "3"`,
+	ifNoneMatch: `"1"`,
+	versions:    []uint64{3},
+}}
+
 func TestUpdateTask(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		m := setup(t)
@@ -170,7 +553,7 @@ func TestUpdateTask(t *testing.T) {
 		require.NoError(t, err)
 
 		// stubs
-		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s"}`, name, status, id)
+		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s","state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"versions":null}`, name, status, id)
 		m.mockTaskCtl.EXPECT().Update(gomock.Any(), EqJSON(t, updateParams)).Return(&task, nil)
 
 		// assert
@@ -207,7 +590,7 @@ func TestUpdateTask(t *testing.T) {
 			name:        "empty status",
 			id:          uuid.NewString(),
 			payload:     `{"name":"test"}`,
-			errContains: `'request.Status' Error:Field validation for 'Status' failed on the 'required' tag`,
+			errContains: `'request.Status' Error:Field validation for 'Status' failed on the 'required_without' tag`,
 		}, {
 			name:        "invalid json",
 			id:          uuid.NewString(),
@@ -218,6 +601,16 @@ func TestUpdateTask(t *testing.T) {
 			id:          uuid.NewString(),
 			payload:     fmt.Sprintf(`{"name":"%s","status":2}`, gofakeit.Name()),
 			errContains: `'request.Status' Error:Field validation for 'Status' failed on the 'oneof' tag`,
+		}, {
+			name:        "expired",
+			id:          uuid.NewString(),
+			payload:     fmt.Sprintf(`{"name":"test","status":0,"expires_at":"%s"}`, time.Now().Add(-time.Minute).Format(time.RFC3339)),
+			errContains: `'request.ExpiresAt' Error:Field validation for 'ExpiresAt' failed on the 'future' tag`,
+		}, {
+			name:        "due before start",
+			id:          uuid.NewString(),
+			payload:     `{"name":"test","status":0,"start_at":"2024-01-02T00:00:00Z","due_at":"2024-01-01T00:00:00Z"}`,
+			errContains: `'request.DueAt' Error:Field validation for 'DueAt' failed on the 'afterfield' tag`,
 		}}
 
 		for _, tc := range testCases {
@@ -251,7 +644,7 @@ func TestUpdateTask(t *testing.T) {
 		c.SetParamValues(id)
 
 		// stubs
-		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s"}`, name, status, id)
+		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s","state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"versions":null}`, name, status, id)
 		m.mockTaskCtl.EXPECT().Update(gomock.Any(), EqJSON(t, updateParams)).Return(nil, controller.ErrNotFound)
 
 		// assert
@@ -260,6 +653,126 @@ func TestUpdateTask(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, rec.Code)
 	})
 
+	t.Run("invalid transition", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.NewString()
+		c, rec := m.prepareContext(strings.NewReader(`{"name":"test","state":"in_review"}`))
+		c.SetParamNames("taskId")
+		c.SetParamValues(id)
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Update(gomock.Any(), gomock.Any()).
+			Return(nil, &controller.TransitionError{
+				From: models.TaskStateDone,
+				To:   models.TaskStateInReview,
+			})
+
+		// assert, the task can't leave its state
+		err := m.handler.UpdateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.JSONEq(t, `{"message":"invalid transition: a task can't move from done to in_review","allowed":[]}`, rec.Body.String())
+	})
+
+	t.Run("if match", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.NewString()
+		name := gofakeit.Name()
+		status := gofakeit.Number(0, 1)
+		payload := fmt.Sprintf(`{"name":"%s","status":%d}`, name, status)
+		c, rec := m.prepareContext(strings.NewReader(payload))
+		c.Request().Header.Set("If-Match", `"7"`)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id)
+
+		task := models.Task{}
+		err := gofakeit.Struct(&task)
+		require.NoError(t, err)
+
+		// stubs
+		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s","state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"versions":[7]}`, name, status, id)
+		m.mockTaskCtl.EXPECT().Update(gomock.Any(), EqJSON(t, updateParams)).Return(&task, nil)
+
+		// assert
+		err = m.handler.UpdateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, fmt.Sprintf(`"%d"`, task.Version), rec.Header().Get("ETag"))
+	})
+
+	t.Run("if match list", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.NewString()
+		name := gofakeit.Name()
+		status := gofakeit.Number(0, 1)
+		payload := fmt.Sprintf(`{"name":"%s","status":%d}`, name, status)
+		c, rec := m.prepareContext(strings.NewReader(payload))
+		c.Request().Header.Set("If-Match", `W/"2", "3", "a,b" ,"7"`)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id)
+
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+
+		// stubs, the weak and unknown tags can't match
+		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s","state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"versions":[3,7]}`, name, status, id)
+		m.mockTaskCtl.EXPECT().Update(gomock.Any(), EqJSON(t, updateParams)).Return(&task, nil)
+
+		// assert
+		err := m.handler.UpdateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("precondition failed", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.NewString()
+		name := gofakeit.Name()
+		status := gofakeit.Number(0, 1)
+		payload := fmt.Sprintf(`{"name":"%s","status":%d}`, name, status)
+		c, rec := m.prepareContext(strings.NewReader(payload))
+		c.Request().Header.Set("If-Match", `"7"`)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id)
+
+		// stubs
+		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s","state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"versions":[7]}`, name, status, id)
+		m.mockTaskCtl.EXPECT().Update(gomock.Any(), EqJSON(t, updateParams)).Return(nil, controller.ErrConflict)
+
+		// assert
+		err := m.handler.UpdateTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	})
+
+	t.Run("invalid if match", func(t *testing.T) {
+		for _, ifMatch := range []string{`W/"7"`, `7`, `"a"`, `"1" "2"`, `"1`, `W/"7", "a"`} {
+			t.Run(ifMatch, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				payload := fmt.Sprintf(`{"name":"%s","status":%d}`, gofakeit.Name(), gofakeit.Number(0, 1))
+				c, rec := m.prepareContext(strings.NewReader(payload))
+				c.Request().Header.Set("If-Match", ifMatch)
+				c.SetParamNames("taskId")
+				c.SetParamValues(uuid.NewString())
+
+				// assert
+				err := m.handler.UpdateTask()(c)
+				require.NoError(t, err)
+				require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+			})
+		}
+	})
+
 	t.Run("error", func(t *testing.T) {
 		m := setup(t)
 
@@ -274,7 +787,7 @@ func TestUpdateTask(t *testing.T) {
 
 		// stubs
 		err := gofakeit.Error()
-		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s"}`, name, status, id)
+		updateParams := fmt.Sprintf(`{"name":"%s","status":%d,"id":"%s","state":"","expiresat":null,"description":"","priority":0,"startat":null,"dueat":null,"versions":null}`, name, status, id)
 		m.mockTaskCtl.EXPECT().Update(gomock.Any(), EqJSON(t, updateParams)).Return(nil, err)
 
 		// assert
@@ -282,6 +795,176 @@ func TestUpdateTask(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
+
+	t.Run("if none match", func(t *testing.T) {
+		for _, tc := range ifNoneMatchCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				task := models.Task{ID: uuid.New(), Version: 3}
+				c, rec := m.prepareContext(strings.NewReader(`{"name":"test","status":0}`))
+				c.Request().Header.Set(headerIfMatch, tc.ifMatch)
+				c.Request().Header.Set(headerIfNoneMatch, tc.ifNoneMatch)
+				c.SetParamNames("taskId")
+				c.SetParamValues(task.ID.String())
+
+				// stubs
+				m.mockTaskCtl.EXPECT().Get(gomock.Any(), task.ID).Return(&task, nil)
+				if tc.versions != nil {
+					m.mockTaskCtl.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, params controller.UpdateTaskParams) (*models.Task, error) {
+						require.Equal(t, tc.versions, params.Versions)
+						return &task, nil
+					})
+				}
+
+				// assert
+				err := m.handler.UpdateTask()(c)
+				require.NoError(t, err)
+				if tc.versions == nil {
+					require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+					return
+				}
+				require.Equal(t, http.StatusOK, rec.Code)
+			})
+		}
+	})
+}
+
+func TestMoveTask(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+		projectID := uuid.New()
+		task.ProjectID = &projectID
+
+		c, rec := m.prepareContext(strings.NewReader(fmt.Sprintf(`{"project_id":%q}`, projectID)))
+		c.Request().Header.Set(headerIfMatch, `"2"`)
+		c.SetParamNames("taskId")
+		c.SetParamValues(task.ID.String())
+
+		// stubs
+		version := uint64(2)
+		m.mockTaskCtl.EXPECT().Move(gomock.Any(), controller.MoveTaskParams{
+			ID:        task.ID,
+			ProjectID: &projectID,
+			Versions:  []uint64{version},
+		}).Return(&task, nil)
+
+		// assert
+		err := m.handler.MoveTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, etag(task.Version), rec.Header().Get(headerETag))
+
+		expectedData, err := json.Marshal(task)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, expectedData), rec.Body.String())
+	})
+
+	t.Run("out of project", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+		c, rec := m.prepareContext(strings.NewReader(`{"project_id":null}`))
+		c.SetParamNames("taskId")
+		c.SetParamValues(task.ID.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Move(gomock.Any(), controller.MoveTaskParams{ID: task.ID}).Return(&task, nil)
+
+		// assert
+		err := m.handler.MoveTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("project not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader(fmt.Sprintf(`{"project_id":%q}`, uuid.New())))
+		c.SetParamNames("taskId")
+		c.SetParamValues(uuid.NewString())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Move(gomock.Any(), gomock.Any()).Return(nil, controller.ErrProjectNotFound)
+
+		// assert
+		err := m.handler.MoveTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.JSONEq(t, `{"message":"project not found"}`, rec.Body.String())
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader(fmt.Sprintf(`{"project_id":%q}`, uuid.New())))
+		c.SetParamNames("taskId")
+		c.SetParamValues(uuid.NewString())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Move(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("%w: task is limited to 1048576 bytes", controller.ErrQuotaExceeded))
+
+		// assert
+		err := m.handler.MoveTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInsufficientStorage, rec.Code)
+		require.JSONEq(t, `{"message":"quota exceeded: task is limited to 1048576 bytes"}`, rec.Body.String())
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(strings.NewReader(`{"project_id":"invalid"}`))
+		c.SetParamNames("taskId")
+		c.SetParamValues(uuid.NewString())
+
+		// assert
+		err := m.handler.MoveTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("if none match", func(t *testing.T) {
+		for _, tc := range ifNoneMatchCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				task := models.Task{ID: uuid.New(), Version: 3}
+				c, rec := m.prepareContext(strings.NewReader(`{"project_id":null}`))
+				c.Request().Header.Set(headerIfMatch, tc.ifMatch)
+				c.Request().Header.Set(headerIfNoneMatch, tc.ifNoneMatch)
+				c.SetParamNames("taskId")
+				c.SetParamValues(task.ID.String())
+
+				// stubs
+				m.mockTaskCtl.EXPECT().Get(gomock.Any(), task.ID).Return(&task, nil)
+				if tc.versions != nil {
+					m.mockTaskCtl.EXPECT().Move(gomock.Any(), controller.MoveTaskParams{ID: task.ID, Versions: tc.versions}).Return(&task, nil)
+				}
+
+				// assert
+				err := m.handler.MoveTask()(c)
+				require.NoError(t, err)
+				if tc.versions == nil {
+					require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+					return
+				}
+				require.Equal(t, http.StatusOK, rec.Code)
+			})
+		}
+	})
 }
 
 func TestDeleteTask(t *testing.T) {
@@ -295,7 +978,7 @@ func TestDeleteTask(t *testing.T) {
 		c.SetParamValues(id.String())
 
 		// stubs
-		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), id).Return(nil)
+		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), controller.DeleteTaskParams{ID: id}).Return(nil)
 
 		// assert
 		err := m.handler.DeleteTask()(c)
@@ -329,7 +1012,7 @@ func TestDeleteTask(t *testing.T) {
 		c.SetParamValues(id.String())
 
 		// stubs
-		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), id).Return(controller.ErrNotFound)
+		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), controller.DeleteTaskParams{ID: id}).Return(controller.ErrNotFound)
 
 		// assert
 		err := m.handler.DeleteTask()(c)
@@ -337,6 +1020,46 @@ func TestDeleteTask(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, rec.Code)
 	})
 
+	t.Run("if match", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		version := uint64(7)
+		c, rec := m.prepareContext(nil)
+		c.Request().Header.Set("If-Match", `"7"`)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), controller.DeleteTaskParams{ID: id, Versions: []uint64{version}}).Return(nil)
+
+		// assert
+		err := m.handler.DeleteTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("precondition failed", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		version := uint64(7)
+		c, rec := m.prepareContext(nil)
+		c.Request().Header.Set("If-Match", `"7"`)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), controller.DeleteTaskParams{ID: id, Versions: []uint64{version}}).Return(controller.ErrConflict)
+
+		// assert
+		err := m.handler.DeleteTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	})
+
 	t.Run("error", func(t *testing.T) {
 		m := setup(t)
 
@@ -348,11 +1071,126 @@ func TestDeleteTask(t *testing.T) {
 
 		// stubs
 		err := gofakeit.Error()
-		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), id).Return(err)
+		m.mockTaskCtl.EXPECT().Delete(gomock.Any(), controller.DeleteTaskParams{ID: id}).Return(err)
 
 		// assert
 		err = m.handler.DeleteTask()(c)
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
+
+	t.Run("if none match", func(t *testing.T) {
+		for _, tc := range ifNoneMatchCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m := setup(t)
+
+				// prepare
+				task := models.Task{ID: uuid.New(), Version: 3}
+				c, rec := m.prepareContext(nil)
+				c.Request().Header.Set(headerIfMatch, tc.ifMatch)
+				c.Request().Header.Set(headerIfNoneMatch, tc.ifNoneMatch)
+				c.SetParamNames("taskId")
+				c.SetParamValues(task.ID.String())
+
+				// stubs
+				m.mockTaskCtl.EXPECT().Get(gomock.Any(), task.ID).Return(&task, nil)
+				if tc.versions != nil {
+					m.mockTaskCtl.EXPECT().Delete(gomock.Any(), controller.DeleteTaskParams{ID: task.ID, Versions: tc.versions}).Return(nil)
+				}
+
+				// assert
+				err := m.handler.DeleteTask()(c)
+				require.NoError(t, err)
+				if tc.versions == nil {
+					require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+					return
+				}
+				require.Equal(t, http.StatusOK, rec.Code)
+			})
+		}
+	})
+
+	t.Run("if none match not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.Request().Header.Set(headerIfNoneMatch, `*`)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Get(gomock.Any(), id).Return(nil, controller.ErrNotFound)
+
+		// assert
+		err := m.handler.DeleteTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestWatchTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Header.Set(headerLastEventID, "3")
+
+		task := models.Task{}
+		err := gofakeit.Struct(&task)
+		require.NoError(t, err)
+
+		events := make(chan models.TaskEvent, 2)
+		events <- models.TaskEvent{Seq: 4, Type: models.TaskCreated, Task: &task}
+		events <- models.TaskEvent{Seq: 5, Type: models.TaskDeleted, Task: &task}
+		close(events)
+
+		after := uint64(3)
+
+		// stubs
+		m.mockTaskCtl.EXPECT().
+			Watch(gomock.Any(), controller.WatchTasksParams{After: &after}).
+			Return(events, nil)
+
+		// assert
+		err = m.handler.WatchTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+
+		data, err := json.Marshal(task)
+		require.NoError(t, err)
+		expectedBody := fmt.Sprintf(
+			"id: 4\nevent: created\ndata: {\"seq\":4,\"type\":\"created\",\"task\":%s}\n\n"+
+				"id: 5\nevent: deleted\ndata: {\"seq\":5,\"type\":\"deleted\",\"task\":%s}\n\n",
+			data, data)
+		require.Equal(t, expectedBody, rec.Body.String())
+	})
+
+	t.Run("invalid Last-Event-ID", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.Request().Header.Set(headerLastEventID, "abc")
+
+		// assert
+		err := m.handler.WatchTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Watch(gomock.Any(), gomock.Any()).Return(nil, controller.ErrFeedTruncated)
+
+		// assert
+		err := m.handler.WatchTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusGone, rec.Code)
+	})
 }
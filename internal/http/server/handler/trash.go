@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	httpserver "github.com/dragon-huang0403/todo-go/pkg/http/server"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary		List Trashed Tasks
+// @Description	List the deleted tasks which are not purged yet, in create order
+// @Tags			Trash
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	handler.ListTrashedTasks.response	"OK"
+// @Router			/tasks/trash [get]
+func (h *Handler) ListTrashedTasks() echo.HandlerFunc {
+	type response struct {
+		Data []*models.TrashedTask `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		tasks, err := h.controller.Task.ListTrash(ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: tasks})
+	}
+}
+
+// @Summary		Restore Task
+// @Description	Move Task out of the trash, back to its place in the list.
+// @Description	A task whose project is gone is taken out of it.
+// @Tags			Trash
+// @Accept			json
+// @Produce		json
+// @Param			taskId	path		string							true	"task id"
+// @Success		200		{object}	handler.RestoreTask.response	"OK"
+// @Failure		400		{object}	Failure							"Bad Request"
+// @Failure		404		{object}	Failure							"Not Found"
+// @Failure		503		{object}	Failure							"Service Unavailable"
+// @Router			/tasks/trash/{taskId}/restore [post]
+func (h *Handler) RestoreTask() echo.HandlerFunc {
+	type response struct {
+		Data models.Task `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		taskId, err := uuid.Parse(c.Param("taskId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid task id"})
+		}
+
+		task, err := h.controller.Task.Restore(ctx, taskId)
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		setETag(c, task.Version)
+		return c.JSON(http.StatusOK, response{Data: *task})
+	}
+}
+
+// @Summary		Purge Task
+// @Description	Remove Task from the trash for good
+// @Tags			Trash
+// @Accept			json
+// @Produce		json
+// @Param			taskId	path		string	true	"task id"
+// @Success		200		{object}	Success	"OK"
+// @Failure		400		{object}	Failure	"Bad Request"
+// @Failure		404		{object}	Failure	"Not Found"
+// @Failure		503		{object}	Failure	"Service Unavailable"
+// @Router			/tasks/trash/{taskId} [delete]
+func (h *Handler) PurgeTask() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		taskId, err := uuid.Parse(c.Param("taskId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid task id"})
+		}
+
+		if err := h.controller.Task.Purge(ctx, taskId); err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, Success{Success: true})
+	}
+}
+
+// @Summary		List Trashed Projects
+// @Description	List the deleted projects which are not purged yet, in create order
+// @Tags			Trash
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	handler.ListTrashedProjects.response	"OK"
+// @Router			/projects/trash [get]
+func (h *Handler) ListTrashedProjects() echo.HandlerFunc {
+	type response struct {
+		Data []*models.TrashedProject `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		projects, err := h.controller.Project.ListTrash(ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, response{Data: projects})
+	}
+}
+
+// @Summary		Restore Project
+// @Description	Move Project out of the trash along with the tasks deleted with it
+// @Tags			Trash
+// @Accept			json
+// @Produce		json
+// @Param			projectId	path		string							true	"project id"
+// @Success		200			{object}	handler.RestoreProject.response	"OK"
+// @Failure		400			{object}	Failure							"Bad Request"
+// @Failure		404			{object}	Failure							"Not Found"
+// @Failure		503			{object}	Failure							"Service Unavailable"
+// @Router			/projects/trash/{projectId}/restore [post]
+func (h *Handler) RestoreProject() echo.HandlerFunc {
+	type response struct {
+		Data models.Project `json:"data" validate:"required"`
+	}
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		projectId, err := uuid.Parse(c.Param("projectId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid project id"})
+		}
+
+		project, err := h.controller.Project.Restore(ctx, projectId)
+		if err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		setETag(c, project.Version)
+		return c.JSON(http.StatusOK, response{Data: *project})
+	}
+}
+
+// @Summary		Purge Project
+// @Description	Remove Project from the trash for good, along with its tasks in the trash
+// @Tags			Trash
+// @Accept			json
+// @Produce		json
+// @Param			projectId	path		string	true	"project id"
+// @Success		200			{object}	Success	"OK"
+// @Failure		400			{object}	Failure	"Bad Request"
+// @Failure		404			{object}	Failure	"Not Found"
+// @Failure		503			{object}	Failure	"Service Unavailable"
+// @Router			/projects/trash/{projectId} [delete]
+func (h *Handler) PurgeProject() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := httpserver.TransformContext(c)
+
+		projectId, err := uuid.Parse(c.Param("projectId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Failure{Message: "invalid project id"})
+		}
+
+		if err := h.controller.Project.Purge(ctx, projectId); err != nil {
+			if errors.Is(err, controller.ErrNotFound) {
+				return c.JSON(http.StatusNotFound, echo.ErrNotFound)
+			}
+			return c.JSON(http.StatusInternalServerError, echo.ErrInternalServerError)
+		}
+
+		return c.JSON(http.StatusOK, Success{Success: true})
+	}
+}
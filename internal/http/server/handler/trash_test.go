@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListTrashedTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+
+		task := models.TrashedTask{DeletedAt: time.Now().UTC()}
+		err := gofakeit.Struct(&task.Task)
+		require.NoError(t, err)
+		data := []*models.TrashedTask{&task}
+
+		// stubs
+		m.mockTaskCtl.EXPECT().ListTrash(gomock.Any()).Return(data, nil)
+
+		// assert
+		err = m.handler.ListTrashedTasks()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		expectedData, err := json.Marshal(data)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, expectedData), rec.Body.String())
+		require.Contains(t, rec.Body.String(), `"deleted_at"`)
+	})
+}
+
+func TestRestoreTask(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		task := models.Task{}
+		err := gofakeit.Struct(&task)
+		require.NoError(t, err)
+
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues(task.ID.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Restore(gomock.Any(), task.ID).Return(&task, nil)
+
+		// assert
+		err = m.handler.RestoreTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, etag(task.Version), rec.Header().Get(headerETag))
+
+		expectedData, err := json.Marshal(task)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, expectedData), rec.Body.String())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Restore(gomock.Any(), id).Return(nil, controller.ErrNotFound)
+
+		// assert
+		err := m.handler.RestoreTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestPurgeTask(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Purge(gomock.Any(), id).Return(nil)
+
+		// assert
+		err := m.handler.PurgeTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"success":true}`, rec.Body.String())
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues("invalid")
+
+		// assert
+		err := m.handler.PurgeTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("taskId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockTaskCtl.EXPECT().Purge(gomock.Any(), id).Return(controller.ErrNotFound)
+
+		// assert
+		err := m.handler.PurgeTask()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestTrashedProjects(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		m := setup(t)
+		// prepare
+		c, rec := m.prepareContext(nil)
+
+		project := models.TrashedProject{DeletedAt: time.Now().UTC()}
+		require.NoError(t, gofakeit.Struct(&project.Project))
+		data := []*models.TrashedProject{&project}
+
+		// stubs
+		m.mockProjectCtl.EXPECT().ListTrash(gomock.Any()).Return(data, nil)
+
+		// assert
+		err := m.handler.ListTrashedProjects()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		expectedData, err := json.Marshal(data)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"data":%s}`, expectedData), rec.Body.String())
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		project := models.Project{}
+		require.NoError(t, gofakeit.Struct(&project))
+
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("projectId")
+		c.SetParamValues(project.ID.String())
+
+		// stubs
+		m.mockProjectCtl.EXPECT().Restore(gomock.Any(), project.ID).Return(&project, nil)
+
+		// assert
+		err := m.handler.RestoreProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, etag(project.Version), rec.Header().Get(headerETag))
+	})
+
+	t.Run("purge not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+		c, rec := m.prepareContext(nil)
+		c.SetParamNames("projectId")
+		c.SetParamValues(id.String())
+
+		// stubs
+		m.mockProjectCtl.EXPECT().Purge(gomock.Any(), id).Return(controller.ErrNotFound)
+
+		// assert
+		err := m.handler.PurgeProject()(c)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -2,16 +2,51 @@ package httpserver
 
 import (
 	"github.com/dragon-huang0403/todo-go/internal/http/server/handler"
+	httpserver "github.com/dragon-huang0403/todo-go/pkg/http/server"
 	"github.com/labstack/echo/v4"
 )
 
-func addRoutes(e *echo.Group, h *handler.Handler) {
+func addRoutes(e *echo.Group, h *handler.Handler, config Config) {
 	e.GET("/health", h.HealthCheck())
 
+	// a follower only takes reads until it is promoted
+	readOnly := h.ReadOnly()
+
 	// Task
 	task := e.Group("/tasks")
 	task.GET("", h.ListTasks())
-	task.POST("", h.CreateTask())
-	task.PUT("/:taskId", h.UpdateTask())
-	task.DELETE("/:taskId", h.DeleteTask())
+	task.POST("", h.CreateTask(), readOnly)
+	task.GET("/events", h.WatchTasks())
+	task.GET("/search", h.SearchTasks())
+	task.GET("/trash", h.ListTrashedTasks())
+	task.POST("/trash/:taskId/restore", h.RestoreTask(), readOnly)
+	task.DELETE("/trash/:taskId", h.PurgeTask(), readOnly)
+	task.GET("/:taskId", h.GetTask())
+	task.PUT("/:taskId", h.UpdateTask(), readOnly)
+	task.POST("/:taskId/move", h.MoveTask(), readOnly)
+	task.DELETE("/:taskId", h.DeleteTask(), readOnly)
+
+	// Project
+	project := e.Group("/projects")
+	project.GET("", h.ListProjects())
+	project.POST("", h.CreateProject(), readOnly)
+	project.GET("/trash", h.ListTrashedProjects())
+	project.POST("/trash/:projectId/restore", h.RestoreProject(), readOnly)
+	project.DELETE("/trash/:projectId", h.PurgeProject(), readOnly)
+	project.GET("/:projectId", h.GetProject())
+	project.PUT("/:projectId", h.UpdateProject(), readOnly)
+	project.DELETE("/:projectId", h.DeleteProject(), readOnly)
+	project.GET("/:projectId/tasks", h.ListProjectTasks())
+
+	// Admin, only served with a token
+	if config.AdminToken == "" {
+		return
+	}
+	admin := e.Group("/admin", httpserver.TokenAuth(config.AdminToken))
+	admin.GET("/backup", h.Backup())
+	admin.POST("/restore", h.RestoreBackup(), readOnly)
+	admin.GET("/usage", h.Usage())
+	admin.GET("/replication", h.Replicate())
+	admin.GET("/replication/status", h.ReplicationStatus())
+	admin.POST("/promote", h.Promote())
 }
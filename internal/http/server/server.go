@@ -18,7 +18,12 @@ import (
 //	@host			localhost:8080
 //	@BasePath		/
 
-func NewServer(ctx context.Context, ctl *controller.Controller, validator *validator.Validator) *echo.Echo {
+//	@securityDefinitions.apikey	AdminToken
+//	@in							header
+//	@name						Authorization
+//	@description				Bearer followed by the admin token, the /admin routes aren't served without one
+
+func NewServer(ctx context.Context, config Config, ctl *controller.Controller, validator *validator.Validator) *echo.Echo {
 	e := echo.New()
 	e.Validator = validator
 
@@ -31,7 +36,7 @@ func NewServer(ctx context.Context, ctl *controller.Controller, validator *valid
 
 	// Add routes
 	router := e.Group("")
-	addRoutes(router, handler)
+	addRoutes(router, handler, config)
 
 	return e
 }
@@ -0,0 +1,161 @@
+package httptest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	httpserver "github.com/dragon-huang0403/todo-go/internal/http/server"
+	"github.com/dragon-huang0403/todo-go/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		tasks := m.prepareTasks(t, 3)
+		err := m.store.DeleteTask(store.DeleteTaskParams{ID: tasks[2].ID})
+		require.NoError(t, err)
+
+		backup := m.admin.GET("/admin/backup").
+			Expect().
+			Status(http.StatusOK).
+			ContentType("application/octet-stream").
+			Body().Raw()
+
+		// assert
+		restored := setup(t)
+		restored.prepareTask(t)
+		restored.admin.POST("/admin/restore").
+			WithBytes([]byte(backup)).
+			WithHeader("Content-Type", "application/octet-stream").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().IsEqual(map[string]int{"created": 3, "overwritten": 0, "skipped": 0})
+
+		list := restored.expect.GET("/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Array()
+		list.Length().IsEqual(3)
+		for i, task := range tasks[:2] {
+			list.Value(i + 1).Object().Value("id").IsEqual(task.ID)
+			list.Value(i + 1).Object().Value("name").IsEqual(task.Name)
+		}
+		restored.expect.GET("/tasks/trash").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Array().
+			Value(0).Object().Value("id").IsEqual(tasks[2].ID)
+
+		// the tasks are there already
+		restored.admin.POST("/admin/restore").
+			WithBytes([]byte(backup)).
+			Expect().
+			Status(http.StatusConflict)
+		restored.admin.POST("/admin/restore").
+			WithQuery("policy", "skip").
+			WithBytes([]byte(backup)).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("skipped").IsEqual(3)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		m.prepareTasks(t, 2)
+		backup := m.admin.GET("/admin/backup").Expect().Status(http.StatusOK).Body().Raw()
+
+		// assert, a truncated backup restores nothing
+		m.admin.POST("/admin/restore").
+			WithQuery("policy", "overwrite").
+			WithBytes([]byte(backup[:len(backup)-10])).
+			Expect().
+			Status(http.StatusBadRequest)
+		m.admin.POST("/admin/restore").
+			WithQuery("policy", "merge").
+			WithBytes([]byte(backup)).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+}
+
+func TestQuota(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		m.db.(db.Limiter).SetQuota(db.Task, db.Quota{MaxRecords: 2})
+		tasks := m.prepareTasks(t, 2)
+
+		// assert
+		m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{"name": gofakeit.Name(), "status": randomTaskStatus()}).
+			Expect().
+			Status(http.StatusInsufficientStorage).
+			JSON().Object().Value("message").IsEqual("quota exceeded: task is limited to 2 records")
+
+		usage := m.admin.GET("/admin/usage").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Array()
+		usage.Length().IsEqual(1)
+		usage.Value(0).Object().Value("model").IsEqual("task")
+		usage.Value(0).Object().Value("records").IsEqual(2)
+		usage.Value(0).Object().Value("bytes").Number().Gt(0)
+		usage.Value(0).Object().Value("max_records").IsEqual(2)
+
+		// a task is only freed once purged from the trash
+		require.NoError(t, m.store.DeleteTask(store.DeleteTaskParams{ID: tasks[0].ID}))
+		require.NoError(t, m.store.PurgeTask(tasks[0].ID))
+		m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{"name": gofakeit.Name(), "status": randomTaskStatus()}).
+			Expect().
+			Status(http.StatusOK)
+	})
+}
+
+func TestAdminAuth(t *testing.T) {
+	t.Run("token", func(t *testing.T) {
+		m := setup(t)
+
+		// assert
+		m.expect.GET("/admin/usage").
+			Expect().
+			Status(http.StatusUnauthorized)
+		m.expect.GET("/admin/usage").
+			WithHeader("Authorization", "Bearer wrong").
+			Expect().
+			Status(http.StatusUnauthorized)
+		m.expect.POST("/admin/promote").
+			WithHeader("Authorization", "Bearer wrong").
+			Expect().
+			Status(http.StatusUnauthorized)
+		m.admin.GET("/admin/usage").
+			Expect().
+			Status(http.StatusOK)
+	})
+
+	t.Run("off without a token", func(t *testing.T) {
+		m := setupServer(t, db.New(), controller.ReplicationConfig{}.Default(), httpserver.Config{})
+
+		// assert
+		m.admin.GET("/admin/backup").
+			Expect().
+			Status(http.StatusNotFound)
+		m.expect.POST("/admin/promote").
+			Expect().
+			Status(http.StatusNotFound)
+		m.expect.GET("/tasks").
+			Expect().
+			Status(http.StatusOK)
+	})
+}
@@ -0,0 +1,111 @@
+//go:build !production
+
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/db/chaos"
+	"github.com/stretchr/testify/require"
+)
+
+func setupChaos(t *testing.T, rules ...chaos.Rule) (*testMain, db.Database) {
+	base := db.New()
+	return setupDatabase(t, chaos.New(base, chaos.Config{Seed: 1, Rules: rules})), base
+}
+
+func TestChaos(t *testing.T) {
+	t.Run("failed creates", func(t *testing.T) {
+		m, _ := setupChaos(t, chaos.Rule{Methods: []string{"Create"}, ErrorRate: 0.5})
+
+		// prepare
+		created := []interface{}{}
+		for range 20 {
+			resp := m.expect.POST("/tasks").
+				WithJSON(map[string]interface{}{"name": gofakeit.Name(), "status": randomTaskStatus()}).
+				Expect()
+
+			if resp.Raw().StatusCode == http.StatusOK {
+				created = append(created, resp.JSON().Object().Value("data").Object().Value("id").Raw())
+				continue
+			}
+			resp.Status(http.StatusInternalServerError)
+		}
+
+		// assert, only the tasks answered with 200 exist
+		require.NotEmpty(t, created)
+		require.Less(t, len(created), 20)
+
+		list := m.expect.GET("/tasks").WithQuery("fields", "id").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Array()
+		list.Length().IsEqual(len(created))
+		for i, id := range created {
+			list.Value(i).Object().Value("id").IsEqual(id)
+		}
+	})
+
+	t.Run("failed after the update", func(t *testing.T) {
+		m, _ := setupChaos(t, chaos.Rule{Methods: []string{"RunInTx"}, ErrorRate: 1, FailAfter: true})
+
+		// prepare
+		task := m.prepareTask(t)
+		etag := fmt.Sprintf(`"%d"`, task.Version)
+
+		// assert, the update is applied though it answers 500, so a retry with the same ETag is refused
+		payload := map[string]interface{}{"name": gofakeit.Name(), "status": task.Status}
+		m.expect.PUT("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", etag).
+			WithJSON(payload).
+			Expect().
+			Status(http.StatusInternalServerError)
+		m.expect.PUT("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", etag).
+			WithJSON(payload).
+			Expect().
+			Status(http.StatusPreconditionFailed)
+
+		updated, err := m.store.GetTask(task.ID)
+		require.NoError(t, err)
+		require.Equal(t, payload["name"], updated.Name)
+		require.Equal(t, task.Version+1, updated.Version)
+	})
+
+	t.Run("failing task", func(t *testing.T) {
+		base := db.New()
+
+		// prepare
+		tasks := setupDatabase(t, base).prepareTasks(t, 2)
+		rule := chaos.Rule{Methods: []string{"Get"}, ID: tasks[0].ID.String(), ErrorRate: 1}
+		m := setupDatabase(t, chaos.New(base, chaos.Config{Rules: []chaos.Rule{rule}}))
+
+		// assert, the other tasks are served
+		m.expect.GET("/tasks/" + tasks[0].ID.String()).Expect().Status(http.StatusInternalServerError)
+		m.expect.GET("/tasks/" + tasks[1].ID.String()).Expect().Status(http.StatusOK)
+		m.expect.GET("/tasks").Expect().Status(http.StatusOK).JSON().Object().Value("data").Array().Length().IsEqual(2)
+	})
+
+	t.Run("failed restore", func(t *testing.T) {
+		source := setup(t)
+
+		// prepare
+		tasks := source.prepareTasks(t, 3)
+		backup := source.admin.GET("/admin/backup").Expect().Status(http.StatusOK).Body().Raw()
+		m, base := setupChaos(t, chaos.Rule{Methods: []string{"Create"}, ID: tasks[2].ID.String(), ErrorRate: 1})
+
+		// assert, a record failing in the middle of a restore restores nothing
+		m.admin.POST("/admin/restore").
+			WithBytes([]byte(backup)).
+			Expect().
+			Status(http.StatusInternalServerError)
+
+		list, err := base.List(db.Task)
+		require.NoError(t, err)
+		require.Empty(t, list)
+	})
+}
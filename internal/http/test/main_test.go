@@ -16,20 +16,43 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// adminToken is the admin token of the test servers
+const adminToken = "admin-token"
+
 type testMain struct {
 	expect *httpexpect.Expect
+	// admin sends the admin token along
+	admin *httpexpect.Expect
+	url   string
 
-	store store.Store
+	store      store.Store
+	db         db.Database
+	controller *controller.Controller
 }
 
 func setup(t *testing.T) *testMain {
+	return setupDatabase(t, db.New())
+}
+
+// setupDatabase serves a store on top of database
+func setupDatabase(t *testing.T, database db.Database) *testMain {
+	return setupReplication(t, database, controller.ReplicationConfig{}.Default())
+}
+
+// setupReplication serves a store on top of database, following the primary of config if any
+func setupReplication(t *testing.T, database db.Database, config controller.ReplicationConfig) *testMain {
+	return setupServer(t, database, config, httpserver.Config{AdminToken: adminToken})
+}
+
+// setupServer serves a store on top of database with the server config
+func setupServer(t *testing.T, database db.Database, config controller.ReplicationConfig, serverConfig httpserver.Config) *testMain {
 	ctx := context.Background()
-	db := db.New()
-	store := store.New(db)
-	controller := controller.New(store)
+	store, err := store.New(database)
+	require.NoError(t, err)
+	controller := controller.New(store, config, controller.WorkflowConfig{}.Default())
 	validator := validator.New()
 
-	server := httptest.NewServer(httpserver.NewServer(ctx, controller, validator))
+	server := httptest.NewServer(httpserver.NewServer(ctx, serverConfig, controller, validator))
 	t.Cleanup(server.Close)
 
 	expect := httpexpect.WithConfig(httpexpect.Config{
@@ -40,9 +63,17 @@ func setup(t *testing.T) *testMain {
 		},
 	})
 
+	admin := expect.Builder(func(req *httpexpect.Request) {
+		req.WithHeader("Authorization", "Bearer "+adminToken)
+	})
+
 	return &testMain{
-		expect: expect,
-		store:  store,
+		expect:     expect,
+		admin:      admin,
+		url:        server.URL,
+		store:      store,
+		db:         database,
+		controller: controller,
 	}
 }
 
@@ -0,0 +1,239 @@
+package httptest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/google/uuid"
+)
+
+func TestProjects(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		name := gofakeit.Name()
+
+		// prepare
+		project := m.expect.POST("/projects").
+			WithJSON(map[string]any{"name": name, "description": "boxes"}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object()
+		project.Value("name").IsEqual(name)
+		project.Value("version").IsEqual(1)
+		id := project.Value("id").String().Raw()
+
+		// assert
+		m.expect.PUT("/projects/"+id).
+			WithHeader("If-Match", `"1"`).
+			WithJSON(map[string]any{"name": "renamed"}).
+			Expect().
+			Status(http.StatusOK).
+			Header("ETag").IsEqual(`"2"`)
+		m.expect.PUT("/projects/"+id).
+			WithHeader("If-Match", `"1"`).
+			WithJSON(map[string]any{"name": "stale"}).
+			Expect().
+			Status(http.StatusPreconditionFailed)
+
+		m.expect.GET("/projects/" + id).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("name").IsEqual("renamed")
+
+		data := m.expect.GET("/projects").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array()
+		data.Length().IsEqual(1)
+		data.Value(0).Object().Value("id").IsEqual(id)
+
+		m.expect.GET("/projects/" + uuid.NewString()).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("tasks", func(t *testing.T) {
+		m := setup(t)
+		first := m.expect.POST("/projects").
+			WithJSON(map[string]any{"name": gofakeit.Name()}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("id").String().Raw()
+		second := m.expect.POST("/projects").
+			WithJSON(map[string]any{"name": gofakeit.Name()}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("id").String().Raw()
+		m.prepareTask(t)
+
+		// prepare
+		task := m.expect.POST("/tasks").
+			WithJSON(map[string]any{"name": gofakeit.Name(), "state": "todo", "project_id": first}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object()
+		task.Value("project_id").IsEqual(first)
+		taskID := task.Value("id").String().Raw()
+
+		// assert
+		data := m.expect.GET("/projects/" + first + "/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array()
+		data.Length().IsEqual(1)
+		data.Value(0).Object().Value("id").IsEqual(taskID)
+
+		m.expect.POST("/tasks/"+taskID+"/move").
+			WithHeader("If-Match", `"1"`).
+			WithJSON(map[string]any{"project_id": second}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("project_id").IsEqual(second)
+
+		m.expect.GET("/projects/" + first + "/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(0)
+		m.expect.GET("/projects/" + second + "/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(1)
+
+		// out of any project
+		m.expect.POST("/tasks/" + taskID + "/move").
+			WithJSON(map[string]any{"project_id": nil}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().NotContainsKey("project_id")
+
+		missing := uuid.NewString()
+		m.expect.POST("/tasks").
+			WithJSON(map[string]any{"name": gofakeit.Name(), "state": "todo", "project_id": missing}).
+			Expect().
+			Status(http.StatusNotFound).
+			JSON().Object().
+			Value("message").IsEqual("project not found")
+		m.expect.POST("/tasks/" + taskID + "/move").
+			WithJSON(map[string]any{"project_id": missing}).
+			Expect().
+			Status(http.StatusNotFound)
+		m.expect.GET("/projects/" + missing + "/tasks").
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestProjectTrash(t *testing.T) {
+	t.Run("restore", func(t *testing.T) {
+		m := setup(t)
+		id := m.expect.POST("/projects").
+			WithJSON(map[string]any{"name": gofakeit.Name()}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("id").String().Raw()
+		for i := 0; i < 2; i++ {
+			m.expect.POST("/tasks").
+				WithJSON(map[string]any{"name": gofakeit.Name(), "state": "todo", "project_id": id}).
+				Expect().
+				Status(http.StatusOK)
+		}
+		other := m.prepareTask(t)
+
+		// prepare
+		m.expect.DELETE("/projects/" + id).
+			Expect().
+			Status(http.StatusOK)
+
+		// assert, the tasks went to the trash with the project
+		m.expect.GET("/projects/" + id).
+			Expect().
+			Status(http.StatusNotFound)
+		data := m.expect.GET("/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array()
+		data.Length().IsEqual(1)
+		data.Value(0).Object().Value("id").IsEqual(other.ID)
+		m.expect.GET("/tasks/trash").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(2)
+
+		trashed := m.expect.GET("/projects/trash").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array()
+		trashed.Length().IsEqual(1)
+		trashed.Value(0).Object().Value("id").IsEqual(id)
+
+		m.expect.POST("/projects/trash/" + id + "/restore").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("id").IsEqual(id)
+
+		m.expect.GET("/projects/" + id + "/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(2)
+		m.expect.GET("/tasks/trash").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(0)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		m := setup(t)
+		id := m.expect.POST("/projects").
+			WithJSON(map[string]any{"name": gofakeit.Name()}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("id").String().Raw()
+		taskID := m.expect.POST("/tasks").
+			WithJSON(map[string]any{"name": gofakeit.Name(), "state": "todo", "project_id": id}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("id").String().Raw()
+
+		// prepare
+		m.expect.DELETE("/projects/" + id).
+			Expect().
+			Status(http.StatusOK)
+
+		// assert, its tasks are gone for good too
+		m.expect.DELETE("/projects/trash/" + id).
+			Expect().
+			Status(http.StatusOK)
+		m.expect.DELETE("/projects/trash/" + id).
+			Expect().
+			Status(http.StatusNotFound)
+		m.expect.POST("/tasks/trash/" + taskID + "/restore").
+			Expect().
+			Status(http.StatusNotFound)
+		m.expect.GET("/tasks/trash").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(0)
+	})
+}
@@ -0,0 +1,151 @@
+package httptest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/store"
+	"github.com/stretchr/testify/require"
+)
+
+// setupFollower serves a follower of primary, which follows it until the test ends
+func setupFollower(t *testing.T, primary *testMain) *testMain {
+	m := setupReplication(t, db.New(), controller.ReplicationConfig{
+		Primary:       primary.url,
+		PrimaryToken:  adminToken,
+		RetryInterval: 50 * time.Millisecond,
+		Timeout:       2 * time.Second,
+	})
+
+	// the stream is over before the servers close, which wait for it
+	ctx, cancel := context.WithCancel(context.Background())
+	followed := make(chan error, 1)
+	go func() {
+		followed <- m.controller.Replication.Follow(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-followed)
+	})
+
+	return m
+}
+
+// waitForSync waits until follower applied every change of primary
+func waitForSync(t *testing.T, primary, follower *testMain) {
+	seq, err := primary.store.Seq()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		got, err := follower.store.Seq()
+		require.NoError(t, err)
+		return got >= seq
+	}, 3*time.Second, 20*time.Millisecond)
+}
+
+func TestReplication(t *testing.T) {
+	t.Run("follow", func(t *testing.T) {
+		primary := setup(t)
+
+		// prepare, the follower starts from a copy and goes on with the changes
+		tasks := primary.prepareTasks(t, 3)
+		err := primary.store.DeleteTask(store.DeleteTaskParams{ID: tasks[2].ID})
+		require.NoError(t, err)
+
+		follower := setupFollower(t, primary)
+		waitForSync(t, primary, follower)
+
+		name := gofakeit.Name()
+		primary.expect.PUT("/tasks/" + tasks[0].ID.String()).
+			WithJSON(map[string]interface{}{"name": name, "status": tasks[0].Status}).
+			Expect().
+			Status(http.StatusOK)
+		primary.prepareTask(t)
+		waitForSync(t, primary, follower)
+
+		// assert, the follower answers the reads as its primary does
+		for _, path := range []string{"/tasks", "/tasks/trash", "/tasks/" + tasks[0].ID.String()} {
+			expected := primary.expect.GET(path).Expect().Status(http.StatusOK).Body().Raw()
+			follower.expect.GET(path).Expect().Status(http.StatusOK).Body().IsEqual(expected)
+		}
+		follower.expect.GET("/tasks/" + tasks[0].ID.String()).
+			Expect().
+			Status(http.StatusOK).
+			Header("ETag").IsEqual(`"2"`)
+
+		status := follower.admin.GET("/admin/replication/status").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Object()
+		status.Value("role").IsEqual("follower")
+		status.Value("primary").IsEqual(primary.url)
+		status.Value("connected").IsEqual(true)
+		status.Value("lag").IsEqual(0)
+		status.Value("seq").IsEqual(status.Value("primary_seq").Raw())
+		status.Value("last_contact").String().AsDateTime(time.RFC3339)
+
+		primary.admin.GET("/admin/replication/status").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Object().
+			Value("role").IsEqual("primary")
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		primary := setup(t)
+		task := primary.prepareTask(t)
+		follower := setupFollower(t, primary)
+		waitForSync(t, primary, follower)
+
+		// assert, the writes go to the primary
+		id := task.ID.String()
+		follower.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{"name": gofakeit.Name(), "status": randomTaskStatus()}).
+			Expect().
+			Status(http.StatusServiceUnavailable)
+		follower.expect.PUT("/tasks/" + id).
+			WithJSON(map[string]interface{}{"name": gofakeit.Name(), "status": randomTaskStatus()}).
+			Expect().
+			Status(http.StatusServiceUnavailable)
+		follower.expect.DELETE("/tasks/" + id).Expect().Status(http.StatusServiceUnavailable)
+		follower.expect.POST("/tasks/trash/" + id + "/restore").Expect().Status(http.StatusServiceUnavailable)
+		follower.expect.DELETE("/tasks/trash/" + id).Expect().Status(http.StatusServiceUnavailable)
+		follower.admin.POST("/admin/restore").Expect().Status(http.StatusServiceUnavailable)
+
+		follower.expect.GET("/tasks/" + id).Expect().Status(http.StatusOK)
+		follower.expect.GET("/tasks/search").WithQuery("q", task.Name).Expect().Status(http.StatusOK)
+	})
+
+	t.Run("promote", func(t *testing.T) {
+		primary := setup(t)
+		primary.prepareTasks(t, 2)
+		follower := setupFollower(t, primary)
+		waitForSync(t, primary, follower)
+
+		// assert, the promoted follower takes writes and doesn't follow anymore
+		follower.admin.POST("/admin/promote").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Object().
+			Value("role").IsEqual("primary")
+
+		follower.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{"name": gofakeit.Name(), "status": randomTaskStatus()}).
+			Expect().
+			Status(http.StatusOK)
+		primary.prepareTask(t)
+		time.Sleep(100 * time.Millisecond)
+		follower.expect.GET("/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Array().Length().IsEqual(3)
+
+		follower.admin.POST("/admin/promote").Expect().Status(http.StatusConflict)
+		primary.admin.POST("/admin/promote").Expect().Status(http.StatusConflict)
+	})
+}
@@ -1,12 +1,21 @@
 package httptest
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/dragon-huang0403/todo-go/internal/controller"
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/dragon-huang0403/todo-go/internal/store"
 	"github.com/gavv/httpexpect/v2"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -31,6 +40,7 @@ func TestListTasks(t *testing.T) {
 			item.Value("status").IsEqual(tasks[index].Status)
 			item.Value("created_at").IsEqual(tasks[index].CreatedAt)
 			item.Value("updated_at").IsEqual(tasks[index].UpdatedAt)
+			item.Value("version").IsEqual(1)
 		})
 	})
 
@@ -42,6 +52,120 @@ func TestListTasks(t *testing.T) {
 			JSON().Object().
 			Value("data").Array().Length().IsEqual(0)
 	})
+
+	t.Run("pages", func(t *testing.T) {
+		m := setup(t)
+		n := gofakeit.Number(10, 30)
+		limit := gofakeit.Number(1, 9)
+		tasks := m.prepareTasks(t, n)
+
+		forward := []string{}
+		backward := []string{}
+		for _, task := range tasks {
+			forward = append(forward, task.ID.String())
+			backward = append([]string{task.ID.String()}, backward...)
+		}
+
+		for direction, expected := range map[string][]string{"forward": forward, "backward": backward} {
+			ids := []string{}
+			cursor := ""
+			for {
+				req := m.expect.GET("/tasks").
+					WithQuery("limit", limit).
+					WithQuery("direction", direction)
+				if cursor != "" {
+					req = req.WithQuery("cursor", cursor)
+				}
+				result := req.Expect().
+					Status(http.StatusOK).
+					JSON().Object()
+
+				data := result.Value("data").Array()
+				data.Length().Le(limit)
+				for _, item := range data.Iter() {
+					ids = append(ids, item.Object().Value("id").String().Raw())
+				}
+
+				next, ok := result.Raw()["next_cursor"]
+				if !ok {
+					break
+				}
+				cursor = next.(string)
+			}
+			require.Equal(t, expected, ids, direction)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		for _, name := range []string{"Buy milk", "Walk dog", "buy bread", "Call mom"} {
+			_, err := m.store.CreateTask(store.CreateTaskParams{Name: name, Status: models.TaskStatusIncomplete})
+			require.NoError(t, err)
+		}
+
+		// assert
+		result := m.expect.GET("/tasks").
+			WithQuery("name", "BUY").
+			WithQuery("status", 0).
+			WithQuery("sort", "-name").
+			WithQuery("fields", "name").
+			WithQuery("limit", 1).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		result.Value("data").IsEqual([]map[string]string{{"name": "buy bread"}})
+
+		m.expect.GET("/tasks").
+			WithQuery("name", "BUY").
+			WithQuery("sort", "-name").
+			WithQuery("fields", "name").
+			WithQuery("cursor", result.Value("next_cursor").String().Raw()).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").IsEqual([]map[string]string{{"name": "Buy milk"}})
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		m := setup(t)
+		for _, query := range []string{
+			"cursor=abc", "limit=-1", "limit=1001", "direction=up",
+			"status=3", "sort=owner", "fields=owner", "created_after=today",
+		} {
+			m.expect.GET("/tasks").
+				WithQueryString(query).
+				Expect().
+				Status(http.StatusBadRequest)
+		}
+	})
+}
+
+func TestGetTask(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		task := m.prepareTask(t)
+
+		// assert
+		resp := m.expect.GET("/tasks/" + task.ID.String()).
+			Expect().
+			Status(http.StatusOK)
+		resp.Header("ETag").IsEqual(`"1"`)
+		resp.JSON().Object().Value("data").Object().Value("name").IsEqual(task.Name)
+
+		m.expect.GET("/tasks/"+task.ID.String()).
+			WithHeader("If-None-Match", `"1"`).
+			Expect().
+			Status(http.StatusNotModified)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := setup(t)
+		m.expect.GET("/tasks/" + uuid.NewString()).
+			Expect().
+			Status(http.StatusNotFound)
+	})
 }
 
 func TestCreateTask(t *testing.T) {
@@ -75,6 +199,82 @@ func TestCreateTask(t *testing.T) {
 		require.Equal(t, name, task.Name)
 		require.Equal(t, status, task.Status)
 	})
+
+	t.Run("expires", func(t *testing.T) {
+		m := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go m.db.(db.Sweeper).RunSweeper(ctx)
+
+		// assert
+		expiresAt := time.Now().Add(time.Second).UTC().Truncate(time.Second)
+		result := m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{
+				"name":       gofakeit.Name(),
+				"status":     randomTaskStatus(),
+				"expires_at": expiresAt,
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		result.Value("data").Object().Value("expires_at").String().AsDateTime(time.RFC3339).IsEqual(expiresAt)
+
+		id := result.Value("data").Object().Value("id").String().Raw()
+		require.Eventually(t, func() bool {
+			return m.expect.GET("/tasks/"+id).Expect().Raw().StatusCode == http.StatusNotFound
+		}, 3*time.Second, 50*time.Millisecond)
+
+		m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{
+				"name":       gofakeit.Name(),
+				"status":     randomTaskStatus(),
+				"expires_at": time.Now().Add(-time.Second),
+			}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("details", func(t *testing.T) {
+		m := setup(t)
+		startAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		dueAt := startAt.Add(24 * time.Hour)
+
+		// assert
+		result := m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{
+				"name":        gofakeit.Name(),
+				"status":      randomTaskStatus(),
+				"description": "Call the **bank** first",
+				"priority":    models.TaskPriorityHigh,
+				"start_at":    startAt,
+				"due_at":      dueAt,
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		result.Value("data").Object().Value("description").IsEqual("Call the **bank** first")
+		result.Value("data").Object().Value("priority").IsEqual(models.TaskPriorityHigh)
+		result.Value("data").Object().Value("start_at").String().AsDateTime(time.RFC3339).IsEqual(startAt)
+		result.Value("data").Object().Value("due_at").String().AsDateTime(time.RFC3339).IsEqual(dueAt)
+
+		m.expect.GET("/tasks").
+			WithQuery("priority", models.TaskPriorityHigh).
+			WithQuery("due_before", dueAt.Add(time.Second).Format(time.RFC3339)).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Array().Length().IsEqual(1)
+
+		m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{
+				"name":     gofakeit.Name(),
+				"status":   randomTaskStatus(),
+				"start_at": dueAt,
+				"due_at":   startAt,
+			}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("message").String().Contains("DueAt")
+	})
 }
 
 func TestUpdateTask(t *testing.T) {
@@ -105,6 +305,149 @@ func TestUpdateTask(t *testing.T) {
 		require.Equal(t, name, task.Name)
 		require.Equal(t, status, task.Status)
 	})
+
+	t.Run("workflow", func(t *testing.T) {
+		m := setup(t)
+		name := gofakeit.Name()
+
+		// prepare
+		id := m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{"name": name, "state": models.TaskStateInProgress}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Object().Value("id").String().Raw()
+
+		// assert
+		result := m.expect.PUT("/tasks/" + id).
+			WithJSON(map[string]interface{}{"name": name, "state": models.TaskStateInReview}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Object()
+		result.Value("state").IsEqual(models.TaskStateInReview)
+		result.Value("status").IsEqual(models.TaskStatusIncomplete)
+
+		// a client which predates the states completes the task
+		result = m.expect.PUT("/tasks/" + id).
+			WithJSON(map[string]interface{}{"name": name, "status": models.TaskStatusCompleted}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Object()
+		result.Value("state").IsEqual(models.TaskStateDone)
+		result.Value("completed_at").String().AsDateTime(time.RFC3339Nano)
+
+		failure := m.expect.PUT("/tasks/" + id).
+			WithJSON(map[string]interface{}{"name": name, "state": models.TaskStateInReview}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON().Object()
+		failure.Value("message").String().Contains("can't move from done to in_review")
+		failure.Value("allowed").IsEqual([]models.TaskState{models.TaskStateTodo, models.TaskStateInProgress})
+
+		m.expect.GET("/tasks").
+			WithQuery("state", models.TaskStateDone).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("data").Array().Length().IsEqual(1)
+
+		m.expect.POST("/tasks").
+			WithJSON(map[string]interface{}{"name": name, "state": models.TaskStateInReview}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON().Object().Value("allowed").Array().Length().IsEqual(5)
+	})
+}
+
+func TestConcurrentEdits(t *testing.T) {
+	t.Run("stale update", func(t *testing.T) {
+		m := setup(t)
+		task := m.prepareTask(t)
+		etag := m.expect.GET("/tasks/" + task.ID.String()).
+			Expect().
+			Status(http.StatusOK).
+			Header("ETag").Raw()
+
+		// first editor wins
+		m.expect.PUT("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", etag).
+			WithJSON(map[string]interface{}{
+				"name":   gofakeit.Name(),
+				"status": randomTaskStatus(),
+			}).
+			Expect().
+			Status(http.StatusOK).
+			Header("ETag").IsEqual(`"2"`)
+
+		// second editor still holds the old ETag
+		name := gofakeit.Name()
+		m.expect.PUT("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", etag).
+			WithJSON(map[string]interface{}{
+				"name":   name,
+				"status": randomTaskStatus(),
+			}).
+			Expect().
+			Status(http.StatusPreconditionFailed)
+
+		// check database
+		result, err := m.store.GetTask(task.ID)
+		require.NoError(t, err)
+		require.NotEqual(t, name, result.Name)
+		require.EqualValues(t, 2, result.Version)
+	})
+
+	t.Run("stale delete", func(t *testing.T) {
+		m := setup(t)
+		task := m.prepareTask(t)
+
+		m.expect.PUT("/tasks/" + task.ID.String()).
+			WithJSON(map[string]interface{}{
+				"name":   gofakeit.Name(),
+				"status": randomTaskStatus(),
+			}).
+			Expect().
+			Status(http.StatusOK)
+
+		// assert
+		m.expect.DELETE("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", `"1"`).
+			Expect().
+			Status(http.StatusPreconditionFailed)
+
+		m.expect.DELETE("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", `"2"`).
+			Expect().
+			Status(http.StatusOK)
+	})
+
+	t.Run("if match list", func(t *testing.T) {
+		m := setup(t)
+		task := m.prepareTask(t)
+
+		// assert, a weak tag never matches
+		m.expect.PUT("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", `W/"1"`).
+			WithJSON(map[string]interface{}{
+				"name":   gofakeit.Name(),
+				"status": randomTaskStatus(),
+			}).
+			Expect().
+			Status(http.StatusPreconditionFailed)
+
+		m.expect.PUT("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", `W/"1", "5", "1"`).
+			WithJSON(map[string]interface{}{
+				"name":   gofakeit.Name(),
+				"status": randomTaskStatus(),
+			}).
+			Expect().
+			Status(http.StatusOK).
+			Header("ETag").IsEqual(`"2"`)
+
+		m.expect.DELETE("/tasks/"+task.ID.String()).
+			WithHeader("If-Match", `"1", "5"`).
+			Expect().
+			Status(http.StatusPreconditionFailed)
+	})
 }
 
 func TestDeleteTask(t *testing.T) {
@@ -122,3 +465,249 @@ func TestDeleteTask(t *testing.T) {
 		require.ErrorIs(t, err, controller.ErrNotFound)
 	})
 }
+
+func TestConcurrentRequests(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		tasks := m.prepareTasks(t, 10)
+		workers := 10
+		// the tasks to delete are created up front, require can't fail the test off its goroutine
+		deleted := m.prepareTasks(t, workers)
+
+		var wg sync.WaitGroup
+		for i := range workers {
+			wg.Add(4)
+
+			// create
+			go func() {
+				defer wg.Done()
+				m.expect.POST("/tasks").
+					WithJSON(map[string]interface{}{
+						"name":   gofakeit.Name(),
+						"status": randomTaskStatus(),
+					}).
+					Expect().
+					Status(http.StatusOK)
+			}()
+
+			// list
+			go func() {
+				defer wg.Done()
+				m.expect.GET("/tasks").
+					Expect().
+					Status(http.StatusOK)
+			}()
+
+			// update
+			go func() {
+				defer wg.Done()
+				m.expect.PUT("/tasks/" + tasks[i].ID.String()).
+					WithJSON(map[string]interface{}{
+						"name":   gofakeit.Name(),
+						"status": randomTaskStatus(),
+					}).
+					Expect().
+					Status(http.StatusOK)
+			}()
+
+			// delete a task nobody else touches
+			go func() {
+				defer wg.Done()
+				m.expect.DELETE("/tasks/" + deleted[i].ID.String()).
+					Expect().
+					Status(http.StatusOK)
+			}()
+		}
+		wg.Wait()
+
+		// check database
+		result, err := m.store.ListTasks(store.ListTasksParams{})
+		require.NoError(t, err)
+		require.Len(t, result.Tasks, len(tasks)+workers)
+	})
+}
+
+func TestWatchTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		before := m.prepareTask(t)
+
+		// prepare
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.url+"/tasks/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "0")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		task := m.prepareTask(t)
+		m.expect.DELETE("/tasks/" + task.ID.String()).
+			Expect().
+			Status(http.StatusOK)
+
+		// assert
+		scanner := bufio.NewScanner(resp.Body)
+		readEvent := func() models.TaskEvent {
+			lines := []string{}
+			for scanner.Scan() && scanner.Text() != "" {
+				lines = append(lines, scanner.Text())
+			}
+			require.Len(t, lines, 3)
+
+			var event models.TaskEvent
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &event))
+			require.Equal(t, fmt.Sprintf("id: %d", event.Seq), lines[0])
+			require.Equal(t, fmt.Sprintf("event: %s", event.Type), lines[1])
+			return event
+		}
+
+		event := readEvent()
+		require.Equal(t, models.TaskCreated, event.Type)
+		require.Equal(t, before.ID, event.Task.ID)
+
+		event = readEvent()
+		require.Equal(t, models.TaskCreated, event.Type)
+		require.Equal(t, task.ID, event.Task.ID)
+
+		event = readEvent()
+		require.Equal(t, uint64(3), event.Seq)
+		require.Equal(t, models.TaskDeleted, event.Type)
+		require.Equal(t, task.ID, event.Task.ID)
+	})
+
+	t.Run("gone", func(t *testing.T) {
+		m := setup(t)
+		m.expect.GET("/tasks/events").
+			WithQuery("after", 1).
+			Expect().
+			Status(http.StatusGone)
+	})
+}
+
+func TestTrash(t *testing.T) {
+	t.Run("restore", func(t *testing.T) {
+		m := setup(t)
+		tasks := m.prepareTasks(t, 3)
+
+		// prepare
+		m.expect.DELETE("/tasks/" + tasks[1].ID.String()).
+			Expect().
+			Status(http.StatusOK)
+
+		// assert
+		m.expect.GET("/tasks/" + tasks[1].ID.String()).
+			Expect().
+			Status(http.StatusNotFound)
+		m.expect.GET("/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(2)
+
+		trashed := m.expect.GET("/tasks/trash").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array()
+		trashed.Length().IsEqual(1)
+		trashed.Value(0).Object().Value("id").IsEqual(tasks[1].ID)
+		trashed.Value(0).Object().Value("deleted_at").String().AsDateTime(time.RFC3339)
+
+		m.expect.POST("/tasks/trash/" + tasks[1].ID.String() + "/restore").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Object().Value("id").IsEqual(tasks[1].ID)
+
+		// back at its place
+		data := m.expect.GET("/tasks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array()
+		data.Length().IsEqual(3)
+		data.Value(1).Object().Value("id").IsEqual(tasks[1].ID)
+
+		m.expect.GET("/tasks/trash").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(0)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		m := setup(t)
+		task := m.prepareTask(t)
+
+		// prepare
+		m.expect.DELETE("/tasks/" + task.ID.String()).
+			Expect().
+			Status(http.StatusOK)
+
+		// assert
+		m.expect.DELETE("/tasks/trash/" + task.ID.String()).
+			Expect().
+			Status(http.StatusOK)
+		m.expect.DELETE("/tasks/trash/" + task.ID.String()).
+			Expect().
+			Status(http.StatusNotFound)
+		m.expect.POST("/tasks/trash/" + task.ID.String() + "/restore").
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestSearchTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		ids := []string{}
+		for _, name := range []string{"Buy milk", "Walk the dog", "Milkshake"} {
+			rawID := m.expect.POST("/tasks").
+				WithJSON(map[string]interface{}{"name": name, "status": 0}).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().
+				Value("data").Object().Value("id").String().Raw()
+			ids = append(ids, rawID)
+		}
+
+		// assert
+		data := m.expect.GET("/tasks/search").
+			WithQuery("q", "MILK").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array()
+		data.Length().IsEqual(2)
+		data.Value(0).Object().Value("task").Object().Value("id").IsEqual(ids[0])
+		data.Value(0).Object().Value("highlights").IsEqual([]map[string]int{{"start": 4, "end": 8}})
+		data.Value(1).Object().Value("task").Object().Value("id").IsEqual(ids[2])
+
+		// deleted tasks are not found
+		m.expect.DELETE("/tasks/" + ids[0]).
+			Expect().
+			Status(http.StatusOK)
+		m.expect.GET("/tasks/search").
+			WithQuery("q", "milk").
+			WithQuery("limit", 1).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			Value("data").Array().Length().IsEqual(1)
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		m := setup(t)
+
+		// assert
+		m.expect.GET("/tasks/search").
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+}
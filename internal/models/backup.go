@@ -0,0 +1,8 @@
+package models
+
+// RestoreResult counts the records of a backup by what the restore did with them
+type RestoreResult struct {
+	Created     int `json:"created" example:"10"`
+	Overwritten int `json:"overwritten" example:"0"`
+	Skipped     int `json:"skipped" example:"2"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Project is a list of tasks, a task belongs to one project at most
+type Project struct {
+	ID uuid.UUID `json:"id" validate:"required" format:"uuid"`
+
+	// project name
+	Name string `json:"name" validate:"required" example:"Moving out"`
+	// markdown text
+	Description string `json:"description,omitempty" example:"Everything before the **keys** are returned"`
+
+	CreatedAt time.Time `json:"created_at" validate:"required" format:"date-time"`
+	UpdatedAt time.Time `json:"updated_at" validate:"required" format:"date-time"`
+
+	// increases on every update, starting from 1
+	Version uint64 `json:"version" validate:"required" example:"1"`
+}
+
+func (p *Project) SetVersion(version uint64) {
+	p.Version = version
+}
+
+// TrashedProject is a deleted project, it can be restored along with its tasks until it is purged
+type TrashedProject struct {
+	Project
+	DeletedAt time.Time `json:"deleted_at" validate:"required" format:"date-time"`
+}
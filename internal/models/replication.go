@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type ReplicationRole string
+
+const (
+	ReplicationPrimary  ReplicationRole = "primary"
+	ReplicationFollower ReplicationRole = "follower"
+)
+
+// ReplicationStatus is the role of a server, and how far a follower got in the changes of its primary
+type ReplicationStatus struct {
+	Role ReplicationRole `json:"role" validate:"required" enums:"primary,follower" example:"follower"`
+	// seq of the last change of the database
+	Seq uint64 `json:"seq" example:"42"`
+
+	// url of the primary, only set on a follower
+	Primary string `json:"primary,omitempty" example:"http://127.0.0.1:8080"`
+	// whether the follower is streaming the changes of its primary
+	Connected bool `json:"connected" example:"true"`
+	// seq of the last change of the primary the follower heard of
+	PrimarySeq uint64 `json:"primary_seq,omitempty" example:"45"`
+	// number of changes of the primary the follower hasn't applied yet
+	Lag uint64 `json:"lag" example:"3"`
+	// when the follower last heard from its primary, which sends a heartbeat every second
+	LastContact *time.Time `json:"last_contact,omitempty" format:"date-time"`
+}
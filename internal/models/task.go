@@ -1,16 +1,13 @@
 package models
 
 import (
-	"errors"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-var (
-	ErrConvertFailed = errors.New("failed to convert interface to Task")
-)
-
+// TaskStatus is the numeric status of the clients which predate the states
 type TaskStatus int
 
 const (
@@ -18,22 +15,257 @@ const (
 	TaskStatusCompleted
 )
 
+// State is the state a task in current moves to when set to the status,
+// current is kept when it has that status already
+func (s TaskStatus) State(current TaskState) TaskState {
+	if current != "" && current.Status() == s {
+		return current
+	}
+	if s == TaskStatusCompleted {
+		return TaskStateDone
+	}
+	return TaskStateTodo
+}
+
+// TaskState is a step of the workflow of a task, the moves between them are configured
+type TaskState string
+
+const (
+	TaskStateTodo       TaskState = "todo"
+	TaskStateInProgress TaskState = "in_progress"
+	TaskStateInReview   TaskState = "in_review"
+	TaskStateBlocked    TaskState = "blocked"
+	TaskStateDone       TaskState = "done"
+	TaskStateCancelled  TaskState = "cancelled"
+)
+
+// Status is TaskStatusCompleted for the states which need no more work
+func (s TaskState) Status() TaskStatus {
+	if s == TaskStateDone || s == TaskStateCancelled {
+		return TaskStatusCompleted
+	}
+	return TaskStatusIncomplete
+}
+
+type TaskPriority int
+
+const (
+	TaskPriorityNone TaskPriority = iota
+	TaskPriorityLow
+	TaskPriorityMedium
+	TaskPriorityHigh
+	TaskPriorityUrgent
+)
+
 type Task struct {
 	ID uuid.UUID `json:"id" validate:"required" format:"uuid"`
 
 	// task name
 	Name string `json:"name" validate:"required" example:"account name"`
 
-	// 0 represents an incomplete task, 1 represents a completed task
+	// 1 when the state is done or cancelled, 0 otherwise, for the clients which predate the states
 	Status    TaskStatus `json:"status" validate:"required" swaggertype:"integer" example:"0"`
 	CreatedAt time.Time  `json:"created_at" validate:"required" format:"date-time"`
 	UpdatedAt time.Time  `json:"updated_at" validate:"required" format:"date-time"`
+
+	// increases on every update, starting from 1
+	Version uint64 `json:"version" validate:"required" example:"1"`
+
+	// the task is deleted for good once it expires, it never expires when empty
+	ExpiresAt *time.Time `json:"expires_at,omitempty" format:"date-time"`
+
+	// markdown text
+	Description string `json:"description,omitempty" example:"Call the **bank** first"`
+	// 0 none, 1 low, 2 medium, 3 high, 4 urgent
+	Priority TaskPriority `json:"priority" swaggertype:"integer" example:"2"`
+	// when work on the task can start, before due_at
+	StartAt *time.Time `json:"start_at,omitempty" format:"date-time"`
+	// when the task should be done
+	DueAt *time.Time `json:"due_at,omitempty" format:"date-time"`
+
+	// the step of the workflow, status follows it
+	State TaskState `json:"state" validate:"required" enums:"todo,in_progress,in_review,blocked,done,cancelled" example:"todo"`
+	// when the task moved to done, empty in other states
+	CompletedAt *time.Time `json:"completed_at,omitempty" format:"date-time"`
+	// when the task moved to cancelled, empty in other states
+	CancelledAt *time.Time `json:"cancelled_at,omitempty" format:"date-time"`
+
+	// the project of the task, empty when it belongs to none
+	ProjectID *uuid.UUID `json:"project_id,omitempty" format:"uuid"`
+}
+
+// UnmarshalJSON gives the tasks written before the states the state of their status
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type task Task
+	if err := json.Unmarshal(data, (*task)(t)); err != nil {
+		return err
+	}
+
+	if t.State == "" {
+		t.State = t.Status.State("")
+	}
+	return nil
+}
+
+// SetState moves the task to state at the given time, along with its status.
+// The times of completion and cancellation only change with the state.
+func (t *Task) SetState(state TaskState, at time.Time) {
+	if state != t.State {
+		t.CompletedAt, t.CancelledAt = nil, nil
+		switch state {
+		case TaskStateDone:
+			t.CompletedAt = &at
+		case TaskStateCancelled:
+			t.CancelledAt = &at
+		}
+	}
+
+	t.State = state
+	t.Status = state.Status()
+}
+
+func (t *Task) SetVersion(version uint64) {
+	t.Version = version
+}
+
+// Expiry is the zero time when the task never expires
+func (t *Task) Expiry() time.Time {
+	if t.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return *t.ExpiresAt
+}
+
+func (t *Task) Clone() interface{} {
+	clone := *t
+	clone.ExpiresAt = cloneTime(t.ExpiresAt)
+	clone.StartAt = cloneTime(t.StartAt)
+	clone.DueAt = cloneTime(t.DueAt)
+	clone.CompletedAt = cloneTime(t.CompletedAt)
+	clone.CancelledAt = cloneTime(t.CancelledAt)
+	clone.ProjectID = cloneID(t.ProjectID)
+	return &clone
 }
 
-func (Task) FromDB(v interface{}) (*Task, error) {
-	task, ok := v.(*Task)
-	if !ok {
-		return nil, ErrConvertFailed
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
 	}
-	return task, nil
+	clone := *t
+	return &clone
+}
+
+func cloneID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	clone := *id
+	return &clone
+}
+
+// TrashedTask is a deleted task, it can be restored until it is purged
+type TrashedTask struct {
+	Task
+	DeletedAt time.Time `json:"deleted_at" validate:"required" format:"date-time"`
+}
+
+// TaskField is the json name of a field of Task
+type TaskField string
+
+const (
+	TaskFieldID        TaskField = "id"
+	TaskFieldName      TaskField = "name"
+	TaskFieldStatus    TaskField = "status"
+	TaskFieldCreatedAt TaskField = "created_at"
+	TaskFieldUpdatedAt TaskField = "updated_at"
+	TaskFieldVersion   TaskField = "version"
+	TaskFieldExpiresAt TaskField = "expires_at"
+
+	TaskFieldDescription TaskField = "description"
+	TaskFieldPriority    TaskField = "priority"
+	TaskFieldStartAt     TaskField = "start_at"
+	TaskFieldDueAt       TaskField = "due_at"
+
+	TaskFieldState       TaskField = "state"
+	TaskFieldCompletedAt TaskField = "completed_at"
+	TaskFieldCancelledAt TaskField = "cancelled_at"
+
+	TaskFieldProjectID TaskField = "project_id"
+)
+
+// PartialTask holds some fields of a task, encoded by their json name
+type PartialTask map[TaskField]json.RawMessage
+
+// Select returns the fields of the task
+func (t *Task) Select(fields []TaskField) PartialTask {
+	// encoding a Task never fails
+	buf, _ := json.Marshal(t)
+	all := PartialTask{}
+	_ = json.Unmarshal(buf, &all)
+
+	partial := make(PartialTask, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			partial[field] = value
+		}
+	}
+
+	return partial
+}
+
+// TaskPage is a page of tasks
+type TaskPage struct {
+	Tasks []*Task
+	// NextCursor is empty on the last page
+	NextCursor string
+	// Fields of the tasks to return, empty returns every field
+	Fields []TaskField
+}
+
+// Data returns the tasks, or only their Fields when some are selected
+func (p *TaskPage) Data() interface{} {
+	if len(p.Fields) == 0 {
+		return p.Tasks
+	}
+
+	partials := make([]PartialTask, 0, len(p.Tasks))
+	for _, task := range p.Tasks {
+		partials = append(partials, task.Select(p.Fields))
+	}
+
+	return partials
+}
+
+type TaskEventType string
+
+const (
+	TaskCreated  TaskEventType = "created"
+	TaskUpdated  TaskEventType = "updated"
+	TaskDeleted  TaskEventType = "deleted"
+	TaskRestored TaskEventType = "restored"
+	TaskPurged   TaskEventType = "purged"
+)
+
+// TaskEvent is a change of a task, Seq orders every change of the database
+type TaskEvent struct {
+	Seq  uint64        `json:"seq" validate:"required" example:"1"`
+	Type TaskEventType `json:"type" validate:"required" enums:"created,updated,deleted,restored,purged" example:"created"`
+
+	// the task after the change, or the deleted task
+	Task *Task `json:"task" validate:"required"`
+}
+
+// TaskMatch is a task found by a search
+type TaskMatch struct {
+	Task *Task `json:"task" validate:"required"`
+	// relevance of the task to the query, higher is better
+	Score int `json:"score" validate:"required" example:"2"`
+	// parts of the name matching the query, by start
+	Highlights []Highlight `json:"highlights" validate:"required"`
+}
+
+// Highlight is the part [Start, End) of a text, the offsets count characters
+type Highlight struct {
+	Start int `json:"start" example:"0"`
+	End   int `json:"end" example:"4"`
 }
@@ -0,0 +1,12 @@
+package models
+
+// Usage is what the records of a model take, trashed records included
+type Usage struct {
+	Model   string `json:"model" example:"task"`
+	Records int    `json:"records" example:"120"`
+	// approximate size of the records
+	Bytes int64 `json:"bytes" example:"24000"`
+	// the limits of the model, 0 is no limit
+	MaxRecords int   `json:"max_records" example:"100000"`
+	MaxBytes   int64 `json:"max_bytes" example:"104857600"`
+}
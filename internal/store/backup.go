@@ -0,0 +1,41 @@
+package store
+
+import (
+	"io"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+)
+
+// ConflictPolicy tells a restore what to do with a record whose id is already used
+type ConflictPolicy = db.ConflictPolicy
+
+const (
+	ConflictSkip      = db.ConflictSkip
+	ConflictOverwrite = db.ConflictOverwrite
+	ConflictFail      = db.ConflictFail
+)
+
+// Backup streams every model to w without blocking writes
+func (s *storeImpl) Backup(w io.Writer) error {
+	snapshotter, err := capability[db.Snapshotter](s.db)
+	if err != nil {
+		return err
+	}
+
+	return db.WriteBackup(snapshotter, w)
+}
+
+// Restore verifies the whole backup before restoring it all at once
+func (s *storeImpl) Restore(r io.Reader, policy ConflictPolicy) (*models.RestoreResult, error) {
+	result, err := db.RestoreBackup(s.db, Schema, r, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RestoreResult{
+		Created:     result.Created,
+		Overwritten: result.Overwritten,
+		Skipped:     result.Skipped,
+	}, nil
+}
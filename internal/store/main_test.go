@@ -1,16 +1,30 @@
 package store
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
+	"github.com/dragon-huang0403/todo-go/internal/db"
 	mock_db "github.com/dragon-huang0403/todo-go/internal/db/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
 type testMain struct {
 	store Store
 
-	mockDB *mock_db.MockDatabase
+	mockDB      *mock_db.MockDatabase
+	mockWatcher *mock_db.MockWatcher
+	mockLimiter *mock_db.MockLimiter
+	mockTx      *mock_db.MockTx
+}
+
+// mockDatabase is a database with the capabilities the store uses
+type mockDatabase struct {
+	*mock_db.MockDatabase
+	*mock_db.MockWatcher
+	*mock_db.MockLimiter
 }
 
 func setup(t *testing.T) *testMain {
@@ -18,11 +32,75 @@ func setup(t *testing.T) *testMain {
 	t.Cleanup(ctl.Finish)
 
 	mockDB := mock_db.NewMockDatabase(ctl)
+	mockWatcher := mock_db.NewMockWatcher(ctl)
+	mockLimiter := mock_db.NewMockLimiter(ctl)
+	mockTx := mock_db.NewMockTx(ctl)
 
-	store := New(mockDB)
+	for _, index := range taskIndexes {
+		mockDB.EXPECT().RegisterIndex(db.Task, index.Name(), gomock.Any()).Return(nil)
+	}
+	store, err := New(mockDatabase{MockDatabase: mockDB, MockWatcher: mockWatcher, MockLimiter: mockLimiter})
+	require.NoError(t, err)
 
 	return &testMain{
-		store:  store,
-		mockDB: mockDB,
+		store:       store,
+		mockDB:      mockDB,
+		mockWatcher: mockWatcher,
+		mockLimiter: mockLimiter,
+		mockTx:      mockTx,
+	}
+}
+
+// setupBare opens a store on a database without any optional capability
+func setupBare(t *testing.T) Store {
+	ctl := gomock.NewController(t)
+	t.Cleanup(ctl.Finish)
+
+	mockDB := mock_db.NewMockDatabase(ctl)
+	mockDB.EXPECT().RegisterIndex(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	store, err := New(mockDB)
+	require.NoError(t, err)
+
+	return store
+}
+
+// expectTx runs the transaction of the store against mockTx
+func (m *testMain) expectTx() {
+	m.mockDB.EXPECT().RunInTx(gomock.Any()).DoAndReturn(func(fn func(db.Tx) error) error {
+		return fn(m.mockTx)
+	})
+}
+
+// databases are the real databases the store is tested against, a new one is opened for every test
+var databases = []struct {
+	name string
+	open func(t *testing.T) db.Database
+}{
+	{
+		name: "memory",
+		open: func(*testing.T) db.Database { return db.New() },
+	},
+	{
+		name: "sql",
+		open: func(t *testing.T) db.Database {
+			config := db.SQLConfig{}.Default()
+			config.DSN = "file:" + filepath.Join(t.TempDir(), "todo.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+			_, err := db.Migrate(context.Background(), config)
+			require.NoError(t, err)
+
+			database, err := db.NewSQL(config, Schema)
+			require.NoError(t, err)
+			t.Cleanup(func() { database.Close() })
+			return database
+		},
+	},
+}
+
+// forEachDatabase runs test once for every database
+func forEachDatabase(t *testing.T, test func(t *testing.T, newDatabase func(t *testing.T) db.Database)) {
+	for _, database := range databases {
+		t.Run(database.name, func(t *testing.T) {
+			test(t, database.open)
+		})
 	}
 }
@@ -10,8 +10,12 @@
 package mock_store
 
 import (
+	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
+	db "github.com/dragon-huang0403/todo-go/internal/db"
 	models "github.com/dragon-huang0403/todo-go/internal/models"
 	store "github.com/dragon-huang0403/todo-go/internal/store"
 	uuid "github.com/google/uuid"
@@ -41,6 +45,35 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// Backup mocks base method.
+func (m *MockStore) Backup(arg0 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Backup", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Backup indicates an expected call of Backup.
+func (mr *MockStoreMockRecorder) Backup(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Backup", reflect.TypeOf((*MockStore)(nil).Backup), arg0)
+}
+
+// CreateProject mocks base method.
+func (m *MockStore) CreateProject(arg0 store.CreateProjectParams) (*models.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProject", arg0)
+	ret0, _ := ret[0].(*models.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProject indicates an expected call of CreateProject.
+func (mr *MockStoreMockRecorder) CreateProject(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProject", reflect.TypeOf((*MockStore)(nil).CreateProject), arg0)
+}
+
 // CreateTask mocks base method.
 func (m *MockStore) CreateTask(arg0 store.CreateTaskParams) (*models.Task, error) {
 	m.ctrl.T.Helper()
@@ -56,8 +89,22 @@ func (mr *MockStoreMockRecorder) CreateTask(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTask", reflect.TypeOf((*MockStore)(nil).CreateTask), arg0)
 }
 
+// DeleteProject mocks base method.
+func (m *MockStore) DeleteProject(arg0 store.DeleteProjectParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProject", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProject indicates an expected call of DeleteProject.
+func (mr *MockStoreMockRecorder) DeleteProject(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProject", reflect.TypeOf((*MockStore)(nil).DeleteProject), arg0)
+}
+
 // DeleteTask mocks base method.
-func (m *MockStore) DeleteTask(arg0 uuid.UUID) error {
+func (m *MockStore) DeleteTask(arg0 store.DeleteTaskParams) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteTask", arg0)
 	ret0, _ := ret[0].(error)
@@ -70,6 +117,35 @@ func (mr *MockStoreMockRecorder) DeleteTask(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTask", reflect.TypeOf((*MockStore)(nil).DeleteTask), arg0)
 }
 
+// Follow mocks base method.
+func (m *MockStore) Follow(arg0 io.Reader, arg1 func(db.ReplicaProgress)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Follow", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Follow indicates an expected call of Follow.
+func (mr *MockStoreMockRecorder) Follow(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Follow", reflect.TypeOf((*MockStore)(nil).Follow), arg0, arg1)
+}
+
+// GetProject mocks base method.
+func (m *MockStore) GetProject(arg0 uuid.UUID) (*models.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProject", arg0)
+	ret0, _ := ret[0].(*models.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProject indicates an expected call of GetProject.
+func (mr *MockStoreMockRecorder) GetProject(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProject", reflect.TypeOf((*MockStore)(nil).GetProject), arg0)
+}
+
 // GetTask mocks base method.
 func (m *MockStore) GetTask(arg0 uuid.UUID) (*models.Task, error) {
 	m.ctrl.T.Helper()
@@ -85,19 +161,241 @@ func (mr *MockStoreMockRecorder) GetTask(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTask", reflect.TypeOf((*MockStore)(nil).GetTask), arg0)
 }
 
+// ListProjects mocks base method.
+func (m *MockStore) ListProjects() ([]*models.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListProjects")
+	ret0, _ := ret[0].([]*models.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListProjects indicates an expected call of ListProjects.
+func (mr *MockStoreMockRecorder) ListProjects() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListProjects", reflect.TypeOf((*MockStore)(nil).ListProjects))
+}
+
 // ListTasks mocks base method.
-func (m *MockStore) ListTasks() ([]*models.Task, error) {
+func (m *MockStore) ListTasks(arg0 store.ListTasksParams) (*models.TaskPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListTasks")
-	ret0, _ := ret[0].([]*models.Task)
+	ret := m.ctrl.Call(m, "ListTasks", arg0)
+	ret0, _ := ret[0].(*models.TaskPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListTasks indicates an expected call of ListTasks.
-func (mr *MockStoreMockRecorder) ListTasks() *gomock.Call {
+func (mr *MockStoreMockRecorder) ListTasks(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTasks", reflect.TypeOf((*MockStore)(nil).ListTasks))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTasks", reflect.TypeOf((*MockStore)(nil).ListTasks), arg0)
+}
+
+// ListTasksByStatus mocks base method.
+func (m *MockStore) ListTasksByStatus(arg0 models.TaskStatus) ([]*models.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTasksByStatus", arg0)
+	ret0, _ := ret[0].([]*models.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTasksByStatus indicates an expected call of ListTasksByStatus.
+func (mr *MockStoreMockRecorder) ListTasksByStatus(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTasksByStatus", reflect.TypeOf((*MockStore)(nil).ListTasksByStatus), arg0)
+}
+
+// ListTrashedProjects mocks base method.
+func (m *MockStore) ListTrashedProjects() ([]*models.TrashedProject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrashedProjects")
+	ret0, _ := ret[0].([]*models.TrashedProject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrashedProjects indicates an expected call of ListTrashedProjects.
+func (mr *MockStoreMockRecorder) ListTrashedProjects() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrashedProjects", reflect.TypeOf((*MockStore)(nil).ListTrashedProjects))
+}
+
+// ListTrashedTasks mocks base method.
+func (m *MockStore) ListTrashedTasks() ([]*models.TrashedTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrashedTasks")
+	ret0, _ := ret[0].([]*models.TrashedTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrashedTasks indicates an expected call of ListTrashedTasks.
+func (mr *MockStoreMockRecorder) ListTrashedTasks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrashedTasks", reflect.TypeOf((*MockStore)(nil).ListTrashedTasks))
+}
+
+// MoveTask mocks base method.
+func (m *MockStore) MoveTask(arg0 store.MoveTaskParams) (*models.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveTask", arg0)
+	ret0, _ := ret[0].(*models.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MoveTask indicates an expected call of MoveTask.
+func (mr *MockStoreMockRecorder) MoveTask(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveTask", reflect.TypeOf((*MockStore)(nil).MoveTask), arg0)
+}
+
+// PurgeProject mocks base method.
+func (m *MockStore) PurgeProject(arg0 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeProject", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeProject indicates an expected call of PurgeProject.
+func (mr *MockStoreMockRecorder) PurgeProject(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeProject", reflect.TypeOf((*MockStore)(nil).PurgeProject), arg0)
+}
+
+// PurgeTask mocks base method.
+func (m *MockStore) PurgeTask(arg0 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeTask", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeTask indicates an expected call of PurgeTask.
+func (mr *MockStoreMockRecorder) PurgeTask(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeTask", reflect.TypeOf((*MockStore)(nil).PurgeTask), arg0)
+}
+
+// PurgeTrash mocks base method.
+func (m *MockStore) PurgeTrash(arg0 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeTrash", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeTrash indicates an expected call of PurgeTrash.
+func (mr *MockStoreMockRecorder) PurgeTrash(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeTrash", reflect.TypeOf((*MockStore)(nil).PurgeTrash), arg0)
+}
+
+// Replicate mocks base method.
+func (m *MockStore) Replicate(arg0 context.Context, arg1 *uint64, arg2 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replicate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replicate indicates an expected call of Replicate.
+func (mr *MockStoreMockRecorder) Replicate(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replicate", reflect.TypeOf((*MockStore)(nil).Replicate), arg0, arg1, arg2)
+}
+
+// Restore mocks base method.
+func (m *MockStore) Restore(arg0 io.Reader, arg1 db.ConflictPolicy) (*models.RestoreResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(*models.RestoreResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStoreMockRecorder) Restore(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStore)(nil).Restore), arg0, arg1)
+}
+
+// RestoreProject mocks base method.
+func (m *MockStore) RestoreProject(arg0 uuid.UUID) (*models.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreProject", arg0)
+	ret0, _ := ret[0].(*models.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreProject indicates an expected call of RestoreProject.
+func (mr *MockStoreMockRecorder) RestoreProject(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreProject", reflect.TypeOf((*MockStore)(nil).RestoreProject), arg0)
+}
+
+// RestoreTask mocks base method.
+func (m *MockStore) RestoreTask(arg0 uuid.UUID) (*models.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreTask", arg0)
+	ret0, _ := ret[0].(*models.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreTask indicates an expected call of RestoreTask.
+func (mr *MockStoreMockRecorder) RestoreTask(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreTask", reflect.TypeOf((*MockStore)(nil).RestoreTask), arg0)
+}
+
+// SearchTasks mocks base method.
+func (m *MockStore) SearchTasks(arg0 store.SearchTasksParams) ([]*models.TaskMatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchTasks", arg0)
+	ret0, _ := ret[0].([]*models.TaskMatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchTasks indicates an expected call of SearchTasks.
+func (mr *MockStoreMockRecorder) SearchTasks(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchTasks", reflect.TypeOf((*MockStore)(nil).SearchTasks), arg0)
+}
+
+// Seq mocks base method.
+func (m *MockStore) Seq() (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Seq")
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Seq indicates an expected call of Seq.
+func (mr *MockStoreMockRecorder) Seq() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Seq", reflect.TypeOf((*MockStore)(nil).Seq))
+}
+
+// UpdateProject mocks base method.
+func (m *MockStore) UpdateProject(arg0 store.UpdateProjectParams) (*models.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProject", arg0)
+	ret0, _ := ret[0].(*models.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProject indicates an expected call of UpdateProject.
+func (mr *MockStoreMockRecorder) UpdateProject(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProject", reflect.TypeOf((*MockStore)(nil).UpdateProject), arg0)
 }
 
 // UpdateTask mocks base method.
@@ -114,3 +412,32 @@ func (mr *MockStoreMockRecorder) UpdateTask(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTask", reflect.TypeOf((*MockStore)(nil).UpdateTask), arg0)
 }
+
+// Usage mocks base method.
+func (m *MockStore) Usage() []*models.Usage {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Usage")
+	ret0, _ := ret[0].([]*models.Usage)
+	return ret0
+}
+
+// Usage indicates an expected call of Usage.
+func (mr *MockStoreMockRecorder) Usage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Usage", reflect.TypeOf((*MockStore)(nil).Usage))
+}
+
+// WatchTasks mocks base method.
+func (m *MockStore) WatchTasks(arg0 context.Context, arg1 store.WatchTasksParams) (<-chan models.TaskEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchTasks", arg0, arg1)
+	ret0, _ := ret[0].(<-chan models.TaskEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchTasks indicates an expected call of WatchTasks.
+func (mr *MockStoreMockRecorder) WatchTasks(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchTasks", reflect.TypeOf((*MockStore)(nil).WatchTasks), arg0, arg1)
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/google/uuid"
+)
+
+var projects = db.NewCollection[models.Project](db.Project)
+
+// tasksByProject finds the tasks which belong to a project, in the trash as well
+var tasksByProject = tasks.Index("project", func(task *models.Task) []string {
+	if task.ProjectID == nil {
+		return nil
+	}
+	return []string{task.ProjectID.String()}
+})
+
+func (s *storeImpl) GetProject(id uuid.UUID) (*models.Project, error) {
+	return projects.Get(s.db, id)
+}
+
+// ListProjects lists the projects in create order
+func (s *storeImpl) ListProjects() ([]*models.Project, error) {
+	return projects.List(s.db)
+}
+
+type CreateProjectParams struct {
+	Name        string
+	Description string
+}
+
+func (s *storeImpl) CreateProject(params CreateProjectParams) (*models.Project, error) {
+	project := &models.Project{
+		ID:          uuid.New(),
+		Name:        params.Name,
+		Description: params.Description,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	if err := projects.Create(s.db, project.ID, project); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+type UpdateProjectParams struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+
+	// the update fails with ErrConflict unless the project is still at one of Versions, none skips the check
+	Versions []uint64
+}
+
+func (s *storeImpl) UpdateProject(params UpdateProjectParams) (*models.Project, error) {
+	var project *models.Project
+	err := s.db.RunInTx(func(tx db.Tx) error {
+		var err error
+		project, err = projects.Get(tx, params.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(project.Version, params.Versions); err != nil {
+			return err
+		}
+
+		project.Name = params.Name
+		project.Description = params.Description
+		project.UpdatedAt = time.Now().UTC()
+
+		return projects.Update(tx, project.ID, project)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+type DeleteProjectParams struct {
+	ID uuid.UUID
+	// the delete fails with ErrConflict unless the project is still at one of Versions, none skips the check
+	Versions []uint64
+}
+
+// DeleteProject moves the project to the trash along with its tasks
+func (s *storeImpl) DeleteProject(params DeleteProjectParams) error {
+	return s.db.RunInTx(func(tx db.Tx) error {
+		project, err := projects.Get(tx, params.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(project.Version, params.Versions); err != nil {
+			return err
+		}
+
+		// the tasks are trashed after the project, which tells them from the ones trashed before
+		if err := projects.Trash(tx, project.ID); err != nil {
+			return err
+		}
+
+		list, err := tasksByProject.FindIDs(tx, db.KeyEquals(project.ID.String()))
+		if err != nil {
+			return err
+		}
+		for _, id := range list {
+			if err := tasks.Trash(tx, id); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *storeImpl) ListTrashedProjects() ([]*models.TrashedProject, error) {
+	trashed, err := projects.ListTrash(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*models.TrashedProject, 0, len(trashed))
+	for _, item := range trashed {
+		list = append(list, &models.TrashedProject{Project: *item.Value, DeletedAt: item.DeletedAt})
+	}
+
+	return list, nil
+}
+
+// RestoreProject moves the project out of the trash along with the tasks deleted with it
+func (s *storeImpl) RestoreProject(id uuid.UUID) (*models.Project, error) {
+	var project *models.Project
+	err := s.db.RunInTx(func(tx db.Tx) error {
+		trashed, err := projects.ListTrash(tx)
+		if err != nil {
+			return err
+		}
+
+		var deletedAt time.Time
+		for _, item := range trashed {
+			if item.ID == id {
+				deletedAt = item.DeletedAt
+			}
+		}
+		if err := projects.Restore(tx, id); err != nil {
+			return err
+		}
+
+		trashedTasks, err := trashedTasksOf(tx, id)
+		if err != nil {
+			return err
+		}
+		for _, item := range trashedTasks {
+			if item.DeletedAt.Before(deletedAt) {
+				continue
+			}
+			if err := tasks.Restore(tx, item.ID); err != nil {
+				return err
+			}
+		}
+
+		project, err = projects.Get(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// PurgeProject removes a project of the trash for good, along with its tasks in the trash
+func (s *storeImpl) PurgeProject(id uuid.UUID) error {
+	return s.db.RunInTx(func(tx db.Tx) error {
+		return purgeProject(tx, id)
+	})
+}
+
+func purgeProject(tx db.Tx, id uuid.UUID) error {
+	if err := projects.Purge(tx, id); err != nil {
+		return err
+	}
+
+	trashedTasks, err := trashedTasksOf(tx, id)
+	if err != nil {
+		return err
+	}
+	for _, item := range trashedTasks {
+		if err := tasks.Purge(tx, item.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trashedTasksOf lists the tasks of the project in the trash
+func trashedTasksOf(tx db.Tx, projectID uuid.UUID) ([]db.TrashedRecord[models.Task], error) {
+	return tasksByProject.FindTrash(tx, db.KeyEquals(projectID.String()))
+}
+
+// checkProject fails with ErrProjectNotFound unless the project is live, nil belongs to none
+func checkProject(tx db.Reader, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+
+	if _, err := projects.Get(tx, *id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrProjectNotFound
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProject(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+		t.Run("ok", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			project, err := store.CreateProject(CreateProjectParams{Name: gofakeit.Name(), Description: gofakeit.Sentence(5)})
+			require.NoError(t, err)
+			require.EqualValues(t, 1, project.Version)
+
+			// assert
+			version := project.Version
+			updated, err := store.UpdateProject(UpdateProjectParams{ID: project.ID, Name: "renamed", Versions: []uint64{version}})
+			require.NoError(t, err)
+			require.Equal(t, "renamed", updated.Name)
+			require.Empty(t, updated.Description)
+
+			_, err = store.UpdateProject(UpdateProjectParams{ID: project.ID, Name: "stale", Versions: []uint64{version}})
+			require.ErrorIs(t, err, ErrConflict)
+
+			got, err := store.GetProject(project.ID)
+			require.NoError(t, err)
+			require.Equal(t, updated, got)
+
+			list, err := store.ListProjects()
+			require.NoError(t, err)
+			require.Equal(t, []*models.Project{updated}, list)
+
+			_, err = store.UpdateProject(UpdateProjectParams{ID: uuid.New(), Name: "missing"})
+			require.ErrorIs(t, err, ErrNotFound)
+		})
+
+		t.Run("tasks", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			first, err := store.CreateProject(CreateProjectParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			second, err := store.CreateProject(CreateProjectParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+
+			task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &first.ID})
+			require.NoError(t, err)
+			_, err = store.CreateTask(CreateTaskParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+
+			// assert
+			page, err := store.ListTasks(ListTasksParams{Filter: TaskFilter{ProjectID: &first.ID}})
+			require.NoError(t, err)
+			require.Equal(t, []*models.Task{task}, page.Tasks)
+
+			version := task.Version
+			moved, err := store.MoveTask(MoveTaskParams{ID: task.ID, ProjectID: &second.ID, Versions: []uint64{version}})
+			require.NoError(t, err)
+			require.Equal(t, second.ID, *moved.ProjectID)
+			require.Equal(t, task.Name, moved.Name)
+
+			_, err = store.MoveTask(MoveTaskParams{ID: task.ID, ProjectID: &first.ID, Versions: []uint64{version}})
+			require.ErrorIs(t, err, ErrConflict)
+
+			page, err = store.ListTasks(ListTasksParams{Filter: TaskFilter{ProjectID: &first.ID}})
+			require.NoError(t, err)
+			require.Empty(t, page.Tasks)
+
+			moved, err = store.MoveTask(MoveTaskParams{ID: task.ID})
+			require.NoError(t, err)
+			require.Nil(t, moved.ProjectID)
+		})
+
+		t.Run("missing project", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			missing := uuid.New()
+
+			// assert
+			_, err = store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &missing})
+			require.ErrorIs(t, err, ErrProjectNotFound)
+			_, err = store.MoveTask(MoveTaskParams{ID: task.ID, ProjectID: &missing})
+			require.ErrorIs(t, err, ErrProjectNotFound)
+			_, err = store.ListTasks(ListTasksParams{Filter: TaskFilter{ProjectID: &missing}})
+			require.ErrorIs(t, err, ErrProjectNotFound)
+
+			page, err := store.ListTasks(ListTasksParams{})
+			require.NoError(t, err)
+			require.Equal(t, []*models.Task{task}, page.Tasks)
+		})
+	})
+}
+
+func TestDeleteProject(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+		t.Run("restore", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			project, err := store.CreateProject(CreateProjectParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			trashedBefore, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &project.ID})
+			require.NoError(t, err)
+			task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &project.ID})
+			require.NoError(t, err)
+			other, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteTask(DeleteTaskParams{ID: trashedBefore.ID}))
+
+			stale := project.Version + 1
+			require.ErrorIs(t, store.DeleteProject(DeleteProjectParams{ID: project.ID, Versions: []uint64{stale}}), ErrConflict)
+
+			// assert, the project goes to the trash along with its tasks
+			require.NoError(t, store.DeleteProject(DeleteProjectParams{ID: project.ID, Versions: []uint64{project.Version}}))
+
+			_, err = store.GetProject(project.ID)
+			require.ErrorIs(t, err, ErrNotFound)
+			page, err := store.ListTasks(ListTasksParams{})
+			require.NoError(t, err)
+			require.Equal(t, []*models.Task{other}, page.Tasks)
+			trashedProjects, err := store.ListTrashedProjects()
+			require.NoError(t, err)
+			require.Len(t, trashedProjects, 1)
+			require.Equal(t, *project, trashedProjects[0].Project)
+			trashedTasks, err := store.ListTrashedTasks()
+			require.NoError(t, err)
+			require.Len(t, trashedTasks, 2)
+
+			_, err = store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &project.ID})
+			require.ErrorIs(t, err, ErrProjectNotFound)
+
+			// only the tasks deleted with the project come back with it
+			restored, err := store.RestoreProject(project.ID)
+			require.NoError(t, err)
+			require.Equal(t, project, restored)
+
+			page, err = store.ListTasks(ListTasksParams{Filter: TaskFilter{ProjectID: &project.ID}})
+			require.NoError(t, err)
+			require.Equal(t, []*models.Task{task}, page.Tasks)
+			trashedTasks, err = store.ListTrashedTasks()
+			require.NoError(t, err)
+			require.Len(t, trashedTasks, 1)
+			require.Equal(t, trashedBefore.ID, trashedTasks[0].ID)
+
+			_, err = store.RestoreProject(project.ID)
+			require.ErrorIs(t, err, ErrNotFound)
+		})
+
+		t.Run("purge", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			project, err := store.CreateProject(CreateProjectParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &project.ID})
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteProject(DeleteProjectParams{ID: project.ID}))
+
+			// assert
+			require.NoError(t, store.PurgeProject(project.ID))
+
+			trashedProjects, err := store.ListTrashedProjects()
+			require.NoError(t, err)
+			require.Empty(t, trashedProjects)
+			trashedTasks, err := store.ListTrashedTasks()
+			require.NoError(t, err)
+			require.Empty(t, trashedTasks)
+			_, err = store.RestoreTask(task.ID)
+			require.ErrorIs(t, err, ErrNotFound)
+		})
+
+		t.Run("purge trash", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			project, err := store.CreateProject(CreateProjectParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			_, err = store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &project.ID})
+			require.NoError(t, err)
+			_, err = store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &project.ID})
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteProject(DeleteProjectParams{ID: project.ID}))
+
+			// assert, the project and its tasks
+			purged, err := store.PurgeTrash(time.Now().Add(time.Minute))
+			require.NoError(t, err)
+			require.Equal(t, 3, purged)
+
+			trashedProjects, err := store.ListTrashedProjects()
+			require.NoError(t, err)
+			require.Empty(t, trashedProjects)
+		})
+
+		t.Run("restore task", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			project, err := store.CreateProject(CreateProjectParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ProjectID: &project.ID})
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteProject(DeleteProjectParams{ID: project.ID}))
+
+			// assert, the task is taken out of the project in the trash
+			restored, err := store.RestoreTask(task.ID)
+			require.NoError(t, err)
+			require.Nil(t, restored.ProjectID)
+
+			got, err := store.GetTask(task.ID)
+			require.NoError(t, err)
+			require.Equal(t, restored, got)
+		})
+	})
+
+	t.Run("tasks of the project only", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		project := &models.Project{ID: uuid.New(), Name: gofakeit.Name()}
+		task := &models.Task{ID: uuid.New(), ProjectID: &project.ID}
+		key := db.KeyEquals(project.ID.String())
+		trashedTask := db.Trashed{ID: task.ID, Value: task, DeletedAt: time.Now()}
+		trashedProject := db.Trashed{ID: project.ID, Value: project, DeletedAt: trashedTask.DeletedAt}
+
+		// stubs, the tasks are never listed so the other ones aren't read
+		m.expectTx()
+		m.mockTx.EXPECT().Get(db.Project, project.ID).Return(project, nil)
+		m.mockTx.EXPECT().Trash(db.Project, project.ID).Return(nil)
+		m.mockTx.EXPECT().FindIDs(db.Task, tasksByProject.Name(), key).Return([]uuid.UUID{task.ID}, nil)
+		m.mockTx.EXPECT().Trash(db.Task, task.ID).Return(nil)
+
+		m.expectTx()
+		m.mockTx.EXPECT().ListTrash(db.Project).Return([]db.Trashed{trashedProject}, nil)
+		m.mockTx.EXPECT().Restore(db.Project, project.ID).Return(nil)
+		m.mockTx.EXPECT().FindTrash(db.Task, tasksByProject.Name(), key).Return([]db.Trashed{trashedTask}, nil)
+		m.mockTx.EXPECT().Restore(db.Task, task.ID).Return(nil)
+		m.mockTx.EXPECT().Get(db.Project, project.ID).Return(project, nil)
+
+		m.expectTx()
+		m.mockTx.EXPECT().Purge(db.Project, project.ID).Return(nil)
+		m.mockTx.EXPECT().FindTrash(db.Task, tasksByProject.Name(), key).Return([]db.Trashed{trashedTask}, nil)
+		m.mockTx.EXPECT().Purge(db.Task, task.ID).Return(nil)
+
+		// assert
+		require.NoError(t, m.store.DeleteProject(DeleteProjectParams{ID: project.ID}))
+		restored, err := m.store.RestoreProject(project.ID)
+		require.NoError(t, err)
+		require.Equal(t, project, restored)
+		require.NoError(t, m.store.PurgeProject(project.ID))
+	})
+}
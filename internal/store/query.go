@@ -0,0 +1,293 @@
+package store
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/google/uuid"
+)
+
+// TaskFilter keeps the tasks matching every field which is set
+type TaskFilter struct {
+	Status *models.TaskStatus
+	State  *models.TaskState
+	// Name keeps the tasks whose name contains it, case insensitive
+	Name string
+	// the ranges include After and exclude Before
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+
+	Priority *models.TaskPriority
+	// the tasks without a start or due date never match these ranges
+	StartAfter  *time.Time
+	StartBefore *time.Time
+	DueAfter    *time.Time
+	DueBefore   *time.Time
+
+	// ProjectID keeps the tasks of the project, see ErrProjectNotFound
+	ProjectID *uuid.UUID
+}
+
+func (f TaskFilter) empty() bool {
+	return f == TaskFilter{}
+}
+
+func (f TaskFilter) match(task *models.Task) bool {
+	if f.Status != nil && task.Status != *f.Status {
+		return false
+	}
+	if f.State != nil && task.State != *f.State {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(task.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if f.Priority != nil && task.Priority != *f.Priority {
+		return false
+	}
+	if f.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *f.ProjectID) {
+		return false
+	}
+
+	return inRange(task.CreatedAt, f.CreatedAfter, f.CreatedBefore) &&
+		inRange(task.UpdatedAt, f.UpdatedAfter, f.UpdatedBefore) &&
+		inOptionalRange(task.StartAt, f.StartAfter, f.StartBefore) &&
+		inOptionalRange(task.DueAt, f.DueAfter, f.DueBefore)
+}
+
+func inRange(t time.Time, after, before *time.Time) bool {
+	if after != nil && t.Before(*after) {
+		return false
+	}
+	return before == nil || t.Before(*before)
+}
+
+// inOptionalRange is inRange where an unset time is out of any range
+func inOptionalRange(t *time.Time, after, before *time.Time) bool {
+	if t == nil {
+		return after == nil && before == nil
+	}
+	return inRange(*t, after, before)
+}
+
+// TaskSort orders the tasks by Field
+type TaskSort struct {
+	Field      models.TaskField
+	Descending bool
+}
+
+// taskComparators order the tasks by each field, every field of a task has one
+var taskComparators = map[models.TaskField]func(a, b *models.Task) int{
+	models.TaskFieldID: func(a, b *models.Task) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	},
+	models.TaskFieldName: func(a, b *models.Task) int {
+		return strings.Compare(a.Name, b.Name)
+	},
+	models.TaskFieldStatus: func(a, b *models.Task) int {
+		return cmp.Compare(a.Status, b.Status)
+	},
+	models.TaskFieldCreatedAt: func(a, b *models.Task) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+	models.TaskFieldUpdatedAt: func(a, b *models.Task) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	},
+	models.TaskFieldVersion: func(a, b *models.Task) int {
+		return cmp.Compare(a.Version, b.Version)
+	},
+	models.TaskFieldExpiresAt: func(a, b *models.Task) int {
+		return compareOptionalTime(a.ExpiresAt, b.ExpiresAt)
+	},
+	models.TaskFieldDescription: func(a, b *models.Task) int {
+		return strings.Compare(a.Description, b.Description)
+	},
+	models.TaskFieldPriority: func(a, b *models.Task) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	},
+	models.TaskFieldStartAt: func(a, b *models.Task) int {
+		return compareOptionalTime(a.StartAt, b.StartAt)
+	},
+	models.TaskFieldDueAt: func(a, b *models.Task) int {
+		return compareOptionalTime(a.DueAt, b.DueAt)
+	},
+	models.TaskFieldState: func(a, b *models.Task) int {
+		return cmp.Compare(stateOrder[a.State], stateOrder[b.State])
+	},
+	models.TaskFieldCompletedAt: func(a, b *models.Task) int {
+		return compareOptionalTime(a.CompletedAt, b.CompletedAt)
+	},
+	models.TaskFieldCancelledAt: func(a, b *models.Task) int {
+		return compareOptionalTime(a.CancelledAt, b.CancelledAt)
+	},
+	models.TaskFieldProjectID: func(a, b *models.Task) int {
+		return compareOptionalID(a.ProjectID, b.ProjectID)
+	},
+}
+
+// stateOrder sorts the states along the course of a task
+var stateOrder = map[models.TaskState]int{
+	models.TaskStateTodo:       0,
+	models.TaskStateInProgress: 1,
+	models.TaskStateInReview:   2,
+	models.TaskStateBlocked:    3,
+	models.TaskStateDone:       4,
+	models.TaskStateCancelled:  5,
+}
+
+// compareOptionalTime orders the unset times after every set one
+func compareOptionalTime(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// compareOptionalID orders the unset ids after every set one
+func compareOptionalID(a, b *uuid.UUID) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+// taskOrder compares tasks by every sort in turn, then by create time and id,
+// so that two distinct tasks are never equal
+type taskOrder struct {
+	sorts    []TaskSort
+	backward bool
+}
+
+func (o taskOrder) compare(a, b *models.Task) int {
+	c := 0
+	for _, s := range o.sorts {
+		if c = taskComparators[s.Field](a, b); c != 0 {
+			if s.Descending {
+				c = -c
+			}
+			break
+		}
+	}
+	if c == 0 {
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID.String(), b.ID.String())
+	}
+
+	if o.backward {
+		return -c
+	}
+	return c
+}
+
+func (o taskOrder) validate() error {
+	for _, s := range o.sorts {
+		if _, ok := taskComparators[s.Field]; !ok {
+			return fmt.Errorf("unknown sort field %q", s.Field)
+		}
+	}
+
+	return nil
+}
+
+// cursorFields are the fields a sort cursor keeps, the ones the order compares
+func (o taskOrder) cursorFields() []models.TaskField {
+	fields := []models.TaskField{models.TaskFieldID, models.TaskFieldCreatedAt}
+	for _, s := range o.sorts {
+		fields = append(fields, s.Field)
+	}
+
+	return fields
+}
+
+// newSortCursor encodes the keys of the last task of a sorted page, the next page starts after it.
+// Only the fields the order compares are kept, so the rest of the task never ends up in a url.
+func (o taskOrder) newSortCursor(task *models.Task) string {
+	// encoding raw JSON never fails
+	buf, _ := json.Marshal(task.Select(o.cursorFields()))
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// parseSortCursor decodes the keys of a cursor into a task, which the order can compare
+func parseSortCursor(cursor string) (*models.Task, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	keys := models.PartialTask{}
+	if err := json.Unmarshal(buf, &keys); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := keys[models.TaskFieldID]; !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	task := &models.Task{}
+	if err := json.Unmarshal(buf, task); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return task, nil
+}
+
+// listSortedTasks reads every task matching the filter to sort them, the cursor is
+// the last task of the previous page so pages stay consistent across writes
+func (s *storeImpl) listSortedTasks(params ListTasksParams) (*models.TaskPage, error) {
+	order := taskOrder{sorts: params.Sort, backward: params.Backward}
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+
+	var after *models.Task
+	if params.Cursor != "" {
+		var err error
+		if after, err = parseSortCursor(params.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	var list []*models.Task
+	var err error
+	if params.Filter.Status != nil {
+		list, err = tasksByStatus.Find(s.db, db.KeyEquals(statusKey(*params.Filter.Status)))
+	} else {
+		list, err = tasks.List(s.db)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list = filterTasks(list, func(task *models.Task) bool {
+		return params.Filter.match(task) && (after == nil || order.compare(task, after) > 0)
+	})
+	sort.Slice(list, func(i, j int) bool { return order.compare(list[i], list[j]) < 0 })
+
+	page := &models.TaskPage{Tasks: list, Fields: params.Fields}
+	if params.Limit > 0 && len(list) > params.Limit {
+		page.Tasks = list[:params.Limit]
+		page.NextCursor = order.newSortCursor(page.Tasks[len(page.Tasks)-1])
+	}
+
+	return page, nil
+}
@@ -0,0 +1,262 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListTasksQuery(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+		// prepare creates tasks one millisecond apart, so their times are distinct
+		prepare := func(t *testing.T, names ...string) (Store, []*models.Task) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			created := make([]*models.Task, 0, len(names))
+			for i, name := range names {
+				task, err := store.CreateTask(CreateTaskParams{Name: name, Status: models.TaskStatus(i % 2)})
+				require.NoError(t, err)
+				created = append(created, task)
+				time.Sleep(time.Millisecond)
+			}
+
+			return store, created
+		}
+
+		names := func(tasks []*models.Task) []string {
+			list := make([]string, 0, len(tasks))
+			for _, task := range tasks {
+				list = append(list, task.Name)
+			}
+			return list
+		}
+
+		// listAll reads every page of params
+		listAll := func(t *testing.T, store Store, params ListTasksParams) []*models.Task {
+			tasks := []*models.Task{}
+			for {
+				page, err := store.ListTasks(params)
+				require.NoError(t, err)
+				tasks = append(tasks, page.Tasks...)
+				if page.NextCursor == "" {
+					return tasks
+				}
+				params.Cursor = page.NextCursor
+			}
+		}
+
+		t.Run("filter", func(t *testing.T) {
+			store, created := prepare(t, "Buy milk", "Walk dog", "Milkshake", "Call mom", "buy bread")
+
+			// assert
+			completed := models.TaskStatusCompleted
+			tasks := listAll(t, store, ListTasksParams{Limit: 1, Filter: TaskFilter{Status: &completed}})
+			require.Equal(t, []string{"Walk dog", "Call mom"}, names(tasks))
+
+			tasks = listAll(t, store, ListTasksParams{Limit: 1, Filter: TaskFilter{Name: "MILK"}})
+			require.Equal(t, []string{"Buy milk", "Milkshake"}, names(tasks))
+
+			tasks = listAll(t, store, ListTasksParams{Filter: TaskFilter{
+				CreatedAfter:  &created[1].CreatedAt,
+				CreatedBefore: &created[3].CreatedAt,
+			}})
+			require.Equal(t, []string{"Walk dog", "Milkshake"}, names(tasks))
+
+			_, err := store.UpdateTask(UpdateTaskParams{ID: created[4].ID, Name: "buy bread", Status: models.TaskStatusCompleted})
+			require.NoError(t, err)
+			tasks = listAll(t, store, ListTasksParams{Filter: TaskFilter{UpdatedAfter: &created[4].CreatedAt, Status: &completed}})
+			require.Equal(t, []string{"buy bread"}, names(tasks))
+		})
+
+		t.Run("sort", func(t *testing.T) {
+			store, _ := prepare(t, "b", "d", "a", "c", "e")
+
+			// assert
+			for _, limit := range []int{0, 1, 2, 5} {
+				tasks := listAll(t, store, ListTasksParams{Limit: limit, Sort: []TaskSort{{Field: models.TaskFieldName}}})
+				require.Equal(t, []string{"a", "b", "c", "d", "e"}, names(tasks), limit)
+
+				tasks = listAll(t, store, ListTasksParams{Limit: limit, Sort: []TaskSort{{Field: models.TaskFieldName}}, Backward: true})
+				require.Equal(t, []string{"e", "d", "c", "b", "a"}, names(tasks), limit)
+
+				// statuses alternate from incomplete, then the newest first
+				tasks = listAll(t, store, ListTasksParams{Limit: limit, Sort: []TaskSort{
+					{Field: models.TaskFieldStatus, Descending: true},
+					{Field: models.TaskFieldCreatedAt, Descending: true},
+				}})
+				require.Equal(t, []string{"c", "d", "e", "a", "b"}, names(tasks), limit)
+			}
+
+			incomplete := models.TaskStatusIncomplete
+			tasks := listAll(t, store, ListTasksParams{
+				Limit:  1,
+				Filter: TaskFilter{Status: &incomplete},
+				Sort:   []TaskSort{{Field: models.TaskFieldName, Descending: true}},
+			})
+			require.Equal(t, []string{"e", "b", "a"}, names(tasks))
+		})
+
+		t.Run("details", func(t *testing.T) {
+			store, created := prepare(t, "a", "b", "c", "d")
+
+			// prepare, d has no dates
+			day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, task := range created[:3] {
+				startAt, dueAt := day, day.Add(time.Duration(3-i)*24*time.Hour)
+				_, err := store.UpdateTask(UpdateTaskParams{
+					ID:       task.ID,
+					Name:     task.Name,
+					Priority: models.TaskPriority(i % 2),
+					StartAt:  &startAt,
+					DueAt:    &dueAt,
+				})
+				require.NoError(t, err)
+			}
+
+			// assert
+			low := models.TaskPriorityLow
+			tasks := listAll(t, store, ListTasksParams{Filter: TaskFilter{Priority: &low}})
+			require.Equal(t, []string{"b"}, names(tasks))
+
+			dueBefore := day.Add(3 * 24 * time.Hour)
+			tasks = listAll(t, store, ListTasksParams{Filter: TaskFilter{DueBefore: &dueBefore}})
+			require.Equal(t, []string{"b", "c"}, names(tasks))
+
+			tasks = listAll(t, store, ListTasksParams{Filter: TaskFilter{StartAfter: &day}})
+			require.Equal(t, []string{"a", "b", "c"}, names(tasks))
+
+			// the tasks without a due date come last
+			for _, limit := range []int{0, 1} {
+				tasks = listAll(t, store, ListTasksParams{Limit: limit, Sort: []TaskSort{{Field: models.TaskFieldDueAt}}})
+				require.Equal(t, []string{"c", "b", "a", "d"}, names(tasks), limit)
+			}
+
+			tasks = listAll(t, store, ListTasksParams{Sort: []TaskSort{{Field: models.TaskFieldPriority, Descending: true}}})
+			require.Equal(t, []string{"b", "a", "c", "d"}, names(tasks))
+		})
+
+		t.Run("sorted pages across writes", func(t *testing.T) {
+			store, created := prepare(t, "a", "c", "e")
+			sortByName := []TaskSort{{Field: models.TaskFieldName}}
+
+			// prepare
+			page, err := store.ListTasks(ListTasksParams{Limit: 2, Sort: sortByName})
+			require.NoError(t, err)
+			require.Equal(t, []string{"a", "c"}, names(page.Tasks))
+
+			_, err = store.CreateTask(CreateTaskParams{Name: "b"})
+			require.NoError(t, err)
+			_, err = store.CreateTask(CreateTaskParams{Name: "d"})
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteTask(DeleteTaskParams{ID: created[1].ID}))
+
+			// assert
+			page, err = store.ListTasks(ListTasksParams{Limit: 2, Sort: sortByName, Cursor: page.NextCursor})
+			require.NoError(t, err)
+			require.Equal(t, []string{"d", "e"}, names(page.Tasks))
+			require.Empty(t, page.NextCursor)
+		})
+
+		t.Run("cursor keys", func(t *testing.T) {
+			store, _ := prepare(t, "a", "b", "c")
+			sortByState := []TaskSort{{Field: models.TaskFieldState}}
+
+			// assert, along the course of a task
+			tasks := listAll(t, store, ListTasksParams{Limit: 1, Sort: sortByState})
+			require.Equal(t, []string{"a", "c", "b"}, names(tasks))
+
+			// a cursor only holds the keys of its order
+			page, err := store.ListTasks(ListTasksParams{Limit: 1, Sort: sortByState})
+			require.NoError(t, err)
+			buf, err := base64.RawURLEncoding.DecodeString(page.NextCursor)
+			require.NoError(t, err)
+			keys := models.PartialTask{}
+			require.NoError(t, json.Unmarshal(buf, &keys))
+			fields := []models.TaskField{}
+			for field := range keys {
+				fields = append(fields, field)
+			}
+			require.ElementsMatch(t, []models.TaskField{models.TaskFieldID, models.TaskFieldCreatedAt, models.TaskFieldState}, fields)
+		})
+
+		t.Run("fields", func(t *testing.T) {
+			store, _ := prepare(t, "a")
+			fields := []models.TaskField{models.TaskFieldName, models.TaskFieldStatus}
+
+			// assert
+			page, err := store.ListTasks(ListTasksParams{Fields: fields})
+			require.NoError(t, err)
+			require.Equal(t, fields, page.Fields)
+
+			page, err = store.ListTasks(ListTasksParams{Fields: fields, Sort: []TaskSort{{Field: models.TaskFieldName}}})
+			require.NoError(t, err)
+			require.Equal(t, fields, page.Fields)
+			require.Equal(t, []models.PartialTask{{"name": []byte(`"a"`), "status": []byte("0")}}, page.Data())
+		})
+
+		t.Run("invalid cursor", func(t *testing.T) {
+			store, _ := prepare(t, "a", "b")
+			sortByName := []TaskSort{{Field: models.TaskFieldName}}
+
+			// prepare
+			page, err := store.ListTasks(ListTasksParams{Limit: 1})
+			require.NoError(t, err)
+			sorted, err := store.ListTasks(ListTasksParams{Limit: 1, Sort: sortByName})
+			require.NoError(t, err)
+
+			// assert, the cursors of the two orders can't be mixed up
+			_, err = store.ListTasks(ListTasksParams{Cursor: page.NextCursor, Sort: sortByName})
+			require.ErrorIs(t, err, ErrInvalidCursor)
+			_, err = store.ListTasks(ListTasksParams{Cursor: sorted.NextCursor})
+			require.ErrorIs(t, err, ErrInvalidCursor)
+			_, err = store.ListTasks(ListTasksParams{Cursor: "?", Sort: sortByName})
+			require.ErrorIs(t, err, ErrInvalidCursor)
+
+			_, err = store.ListTasks(ListTasksParams{Sort: []TaskSort{{Field: "owner"}}})
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestTaskOrder(t *testing.T) {
+	t.Run("every field", func(t *testing.T) {
+		task := models.Task{}
+		require.NoError(t, gofakeit.Struct(&task))
+		buf, err := json.Marshal(task)
+		require.NoError(t, err)
+		all := models.PartialTask{}
+		require.NoError(t, json.Unmarshal(buf, &all))
+
+		// assert
+		for field := range all {
+			order := taskOrder{sorts: []TaskSort{{Field: field}}}
+			require.NoError(t, order.validate(), field)
+		}
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		now := time.Now()
+		tasks := []*models.Task{
+			{Name: "b", CreatedAt: now},
+			{Name: "a", CreatedAt: now.Add(time.Second)},
+			{Name: "a", CreatedAt: now},
+		}
+
+		order := taskOrder{sorts: []TaskSort{{Field: models.TaskFieldName}}}
+		sorted := slices.Clone(tasks)
+		slices.SortFunc(sorted, order.compare)
+		require.Equal(t, []*models.Task{tasks[2], tasks[1], tasks[0]}, sorted)
+
+		order.backward = true
+		slices.SortFunc(sorted, order.compare)
+		require.Equal(t, []*models.Task{tasks[0], tasks[1], tasks[2]}, sorted)
+	})
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"io"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+)
+
+// ReplicaProgress is how far a follower got in the changes of its primary
+type ReplicaProgress = db.ReplicaProgress
+
+// Replicate streams the changes after `after` to a follower until ctx is done,
+// starting with a copy of every record when after is nil or those changes are gone
+func (s *storeImpl) Replicate(ctx context.Context, after *uint64, w io.Writer) error {
+	replicator, err := capability[db.Replicator](s.db)
+	if err != nil {
+		return err
+	}
+
+	return db.WriteReplication(ctx, replicator, w, after)
+}
+
+// Follow applies a stream of Replicate until it ends
+func (s *storeImpl) Follow(r io.Reader, progress func(ReplicaProgress)) error {
+	replicator, err := capability[db.Replicator](s.db)
+	if err != nil {
+		return err
+	}
+
+	return db.ReadReplication(replicator, Schema, r, progress)
+}
+
+// Seq returns the sequence of the last change
+func (s *storeImpl) Seq() (uint64, error) {
+	snapshotter, err := capability[db.Snapshotter](s.db)
+	if err != nil {
+		return 0, err
+	}
+	snapshot, err := snapshotter.OpenSnapshot()
+	if err != nil {
+		return 0, err
+	}
+	defer snapshot.Close()
+
+	return snapshot.Seq(), nil
+}
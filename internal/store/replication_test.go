@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicate(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			primary, err := New(newDatabase(t))
+			require.NoError(t, err)
+			follower, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			milk, err := primary.CreateTask(CreateTaskParams{Name: "Buy milk"})
+			require.NoError(t, err)
+			_, err = primary.CreateTask(CreateTaskParams{Name: "Call mom", Status: models.TaskStatusCompleted})
+			require.NoError(t, err)
+
+			r, w := io.Pipe()
+			go func() {
+				primary.Replicate(ctx, nil, w)
+				w.Close()
+			}()
+			go follower.Follow(r, func(ReplicaProgress) {})
+
+			caughtUp := func() bool {
+				seq, err := primary.Seq()
+				require.NoError(t, err)
+				followerSeq, err := follower.Seq()
+				require.NoError(t, err)
+				return seq == followerSeq
+			}
+
+			// assert, the indexes of the follower are up to date
+			require.Eventually(t, caughtUp, time.Second, 10*time.Millisecond)
+			expected, err := primary.ListTasksByStatus(models.TaskStatusCompleted)
+			require.NoError(t, err)
+			completed, err := follower.ListTasksByStatus(models.TaskStatusCompleted)
+			require.NoError(t, err)
+			require.Equal(t, expected, completed)
+
+			milk, err = primary.UpdateTask(UpdateTaskParams{ID: milk.ID, Name: "Buy oat milk"})
+			require.NoError(t, err)
+			require.Eventually(t, caughtUp, time.Second, 10*time.Millisecond)
+
+			task, err := follower.GetTask(milk.ID)
+			require.NoError(t, err)
+			require.Equal(t, milk, task)
+
+			matches, err := follower.SearchTasks(SearchTasksParams{Query: "oat"})
+			require.NoError(t, err)
+			require.Len(t, matches, 1)
+			require.Equal(t, milk, matches[0].Task)
+		})
+	})
+}
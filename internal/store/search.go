@@ -0,0 +1,184 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/google/uuid"
+)
+
+// tasksByWord is the inverted index of the words of task names
+var tasksByWord = tasks.Index("name_words", func(task *models.Task) []string {
+	return words(task.Name)
+})
+
+const (
+	// scoreExact is the score of a term matching a whole word, scorePrefix of a term starting a word
+	scoreExact  = 2
+	scorePrefix = 1
+)
+
+// token is a case folded word of a text, at [start, end) in characters
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokenize splits text into runs of letters and digits
+func tokenize(text string) []token {
+	tokens := []token{}
+	var b strings.Builder
+	start, i := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if b.Len() == 0 {
+				start = i
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else if b.Len() > 0 {
+			tokens = append(tokens, token{word: b.String(), start: start, end: i})
+			b.Reset()
+		}
+		i++
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, token{word: b.String(), start: start, end: i})
+	}
+
+	return tokens
+}
+
+// words returns the distinct words of text
+func words(text string) []string {
+	words := []string{}
+	seen := map[string]bool{}
+	for _, t := range tokenize(text) {
+		if !seen[t.word] {
+			seen[t.word] = true
+			words = append(words, t.word)
+		}
+	}
+
+	return words
+}
+
+// match scores name against terms, a term matches the words it starts.
+// ok is false unless every term matches.
+func match(name string, terms []string) (score int, highlights []models.Highlight, ok bool) {
+	tokens := tokenize(name)
+	highlights = []models.Highlight{}
+
+	for _, term := range terms {
+		best := 0
+		for _, t := range tokens {
+			if t.word == term {
+				best = scoreExact
+			} else if strings.HasPrefix(t.word, term) {
+				best = max(best, scorePrefix)
+			}
+		}
+		if best == 0 {
+			return 0, nil, false
+		}
+		score += best
+	}
+
+	// the longest term starting a word is highlighted
+	for _, t := range tokens {
+		length := 0
+		for _, term := range terms {
+			if strings.HasPrefix(t.word, term) {
+				length = max(length, utf8.RuneCountInString(term))
+			}
+		}
+		if length > 0 {
+			highlights = append(highlights, models.Highlight{Start: t.start, End: min(t.start+length, t.end)})
+		}
+	}
+
+	return score, highlights, true
+}
+
+type SearchTasksParams struct {
+	// Query is split into terms like names, every term has to start a word of the name
+	Query string
+	// Limit is the max number of tasks, zero means no limit
+	Limit int
+}
+
+// SearchTasks ranks the tasks matching the query by score, then by the most recently updated
+func (s *storeImpl) SearchTasks(params SearchTasksParams) ([]*models.TaskMatch, error) {
+	terms := words(params.Query)
+	if len(terms) == 0 {
+		return []*models.TaskMatch{}, nil
+	}
+
+	// every lookup reads the same state, whatever is written meanwhile
+	snapshotter, err := capability[db.Snapshotter](s.db)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := snapshotter.OpenSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Close()
+
+	// the first term gives the candidates, the others narrow them down
+	candidates, err := tasksByWord.Find(snapshot, db.KeyPrefix(terms[0]))
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range terms[1:] {
+		if len(candidates) == 0 {
+			break
+		}
+
+		ids, err := tasksByWord.FindIDs(snapshot, db.KeyPrefix(term))
+		if err != nil {
+			return nil, err
+		}
+
+		found := make(map[uuid.UUID]bool, len(ids))
+		for _, id := range ids {
+			found[id] = true
+		}
+		candidates = filterTasks(candidates, func(task *models.Task) bool { return found[task.ID] })
+	}
+
+	matches := []*models.TaskMatch{}
+	for _, task := range candidates {
+		score, highlights, ok := match(task.Name, terms)
+		if !ok {
+			continue
+		}
+		matches = append(matches, &models.TaskMatch{Task: task, Score: score, Highlights: highlights})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Task.UpdatedAt.After(matches[j].Task.UpdatedAt)
+	})
+	if params.Limit > 0 && len(matches) > params.Limit {
+		matches = matches[:params.Limit]
+	}
+
+	return matches, nil
+}
+
+func filterTasks(list []*models.Task, keep func(*models.Task) bool) []*models.Task {
+	filtered := list[:0]
+	for _, task := range list {
+		if keep(task) {
+			filtered = append(filtered, task)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	require.Equal(t, []token{
+		{word: "buy", start: 0, end: 3},
+		{word: "2", start: 4, end: 5},
+		{word: "crème", start: 7, end: 12},
+		{word: "brûlée", start: 13, end: 19},
+	}, tokenize("Buy 2 «Crème-BRÛLÉE»"))
+	require.Empty(t, tokenize(" -- "))
+
+	require.Equal(t, []string{"milk", "and"}, words("milk and MILK"))
+}
+
+func TestSearchTasks(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+		setupSearch := func(t *testing.T, names ...string) (Store, []*models.Task) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			created := make([]*models.Task, 0, len(names))
+			for _, name := range names {
+				task, err := store.CreateTask(CreateTaskParams{Name: name})
+				require.NoError(t, err)
+				created = append(created, task)
+				// apart, so recency is deterministic
+				time.Sleep(time.Millisecond)
+			}
+
+			return store, created
+		}
+
+		taskIDs := func(matches []*models.TaskMatch) []string {
+			ids := make([]string, 0, len(matches))
+			for _, m := range matches {
+				ids = append(ids, m.Task.ID.String())
+			}
+			return ids
+		}
+
+		t.Run("ranked", func(t *testing.T) {
+			store, created := setupSearch(t, "Buy milk", "Milkshake recipe", "Call mom", "buy MILK and eggs")
+
+			// assert
+			matches, err := store.SearchTasks(SearchTasksParams{Query: "milk"})
+			require.NoError(t, err)
+
+			// exact words first, then the most recent
+			require.Equal(t, []string{created[3].ID.String(), created[0].ID.String(), created[1].ID.String()}, taskIDs(matches))
+			require.Equal(t, scoreExact, matches[0].Score)
+			require.Equal(t, []models.Highlight{{Start: 4, End: 8}}, matches[0].Highlights)
+			require.Equal(t, scorePrefix, matches[2].Score)
+			require.Equal(t, []models.Highlight{{Start: 0, End: 4}}, matches[2].Highlights)
+		})
+
+		t.Run("every term", func(t *testing.T) {
+			store, created := setupSearch(t, "Buy milk", "Buy bread", "buy MILK and eggs")
+
+			// assert
+			matches, err := store.SearchTasks(SearchTasksParams{Query: "BU mil"})
+			require.NoError(t, err)
+			require.Equal(t, []string{created[2].ID.String(), created[0].ID.String()}, taskIDs(matches))
+			require.Equal(t, 2*scorePrefix, matches[0].Score)
+			require.Equal(t, []models.Highlight{{Start: 0, End: 2}, {Start: 4, End: 7}}, matches[0].Highlights)
+
+			matches, err = store.SearchTasks(SearchTasksParams{Query: "buy milk", Limit: 1})
+			require.NoError(t, err)
+			require.Equal(t, []string{created[2].ID.String()}, taskIDs(matches))
+
+			matches, err = store.SearchTasks(SearchTasksParams{Query: "buy cheese"})
+			require.NoError(t, err)
+			require.Empty(t, matches)
+
+			matches, err = store.SearchTasks(SearchTasksParams{Query: " ?! "})
+			require.NoError(t, err)
+			require.NotNil(t, matches)
+			require.Empty(t, matches)
+		})
+
+		t.Run("in sync with writes", func(t *testing.T) {
+			store, created := setupSearch(t, "Buy milk", "Walk the dog")
+
+			// prepare
+			_, err := store.UpdateTask(UpdateTaskParams{ID: created[1].ID, Name: "Buy dog food"})
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteTask(DeleteTaskParams{ID: created[0].ID}))
+
+			// assert
+			matches, err := store.SearchTasks(SearchTasksParams{Query: "buy"})
+			require.NoError(t, err)
+			require.Equal(t, []string{created[1].ID.String()}, taskIDs(matches))
+			require.Equal(t, "Buy dog food", matches[0].Task.Name)
+
+			matches, err = store.SearchTasks(SearchTasksParams{Query: "walk"})
+			require.NoError(t, err)
+			require.Empty(t, matches)
+
+			// restored tasks are found again
+			_, err = store.RestoreTask(created[0].ID)
+			require.NoError(t, err)
+			matches, err = store.SearchTasks(SearchTasksParams{Query: "milk"})
+			require.NoError(t, err)
+			require.Equal(t, []string{created[0].ID.String()}, taskIDs(matches))
+		})
+	})
+}
@@ -1,30 +1,101 @@
 package store
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"time"
+
 	"github.com/dragon-huang0403/todo-go/internal/db"
 	"github.com/dragon-huang0403/todo-go/internal/models"
 	"github.com/google/uuid"
 )
 
 var (
-	ErrNotFound = db.ErrNotFound
-	ErrConflict = db.ErrAlreadyExists
+	ErrNotFound      = db.ErrNotFound
+	ErrConflict      = db.ErrConflict
+	ErrInvalidCursor = db.ErrInvalidCursor
+	ErrFeedTruncated = db.ErrFeedTruncated
+	ErrInvalidBackup = db.ErrInvalidBackup
+	ErrQuotaExceeded = db.ErrQuotaExceeded
+	// ErrProjectNotFound is returned when a task is put in a project which doesn't exist or is in the trash
+	ErrProjectNotFound = fmt.Errorf("project %w", db.ErrNotFound)
 )
 
+// Schema tells the persistent database how to decode every model the store writes
+var Schema = db.Schema{
+	tasks.Model():    tasks.New,
+	projects.Model(): projects.New,
+}
+
 type Store interface {
 	GetTask(uuid.UUID) (*models.Task, error)
-	ListTasks() ([]*models.Task, error)
+	ListTasks(ListTasksParams) (*models.TaskPage, error)
+	ListTasksByStatus(models.TaskStatus) ([]*models.Task, error)
+	SearchTasks(SearchTasksParams) ([]*models.TaskMatch, error)
 	CreateTask(CreateTaskParams) (*models.Task, error)
 	UpdateTask(UpdateTaskParams) (*models.Task, error)
-	DeleteTask(uuid.UUID) error
+	MoveTask(MoveTaskParams) (*models.Task, error)
+	DeleteTask(DeleteTaskParams) error
+	ListTrashedTasks() ([]*models.TrashedTask, error)
+	RestoreTask(uuid.UUID) (*models.Task, error)
+	PurgeTask(uuid.UUID) error
+	PurgeTrash(before time.Time) (int, error)
+	WatchTasks(context.Context, WatchTasksParams) (<-chan models.TaskEvent, error)
+
+	GetProject(uuid.UUID) (*models.Project, error)
+	ListProjects() ([]*models.Project, error)
+	CreateProject(CreateProjectParams) (*models.Project, error)
+	UpdateProject(UpdateProjectParams) (*models.Project, error)
+	DeleteProject(DeleteProjectParams) error
+	ListTrashedProjects() ([]*models.TrashedProject, error)
+	RestoreProject(uuid.UUID) (*models.Project, error)
+	PurgeProject(uuid.UUID) error
+
+	Backup(io.Writer) error
+	Restore(io.Reader, ConflictPolicy) (*models.RestoreResult, error)
+	Usage() []*models.Usage
+
+	Replicate(ctx context.Context, after *uint64, w io.Writer) error
+	Follow(r io.Reader, progress func(ReplicaProgress)) error
+	Seq() (uint64, error)
 }
 
 type storeImpl struct {
 	db db.Database
 }
 
-func New(database db.Database) Store {
+// taskIndexes are kept by the database on every write of a task
+var taskIndexes = []db.Index[models.Task]{tasksByStatus, tasksByWord, tasksByProject}
+
+// New registers the indexes the store queries on database
+func New(database db.Database) (Store, error) {
+	for _, index := range taskIndexes {
+		if err := index.Register(database); err != nil {
+			return nil, fmt.Errorf("failed to register index %s: %w", index.Name(), err)
+		}
+	}
+
 	return &storeImpl{
 		db: database,
+	}, nil
+}
+
+// capability returns the database as T, an optional capability of the database
+func capability[T any](database db.Database) (T, error) {
+	c, ok := database.(T)
+	if !ok {
+		return c, fmt.Errorf("%w: %s", db.ErrNotSupported, reflect.TypeFor[T]())
+	}
+	return c, nil
+}
+
+// checkVersion fails with ErrConflict unless version is one of versions, none skips the check
+func checkVersion(version uint64, versions []uint64) error {
+	if len(versions) == 0 || slices.Contains(versions, version) {
+		return nil
 	}
+	return db.ErrVersionMismatch
 }
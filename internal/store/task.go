@@ -1,6 +1,8 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/dragon-huang0403/todo-go/internal/db"
@@ -8,49 +10,126 @@ import (
 	"github.com/google/uuid"
 )
 
+var tasks = db.NewCollection[models.Task](db.Task)
+
 func (s *storeImpl) GetTask(id uuid.UUID) (*models.Task, error) {
-	task, err := s.db.Get(db.Task, id)
-	if err != nil {
-		return nil, err
-	}
+	return tasks.Get(s.db, id)
+}
+
+type ListTasksParams struct {
+	// Cursor is the NextCursor of the previous page, empty starts from the beginning
+	Cursor string
+	// Limit is the max number of tasks in the page, zero means no limit
+	Limit int
+	// Backward lists from the newest task, or reverses Sort
+	Backward bool
 
-	return models.Task{}.FromDB(task)
+	Filter TaskFilter
+	// Sort orders the tasks by each field in turn, empty lists them in create order
+	Sort []TaskSort
+	// Fields of the tasks to return, empty returns every field
+	Fields []models.TaskField
 }
 
-func (s *storeImpl) ListTasks() ([]*models.Task, error) {
-	tasks, err := s.db.List(db.Task)
-	if err != nil {
+func (s *storeImpl) ListTasks(params ListTasksParams) (*models.TaskPage, error) {
+	if err := checkProject(s.db, params.Filter.ProjectID); err != nil {
 		return nil, err
 	}
 
-	result := make([]*models.Task, 0, len(tasks))
-	for _, task := range tasks {
-		t, err := models.Task{}.FromDB(task)
-		if err != nil {
-			return nil, err
+	if len(params.Sort) > 0 {
+		return s.listSortedTasks(params)
+	}
+
+	opts := db.ListOptions{
+		After:     db.Cursor(params.Cursor),
+		Limit:     params.Limit,
+		Direction: db.DirectionForward,
+	}
+	if params.Backward {
+		opts.Direction = db.DirectionBackward
+	}
+	if filter := params.Filter; !filter.empty() {
+		opts.Filter = func(value interface{}) bool {
+			task, ok := value.(*models.Task)
+			return ok && filter.match(task)
 		}
+	}
 
-		result = append(result, t)
+	list, next, err := tasks.ListRange(s.db, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return &models.TaskPage{Tasks: list, NextCursor: string(next), Fields: params.Fields}, nil
+}
+
+var tasksByStatus = tasks.Index("status", func(task *models.Task) []string {
+	return []string{statusKey(task.Status)}
+})
+
+func statusKey(status models.TaskStatus) string {
+	return db.IntKey(int64(status))
+}
+
+// ListTasksByStatus lists the tasks with status in create order
+func (s *storeImpl) ListTasksByStatus(status models.TaskStatus) ([]*models.Task, error) {
+	return tasksByStatus.Find(s.db, db.KeyEquals(statusKey(status)))
 }
 
 type CreateTaskParams struct {
 	Name   string
 	Status models.TaskStatus
+	// State of the task, the state of Status when empty
+	State models.TaskState
+	// the task is deleted for good at ExpiresAt, nil never expires
+	ExpiresAt *time.Time
+
+	Description string
+	Priority    models.TaskPriority
+	StartAt     *time.Time
+	DueAt       *time.Time
+
+	// the task is created in the project, nil belongs to none, see ErrProjectNotFound
+	ProjectID *uuid.UUID
 }
 
 func (s *storeImpl) CreateTask(params CreateTaskParams) (*models.Task, error) {
 	task := &models.Task{
 		ID:        uuid.New(),
 		Name:      params.Name,
-		Status:    params.Status,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
+		ExpiresAt: utc(params.ExpiresAt),
+
+		Description: params.Description,
+		Priority:    params.Priority,
+		StartAt:     utc(params.StartAt),
+		DueAt:       utc(params.DueAt),
+
+		ProjectID: params.ProjectID,
+	}
+
+	state := params.State
+	if state == "" {
+		state = params.Status.State("")
+	}
+	task.SetState(state, task.CreatedAt)
+
+	if params.ProjectID == nil {
+		if err := tasks.Create(s.db, task.ID, task); err != nil {
+			return nil, err
+		}
+		return task, nil
 	}
 
-	if err := s.db.Create(db.Task, task.ID, task); err != nil {
+	// the project can't be deleted in between
+	err := s.db.RunInTx(func(tx db.Tx) error {
+		if err := checkProject(tx, params.ProjectID); err != nil {
+			return err
+		}
+		return tasks.Create(tx, task.ID, task)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -61,25 +140,273 @@ type UpdateTaskParams struct {
 	ID     uuid.UUID
 	Name   string
 	Status models.TaskStatus
+	// State the task moves to, the state of Status from the current one when empty
+	State models.TaskState
+	// Transition fails the update when the task can't move from its state to another, nil allows every move
+	Transition func(from, to models.TaskState) error
+	// ExpiresAt replaces the expiry of the task, nil never expires
+	ExpiresAt *time.Time
+
+	// the details replace the ones of the task, nil clears a date
+	Description string
+	Priority    models.TaskPriority
+	StartAt     *time.Time
+	DueAt       *time.Time
+
+	// the update fails with ErrConflict unless the task is still at one of Versions, none skips the check
+	Versions []uint64
 }
 
 func (s *storeImpl) UpdateTask(params UpdateTaskParams) (*models.Task, error) {
-	task, err := s.GetTask(params.ID)
+	var task *models.Task
+	err := s.db.RunInTx(func(tx db.Tx) error {
+		var err error
+		task, err = tasks.Get(tx, params.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(task.Version, params.Versions); err != nil {
+			return err
+		}
+
+		state := params.State
+		if state == "" {
+			state = params.Status.State(task.State)
+		}
+		if state != task.State && params.Transition != nil {
+			if err := params.Transition(task.State, state); err != nil {
+				return err
+			}
+		}
+
+		task.Name = params.Name
+		task.ExpiresAt = utc(params.ExpiresAt)
+		task.Description = params.Description
+		task.Priority = params.Priority
+		task.StartAt = utc(params.StartAt)
+		task.DueAt = utc(params.DueAt)
+		task.UpdatedAt = time.Now().UTC()
+		task.SetState(state, task.UpdatedAt)
+
+		return tasks.Update(tx, task.ID, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+type MoveTaskParams struct {
+	ID uuid.UUID
+	// ProjectID is the project the task moves to, nil takes it out of its project, see ErrProjectNotFound
+	ProjectID *uuid.UUID
+
+	// the move fails with ErrConflict unless the task is still at one of Versions, none skips the check
+	Versions []uint64
+}
+
+// MoveTask moves the task to another project
+func (s *storeImpl) MoveTask(params MoveTaskParams) (*models.Task, error) {
+	var task *models.Task
+	err := s.db.RunInTx(func(tx db.Tx) error {
+		var err error
+		task, err = tasks.Get(tx, params.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(task.Version, params.Versions); err != nil {
+			return err
+		}
+
+		if err := checkProject(tx, params.ProjectID); err != nil {
+			return err
+		}
+
+		task.ProjectID = params.ProjectID
+		task.UpdatedAt = time.Now().UTC()
+
+		return tasks.Update(tx, task.ID, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// utc copies t in UTC, like the other times of a task
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+type DeleteTaskParams struct {
+	ID uuid.UUID
+	// the delete fails with ErrConflict unless the task is still at one of Versions, none skips the check
+	Versions []uint64
+}
+
+// DeleteTask moves the task to the trash
+func (s *storeImpl) DeleteTask(params DeleteTaskParams) error {
+	if len(params.Versions) == 0 {
+		return tasks.Trash(s.db, params.ID)
+	}
+
+	return s.db.RunInTx(func(tx db.Tx) error {
+		task, err := tasks.Get(tx, params.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(task.Version, params.Versions); err != nil {
+			return err
+		}
+
+		return tasks.Trash(tx, params.ID)
+	})
+}
+
+func (s *storeImpl) ListTrashedTasks() ([]*models.TrashedTask, error) {
+	trashed, err := tasks.ListTrash(s.db)
 	if err != nil {
 		return nil, err
 	}
 
-	task.Name = params.Name
-	task.Status = params.Status
-	task.UpdatedAt = time.Now().UTC()
+	list := make([]*models.TrashedTask, 0, len(trashed))
+	for _, item := range trashed {
+		list = append(list, &models.TrashedTask{Task: *item.Value, DeletedAt: item.DeletedAt})
+	}
+
+	return list, nil
+}
 
-	if err := s.db.Update(db.Task, task.ID, task); err != nil {
+// RestoreTask moves the task out of the trash, back to its position in the list.
+// A task whose project is gone is taken out of it.
+func (s *storeImpl) RestoreTask(id uuid.UUID) (*models.Task, error) {
+	var task *models.Task
+	err := s.db.RunInTx(func(tx db.Tx) error {
+		if err := tasks.Restore(tx, id); err != nil {
+			return err
+		}
+
+		var err error
+		task, err = tasks.Get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkProject(tx, task.ProjectID)
+		if !errors.Is(err, ErrProjectNotFound) {
+			return err
+		}
+		task.ProjectID = nil
+		return tasks.Update(tx, task.ID, task)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return task, nil
 }
 
-func (s *storeImpl) DeleteTask(id uuid.UUID) error {
-	return s.db.Delete(db.Task, id)
+// PurgeTask removes a task of the trash for good
+func (s *storeImpl) PurgeTask(id uuid.UUID) error {
+	return tasks.Purge(s.db, id)
+}
+
+// PurgeTrash removes the tasks and projects deleted before the given time for good,
+// the tasks deleted along with a project are removed with it
+func (s *storeImpl) PurgeTrash(before time.Time) (int, error) {
+	purged := 0
+	err := s.db.RunInTx(func(tx db.Tx) error {
+		trashedProjects, err := projects.ListTrash(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range trashedProjects {
+			if !item.DeletedAt.Before(before) {
+				continue
+			}
+			trashedTasks, err := trashedTasksOf(tx, item.ID)
+			if err != nil {
+				return err
+			}
+			if err := purgeProject(tx, item.ID); err != nil {
+				return err
+			}
+			purged += 1 + len(trashedTasks)
+		}
+
+		trashed, err := tasks.ListTrash(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range trashed {
+			if !item.DeletedAt.Before(before) {
+				continue
+			}
+			if err := tasks.Purge(tx, item.ID); err != nil {
+				return err
+			}
+			purged++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+type WatchTasksParams struct {
+	// After resumes after the event with this sequence, nil only streams new changes
+	After *uint64
+}
+
+var taskEventTypes = map[db.EventKind]models.TaskEventType{
+	db.EventCreate:  models.TaskCreated,
+	db.EventUpdate:  models.TaskUpdated,
+	db.EventTrash:   models.TaskDeleted,
+	db.EventRestore: models.TaskRestored,
+	db.EventPurge:   models.TaskPurged,
+	db.EventDelete:  models.TaskPurged,
+}
+
+// WatchTasks streams the changes of tasks until ctx is done. The channel is also
+// closed when the subscriber falls too far behind, it can resume from the last Seq.
+func (s *storeImpl) WatchTasks(ctx context.Context, params WatchTasksParams) (<-chan models.TaskEvent, error) {
+	watcher, err := capability[db.Watcher](s.db)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := watcher.Watch(ctx, db.WatchOptions{
+		After:  params.After,
+		Models: []db.Model{tasks.Model()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan models.TaskEvent)
+	go func() {
+		defer close(events)
+
+		for e := range subscription.Events() {
+			select {
+			case events <- models.TaskEvent{Seq: e.Seq, Type: taskEventTypes[e.Kind], Task: tasks.EventValue(e)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
 }
@@ -1,6 +1,9 @@
 package store
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -72,25 +75,122 @@ func TestListTasks(t *testing.T) {
 		}
 
 		// stubs
-		m.mockDB.EXPECT().List(db.Task).Return(mockReturned, nil)
+		m.mockDB.EXPECT().
+			ListRange(db.Task, db.ListOptions{Direction: db.DirectionForward}).
+			Return(db.Page{Values: mockReturned}, nil)
 
 		// assert
-		tasks, err := m.store.ListTasks()
+		page, err := m.store.ListTasks(ListTasksParams{})
 		require.NoError(t, err)
-		require.Equal(t, expectedTasks, tasks)
+		require.Equal(t, expectedTasks, page.Tasks)
+		require.Empty(t, page.NextCursor)
+	})
+
+	t.Run("page", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		task := &models.Task{ID: uuid.New(), Name: gofakeit.Name()}
+		params := ListTasksParams{
+			Cursor:   gofakeit.UUID(),
+			Limit:    gofakeit.Number(1, 10),
+			Backward: true,
+		}
+		next := db.Cursor(gofakeit.UUID())
+
+		// stubs
+		m.mockDB.EXPECT().
+			ListRange(db.Task, db.ListOptions{
+				After:     db.Cursor(params.Cursor),
+				Limit:     params.Limit,
+				Direction: db.DirectionBackward,
+			}).
+			Return(db.Page{Values: []interface{}{task}, Next: next}, nil)
+
+		// assert
+		page, err := m.store.ListTasks(params)
+		require.NoError(t, err)
+		require.Equal(t, []*models.Task{task}, page.Tasks)
+		require.Equal(t, string(next), page.NextCursor)
 	})
 
 	t.Run("no rows", func(t *testing.T) {
 		m := setup(t)
 
 		// stubs
-		m.mockDB.EXPECT().List(db.Task).Return([]interface{}{}, nil)
+		m.mockDB.EXPECT().
+			ListRange(db.Task, gomock.Any()).
+			Return(db.Page{Values: []interface{}{}}, nil)
+
+		// assert
+		page, err := m.store.ListTasks(ListTasksParams{})
+		require.NoError(t, err)
+		require.NotNil(t, page.Tasks)
+		require.Empty(t, page.Tasks)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		m := setup(t)
+
+		// stubs
+		m.mockDB.EXPECT().
+			ListRange(db.Task, gomock.Any()).
+			Return(db.Page{}, db.ErrInvalidCursor)
+
+		// assert
+		page, err := m.store.ListTasks(ListTasksParams{Cursor: "?"})
+		require.ErrorIs(t, err, ErrInvalidCursor)
+		require.Nil(t, page)
+	})
+}
+
+func TestListTasksByStatus(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		status := models.TaskStatus(gofakeit.Number(0, 1))
+		task := &models.Task{ID: uuid.New(), Name: gofakeit.Name(), Status: status}
+
+		// stubs
+		m.mockDB.EXPECT().
+			Find(db.Task, tasksByStatus.Name(), db.KeyEquals(statusKey(status))).
+			Return([]interface{}{task}, nil)
 
 		// assert
-		tasks, err := m.store.ListTasks()
+		list, err := m.store.ListTasksByStatus(status)
 		require.NoError(t, err)
-		require.NotNil(t, tasks)
-		require.Empty(t, tasks)
+		require.Equal(t, []*models.Task{task}, list)
+	})
+
+	t.Run("index", func(t *testing.T) {
+		forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			created := make([]*models.Task, 0, 4)
+			for _, status := range []models.TaskStatus{0, 1, 0, 1} {
+				task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), Status: status})
+				require.NoError(t, err)
+				created = append(created, task)
+			}
+
+			_, err = store.UpdateTask(UpdateTaskParams{ID: created[0].ID, Name: created[0].Name, Status: models.TaskStatusCompleted})
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteTask(DeleteTaskParams{ID: created[3].ID}))
+
+			// assert
+			list, err := store.ListTasksByStatus(models.TaskStatusIncomplete)
+			require.NoError(t, err)
+			require.Equal(t, []*models.Task{created[2]}, list)
+
+			list, err = store.ListTasksByStatus(models.TaskStatusCompleted)
+			require.NoError(t, err)
+			require.Len(t, list, 2)
+			require.Equal(t, created[0].ID, list[0].ID)
+			require.Equal(t, created[1], list[1])
+		})
 	})
 }
 
@@ -120,6 +220,42 @@ func TestCreateTask(t *testing.T) {
 	})
 }
 
+func TestTaskExpiry(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+		t.Run("ok", func(t *testing.T) {
+			database := newDatabase(t)
+			store, err := New(database)
+			require.NoError(t, err)
+
+			// prepare
+			expiresAt := time.Now().Add(50 * time.Millisecond)
+			expiring, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ExpiresAt: &expiresAt})
+			require.NoError(t, err)
+			kept, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), ExpiresAt: &expiresAt})
+			require.NoError(t, err)
+
+			// updating without an expiry keeps the task forever
+			kept, err = store.UpdateTask(UpdateTaskParams{ID: kept.ID, Name: kept.Name})
+			require.NoError(t, err)
+			require.Nil(t, kept.ExpiresAt)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go database.(db.Sweeper).RunSweeper(ctx)
+
+			// assert
+			require.Equal(t, expiresAt.UTC(), *expiring.ExpiresAt)
+			require.Eventually(t, func() bool {
+				_, err := store.GetTask(expiring.ID)
+				return errors.Is(err, ErrNotFound)
+			}, time.Second, 10*time.Millisecond)
+
+			_, err = store.GetTask(kept.ID)
+			require.NoError(t, err)
+		})
+	})
+}
+
 func TestUpdateTask(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		m := setup(t)
@@ -141,8 +277,9 @@ func TestUpdateTask(t *testing.T) {
 		}
 
 		// stubs
-		m.mockDB.EXPECT().Get(db.Task, taskID).Return(oldTask, nil)
-		m.mockDB.EXPECT().Update(db.Task, taskID, gomock.Any()).Return(nil)
+		m.expectTx()
+		m.mockTx.EXPECT().Get(db.Task, taskID).Return(oldTask, nil)
+		m.mockTx.EXPECT().Update(db.Task, taskID, gomock.Any()).Return(nil)
 
 		// assert
 		task, err := m.store.UpdateTask(arg)
@@ -168,13 +305,127 @@ func TestUpdateTask(t *testing.T) {
 		}
 
 		// stubs
-		m.mockDB.EXPECT().Get(db.Task, taskID).Return(nil, db.ErrNotFound)
+		m.expectTx()
+		m.mockTx.EXPECT().Get(db.Task, taskID).Return(nil, db.ErrNotFound)
 
 		// assert
 		task, err := m.store.UpdateTask(arg)
 		require.ErrorIs(t, err, ErrNotFound)
 		require.Nil(t, task)
 	})
+
+	t.Run("with version", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		taskID := uuid.New()
+		version := uint64(gofakeit.Number(1, 10))
+		arg := UpdateTaskParams{
+			ID:       taskID,
+			Name:     gofakeit.Name(),
+			Status:   models.TaskStatus(gofakeit.Number(0, 1)),
+			Versions: []uint64{version},
+		}
+
+		// stubs
+		m.expectTx()
+		m.mockTx.EXPECT().Get(db.Task, taskID).Return(&models.Task{ID: taskID, Version: version + 1}, nil)
+
+		// assert
+		task, err := m.store.UpdateTask(arg)
+		require.ErrorIs(t, err, ErrConflict)
+		require.Nil(t, task)
+	})
+}
+
+func TestTaskState(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, newDatabase func(t *testing.T) db.Database) {
+		t.Run("ok", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name(), Status: models.TaskStatusCompleted})
+			require.NoError(t, err)
+			require.Equal(t, models.TaskStateDone, task.State)
+			require.Equal(t, task.CreatedAt, *task.CompletedAt)
+
+			// assert
+			task, err = store.UpdateTask(UpdateTaskParams{ID: task.ID, Name: task.Name, State: models.TaskStateInProgress})
+			require.NoError(t, err)
+			require.Equal(t, models.TaskStatusIncomplete, task.Status)
+			require.Nil(t, task.CompletedAt)
+
+			// the status of the current state keeps it
+			task, err = store.UpdateTask(UpdateTaskParams{ID: task.ID, Name: task.Name, Status: models.TaskStatusIncomplete})
+			require.NoError(t, err)
+			require.Equal(t, models.TaskStateInProgress, task.State)
+
+			task, err = store.UpdateTask(UpdateTaskParams{ID: task.ID, Name: task.Name, State: models.TaskStateCancelled})
+			require.NoError(t, err)
+			require.Equal(t, models.TaskStatusCompleted, task.Status)
+			require.Equal(t, task.UpdatedAt, *task.CancelledAt)
+
+			// the time of cancellation only changes with the state
+			cancelledAt := *task.CancelledAt
+			task, err = store.UpdateTask(UpdateTaskParams{ID: task.ID, Name: gofakeit.Name(), Status: models.TaskStatusCompleted})
+			require.NoError(t, err)
+			require.Equal(t, models.TaskStateCancelled, task.State)
+			require.Equal(t, cancelledAt, *task.CancelledAt)
+
+			completed := models.TaskStateCancelled
+			page, err := store.ListTasks(ListTasksParams{Filter: TaskFilter{State: &completed}})
+			require.NoError(t, err)
+			require.Equal(t, []*models.Task{task}, page.Tasks)
+		})
+
+		t.Run("transition", func(t *testing.T) {
+			store, err := New(newDatabase(t))
+			require.NoError(t, err)
+
+			// prepare
+			task, err := store.CreateTask(CreateTaskParams{Name: gofakeit.Name()})
+			require.NoError(t, err)
+			require.Equal(t, models.TaskStateTodo, task.State)
+
+			moves := [][2]models.TaskState{}
+			transition := func(from, to models.TaskState) error {
+				moves = append(moves, [2]models.TaskState{from, to})
+				if to == models.TaskStateDone {
+					return gofakeit.Error()
+				}
+				return nil
+			}
+
+			// assert, only a move to another state is checked
+			_, err = store.UpdateTask(UpdateTaskParams{ID: task.ID, Name: task.Name, Transition: transition})
+			require.NoError(t, err)
+			_, err = store.UpdateTask(UpdateTaskParams{ID: task.ID, Name: task.Name, State: models.TaskStateBlocked, Transition: transition})
+			require.NoError(t, err)
+			_, err = store.UpdateTask(UpdateTaskParams{ID: task.ID, Name: "failed", Status: models.TaskStatusCompleted, Transition: transition})
+			require.Error(t, err)
+
+			require.Equal(t, [][2]models.TaskState{
+				{models.TaskStateTodo, models.TaskStateBlocked},
+				{models.TaskStateBlocked, models.TaskStateDone},
+			}, moves)
+
+			task, err = store.GetTask(task.ID)
+			require.NoError(t, err)
+			require.Equal(t, models.TaskStateBlocked, task.State)
+			require.Equal(t, uint64(3), task.Version)
+		})
+	})
+
+	t.Run("written before the states", func(t *testing.T) {
+		task := &models.Task{}
+		require.NoError(t, json.Unmarshal([]byte(`{"name":"Buy milk","status":1}`), task))
+		require.Equal(t, models.TaskStateDone, task.State)
+
+		task = &models.Task{}
+		require.NoError(t, json.Unmarshal([]byte(`{"name":"Buy milk","status":1,"state":"cancelled"}`), task))
+		require.Equal(t, models.TaskStateCancelled, task.State)
+	})
 }
 
 func TestDeleteTask(t *testing.T) {
@@ -185,10 +436,10 @@ func TestDeleteTask(t *testing.T) {
 		taskID := uuid.New()
 
 		// stubs
-		m.mockDB.EXPECT().Delete(db.Task, taskID).Return(nil)
+		m.mockDB.EXPECT().Trash(db.Task, taskID).Return(nil)
 
 		// assert
-		err := m.store.DeleteTask(taskID)
+		err := m.store.DeleteTask(DeleteTaskParams{ID: taskID})
 		require.NoError(t, err)
 	})
 
@@ -199,10 +450,193 @@ func TestDeleteTask(t *testing.T) {
 		taskID := uuid.New()
 
 		// stubs
-		m.mockDB.EXPECT().Delete(db.Task, taskID).Return(db.ErrNotFound)
+		m.mockDB.EXPECT().Trash(db.Task, taskID).Return(db.ErrNotFound)
+
+		// assert
+		err := m.store.DeleteTask(DeleteTaskParams{ID: taskID})
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("with version", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		version := uint64(gofakeit.Number(1, 10))
+		task := &models.Task{ID: uuid.New(), Version: version}
+
+		// stubs
+		m.expectTx()
+		m.mockTx.EXPECT().Get(db.Task, task.ID).Return(task, nil)
+		m.mockTx.EXPECT().Trash(db.Task, task.ID).Return(nil)
+
+		// assert
+		err := m.store.DeleteTask(DeleteTaskParams{ID: task.ID, Versions: []uint64{version}})
+		require.NoError(t, err)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		version := uint64(gofakeit.Number(1, 10))
+		task := &models.Task{ID: uuid.New(), Version: version + 1}
+
+		// stubs
+		m.expectTx()
+		m.mockTx.EXPECT().Get(db.Task, task.ID).Return(task, nil)
+
+		// assert
+		err := m.store.DeleteTask(DeleteTaskParams{ID: task.ID, Versions: []uint64{version}})
+		require.ErrorIs(t, err, ErrConflict)
+	})
+}
+
+func TestListTrashedTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		task := &models.Task{ID: uuid.New(), Name: gofakeit.Name()}
+		deletedAt := gofakeit.Date()
+
+		// stubs
+		m.mockDB.EXPECT().
+			ListTrash(db.Task).
+			Return([]db.Trashed{{ID: task.ID, Value: task, DeletedAt: deletedAt}}, nil)
+
+		// assert
+		tasks, err := m.store.ListTrashedTasks()
+		require.NoError(t, err)
+		require.Equal(t, []*models.TrashedTask{{Task: *task, DeletedAt: deletedAt}}, tasks)
+	})
+}
+
+func TestRestoreTask(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		task := &models.Task{ID: uuid.New(), Name: gofakeit.Name()}
+
+		// stubs
+		m.expectTx()
+		m.mockTx.EXPECT().Restore(db.Task, task.ID).Return(nil)
+		m.mockTx.EXPECT().Get(db.Task, task.ID).Return(task, nil)
+
+		// assert
+		restored, err := m.store.RestoreTask(task.ID)
+		require.NoError(t, err)
+		require.Equal(t, task, restored)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+
+		// stubs
+		m.expectTx()
+		m.mockTx.EXPECT().Restore(db.Task, id).Return(db.ErrNotFound)
 
 		// assert
-		err := m.store.DeleteTask(taskID)
+		task, err := m.store.RestoreTask(id)
 		require.ErrorIs(t, err, ErrNotFound)
+		require.Nil(t, task)
+	})
+}
+
+func TestPurgeTask(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		id := uuid.New()
+
+		// stubs
+		m.mockDB.EXPECT().Purge(db.Task, id).Return(nil)
+
+		// assert
+		err := m.store.PurgeTask(id)
+		require.NoError(t, err)
+	})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// prepare
+		before := time.Now()
+		expired := db.Trashed{ID: uuid.New(), Value: &models.Task{}, DeletedAt: before.Add(-time.Minute)}
+		kept := db.Trashed{ID: uuid.New(), Value: &models.Task{}, DeletedAt: before.Add(time.Minute)}
+
+		// stubs
+		m.expectTx()
+		m.mockTx.EXPECT().ListTrash(db.Project).Return([]db.Trashed{}, nil)
+		m.mockTx.EXPECT().ListTrash(db.Task).Return([]db.Trashed{expired, kept}, nil)
+		m.mockTx.EXPECT().Purge(db.Task, expired.ID).Return(nil)
+
+		// assert
+		purged, err := m.store.PurgeTrash(before)
+		require.NoError(t, err)
+		require.Equal(t, 1, purged)
+	})
+}
+
+func TestWatchTasks(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// prepare
+		source := db.New()
+		after := uint64(gofakeit.Number(1, 10))
+		subscription, err := source.Watch(ctx, db.WatchOptions{})
+		require.NoError(t, err)
+
+		task := &models.Task{ID: uuid.New(), Name: gofakeit.Name()}
+
+		// stubs
+		m.mockWatcher.EXPECT().
+			Watch(gomock.Any(), db.WatchOptions{After: &after, Models: []db.Model{db.Task}}).
+			Return(subscription, nil)
+
+		// assert
+		events, err := m.store.WatchTasks(ctx, WatchTasksParams{After: &after})
+		require.NoError(t, err)
+
+		require.NoError(t, source.Create(db.Task, task.ID, task))
+		require.NoError(t, source.Trash(db.Task, task.ID))
+
+		task.Version = 1
+		require.Equal(t, models.TaskEvent{Seq: 1, Type: models.TaskCreated, Task: task}, <-events)
+		require.Equal(t, models.TaskEvent{Seq: 2, Type: models.TaskDeleted, Task: task}, <-events)
+
+		cancel()
+		for range events {
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		m := setup(t)
+
+		// stubs
+		m.mockWatcher.EXPECT().Watch(gomock.Any(), gomock.Any()).Return(nil, db.ErrFeedTruncated)
+
+		// assert
+		events, err := m.store.WatchTasks(context.Background(), WatchTasksParams{})
+		require.ErrorIs(t, err, ErrFeedTruncated)
+		require.Nil(t, events)
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		store := setupBare(t)
+
+		// assert
+		events, err := store.WatchTasks(context.Background(), WatchTasksParams{})
+		require.ErrorIs(t, err, db.ErrNotSupported)
+		require.Nil(t, events)
 	})
 }
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+)
+
+// Usage returns the usage of every model by name, none when the database doesn't account for it
+func (s *storeImpl) Usage() []*models.Usage {
+	limiter, err := capability[db.Limiter](s.db)
+	if err != nil {
+		return []*models.Usage{}
+	}
+	list := limiter.Usage()
+
+	usages := make([]*models.Usage, 0, len(list))
+	for _, usage := range list {
+		usages = append(usages, &models.Usage{
+			Model:      string(usage.Model),
+			Records:    usage.Records,
+			Bytes:      usage.Bytes,
+			MaxRecords: usage.Quota.MaxRecords,
+			MaxBytes:   usage.Quota.MaxBytes,
+		})
+	}
+
+	return usages
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/dragon-huang0403/todo-go/internal/db"
+	"github.com/dragon-huang0403/todo-go/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		m := setup(t)
+
+		// stubs
+		m.mockLimiter.EXPECT().Usage().Return([]db.Usage{{
+			Model:   db.Task,
+			Records: 2,
+			Bytes:   100,
+			Quota:   db.Quota{MaxRecords: 10, MaxBytes: 1000},
+		}})
+
+		// assert
+		require.Equal(t, []*models.Usage{{
+			Model:      "task",
+			Records:    2,
+			Bytes:      100,
+			MaxRecords: 10,
+			MaxBytes:   1000,
+		}}, m.store.Usage())
+	})
+
+	t.Run("not accounted", func(t *testing.T) {
+		store := setupBare(t)
+
+		// assert
+		require.Empty(t, store.Usage())
+	})
+}
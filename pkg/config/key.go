@@ -0,0 +1,27 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// ReadKeyFile reads a base64 encoded key of size bytes from path,
+// like the one written by `openssl rand -base64 32 > key`
+func ReadKeyFile(path string, size int) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	if err != nil {
+		return nil, fmt.Errorf("key file %s is not base64: %w", path, err)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("key file %s holds %d bytes instead of %d", path, len(key), size)
+	}
+
+	return key, nil
+}
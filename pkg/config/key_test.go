@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+
+	err := os.WriteFile(keyFile, []byte("AAECAwQFBgcICQoLDA0ODw==\n"), 0600)
+	require.NoError(t, err)
+
+	key, err := ReadKeyFile(keyFile, 16)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, key)
+
+	_, err = ReadKeyFile(keyFile, 32)
+	require.ErrorContains(t, err, "holds 16 bytes")
+
+	err = os.WriteFile(keyFile, []byte("not a key"), 0600)
+	require.NoError(t, err)
+	_, err = ReadKeyFile(keyFile, 16)
+	require.ErrorContains(t, err, "not base64")
+
+	_, err = ReadKeyFile(filepath.Join(t.TempDir(), "missing"), 16)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
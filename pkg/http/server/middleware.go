@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"context"
+	"crypto/subtle"
 
 	"github.com/dragon-huang0403/todo-go/pkg/logger"
 	"github.com/labstack/echo/v4"
@@ -46,3 +47,16 @@ func LogMiddleware(pctx context.Context) echo.MiddlewareFunc {
 		},
 	})
 }
+
+// TokenAuth only lets through the requests with `Authorization: Bearer <token>`
+func TokenAuth(token string) echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1, nil
+		},
+		// a missing token is as unauthorized as a wrong one
+		ErrorHandler: func(err error, c echo.Context) error {
+			return echo.ErrUnauthorized
+		},
+	})
+}
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Explain rewrites the errors of validating value into one sentence per field, naming
+// the fields after their query or json tag. Other errors are returned as they are.
+func Explain(value interface{}, err error) error {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, explainField(t, e))
+	}
+
+	return errors.New(strings.Join(messages, "; "))
+}
+
+func explainField(t reflect.Type, e validator.FieldError) string {
+	name := fieldName(t, e.StructField())
+	switch e.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", name)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", name, e.Param())
+	case "gte", "min":
+		return fmt.Sprintf("%s must be at least %s", name, e.Param())
+	case "lte", "max":
+		return fmt.Sprintf("%s must be at most %s", name, e.Param())
+	case "csvoneof":
+		return fmt.Sprintf("%s must be a comma separated list of [%s]", name, e.Param())
+	case "sortby":
+		return fmt.Sprintf("%s must be a comma separated list of [%s] without repeats, each optionally prefixed by - for descending order", name, e.Param())
+	case "afterfield":
+		return fmt.Sprintf("%s must be after %s", name, fieldName(t, e.Param()))
+	case "future":
+		return fmt.Sprintf("%s must be in the future", name)
+	default:
+		return fmt.Sprintf("%s is invalid (%s)", name, e.Tag())
+	}
+}
+
+// fieldName returns the query or json name of a field of t, or the Go name
+func fieldName(t reflect.Type, field string) string {
+	if t == nil || t.Kind() != reflect.Struct {
+		return field
+	}
+
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+	for _, tag := range []string{"query", "json"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return field
+}
@@ -0,0 +1,85 @@
+package validator
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func registerRules(v *validator.Validate) {
+	rules := map[string]validator.Func{
+		"csvoneof":   csvOneOf,
+		"sortby":     sortBy,
+		"afterfield": afterField,
+		"future":     future,
+	}
+	for tag, fn := range rules {
+		// only fails on an empty tag or a nil function
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// csvItems splits a comma separated list, ignoring the spaces around items
+func csvItems(value string) []string {
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
+
+// csvOneOf validates a comma separated list whose items are among the space separated param,
+// e.g. `validate:"csvoneof=id name"` accepts "name,id"
+func csvOneOf(fl validator.FieldLevel) bool {
+	allowed := strings.Fields(fl.Param())
+	for _, item := range csvItems(fl.Field().String()) {
+		if !slices.Contains(allowed, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortBy is csvoneof where every item may be prefixed by - to sort in descending order
+func sortBy(fl validator.FieldLevel) bool {
+	allowed := strings.Fields(fl.Param())
+	seen := map[string]bool{}
+	for _, item := range csvItems(fl.Field().String()) {
+		item = strings.TrimPrefix(item, "-")
+		if !slices.Contains(allowed, item) || seen[item] {
+			return false
+		}
+		seen[item] = true
+	}
+	return true
+}
+
+// afterField validates a time is after the time of the field named by param, when that one is set
+func afterField(fl validator.FieldLevel) bool {
+	other, kind, _, ok := fl.GetStructFieldOKAdvanced2(fl.Parent(), fl.Param())
+	if !ok || kind == reflect.Invalid || (kind == reflect.Ptr && other.IsNil()) {
+		return true
+	}
+
+	after, ok := other.Interface().(time.Time)
+	if !ok {
+		return false
+	}
+	value, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+
+	return value.After(after)
+}
+
+// future validates a time is after now
+func future(fl validator.FieldLevel) bool {
+	value, ok := fl.Field().Interface().(time.Time)
+	return ok && value.After(time.Now())
+}
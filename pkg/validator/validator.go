@@ -6,6 +6,7 @@ import (
 
 func New() *Validator {
 	v := validator.New(validator.WithRequiredStructEnabled())
+	registerRules(v)
 
 	return &Validator{validator: v}
 }
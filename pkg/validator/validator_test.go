@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type exampleQuery struct {
+	Limit  int        `query:"limit" validate:"lte=10"`
+	Fields string     `query:"fields" validate:"omitempty,csvoneof=id name"`
+	Sort   string     `query:"sort" validate:"omitempty,sortby=id name"`
+	From   *time.Time `query:"from"`
+	To     *time.Time `query:"to" validate:"omitempty,afterfield=From"`
+	Until  *time.Time `query:"until" validate:"omitempty,future"`
+	Name   string     `json:"name" validate:"required"`
+}
+
+func TestRules(t *testing.T) {
+	v := New()
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+
+	valid := []exampleQuery{
+		{Name: "a"},
+		{Name: "a", Fields: "name, id", Sort: "-name,id"},
+		{Name: "a", To: &now},
+		{Name: "a", From: &earlier, To: &now},
+		{Name: "a", Until: &later},
+	}
+	for _, query := range valid {
+		require.NoError(t, v.Validate(query), query)
+	}
+
+	invalid := []exampleQuery{
+		{Name: "a", Fields: "id,status"},
+		{Name: "a", Fields: "id,"},
+		{Name: "a", Sort: "name,-name"},
+		{Name: "a", Sort: "--id"},
+		{Name: "a", From: &now, To: &earlier},
+		{Name: "a", From: &now, To: &now},
+		{Name: "a", Until: &earlier},
+	}
+	for _, query := range invalid {
+		require.Error(t, v.Validate(query), query)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	v := New()
+	now := time.Now()
+	query := &exampleQuery{Limit: 11, Fields: "status", Sort: "x", From: &now, To: &now, Until: &now}
+
+	err := Explain(query, v.Validate(query))
+	require.EqualError(t, err, "limit must be at most 10; "+
+		"fields must be a comma separated list of [id name]; "+
+		"sort must be a comma separated list of [id name] without repeats, each optionally prefixed by - for descending order; "+
+		"to must be after from; "+
+		"until must be in the future; "+
+		"name is required")
+
+	other := errors.New("other")
+	require.Equal(t, other, Explain(query, other))
+}